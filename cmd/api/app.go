@@ -2,21 +2,44 @@
 package App
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	Routes "GraduationProject.com/m/internal/Routes"
+	"GraduationProject.com/m/internal/alerts"
+	"GraduationProject.com/m/internal/cache"
+	"GraduationProject.com/m/internal/config"
 	Database "GraduationProject.com/m/internal/db"
+	"GraduationProject.com/m/internal/geo"
 	Handlers "GraduationProject.com/m/internal/handler"
+	"GraduationProject.com/m/internal/logging"
+	"GraduationProject.com/m/internal/metrics"
+	"GraduationProject.com/m/internal/ratelimit"
+	"GraduationProject.com/m/internal/repository"
+	"GraduationProject.com/m/internal/search"
+	"GraduationProject.com/m/internal/tracing"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // App encapsulates Environment, Router, and DB connections
 type App struct {
+	Config                      config.Config
+	Logger                      *slog.Logger // Built from the config by Initialize when nil
 	Router                      *gin.Engine
 	DB                          *Database.DBExecutor
+	Repositories                repository.Repositories // Backed by the database by Initialize unless set before, like in-memory ones
 	UserHandler                 *Handlers.UserHandler
 	ReviewHandler               *Handlers.ReviewHandler
 	UnitHandler                 *Handlers.UnitHandler
@@ -26,41 +49,299 @@ type App struct {
 	MaintenanceTicketHandler    *Handlers.MaintenanceTicketHandler
 	PropertyHandler             *Handlers.PropertyHandler
 	MessageHandler              *Handlers.MessageHandler
+	SearchHandler               *Handlers.SearchHandler
+	WishlistHandler             *Handlers.WishlistHandler
+	NotificationHandler         *Handlers.NotificationHandler
+	AuditHandler                *Handlers.AuditHandler
+	HealthHandler               *Handlers.HealthHandler
+	SearchIndex                 *search.Index
+	GeoIndex                    *geo.Index
+	Alerts                      *alerts.Alerts
+	Metrics                     *metrics.Metrics // Nil when the metrics feature is off
+	Limiter                     ratelimit.Store  // Rate limits and login lockouts, kept in memory by Initialize when nil
+	flushTraces                 func(context.Context) error
+	stopSweeps                  context.CancelFunc
 }
 
 // Initialize sets up the database connection and the router
-func (a *App) Initialize(user, password, address, dbname string) {
-	connectionString := fmt.Sprintf("%s:%s@tcp(%s)/%s", user, password, address, dbname)
+func (a *App) Initialize(cfg config.Config) {
 	var err error
-	a.DB, err = Database.InitDB(connectionString)
+	a.Config = cfg
+	if a.Logger == nil {
+		a.Logger = logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	}
+	// Before the database, whose statements are traced
+	a.flushTraces, err = tracing.Setup(context.Background(), cfg.Tracing, os.Stdout)
+	if err != nil {
+		a.fatal("Could not set up tracing", err)
+	}
+	a.DB, err = Database.InitDB(cfg.Database)
 	if err != nil {
-		log.Fatal(err)
-	}
-	a.Router = gin.Default()
-	a.Router.Use(cors.Default()) // Add this line
-	a.UserHandler = Handlers.NewUserHandler(a.DB.Db)
-	a.UnitHandler = Handlers.NewUnitHandler(a.DB.Db)
-	a.ReviewHandler = Handlers.NewReviewHandler(a.DB.Db)
-	a.BookingHandler = Handlers.NewBookingHandler(a.DB.Db)
-	a.FinancialTransactionHandler = Handlers.NewFinancialTransactionHandler(a.DB.Db)
-	a.PropertyHandler = Handlers.NewPropertyHandler(a.DB.Db)
-	a.MessageHandler = Handlers.NewMessageHandler(a.DB.Db)
+		a.fatal("Could not connect to the database", err)
+	}
+	if a.DB.Dialect == Database.SQLite {
+		// Local databases are brought up to date on startup, an in-memory one starts empty every time
+		if _, err := Database.MigrateUp(a.DB); err != nil {
+			a.fatal("Could not migrate the database", err)
+		}
+	}
+	if cfg.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	a.Router = gin.New()
+	// Without trusted proxies the client IP is the peer address, clients could pick theirs in X-Forwarded-For otherwise
+	if err := a.Router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		a.fatal("Invalid trusted proxies", err)
+	}
+	if a.Limiter == nil {
+		a.Limiter = ratelimit.NewMemoryStore()
+	}
+	// Every request gets a span, an ID, an access log line and its metrics. Panics and unknown routes are
+	// answered with the same error envelope as every other failure, and logged by it.
+	a.Router.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(notProbe)), Handlers.AuditOrigin(), Handlers.RequestLog(a.Logger))
+	if cfg.Features.Metrics {
+		a.Metrics = metrics.New()
+		a.Metrics.CollectDB(a.DB.Db, databaseName(cfg.Database))
+		a.Router.Use(a.Metrics.Middleware())
+	}
+	a.Router.Use(gin.CustomRecoveryWithWriter(io.Discard, Handlers.Recovered))
+	a.Router.NoRoute(Handlers.RouteNotFound)
+	a.Router.Use(corsMiddleware(cfg.CORSOrigins), a.rateLimit("default", cfg.RateLimits.Default))
+	// Audited writes read the entity before and after from the database, so the cache goes in front
+	if a.Repositories.Users == nil {
+		a.Repositories = repository.NewSQLRepositories(a.DB.Db)
+	}
+	a.Repositories = repository.WithAudit(a.Repositories)
+	if a.Metrics != nil {
+		a.Repositories = repository.WithMetrics(a.Repositories, a.Metrics)
+	}
+	if cfg.Features.Cache {
+		a.Repositories = repository.WithCache(a.Repositories, cache.Options{TTL: cfg.Cache.TTL.Duration, MaxEntries: cfg.Cache.MaxEntries})
+	}
+	repos := a.Repositories
+	var logins *ratelimit.Lockout
+	if lockout := cfg.RateLimits.Lockout; lockout.Threshold > 0 {
+		logins = &ratelimit.Lockout{Store: a.Limiter, Threshold: lockout.Threshold, Duration: lockout.Duration.Duration, Delay: lockout.Delay.Duration}
+	}
+	a.UserHandler = Handlers.NewUserHandler(repos, a.reindex, logins)
+	a.SearchIndex = search.NewIndex()
+	a.GeoIndex = geo.NewIndex()
+	if cfg.Features.Alerts {
+		a.Alerts = alerts.New(repos.SavedSearches, repos.Wishlists, repos.Notifications)
+	}
+	a.UnitHandler = Handlers.NewUnitHandler(repos.Units, repos.Properties, a.SearchIndex, a.GeoIndex, a.Alerts)
+	a.ReviewHandler = Handlers.NewReviewHandler(repos.Reviews)
+	a.BookingHandler = Handlers.NewBookingHandler(repos.Bookings, repos.Units, repos.Properties, a.Alerts)
+	a.FinancialTransactionHandler = Handlers.NewFinancialTransactionHandler(repos.Transactions)
+	a.ReportHandler = Handlers.NewReportHandler(repos.Reports)
+	a.MaintenanceTicketHandler = Handlers.NewMaintenanceTicketHandler(repos.Tickets)
+	a.PropertyHandler = Handlers.NewPropertyHandler(repos.Properties, repos.Units, a.SearchIndex, a.reindex, a.UnitHandler.ReindexPropertyUnits)
+	a.MessageHandler = Handlers.NewMessageHandler(repos.Messages)
+	a.SearchHandler = Handlers.NewSearchHandler(a.SearchIndex)
+	a.WishlistHandler = Handlers.NewWishlistHandler(repos.Wishlists, repos.SavedSearches, repos.Units)
+	a.NotificationHandler = Handlers.NewNotificationHandler(repos.Notifications)
+	a.AuditHandler = Handlers.NewAuditHandler(repos.Audit)
+	a.HealthHandler = Handlers.NewHealthHandler(
+		Handlers.HealthCheck{Name: "database", Check: a.DB.Ping},
+		Handlers.HealthCheck{Name: "migrations", Check: a.migrationsAtHead},
+		Handlers.HealthCheck{Name: "storage", Check: a.storageReachable},
+	)
+	a.buildSearchIndex()
 	a.initializeRoutes()
+
+	var sweeps context.Context
+	sweeps, a.stopSweeps = context.WithCancel(context.Background())
+	go a.expireIdempotencyKeys(sweeps)
 }
 
 // InitializeRoutes sets up the routes for the application
 func (a *App) initializeRoutes() {
-	Routes.RegisterUserRoutes(a.Router, a.UserHandler)
-	Routes.RegisterReviewRoutes(a.Router, a.ReviewHandler)
-	Routes.RegisterUnitRoutes(a.Router, a.UnitHandler)
-	Routes.RegisterBookingRoutes(a.Router, a.BookingHandler)
-	Routes.RegisterFinancialTransactionRoutes(a.Router, a.FinancialTransactionHandler)
-	Routes.RegisterPropertyRoutes(a.Router, a.PropertyHandler)
-	Routes.RegisterMessageRoutes(a.Router, a.MessageHandler)
+	// Creates can be retried with an Idempotency-Key without creating twice
+	idempotent := Handlers.Idempotent(a.Repositories.Idempotency, a.Config.IdempotencyTTL.Duration)
+	Routes.RegisterUserRoutes(a.Router, a.UserHandler, a.rateLimit("login", a.Config.RateLimits.Login), idempotent)
+	Routes.RegisterReviewRoutes(a.Router, a.ReviewHandler, idempotent)
+	Routes.RegisterUnitRoutes(a.Router, a.UnitHandler, idempotent)
+	Routes.RegisterBookingRoutes(a.Router, a.BookingHandler, idempotent)
+	Routes.RegisterFinancialTransactionRoutes(a.Router, a.FinancialTransactionHandler, idempotent)
+	Routes.RegisterReportRoutes(a.Router, a.ReportHandler, idempotent)
+	Routes.RegisterMaintenanceTicketRoutes(a.Router, a.MaintenanceTicketHandler, idempotent)
+	Routes.RegisterPropertyRoutes(a.Router, a.PropertyHandler, idempotent)
+	Routes.RegisterMessageRoutes(a.Router, a.MessageHandler, a.rateLimit("messages", a.Config.RateLimits.Messages), idempotent)
+	Routes.RegisterSearchRoutes(a.Router, a.SearchHandler)
+	Routes.RegisterWishlistRoutes(a.Router, a.WishlistHandler, idempotent)
+	Routes.RegisterNotificationRoutes(a.Router, a.NotificationHandler)
+	Routes.RegisterDocsRoutes(a.Router)
+	Routes.RegisterHealthRoutes(a.Router, a.HealthHandler)
+	if a.Config.AdminToken != "" {
+		Routes.RegisterAdminRoutes(a.Router, a.Config.AdminToken.Value(), a.AuditHandler)
+	}
+	if a.Config.Features.DebugVars {
+		// Cache hit and miss counts, among the other published variables
+		a.Router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	}
+	if a.Metrics != nil {
+		a.Router.GET("/metrics", gin.WrapH(a.Metrics.Handler()))
+	}
+}
+
+// databaseName labels the connection pool metrics
+func databaseName(cfg config.Database) string {
+	if cfg.Driver == "sqlite" {
+		return cfg.Path
+	}
+	return cfg.Name
+}
+
+// rateLimit limits the requests to a group of routes, each group has its own buckets
+func (a *App) rateLimit(group string, limits config.RateLimitGroup) gin.HandlerFunc {
+	return Handlers.RateLimit(a.Limiter, Handlers.RateLimitPolicy{
+		Group:   group,
+		PerIP:   ratelimit.Limit{Requests: limits.PerIP.Requests, Per: limits.PerIP.Per.Duration},
+		PerUser: ratelimit.Limit{Requests: limits.PerUser.Requests, Per: limits.PerUser.Per.Duration},
+	})
+}
+
+// corsMiddleware allows the configured origins, or every origin when there are none or one of them is "*"
+func corsMiddleware(origins []string) gin.HandlerFunc {
+	for _, origin := range origins {
+		if origin == "*" {
+			return cors.Default()
+		}
+	}
+	if len(origins) == 0 {
+		return cors.Default()
+	}
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = origins
+	return cors.New(corsConfig)
+}
+
+// buildSearchIndex fills the search and map indexes from the database, the handlers keep them up to date afterwards
+func (a *App) buildSearchIndex() {
+	a.reindex(context.Background())
+	a.Logger.Info("Indexed units and properties for search", slog.Int("entries", a.SearchIndex.Len()))
+}
+
+// reindex refills the search and map indexes, after startup and after deletes that cascade to many units
+func (a *App) reindex(ctx context.Context) {
+	if err := a.UnitHandler.IndexUnits(ctx); err != nil {
+		logging.FromContext(ctx).Error("Failed to index units", slog.Any("error", err))
+	}
+	if err := a.PropertyHandler.IndexProperties(ctx); err != nil {
+		logging.FromContext(ctx).Error("Failed to index properties", slog.Any("error", err))
+	}
+}
+
+// expireIdempotencyKeys deletes the idempotency keys past their retention every hour, until ctx is done
+func (a *App) expireIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := a.Repositories.Idempotency.DeleteExpired(ctx, now)
+			if err != nil {
+				a.Logger.Error("Failed to delete expired idempotency keys", slog.Any("error", err))
+			} else if deleted > 0 {
+				a.Logger.Info("Deleted expired idempotency keys", slog.Int64("deleted", deleted))
+			}
+		}
+	}
 }
 
-// Run starts the server on a specified port
+// migrationsAtHead fails while the database lacks migrations this binary ships with
+func (a *App) migrationsAtHead(ctx context.Context) error {
+	pending, err := Database.PendingMigrations(a.DB)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		newest := pending[len(pending)-1]
+		return fmt.Errorf("%d migrations are pending, up to %04d %s", len(pending), newest.Version, newest.Name)
+	}
+	return nil
+}
+
+// storageReachable checks where images and proofs are kept
+func (a *App) storageReachable(ctx context.Context) error {
+	switch a.Config.StorageBackend {
+	case "database":
+		return a.DB.Reachable(ctx, "Images")
+	}
+	return fmt.Errorf("unknown storage backend %q", a.Config.StorageBackend)
+}
+
+// Run serves on addr until SIGTERM or SIGINT, then stops taking new connections, lets the requests in
+// flight finish for up to the shutdown timeout and closes the database
 func (a *App) Run(addr string) {
-	log.Printf("Listening on %s\n", addr)
-	log.Fatal(a.Router.Run(addr))
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      a.Router,
+		ReadTimeout:  a.Config.Server.ReadTimeout.Duration,
+		WriteTimeout: a.Config.Server.WriteTimeout.Duration,
+		IdleTimeout:  a.Config.Server.IdleTimeout.Duration,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		a.Logger.Info("Listening", slog.String("addr", addr))
+		served <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-served:
+		a.fatal("Could not serve", err)
+	case <-ctx.Done():
+	}
+
+	timeout := a.Config.Server.ShutdownTimeout.Duration
+	a.Logger.Info("Shutting down, waiting for requests in flight", slog.String("timeout", timeout.String()))
+	a.HealthHandler.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		a.Logger.Warn("Requests were still running, closing their connections", slog.String("timeout", timeout.String()), slog.Any("error", err))
+		server.Close()
+	}
+	if err := a.Close(); err != nil {
+		a.Logger.Error("Failed to close", slog.Any("error", err))
+	}
+	a.Logger.Info("Stopped")
+}
+
+// fatal logs err and exits, for failures the app cannot start or keep serving after
+func (a *App) fatal(message string, err error) {
+	a.Logger.Error(message, slog.Any("error", err))
+	os.Exit(1)
+}
+
+// Close stops the background sweeps, waits for the alerts still running, exports the spans still buffered
+// and releases the database connections, the app cannot serve requests afterwards
+func (a *App) Close() error {
+	if a.stopSweeps != nil {
+		a.stopSweeps()
+	}
+	a.Alerts.Wait()
+	var errs []error
+	if a.flushTraces != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		errs = append(errs, a.flushTraces(ctx))
+	}
+	if a.DB != nil {
+		errs = append(errs, a.DB.Db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// notProbe leaves the probes and metric scrapes out of the traces, they would drown the requests
+func notProbe(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
 }
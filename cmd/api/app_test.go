@@ -0,0 +1,54 @@
+package App
+
+import (
+	"strings"
+	"testing"
+
+	"GraduationProject.com/m/internal/config"
+	"GraduationProject.com/m/internal/metrics"
+	"GraduationProject.com/m/internal/openapi"
+	"GraduationProject.com/m/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// The routes are registered with nil handlers, they are listed but never called
+func registeredRoutes() gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	a := App{Router: gin.New()}
+	a.Config.AdminToken = config.Secret("token")
+	a.Config.Features.DebugVars = true
+	a.Metrics = metrics.New()
+	a.Limiter = ratelimit.NewMemoryStore()
+	a.initializeRoutes()
+	return a.Router.Routes()
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	spec := openapi.Spec()
+	registered := make(map[string]bool)
+	for _, route := range registeredRoutes() {
+		registered[route.Method+" "+openapi.Path(route.Path)] = true
+		if !spec.Has(route.Method, route.Path) {
+			t.Errorf("%s %s is registered but missing from the OpenAPI document, add it to internal/openapi/routes.go", route.Method, route.Path)
+		}
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is in the OpenAPI document but not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIOperationIDsAreUnique(t *testing.T) {
+	seen := make(map[string]string)
+	for path, operations := range openapi.Spec().Paths {
+		for method, operation := range operations {
+			if other, ok := seen[operation.OperationID]; ok {
+				t.Errorf("%s %s and %s share the operation ID %s", method, path, other, operation.OperationID)
+			}
+			seen[operation.OperationID] = method + " " + path
+		}
+	}
+}
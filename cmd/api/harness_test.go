@@ -0,0 +1,235 @@
+package App
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/config"
+	Database "GraduationProject.com/m/internal/db"
+	"GraduationProject.com/m/internal/logging"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository/memory"
+	"github.com/google/uuid"
+)
+
+// testServer is the whole app on a fresh database, driven through its router without a network
+type testServer struct {
+	t    *testing.T
+	app  *App
+	logs *logBuffer
+}
+
+// logBuffer keeps what the app logs, the test prints it when it fails
+type logBuffer struct {
+	mu      sync.Mutex
+	content bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content.String()
+}
+
+// newTestServer boots the app on an in-memory SQLite database, migrated on startup, with the config changed
+// by configure. With TEST_DB_DRIVER=mysql it uses the MySQL database of the DB_* variables instead, which
+// must be a disposable one: it is migrated up and the tests leave their rows behind.
+func newTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+	t.Helper()
+	return startTestServer(t, &App{}, configure)
+}
+
+// newMemoryTestServer is newTestServer on the in-memory repositories, the database only answers the health checks
+func newMemoryTestServer(t *testing.T, configure ...func(*config.Config)) *testServer {
+	t.Helper()
+	return startTestServer(t, &App{Repositories: memory.New()}, configure)
+}
+
+// newServer is newTestServer or newMemoryTestServer
+type newServer func(t *testing.T, configure ...func(*config.Config)) *testServer
+
+// onEveryBackend runs test on the database and on the in-memory repositories, which must behave the same
+func onEveryBackend(t *testing.T, test func(*testing.T, newServer)) {
+	t.Run("sql", func(t *testing.T) { test(t, newTestServer) })
+	t.Run("memory", func(t *testing.T) { test(t, newMemoryTestServer) })
+}
+
+func startTestServer(t *testing.T, a *App, configure []func(*config.Config)) *testServer {
+	t.Helper()
+	cfg := config.Default()
+	cfg.AdminToken = "test-admin-token"
+	cfg.Features.Cache = false
+	for _, change := range configure {
+		change(&cfg)
+	}
+	if os.Getenv("TEST_DB_DRIVER") == "mysql" && a.Repositories.Users == nil {
+		loaded, err := config.Load("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Database = loaded.Database
+		cfg.Database.Driver = "mysql"
+		database, err := Database.InitDB(cfg.Database)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Database.MigrateUp(database); err != nil {
+			t.Fatal(err)
+		}
+		database.Db.Close()
+	} else {
+		cfg.Database = config.Database{Driver: "sqlite", Path: ":memory:"}
+	}
+
+	logs := &logBuffer{}
+	a.Logger = logging.New(logs, "debug", "json")
+	a.Initialize(cfg)
+	t.Cleanup(func() {
+		a.Close()
+		if t.Failed() {
+			t.Logf("app logs:\n%s", logs)
+		}
+	})
+	return &testServer{t: t, app: a, logs: logs}
+}
+
+// envelope is a decoded response, Data is left as JSON for the test to decode
+type envelope struct {
+	Status  string                `json:"status"`
+	Message string                `json:"message"`
+	Data    json.RawMessage       `json:"data"`
+	Code    apperror.Code         `json:"code"`
+	Details []apperror.FieldError `json:"details"`
+}
+
+// do sends body as JSON and decodes the response envelope. It fails the test when the response is
+// not wantStatus, and decodes data into out when out is not nil.
+func (s *testServer) do(method, path string, body interface{}, wantStatus int, out interface{}) envelope {
+	s.t.Helper()
+	return s.decode(method, path, s.send(method, path, body, nil), wantStatus, out)
+}
+
+// update is do for a PUT or PATCH of a versioned entity, it sends the ETag of version in If-Match
+func (s *testServer) update(method, path string, version int, body interface{}, wantStatus int, out interface{}) envelope {
+	s.t.Helper()
+	header := http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+	return s.decode(method, path, s.send(method, path, body, header), wantStatus, out)
+}
+
+// decode checks the status of a response and decodes its envelope, and its data into out when out is not nil
+func (s *testServer) decode(method, path string, recorder *httptest.ResponseRecorder, wantStatus int, out interface{}) envelope {
+	s.t.Helper()
+	if recorder.Code != wantStatus {
+		s.t.Fatalf("%s %s: got status %d, want %d: %s", method, path, recorder.Code, wantStatus, recorder.Body.String())
+	}
+	var response envelope
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		s.t.Fatalf("%s %s: response is not an envelope: %v: %s", method, path, err, recorder.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			s.t.Fatalf("%s %s: could not decode data: %v: %s", method, path, err, response.Data)
+		}
+	}
+	return response
+}
+
+// send sends body as JSON with the headers and returns the response as it is
+func (s *testServer) send(method, path string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	s.t.Helper()
+	reader := bytes.NewReader(nil)
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(content)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// fixtures are the rows most tests start from: a landlord with a property and a unit, and a tenant who
+// booked the unit for three nights a month from now
+type fixtures struct {
+	Landlord Entities.User
+	Tenant   Entities.User
+	Property Entities.Property
+	Unit     Entities.Unit
+	Booking  Entities.Booking
+}
+
+// seed writes the fixtures through the repositories, so they do not depend on the handlers under test.
+// Emails are unique, the same MySQL database can be seeded many times.
+func (s *testServer) seed() fixtures {
+	s.t.Helper()
+	ctx := context.Background()
+	repos := s.app.Repositories
+	var f fixtures
+
+	f.Landlord = Entities.User{Name: "Layla Landlord", Email: uniqueEmail("landlord"), Password: "landlord-password", UserRole: "LandLord",
+		Address: Entities.Address{Country: "Jordan", City: "Amman"}}
+	f.Tenant = Entities.User{Name: "Tariq Tenant", Email: uniqueEmail("tenant"), Password: "tenant-password", UserRole: "Tenant"}
+	for _, user := range []*Entities.User{&f.Landlord, &f.Tenant} {
+		if err := repos.Users.Create(ctx, user); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+
+	f.Property = Entities.Property{OwnerID: f.Landlord.UserID, Name: "Olive Court", Type: "Apartment", Description: "Near the old town",
+		Rules: "No parties", TimeZone: "Asia/Amman", Address: Entities.Address{Country: "Jordan", City: "Amman", Street: "Rainbow Street"}}
+	f.Property.SetScheduleDefaults()
+	if err := repos.Properties.Create(ctx, &f.Property); err != nil {
+		s.t.Fatal(err)
+	}
+
+	f.Unit = Entities.Unit{PropertyID: f.Property.PropertyID, Name: "Garden flat", RentalPrice: 80,
+		Attributes: Entities.UnitAttributes{Bedrooms: 1, Beds: 2, Bathrooms: 1, MaxGuests: 2}, Amenities: []string{"wifi"},
+		Address: Entities.Address{Country: "Jordan", City: "Amman", Street: "Rainbow Street"}}
+	if err := repos.Units.Create(ctx, &f.Unit); err != nil {
+		s.t.Fatal(err)
+	}
+
+	checkIn := Entities.DateOf(time.Now().AddDate(0, 1, 0))
+	f.Booking = Entities.Booking{UnitID: f.Unit.UnitID, UserID: f.Tenant.UserID, CheckIn: checkIn, CheckOut: addDays(checkIn, 3), Summary: "Family visit"}
+	if err := f.Booking.Schedule(f.Property); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := repos.Bookings.Create(ctx, &f.Booking); err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.app.reindex(ctx)
+	return f
+}
+
+func uniqueEmail(name string) string {
+	return name + "-" + uuid.New().String() + "@example.com"
+}
+
+func addDays(date Entities.Date, days int) Entities.Date {
+	return Entities.DateOf(date.At(12, 0, time.UTC).AddDate(0, 0, days))
+}
@@ -0,0 +1,1217 @@
+package App
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/config"
+	Database "GraduationProject.com/m/internal/db"
+	"GraduationProject.com/m/internal/dto"
+	Handlers "GraduationProject.com/m/internal/handler"
+	"GraduationProject.com/m/internal/listing"
+	"GraduationProject.com/m/internal/logging"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+	"GraduationProject.com/m/internal/search"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func hasDetail(response envelope, field string) bool {
+	for _, detail := range response.Details {
+		if detail.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUserSignupAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	email := uniqueEmail("signup")
+	signup := map[string]interface{}{"name": "Sara", "email": email, "password": "short", "userRole": "Tenant"}
+
+	response := s.do(http.MethodPost, "/users/create", signup, http.StatusBadRequest, nil)
+	if response.Code != apperror.ValidationFailed || !hasDetail(response, "password") {
+		t.Errorf("a short password was not rejected on the password field: %+v", response)
+	}
+
+	signup["password"] = "long enough password"
+	var user dto.UserResponse
+	response = s.do(http.MethodPost, "/users/create", signup, http.StatusCreated, &user)
+	if user.UserID == "" || user.Email != email || user.UserRole != "Tenant" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(response.Data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["password"]; ok {
+		t.Error("the password was returned")
+	}
+
+	response = s.do(http.MethodPost, "/users/create", signup, http.StatusConflict, nil)
+	if response.Code != apperror.Conflict {
+		t.Errorf("got code %q for a taken email, want %q", response.Code, apperror.Conflict)
+	}
+
+	var loggedIn dto.UserResponse
+	s.do(http.MethodPost, "/users/login", map[string]string{"email": email, "password": "long enough password"}, http.StatusOK, &loggedIn)
+	if loggedIn.UserID != user.UserID {
+		t.Errorf("logged in as %q, want %q", loggedIn.UserID, user.UserID)
+	}
+	s.do(http.MethodPost, "/users/login", map[string]string{"email": email, "password": "wrong password"}, http.StatusUnauthorized, nil)
+	s.do(http.MethodPost, "/users/login", map[string]string{"email": uniqueEmail("nobody"), "password": "whatever"}, http.StatusUnauthorized, nil)
+
+	var fetched dto.UserResponse
+	s.do(http.MethodGet, "/users/"+user.UserID, nil, http.StatusOK, &fetched)
+	if fetched.Name != "Sara" {
+		t.Errorf("got name %q, want Sara", fetched.Name)
+	}
+	s.do(http.MethodGet, "/users/does-not-exist", nil, http.StatusNotFound, nil)
+
+	// A deleted user's email is free to sign up with again, and restoring them waits until it is free again
+	s.do(http.MethodDelete, "/users/"+user.UserID, nil, http.StatusOK, nil)
+	var again dto.UserResponse
+	s.do(http.MethodPost, "/users/create", signup, http.StatusCreated, &again)
+	if again.UserID == user.UserID {
+		t.Error("the deleted user was signed up again instead of a new one")
+	}
+	response = s.do(http.MethodPost, "/users/"+user.UserID+"/restore", nil, http.StatusConflict, nil)
+	if response.Code != apperror.Conflict {
+		t.Errorf("got code %q for restoring a user whose email is taken, want %q", response.Code, apperror.Conflict)
+	}
+	s.do(http.MethodDelete, "/users/"+again.UserID, nil, http.StatusOK, nil)
+	s.do(http.MethodPost, "/users/"+user.UserID+"/restore", nil, http.StatusOK, nil)
+}
+
+func TestPropertyAndUnitCreation(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+
+	var property Entities.Property
+	s.do(http.MethodPost, "/property/create", map[string]interface{}{
+		"ownerID": f.Landlord.UserID, "name": "Cedar House", "type": "Villa", "description": "Quiet", "rules": "No smoking",
+		"address": map[string]string{"Country": "Lebanon", "city": "Beirut"},
+	}, http.StatusCreated, &property)
+	if property.PropertyID == "" || property.TimeZone == "" || property.CheckInTime == "" {
+		t.Errorf("the property was not created with schedule defaults: %+v", property)
+	}
+
+	unit := map[string]interface{}{
+		"propertyID": property.PropertyID, "name": "Sea view suite", "rentalPrice": 150,
+		"amenities": []string{"wifi", "pool"}, "attributes": map[string]int{"maxGuests": 101},
+		"address": map[string]string{"Country": "Lebanon", "city": "Beirut"},
+	}
+	response := s.do(http.MethodPost, "/units/create", unit, http.StatusBadRequest, nil)
+	if !hasDetail(response, "attributes.maxGuests") {
+		t.Errorf("a unit for 101 guests was not rejected on maxGuests: %+v", response)
+	}
+
+	// Units that leave out maxGuests take one guest per bed
+	unit["attributes"] = map[string]int{"bedrooms": 2, "beds": 3, "bathrooms": 1}
+	var created Entities.Unit
+	s.do(http.MethodPost, "/units/create", unit, http.StatusCreated, &created)
+	if created.UnitID == "" || created.PropertyID != property.PropertyID || created.Attributes.MaxGuests != 3 {
+		t.Errorf("unexpected unit %+v", created)
+	}
+
+	// Legacy structural properties that are not JSON give no attributes, the unit then takes one guest
+	var legacy Entities.Unit
+	s.do(http.MethodPost, "/units/create", map[string]interface{}{"propertyID": property.PropertyID, "name": "Attic", "rentalPrice": 40,
+		"structuralProperties": "two rooms, one bed"}, http.StatusCreated, &legacy)
+	if legacy.Attributes != (Entities.UnitAttributes{MaxGuests: 1}) || legacy.StructuralProperties != "two rooms, one bed" {
+		t.Errorf("unexpected unit from unparseable structural properties %+v", legacy)
+	}
+	s.do(http.MethodDelete, "/units/"+legacy.UnitID, nil, http.StatusOK, nil)
+
+	var units []Entities.Unit
+	s.do(http.MethodGet, "/property/AllUnits/"+property.PropertyID, nil, http.StatusOK, &units)
+	if len(units) != 1 || units[0].UnitID != created.UnitID {
+		t.Errorf("got units %+v of the property, want only %s", units, created.UnitID)
+	}
+
+	var page Handlers.UnitPage
+	s.do(http.MethodGet, "/units/?city=Beirut&guests=3&amenities=pool", nil, http.StatusOK, &page)
+	if page.Total != 1 || len(page.Units) != 1 || page.Units[0].UnitID != created.UnitID {
+		t.Errorf("the listing did not find the new unit alone: %+v", page)
+	}
+	s.do(http.MethodGet, "/units/?minPrice=-1", nil, http.StatusBadRequest, nil)
+}
+
+func TestBookingConflicts(t *testing.T) {
+	onEveryBackend(t, testBookingConflicts)
+}
+
+func testBookingConflicts(t *testing.T, start newServer) {
+	s := start(t)
+	f := s.seed()
+	book := func(checkIn, checkOut Entities.Date, wantStatus int) (Entities.Booking, envelope) {
+		t.Helper()
+		var booking Entities.Booking
+		response := s.do(http.MethodPost, "/booking/create", map[string]interface{}{
+			"unitID": f.Unit.UnitID, "userID": f.Tenant.UserID, "checkIn": checkIn, "checkOut": checkOut,
+		}, wantStatus, nil)
+		if wantStatus == http.StatusCreated {
+			if err := json.Unmarshal(response.Data, &booking); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return booking, response
+	}
+
+	if _, response := book(addDays(f.Booking.CheckIn, 1), addDays(f.Booking.CheckOut, 2), http.StatusConflict); response.Code != apperror.Conflict {
+		t.Errorf("got code %q for overlapping dates, want %q", response.Code, apperror.Conflict)
+	}
+	book(f.Booking.CheckIn, f.Booking.CheckIn, http.StatusBadRequest)
+
+	// Guests arrive in the afternoon of the day the previous ones leave in the morning
+	next, _ := book(f.Booking.CheckOut, addDays(f.Booking.CheckOut, 2), http.StatusCreated)
+	if next.CheckIn != f.Booking.CheckOut {
+		t.Errorf("got check-in %s, want %s", next.CheckIn, f.Booking.CheckOut)
+	}
+
+	response := s.update(http.MethodPut, "/booking/"+next.BookingID, next.Version, map[string]interface{}{
+		"checkIn": f.Booking.CheckIn, "checkOut": addDays(f.Booking.CheckOut, 2),
+	}, http.StatusConflict, nil)
+	if response.Code != apperror.Conflict {
+		t.Errorf("got code %q for moving onto a booking, want %q", response.Code, apperror.Conflict)
+	}
+
+	var bookings []Entities.Booking
+	s.do(http.MethodGet, "/booking/unit/"+f.Unit.UnitID, nil, http.StatusOK, &bookings)
+	if len(bookings) != 2 {
+		t.Errorf("got %d bookings of the unit, want 2", len(bookings))
+	}
+
+	s.do(http.MethodDelete, "/booking/"+f.Booking.BookingID, nil, http.StatusOK, nil)
+	book(addDays(f.Booking.CheckIn, 1), f.Booking.CheckOut, http.StatusCreated)
+
+	// Bookings of the same nights sent at once cannot both pass the overlap check
+	checkIn := addDays(f.Booking.CheckOut, 30)
+	body := map[string]interface{}{"unitID": f.Unit.UnitID, "userID": f.Tenant.UserID, "checkIn": checkIn, "checkOut": addDays(checkIn, 2)}
+	const attempts = 8
+	statuses := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- s.send(http.MethodPost, "/booking/create", body, nil).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != attempts-1 {
+		t.Errorf("got statuses %v for %d bookings of the same nights, want one created and the rest conflicts", counts, attempts)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	onEveryBackend(t, testReviews)
+}
+
+func testReviews(t *testing.T, start newServer) {
+	s := start(t)
+	f := s.seed()
+
+	review := map[string]interface{}{"userID": f.Tenant.UserID, "unitID": f.Unit.UnitID, "review": "Lovely stay", "rating": 6}
+	response := s.do(http.MethodPost, "/reviews/create", review, http.StatusBadRequest, nil)
+	if !hasDetail(response, "rating") {
+		t.Errorf("a rating of 6 was not rejected on the rating field: %+v", response)
+	}
+
+	review["rating"] = 5
+	var created Entities.Review
+	s.do(http.MethodPost, "/reviews/create", review, http.StatusCreated, &created)
+
+	var updated Entities.Review
+	s.do(http.MethodPut, "/reviews/"+created.ReviewID, map[string]interface{}{"rating": 4, "comment": "The wifi was slow", "userID": f.Landlord.UserID},
+		http.StatusOK, &updated)
+	if updated.Rating != 4 || updated.Comment != "The wifi was slow" || updated.Review != "Lovely stay" {
+		t.Errorf("unexpected review after the update %+v", updated)
+	}
+	if updated.UserID != f.Tenant.UserID {
+		t.Errorf("the update moved the review to user %q", updated.UserID)
+	}
+
+	var reviews []Entities.Review
+	s.do(http.MethodGet, "/reviews/ByUnit/"+f.Unit.UnitID, nil, http.StatusOK, &reviews)
+	if len(reviews) != 1 || reviews[0].ReviewID != created.ReviewID {
+		t.Errorf("got reviews %+v of the unit, want only %s", reviews, created.ReviewID)
+	}
+
+	s.do(http.MethodDelete, "/reviews/"+created.ReviewID, nil, http.StatusOK, nil)
+	s.do(http.MethodGet, "/reviews/"+created.ReviewID, nil, http.StatusNotFound, nil)
+}
+
+func TestChat(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+
+	var question, answer Entities.Message
+	s.do(http.MethodPost, "/message/send", map[string]string{"senderID": f.Tenant.UserID, "receiverID": f.Landlord.UserID, "content": "Is parking included?"},
+		http.StatusCreated, &question)
+	s.do(http.MethodPost, "/message/send", map[string]string{"senderID": f.Landlord.UserID, "receiverID": f.Tenant.UserID, "content": "Yes, one spot."},
+		http.StatusCreated, &answer)
+	if question.ChatID == "" || answer.ChatID != question.ChatID {
+		t.Errorf("the reply went to chat %q, want %q", answer.ChatID, question.ChatID)
+	}
+
+	var chat Entities.Chat
+	s.do(http.MethodGet, "/chat/"+question.ChatID, nil, http.StatusOK, &chat)
+	if len(chat.Messages) != 2 || chat.Messages[0].Content != "Is parking included?" {
+		t.Errorf("unexpected messages in the chat %+v", chat.Messages)
+	}
+
+	var chats []Entities.Chat
+	s.do(http.MethodGet, "/user/chat/"+f.Tenant.UserID, nil, http.StatusOK, &chats)
+	if len(chats) != 1 {
+		t.Errorf("got %d chats of the tenant, want 1", len(chats))
+	}
+
+	response := s.do(http.MethodPost, "/message/send", map[string]string{"senderID": f.Tenant.UserID, "receiverID": f.Tenant.UserID, "content": "Hello me"},
+		http.StatusBadRequest, nil)
+	if !hasDetail(response, "receiverID") {
+		t.Errorf("a message to oneself was not rejected on receiverID: %+v", response)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+
+	payment := map[string]interface{}{"userID": f.Tenant.UserID, "BookingID": f.Booking.BookingID, "paymentMethod": "card", "amount": 0}
+	response := s.do(http.MethodPost, "/financialTransaction/create", payment, http.StatusBadRequest, nil)
+	if !hasDetail(response, "amount") {
+		t.Errorf("an amount of 0 was not rejected on the amount field: %+v", response)
+	}
+
+	payment["amount"] = 240
+	var created Entities.FinancialTransaction
+	s.do(http.MethodPost, "/financialTransaction/create", payment, http.StatusCreated, &created)
+
+	var updated Entities.FinancialTransaction
+	s.do(http.MethodPut, "/financialTransaction/"+created.TransactionID, map[string]interface{}{"amount": 250}, http.StatusOK, &updated)
+	if updated.Amount != 250 || updated.PaymentMethod != "card" {
+		t.Errorf("unexpected transaction after the update %+v", updated)
+	}
+
+	var report Handlers.Report
+	s.do(http.MethodGet, "/users/report/"+f.Landlord.UserID, nil, http.StatusOK, &report)
+	if report.TotalEarnings != 250 || len(report.FinancialTransactions) != 1 || len(report.Bookings) != 1 {
+		t.Errorf("unexpected owner report %+v", report)
+	}
+
+	s.do(http.MethodDelete, "/financialTransaction/"+created.TransactionID, nil, http.StatusOK, nil)
+	s.do(http.MethodGet, "/financialTransaction/"+created.TransactionID, nil, http.StatusNotFound, nil)
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s := newTestServer(t)
+	s.do(http.MethodGet, "/healthz", nil, http.StatusOK, nil)
+
+	var checks map[string]string
+	s.do(http.MethodGet, "/readyz", nil, http.StatusOK, &checks)
+	for _, name := range []string{"database", "migrations", "storage"} {
+		if checks[name] != "ok" {
+			t.Errorf("got %q for the %s check, want ok", checks[name], name)
+		}
+	}
+
+	if _, err := Database.MigrateDown(s.app.DB, 1); err != nil {
+		t.Fatal(err)
+	}
+	response := s.do(http.MethodGet, "/readyz", nil, http.StatusServiceUnavailable, nil)
+	if response.Code != apperror.Unavailable || !hasDetail(response, "migrations") || hasDetail(response, "database") {
+		t.Errorf("only the migrations check should fail with a migration pending: %+v", response)
+	}
+	if _, err := Database.MigrateUp(s.app.DB); err != nil {
+		t.Fatal(err)
+	}
+	s.do(http.MethodGet, "/readyz", nil, http.StatusOK, nil)
+
+	s.app.HealthHandler.Drain()
+	s.do(http.MethodGet, "/readyz", nil, http.StatusServiceUnavailable, nil)
+	s.do(http.MethodGet, "/healthz", nil, http.StatusOK, nil)
+}
+
+func TestRequestLogging(t *testing.T) {
+	s := newTestServer(t)
+	password := "a password for the logs"
+	signup := map[string]string{"name": "Lina", "email": uniqueEmail("logging"), "password": password, "userRole": "Tenant"}
+	content, err := json.Marshal(signup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/users/create", bytes.NewReader(content))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Request-ID", "logging-test-request")
+	request.Header.Set("X-User-ID", "logging-test-user")
+	recorder := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("X-Request-ID"); got != "logging-test-request" {
+		t.Errorf("got request ID %q back, want logging-test-request", got)
+	}
+	s.do(http.MethodGet, "/wishlist/shared/a-share-token", nil, http.StatusNotFound, nil)
+
+	var access []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(s.logs.String()))
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("log line is not JSON: %v: %s", err, scanner.Text())
+		}
+		if record["msg"] == "Request" {
+			access = append(access, record)
+		}
+	}
+	if len(access) != 2 {
+		t.Fatalf("got %d access log lines, want 2", len(access))
+	}
+	signedUp := access[0]
+	if signedUp["requestID"] != "logging-test-request" || signedUp["userID"] != "logging-test-user" ||
+		signedUp["status"] != float64(http.StatusCreated) || signedUp["route"] != "/users/create" {
+		t.Errorf("unexpected access log line %v", signedUp)
+	}
+	if _, ok := signedUp["latencyMs"].(float64); !ok {
+		t.Errorf("the access log line has no latency: %v", signedUp)
+	}
+	if path := access[1]["path"]; path != "/wishlist/shared/"+logging.Redacted {
+		t.Errorf("got path %q, want the share token redacted", path)
+	}
+	if logs := s.logs.String(); strings.Contains(logs, password) || strings.Contains(logs, "a-share-token") {
+		t.Errorf("a secret reached the logs:\n%s", logs)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+
+	s.do(http.MethodGet, "/units/"+f.Unit.UnitID, nil, http.StatusOK, nil)
+	s.do(http.MethodGet, "/units/"+f.Unit.UnitID, nil, http.StatusOK, nil)
+	s.do(http.MethodGet, "/no/such/endpoint", nil, http.StatusNotFound, nil)
+	s.do(http.MethodPost, "/message/send", map[string]string{"senderID": f.Tenant.UserID, "receiverID": f.Landlord.UserID, "content": "Hi"},
+		http.StatusCreated, nil)
+	s.do(http.MethodDelete, "/booking/"+f.Booking.BookingID, nil, http.StatusOK, nil)
+
+	recorder := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+	exposed := recorder.Body.String()
+	for _, want := range []string{
+		`rentals_http_requests_total{method="GET",route="/units/:id",status="200"} 2`,
+		`rentals_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`rentals_http_request_duration_seconds_count{method="GET",route="/units/:id"} 2`,
+		`rentals_bookings_created_total 1`,
+		`rentals_bookings_cancelled_total 1`,
+		`rentals_messages_sent_total 1`,
+		`rentals_payments_total{result="failed"} 0`,
+		`go_sql_max_open_connections{db_name=":memory:"} 1`,
+	} {
+		if !strings.Contains(exposed, want) {
+			t.Errorf("the metrics lack %s", want)
+		}
+	}
+	if strings.Contains(exposed, `route="/units/`+f.Unit.UnitID+`"`) {
+		t.Error("a unit ID is used as a route label")
+	}
+}
+
+func TestTracing(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	s := newTestServer(t)
+	f := s.seed()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := httptest.NewRequest(http.MethodGet, "/users/report/"+f.Landlord.UserID, nil)
+	request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body.String())
+	}
+	s.do(http.MethodGet, "/readyz", nil, http.StatusOK, nil)
+
+	var server sdktrace.ReadOnlySpan
+	statements := 0
+	for _, span := range spans.GetSpans().Snapshots() {
+		if span.SpanContext().TraceID().String() != traceID {
+			t.Errorf("span %s is not part of the incoming trace", span.Name())
+			continue
+		}
+		switch {
+		case span.Name() == "/users/report/:id":
+			server = span
+		case strings.HasPrefix(span.Name(), "sql."):
+			statements++
+		}
+	}
+	if server == nil {
+		t.Fatal("the request has no span named after its route")
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("the request span does not continue the caller's span, its parent is %s", server.Parent().SpanID())
+	}
+	// The user, then the owner's properties, units, their amenities, bookings and transactions, one query each
+	if statements != 6 {
+		t.Errorf("got %d SQL spans for the report, want 6", statements)
+	}
+	if !strings.Contains(s.logs.String(), `"traceID":"`+traceID+`"`) {
+		t.Error("the request logs lack the trace ID")
+	}
+}
+
+// tooMany checks a response is a 429 that says how long to wait
+func tooMany(t *testing.T, recorder *httptest.ResponseRecorder, wantWait time.Duration) {
+	t.Helper()
+	var response envelope
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || recorder.Code != http.StatusTooManyRequests || response.Code != apperror.TooManyRequests {
+		t.Fatalf("got status %d, want %d with code %s: %s", recorder.Code, http.StatusTooManyRequests, apperror.TooManyRequests, recorder.Body.String())
+	}
+	seconds, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > wantWait {
+		t.Errorf("got Retry-After %q, want up to %s", recorder.Header().Get("Retry-After"), wantWait)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimits.Messages = config.RateLimitGroup{PerUser: config.RateLimit{Requests: 2, Per: config.Duration{Duration: time.Hour}}}
+		cfg.RateLimits.Login = config.RateLimitGroup{PerIP: config.RateLimit{Requests: 2, Per: config.Duration{Duration: time.Hour}}}
+		cfg.RateLimits.Lockout.Threshold = 0
+	})
+	f := s.seed()
+
+	asTenant := http.Header{"X-User-ID": {f.Tenant.UserID}}
+	message := map[string]string{"senderID": f.Tenant.UserID, "receiverID": f.Landlord.UserID, "content": "Hello"}
+	for i := 0; i < 2; i++ {
+		if recorder := s.send(http.MethodPost, "/message/send", message, asTenant); recorder.Code != http.StatusCreated {
+			t.Fatalf("message %d: got status %d, want %d", i+1, recorder.Code, http.StatusCreated)
+		}
+	}
+	tooMany(t, s.send(http.MethodPost, "/message/send", message, asTenant), 30*time.Minute)
+	reply := map[string]string{"senderID": f.Landlord.UserID, "receiverID": f.Tenant.UserID, "content": "Hi"}
+	if recorder := s.send(http.MethodPost, "/message/send", reply, http.Header{"X-User-ID": {f.Landlord.UserID}}); recorder.Code != http.StatusCreated {
+		t.Errorf("another user was limited too, got status %d", recorder.Code)
+	}
+
+	login := map[string]string{"email": f.Landlord.Email, "password": "landlord-password"}
+	s.do(http.MethodPost, "/users/login", login, http.StatusOK, nil)
+	s.do(http.MethodPost, "/users/login", login, http.StatusOK, nil)
+	// The client IP cannot be picked in X-Forwarded-For, no proxy is trusted
+	tooMany(t, s.send(http.MethodPost, "/users/login", login, http.Header{"X-Forwarded-For": {"203.0.113.9"}}), 30*time.Minute)
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimits.Lockout = config.Lockout{Threshold: 3, Duration: config.Duration{Duration: time.Hour}}
+	})
+	f := s.seed()
+
+	wrong := map[string]string{"email": f.Tenant.Email, "password": "not the password"}
+	for i := 0; i < 3; i++ {
+		s.do(http.MethodPost, "/users/login", wrong, http.StatusUnauthorized, nil)
+	}
+	// A wrong password and an email of nobody get the same answer
+	unknown := s.do(http.MethodPost, "/users/login", map[string]string{"email": uniqueEmail("unknown"), "password": "guess"}, http.StatusUnauthorized, nil)
+	if unknown.Message != "Invalid email or password" {
+		t.Errorf("got %q for an unknown email, want the same message as for a wrong password", unknown.Message)
+	}
+	// Locked, even with the right password and however the email is written
+	right := map[string]string{"email": strings.ToUpper(f.Tenant.Email), "password": "tenant-password"}
+	tooMany(t, s.send(http.MethodPost, "/users/login", right, nil), time.Hour)
+	s.do(http.MethodPost, "/users/login", map[string]string{"email": f.Landlord.Email, "password": "landlord-password"}, http.StatusOK, nil)
+
+	// Emails of nobody are locked the same way, lockouts do not tell which emails have an account
+	nobody := map[string]string{"email": uniqueEmail("nobody"), "password": "guess"}
+	for i := 0; i < 3; i++ {
+		s.do(http.MethodPost, "/users/login", nobody, http.StatusUnauthorized, nil)
+	}
+	tooMany(t, s.send(http.MethodPost, "/users/login", nobody, nil), time.Hour)
+
+	delayed := newTestServer(t, func(cfg *config.Config) {
+		cfg.RateLimits.Lockout = config.Lockout{Threshold: 5, Duration: config.Duration{Duration: time.Hour}, Delay: config.Duration{Duration: time.Minute}}
+	})
+	f = delayed.seed()
+	delayed.do(http.MethodPost, "/users/login", map[string]string{"email": f.Tenant.Email, "password": "not the password"}, http.StatusUnauthorized, nil)
+	tooMany(t, delayed.send(http.MethodPost, "/users/login", map[string]string{"email": f.Tenant.Email, "password": "tenant-password"}, nil), time.Minute)
+
+	// Guesses sent at once do not all get past the delay, each is counted before its password is checked
+	guesses := make([]*httptest.ResponseRecorder, 10)
+	var wg sync.WaitGroup
+	for i := range guesses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			guesses[i] = delayed.send(http.MethodPost, "/users/login", map[string]string{"email": f.Landlord.Email, "password": fmt.Sprint("guess ", i)}, nil)
+		}(i)
+	}
+	wg.Wait()
+	checked := 0
+	for _, recorder := range guesses {
+		switch recorder.Code {
+		case http.StatusUnauthorized:
+			checked++
+		case http.StatusTooManyRequests:
+		default:
+			t.Errorf("got status %d for a guess, want %d or %d", recorder.Code, http.StatusUnauthorized, http.StatusTooManyRequests)
+		}
+	}
+	if checked != 1 {
+		t.Errorf("%d of %d guesses at once had their password checked, want 1", checked, len(guesses))
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+	ctx := context.Background()
+	keyed := func(key string) http.Header {
+		return http.Header{"Idempotency-Key": {key}, "X-User-ID": {f.Tenant.UserID}}
+	}
+	created := func(recorder *httptest.ResponseRecorder, out interface{}) {
+		t.Helper()
+		var response envelope
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || recorder.Code != http.StatusCreated {
+			t.Fatalf("got status %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+		}
+		if err := json.Unmarshal(response.Data, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A retried booking gets the booking it made, not a conflict with itself
+	booking := map[string]interface{}{
+		"unitID": f.Unit.UnitID, "userID": f.Tenant.UserID, "checkIn": f.Booking.CheckOut, "checkOut": addDays(f.Booking.CheckOut, 3),
+	}
+	var first, retried Entities.Booking
+	created(s.send(http.MethodPost, "/booking/create", booking, keyed("booking-1")), &first)
+	recorder := s.send(http.MethodPost, "/booking/create", booking, keyed("booking-1"))
+	created(recorder, &retried)
+	if retried.BookingID != first.BookingID || recorder.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("the retry was not replayed: got booking %s after %s, Idempotent-Replayed %q", retried.BookingID, first.BookingID, recorder.Header().Get("Idempotent-Replayed"))
+	}
+
+	// Payments are charged once, however the retry orders the fields of its body
+	payment := `{"userID": "` + f.Tenant.UserID + `", "BookingID": "` + first.BookingID + `", "paymentMethod": "card", "amount": 240}`
+	reordered := `{"amount": 240, "paymentMethod": "card", "BookingID": "` + first.BookingID + `", "userID": "` + f.Tenant.UserID + `"}`
+	var charged, recharged Entities.FinancialTransaction
+	created(s.send(http.MethodPost, "/financialTransaction/create", json.RawMessage(payment), keyed("payment-1")), &charged)
+	created(s.send(http.MethodPost, "/financialTransaction/create", json.RawMessage(reordered), keyed("payment-1")), &recharged)
+	transactions, err := s.app.Repositories.Transactions.ListByUser(ctx, f.Tenant.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recharged.TransactionID != charged.TransactionID || len(transactions) != 1 {
+		t.Errorf("got %d transactions for one payment retried", len(transactions))
+	}
+
+	// The key cannot be reused for another body or another route, but other users have keys of their own
+	changed := map[string]interface{}{"userID": f.Tenant.UserID, "BookingID": first.BookingID, "paymentMethod": "card", "amount": 480}
+	for path, body := range map[string]interface{}{"/financialTransaction/create": changed, "/booking/create": booking} {
+		recorder := s.send(http.MethodPost, path, body, keyed("payment-1"))
+		var response envelope
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || recorder.Code != http.StatusConflict || response.Code != apperror.Conflict {
+			t.Errorf("%s with a used key: got status %d, want %d: %s", path, recorder.Code, http.StatusConflict, recorder.Body.String())
+		}
+	}
+	var landlords Entities.FinancialTransaction
+	created(s.send(http.MethodPost, "/financialTransaction/create", changed, http.Header{"Idempotency-Key": {"payment-1"}, "X-User-ID": {f.Landlord.UserID}}), &landlords)
+	if landlords.TransactionID == charged.TransactionID {
+		t.Error("the key of another user was replayed")
+	}
+
+	if recorder := s.send(http.MethodPost, "/booking/create", booking, keyed("not a key")); recorder.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for a key with spaces, want %d", recorder.Code, http.StatusBadRequest)
+	}
+
+	// Expired keys are deleted and can be used again
+	deleted, err := s.app.Repositories.Idempotency.DeleteExpired(ctx, time.Now().Add(s.app.Config.IdempotencyTTL.Duration+time.Hour))
+	if err != nil || deleted != 3 {
+		t.Fatalf("deleted %d expired keys, want 3: %v", deleted, err)
+	}
+	var again Entities.FinancialTransaction
+	created(s.send(http.MethodPost, "/financialTransaction/create", changed, keyed("payment-1")), &again)
+	if again.TransactionID == charged.TransactionID {
+		t.Error("an expired key was replayed")
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+	ifMatch := func(tag string) http.Header {
+		return http.Header{"If-Match": {tag}}
+	}
+	status := func(recorder *httptest.ResponseRecorder, want int, wantETag string) {
+		t.Helper()
+		if recorder.Code != want || recorder.Header().Get("ETag") != wantETag {
+			t.Fatalf("got status %d with ETag %q, want %d with %q: %s", recorder.Code, recorder.Header().Get("ETag"), want, wantETag, recorder.Body.String())
+		}
+	}
+
+	unitPath := "/units/" + f.Unit.UnitID
+	status(s.send(http.MethodGet, unitPath, nil, nil), http.StatusOK, `"1"`)
+	status(s.send(http.MethodPut, unitPath, map[string]string{"name": "Staff A"}, ifMatch(`"1"`)), http.StatusOK, `"2"`)
+	// The second editor read version 1 as well, their change is refused instead of overwriting the first
+	stale := s.send(http.MethodPut, unitPath, map[string]string{"name": "Staff B"}, ifMatch(`"1"`))
+	status(stale, http.StatusPreconditionFailed, `"2"`)
+	var response envelope
+	if err := json.Unmarshal(stale.Body.Bytes(), &response); err != nil || response.Code != apperror.PreconditionFailed {
+		t.Errorf("got code %q for a stale If-Match, want %q", response.Code, apperror.PreconditionFailed)
+	}
+	var unit Entities.Unit
+	s.do(http.MethodGet, unitPath, nil, http.StatusOK, &unit)
+	if unit.Name != "Staff A" || unit.Version != 2 {
+		t.Errorf("got unit %q at version %d, want the first change at version 2", unit.Name, unit.Version)
+	}
+	// Clients that do not send If-Match update as before
+	status(s.send(http.MethodPut, unitPath, map[string]string{"name": "Staff B"}, nil), http.StatusOK, `"3"`)
+
+	propertyPath := "/property/" + f.Property.PropertyID
+	status(s.send(http.MethodPut, propertyPath, map[string]string{"rules": "No parties"}, ifMatch(`W/"1"`)), http.StatusPreconditionFailed, `"1"`)
+	status(s.send(http.MethodPut, propertyPath, map[string]string{"rules": "No parties"}, ifMatch(`"7", "1"`)), http.StatusOK, `"2"`)
+
+	bookingPath := "/booking/" + f.Booking.BookingID
+	status(s.send(http.MethodGet, bookingPath, nil, nil), http.StatusOK, `"1"`)
+	status(s.send(http.MethodPut, bookingPath, map[string]string{"summary": "Late arrival"}, ifMatch("*")), http.StatusOK, `"2"`)
+
+	// The other entities are versioned the same way
+	ctx := context.Background()
+	repos := s.app.Repositories
+	review := Entities.Review{UserID: f.Tenant.UserID, UnitID: f.Unit.UnitID, Review: "Lovely stay", Rating: 5}
+	ticket := Entities.MaintenanceTicket{TenantID: f.Tenant.UserID, PropertyID: f.Property.PropertyID, Description: "Leaking tap", UrgencyLevel: "low", Status: "open"}
+	transaction := Entities.FinancialTransaction{UserID: f.Tenant.UserID, BookingID: f.Booking.BookingID, PaymentMethod: "card", Amount: 240}
+	report := Entities.Report{UserID: f.Landlord.UserID, Data: `{"bookings":1}`}
+	for _, err := range []error{repos.Reviews.Create(ctx, &review), repos.Tickets.Create(ctx, &ticket),
+		repos.Transactions.Create(ctx, &transaction), repos.Reports.Create(ctx, &report)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, body := range map[string]interface{}{
+		"/users/" + f.Tenant.UserID:                          map[string]string{"phoneNumber": "0790000000"},
+		"/reviews/" + review.ReviewID:                        map[string]string{"comment": "Quiet street"},
+		"/maintenanceTicket/" + ticket.TicketID:              map[string]string{"status": "closed"},
+		"/financialTransaction/" + transaction.TransactionID: map[string]string{"paymentMethod": "cash"},
+		"/report/" + report.ReportID:                         map[string]string{"data": `{"bookings":2}`},
+	} {
+		status(s.send(http.MethodGet, path, nil, nil), http.StatusOK, `"1"`)
+		status(s.send(http.MethodPut, path, body, ifMatch(`"1"`)), http.StatusOK, `"2"`)
+		status(s.send(http.MethodPut, path, body, ifMatch(`"1"`)), http.StatusPreconditionFailed, `"2"`)
+		status(s.send(http.MethodPut, path, body, nil), http.StatusOK, `"3"`)
+	}
+
+	// Updates that read the same version race in the database, only the first one is written
+	booking, err := s.app.Repositories.Bookings.GetByID(ctx, f.Booking.BookingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.app.Repositories.Bookings.Update(ctx, booking); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.app.Repositories.Bookings.Update(ctx, booking); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("got %v for an update from an old version, want %v", err, repository.ErrVersionConflict)
+	}
+	user, err := repos.Users.GetByID(ctx, f.Landlord.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Users.Update(ctx, user); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("got %v for a user update from an old version, want %v", err, repository.ErrVersionConflict)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+	unitPath := "/units/" + f.Unit.UnitID
+
+	// Fields set to null are cleared and zero is a price like any other, nested objects are merged
+	var unit Entities.Unit
+	s.update(http.MethodPatch, unitPath, 1, map[string]interface{}{
+		"description": nil,
+		"rentalPrice": 0,
+		"attributes":  map[string]interface{}{"bedrooms": 2},
+		"address":     map[string]interface{}{"street": nil, "city": "Irbid"},
+	}, http.StatusOK, &unit)
+	if unit.Description != "" || unit.RentalPrice != 0 || unit.Name != "Garden flat" || unit.Version != 2 {
+		t.Errorf("unexpected unit after the patch %+v", unit)
+	}
+	if unit.Attributes.Bedrooms != 2 || unit.Attributes.MaxGuests != 2 || len(unit.Amenities) != 1 {
+		t.Errorf("the patch lost the fields it left out: %+v %v", unit.Attributes, unit.Amenities)
+	}
+	if unit.Address.Street != "" || unit.Address.City != "Irbid" || unit.Address.Country != "Jordan" {
+		t.Errorf("unexpected address after the patch %+v", unit.Address)
+	}
+
+	// The unit after the patch is validated, fields that cannot be changed are named
+	response := s.update(http.MethodPatch, unitPath, 2, map[string]interface{}{"propertyID": nil}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "propertyID") {
+		t.Errorf("clearing the property was not rejected on propertyID: %+v", response)
+	}
+	response = s.update(http.MethodPatch, unitPath, 2, map[string]interface{}{"attributes": map[string]interface{}{"maxGuests": 101}}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "attributes.maxGuests") {
+		t.Errorf("a maxGuests of 101 was not rejected on attributes.maxGuests: %+v", response)
+	}
+	response = s.update(http.MethodPatch, unitPath, 2, map[string]interface{}{"rating": 5}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "rating") {
+		t.Errorf("patching the rating was not rejected on rating: %+v", response)
+	}
+	s.update(http.MethodPatch, unitPath, 2, []string{"name"}, http.StatusBadRequest, nil)
+	if stale := s.send(http.MethodPatch, unitPath, map[string]string{"name": "Staff B"}, http.Header{"If-Match": {`"1"`}}); stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("got status %d for a patch from an old version, want %d", stale.Code, http.StatusPreconditionFailed)
+	}
+
+	var property Entities.Property
+	s.update(http.MethodPatch, "/property/"+f.Property.PropertyID, 1, map[string]interface{}{"rules": nil, "description": ""}, http.StatusOK, &property)
+	if property.Rules != "" || property.Description != "" || property.Name != "Olive Court" || property.TimeZone != "Asia/Amman" {
+		t.Errorf("unexpected property after the patch %+v", property)
+	}
+	response = s.update(http.MethodPatch, "/property/"+f.Property.PropertyID, 2, map[string]interface{}{"timeZone": nil}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "timeZone") {
+		t.Errorf("clearing the time zone was not rejected on timeZone: %+v", response)
+	}
+
+	bookingPath := "/booking/" + f.Booking.BookingID
+	var booking Entities.Booking
+	s.update(http.MethodPatch, bookingPath, 1, map[string]interface{}{"summary": nil, "checkOut": addDays(f.Booking.CheckIn, 5)}, http.StatusOK, &booking)
+	if booking.Summary != "" || booking.CheckOut != addDays(f.Booking.CheckIn, 5) || !booking.EndDate.After(f.Booking.EndDate) {
+		t.Errorf("unexpected booking after the patch %+v", booking)
+	}
+	response = s.update(http.MethodPatch, bookingPath, 2, map[string]interface{}{"checkIn": nil}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "checkIn") {
+		t.Errorf("clearing the check-in date was not rejected on checkIn: %+v", response)
+	}
+
+	var user dto.UserResponse
+	s.do(http.MethodPatch, "/users/"+f.Landlord.UserID, map[string]interface{}{"phoneNumber": nil, "address": map[string]interface{}{"city": nil}},
+		http.StatusOK, &user)
+	if user.Name != f.Landlord.Name || user.Address.City != "" || user.Address.Country != "Jordan" {
+		t.Errorf("unexpected user after the patch %+v", user)
+	}
+	s.do(http.MethodPatch, "/users/"+f.Landlord.UserID, map[string]interface{}{"email": f.Tenant.Email}, http.StatusConflict, nil)
+
+	var review Entities.Review
+	s.do(http.MethodPost, "/reviews/create", map[string]interface{}{"userID": f.Tenant.UserID, "unitID": f.Unit.UnitID, "review": "Lovely stay", "rating": 5,
+		"comment": "Quiet street"}, http.StatusCreated, &review)
+	var patched Entities.Review
+	s.do(http.MethodPatch, "/reviews/"+review.ReviewID, map[string]interface{}{"comment": nil}, http.StatusOK, &patched)
+	if patched.Comment != "" || patched.Rating != 5 || patched.Review != "Lovely stay" {
+		t.Errorf("unexpected review after the patch %+v", patched)
+	}
+	response = s.do(http.MethodPatch, "/reviews/"+review.ReviewID, map[string]interface{}{"rating": nil}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "rating") {
+		t.Errorf("clearing the rating was not rejected on rating: %+v", response)
+	}
+
+	var ticket Entities.MaintenanceTicket
+	s.do(http.MethodPost, "/maintenanceTicket/create", map[string]interface{}{"tenantID": f.Tenant.UserID, "propertyID": f.Property.PropertyID,
+		"maintenancePresenterID": f.Landlord.UserID, "description": "Leaking tap", "urgencyLevel": "low"}, http.StatusCreated, &ticket)
+	s.do(http.MethodPatch, "/maintenanceTicket/"+ticket.TicketID, map[string]interface{}{"maintenancePresenterID": nil, "urgencyLevel": "high"},
+		http.StatusOK, &ticket)
+	if ticket.MaintenancePresenterID != "" || ticket.UrgencyLevel != "high" || ticket.Status != "open" {
+		t.Errorf("unexpected ticket after the patch %+v", ticket)
+	}
+	s.do(http.MethodGet, "/maintenanceTicket/"+ticket.TicketID, nil, http.StatusOK, &ticket)
+	if ticket.MaintenancePresenterID != "" {
+		t.Errorf("the ticket is still assigned to %q after clearing the maintenance presenter", ticket.MaintenancePresenterID)
+	}
+}
+
+func TestPropertyAddressReachesItsUnits(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+
+	s.update(http.MethodPatch, "/property/"+f.Property.PropertyID, f.Property.Version, map[string]interface{}{
+		"address": map[string]interface{}{"city": "Aqaba", "Latitude": 29.53, "Longitude": 35.0},
+	}, http.StatusOK, nil)
+
+	var nearby []Handlers.UnitDistance
+	s.do(http.MethodGet, "/units/nearby?lat=29.53&lng=35.0&radius=1", nil, http.StatusOK, &nearby)
+	if len(nearby) != 1 || nearby[0].Unit.UnitID != f.Unit.UnitID || nearby[0].Unit.Address.City != "Aqaba" {
+		t.Errorf("got %+v near the property's new address, want its unit", nearby)
+	}
+	var result search.Result
+	s.do(http.MethodGet, "/search?q=aqaba&kind=unit", nil, http.StatusOK, &result)
+	if result.Total != 1 || result.Hits[0].ID != f.Unit.UnitID {
+		t.Errorf("got %+v searching the property's new city, want its unit", result)
+	}
+}
+
+func TestUnitAddressReachesItsProperty(t *testing.T) {
+	s := newTestServer(t, func(cfg *config.Config) { cfg.Features.Cache = true })
+	f := s.seed()
+	sibling := Entities.Unit{PropertyID: f.Property.PropertyID, Name: "Roof studio", RentalPrice: 60,
+		Attributes: Entities.UnitAttributes{Bedrooms: 1, Beds: 1, Bathrooms: 1, MaxGuests: 1}}
+	if err := s.app.Repositories.Units.Create(context.Background(), &sibling); err != nil {
+		t.Fatal(err)
+	}
+	s.app.reindex(context.Background())
+
+	// Cache the property and both units before the address changes under them
+	s.do(http.MethodGet, "/property/"+f.Property.PropertyID, nil, http.StatusOK, nil)
+	s.do(http.MethodGet, "/units/"+sibling.UnitID, nil, http.StatusOK, nil)
+	s.update(http.MethodPatch, "/units/"+f.Unit.UnitID, f.Unit.Version, map[string]interface{}{
+		"address": map[string]interface{}{"city": "Irbid", "Latitude": 32.55, "Longitude": 35.85},
+	}, http.StatusOK, nil)
+
+	var property Entities.Property
+	s.do(http.MethodGet, "/property/"+f.Property.PropertyID, nil, http.StatusOK, &property)
+	var unit Entities.Unit
+	s.do(http.MethodGet, "/units/"+sibling.UnitID, nil, http.StatusOK, &unit)
+	if property.Address.City != "Irbid" || unit.Address.City != "Irbid" {
+		t.Errorf("got the property in %q and the other unit in %q, want both in Irbid", property.Address.City, unit.Address.City)
+	}
+	var result search.Result
+	s.do(http.MethodGet, "/search?q=irbid", nil, http.StatusOK, &result)
+	if result.Total != 3 {
+		t.Errorf("got %d results searching the new city, want the property and both units: %+v", result.Total, result)
+	}
+	var nearby []Handlers.UnitDistance
+	s.do(http.MethodGet, "/units/nearby?lat=32.55&lng=35.85&radius=1", nil, http.StatusOK, &nearby)
+	if len(nearby) != 2 {
+		t.Errorf("got %d units near the new address, want both", len(nearby))
+	}
+}
+
+func TestUnitMovesToAnotherProperty(t *testing.T) {
+	onEveryBackend(t, testUnitMovesToAnotherProperty)
+}
+
+func testUnitMovesToAnotherProperty(t *testing.T, start newServer) {
+	s := start(t, func(cfg *config.Config) { cfg.Features.Cache = true })
+	f := s.seed()
+	lodge := Entities.Property{OwnerID: f.Landlord.UserID, Name: "Dead Sea Lodge", Type: "Villa", TimeZone: "Asia/Amman",
+		Address: Entities.Address{Country: "Jordan", City: "Sweimeh"}}
+	lodge.SetScheduleDefaults()
+	if err := s.app.Repositories.Properties.Create(context.Background(), &lodge); err != nil {
+		t.Fatal(err)
+	}
+	s.app.reindex(context.Background())
+
+	unitPath := "/units/" + f.Unit.UnitID
+	s.do(http.MethodGet, unitPath, nil, http.StatusOK, nil)
+	response := s.update(http.MethodPut, unitPath, f.Unit.Version, map[string]interface{}{"propertyID": "999999"}, http.StatusBadRequest, nil)
+	if !hasDetail(response, "propertyID") {
+		t.Errorf("a move to a property that does not exist was not rejected on propertyID: %+v", response)
+	}
+
+	// The unit takes the address of its new property, the old one keeps its own
+	var unit Entities.Unit
+	s.update(http.MethodPut, unitPath, f.Unit.Version, map[string]interface{}{"propertyID": lodge.PropertyID}, http.StatusOK, &unit)
+	if unit.PropertyID != lodge.PropertyID || unit.PropertyType != "Villa" || unit.Address.City != "Sweimeh" {
+		t.Errorf("unexpected unit after the move %+v", unit)
+	}
+	s.do(http.MethodGet, unitPath, nil, http.StatusOK, &unit)
+	if unit.Address.City != "Sweimeh" {
+		t.Errorf("got the moved unit in %q, want Sweimeh", unit.Address.City)
+	}
+	var property Entities.Property
+	s.do(http.MethodGet, "/property/"+f.Property.PropertyID, nil, http.StatusOK, &property)
+	if property.Address.City != "Amman" {
+		t.Errorf("the move took the old property to %q", property.Address.City)
+	}
+	var units []Entities.Unit
+	s.do(http.MethodGet, "/property/AllUnits/"+lodge.PropertyID, nil, http.StatusOK, &units)
+	if len(units) != 1 || units[0].UnitID != f.Unit.UnitID {
+		t.Errorf("got units %+v of the new property, want only %s", units, f.Unit.UnitID)
+	}
+	var result search.Result
+	s.do(http.MethodGet, "/search?q=sweimeh&kind=unit", nil, http.StatusOK, &result)
+	if result.Total != 1 || result.Hits[0].ID != f.Unit.UnitID {
+		t.Errorf("got %+v searching the new property's city, want the moved unit", result)
+	}
+}
+
+func TestWishlistsAndAlerts(t *testing.T) {
+	s := newTestServer(t)
+	f := s.seed()
+	asTenant := http.Header{"X-User-ID": {f.Tenant.UserID}}
+	asLandlord := http.Header{"X-User-ID": {f.Landlord.UserID}}
+	wishlist := func(recorder *httptest.ResponseRecorder, wantStatus int) Entities.Wishlist {
+		t.Helper()
+		var response envelope
+		var wishlist Entities.Wishlist
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || recorder.Code != wantStatus {
+			t.Fatalf("got status %d, want %d: %s", recorder.Code, wantStatus, recorder.Body.String())
+		}
+		if wantStatus < 300 {
+			if err := json.Unmarshal(response.Data, &wishlist); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return wishlist
+	}
+
+	// The wishlist belongs to the user in X-User-ID, not to a user named in the body
+	wishlist(s.send(http.MethodPost, "/wishlist/create", map[string]string{"name": "Summer"}, nil), http.StatusUnauthorized)
+	created := wishlist(s.send(http.MethodPost, "/wishlist/create", map[string]string{"userID": f.Landlord.UserID, "name": "Summer"}, asTenant), http.StatusCreated)
+	if created.UserID != f.Tenant.UserID {
+		t.Errorf("the wishlist was created for %q, want the tenant %q", created.UserID, f.Tenant.UserID)
+	}
+	path := "/wishlist/" + created.WishlistID
+	wishlist(s.send(http.MethodPost, path+"/units", map[string]string{"unitID": f.Unit.UnitID}, asTenant), http.StatusOK)
+	wishlist(s.send(http.MethodGet, path, nil, asLandlord), http.StatusNotFound)
+	wishlist(s.send(http.MethodGet, path, nil, nil), http.StatusNotFound)
+
+	shared := wishlist(s.send(http.MethodPost, path+"/share", nil, asTenant), http.StatusOK)
+	if shared.ShareToken == "" {
+		t.Fatal("the owner did not get the share token")
+	}
+	if seen := wishlist(s.send(http.MethodGet, path, nil, asLandlord), http.StatusOK); seen.ShareToken != "" || len(seen.Items) != 1 {
+		t.Errorf("another user saw the shared wishlist as %+v, want its unit without the share token", seen)
+	}
+	if seen := wishlist(s.send(http.MethodGet, "/wishlist/shared/"+shared.ShareToken, nil, nil), http.StatusOK); seen.ShareToken != "" {
+		t.Errorf("the share link showed the share token %q", seen.ShareToken)
+	}
+	if seen := wishlist(s.send(http.MethodGet, path, nil, asTenant), http.StatusOK); seen.ShareToken != shared.ShareToken {
+		t.Errorf("the owner got the share token %q, want %q", seen.ShareToken, shared.ShareToken)
+	}
+
+	// Only the owner changes the wishlist, to anyone else it does not exist
+	for _, change := range []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodPut, path, map[string]string{"name": "Mine now"}},
+		{http.MethodPost, path + "/units", map[string]string{"unitID": f.Unit.UnitID}},
+		{http.MethodDelete, path + "/units/" + f.Unit.UnitID, nil},
+		{http.MethodPost, path + "/share", nil},
+		{http.MethodDelete, path + "/share", nil},
+		{http.MethodDelete, path, nil},
+	} {
+		for _, header := range []http.Header{asLandlord, nil} {
+			if recorder := s.send(change.method, change.path, change.body, header); recorder.Code != http.StatusNotFound {
+				t.Errorf("%s %s by %v: got status %d, want %d", change.method, change.path, header, recorder.Code, http.StatusNotFound)
+			}
+		}
+	}
+	if seen := wishlist(s.send(http.MethodGet, path, nil, asTenant), http.StatusOK); seen.Name != "Summer" || len(seen.Items) != 1 || seen.ShareToken != shared.ShareToken {
+		t.Errorf("other users changed the wishlist to %+v", seen)
+	}
+
+	// Shortening the booking frees its last night for the tenant who wishlisted the unit
+	s.update(http.MethodPatch, "/booking/"+f.Booking.BookingID, f.Booking.Version, map[string]interface{}{"checkOut": addDays(f.Booking.CheckIn, 2)}, http.StatusOK, nil)
+	var notifications []Entities.Notification
+	s.do(http.MethodGet, "/notifications/user/"+f.Tenant.UserID, nil, http.StatusOK, &notifications)
+	freed := fmt.Sprintf("from %s to %s", addDays(f.Booking.CheckIn, 2), f.Booking.CheckOut)
+	if len(notifications) != 1 || notifications[0].Type != Entities.NotificationDatesAvailable || !strings.Contains(notifications[0].Message, freed) {
+		t.Fatalf("got notifications %+v, want one that the nights %s are available", notifications, freed)
+	}
+
+	// Saved searches are matched after the new unit is returned
+	s.do(http.MethodPost, "/savedSearch/create", map[string]interface{}{
+		"userID": f.Tenant.UserID, "name": "Amman", "criteria": map[string]interface{}{"cities": []string{"Amman"}},
+	}, http.StatusCreated, nil)
+	var unit Entities.Unit
+	s.do(http.MethodPost, "/units/create", map[string]interface{}{
+		"propertyID": f.Property.PropertyID, "name": "Roof studio", "rentalPrice": 60, "attributes": map[string]int{"maxGuests": 2},
+		"address": map[string]string{"Country": "Jordan", "city": "Amman"},
+	}, http.StatusCreated, &unit)
+	s.app.Alerts.Wait()
+	s.do(http.MethodGet, "/notifications/user/"+f.Tenant.UserID, nil, http.StatusOK, &notifications)
+	if len(notifications) != 2 || notifications[0].Type != Entities.NotificationNewMatch || notifications[0].UnitID != unit.UnitID {
+		t.Errorf("got notifications %+v, want the new match first", notifications)
+	}
+}
+
+func TestUnitListing(t *testing.T) {
+	onEveryBackend(t, testUnitListing)
+}
+
+func testUnitListing(t *testing.T, start newServer) {
+	s := start(t)
+	f := s.seed()
+	ctx := context.Background()
+	for i, price := range []int{300, 120, 80, 950, 120} {
+		unit := Entities.Unit{PropertyID: f.Property.PropertyID, Name: "Flat " + strconv.Itoa(i), RentalPrice: price,
+			Attributes: Entities.UnitAttributes{MaxGuests: i + 1}, Amenities: []string{"wifi", "pool"}[:i%2+1]}
+		if err := s.app.Repositories.Units.Create(ctx, &unit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sortBy := range []string{"price", "-price", "rating", "newest"} {
+		var seen []string
+		var prices []int
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 6 {
+				t.Fatalf("sort %s: the pages did not end", sortBy)
+			}
+			var page Handlers.UnitPage
+			s.do(http.MethodGet, "/units/?city=AMMAN&limit=2&sort="+url.QueryEscape(sortBy)+"&cursor="+cursor, nil, http.StatusOK, &page)
+			if page.Total != 6 {
+				t.Fatalf("sort %s: got total %d, want 6", sortBy, page.Total)
+			}
+			for _, unit := range page.Units {
+				seen = append(seen, unit.UnitID)
+				prices = append(prices, unit.RentalPrice)
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if unique := map[string]bool{}; len(seen) != 6 {
+			t.Errorf("sort %s: got units %v across the pages, want all 6 once", sortBy, seen)
+		} else {
+			for _, id := range seen {
+				unique[id] = true
+			}
+			if len(unique) != 6 {
+				t.Errorf("sort %s: got units %v across the pages, want all 6 once", sortBy, seen)
+			}
+		}
+		if sortBy == "price" && !sort.IntsAreSorted(prices) {
+			t.Errorf("got prices %v, want them in ascending order", prices)
+		}
+	}
+	s.do(http.MethodGet, "/units/?sort=price&cursor="+listing.CursorOf("rating", f.Unit).Encode(), nil, http.StatusBadRequest, nil)
+
+	var page Handlers.UnitPage
+	s.do(http.MethodGet, "/units/?maxPrice=150&amenities=pool", nil, http.StatusOK, &page)
+	if page.Total != 1 {
+		t.Errorf("got %d units up to 150 with a pool, want 1", page.Total)
+	}
+	// Every facet counts the units that match the other filters
+	wantFacets := map[string]listing.FacetValue{
+		listing.FacetPrice:     {Value: "500-999", Count: 1},
+		listing.FacetAmenities: {Value: "wifi", Count: 4},
+		listing.FacetCity:      {Value: "Amman", Count: 1},
+		listing.FacetGuests:    {Value: "2+", Count: 1},
+	}
+	for facet, want := range wantFacets {
+		found := false
+		for _, value := range page.Facets[facet] {
+			if value == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("got %s facet %+v, want %+v in it", facet, page.Facets[facet], want)
+		}
+	}
+
+	var units []Entities.Unit
+	s.do(http.MethodPost, "/units/SearchByAddress", map[string]string{"street": "rainbow"}, http.StatusOK, &units)
+	if len(units) != 6 {
+		t.Errorf("got %d units on Rainbow Street, want 6", len(units))
+	}
+	s.do(http.MethodPost, "/units/SearchByAddress", map[string]string{"street": "%"}, http.StatusOK, &units)
+	if len(units) != 0 {
+		t.Errorf("a %% in the street matched %d units, want it to be matched literally", len(units))
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	onEveryBackend(t, testAuditLog)
+}
+
+func testAuditLog(t *testing.T, start newServer) {
+	s := start(t)
+	f := s.seed()
+	ctx := context.Background()
+	auditLog := func(entityType, entityID string) []Entities.AuditEntry {
+		t.Helper()
+		var entries []Entities.AuditEntry
+		path := "/admin/audit?entityType=" + entityType + "&entityID=" + entityID
+		s.decode(http.MethodGet, path, s.send(http.MethodGet, path, nil, http.Header{"X-Admin-Token": {"test-admin-token"}}), http.StatusOK, &entries)
+		return entries
+	}
+
+	// Every entry of updates at once starts from where the one before it left the review
+	review := Entities.Review{UserID: f.Tenant.UserID, UnitID: f.Unit.UnitID, Review: "Lovely stay", Rating: 5}
+	if err := s.app.Repositories.Reviews.Create(ctx, &review); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for rating := 1; rating <= 4; rating++ {
+		wg.Add(1)
+		go func(rating int) {
+			defer wg.Done()
+			s.send(http.MethodPut, "/reviews/"+review.ReviewID, map[string]interface{}{"rating": rating}, nil)
+		}(rating)
+	}
+	wg.Wait()
+	entries := auditLog("Review", review.ReviewID)
+	last := "5"
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Action != Entities.AuditUpdate {
+			continue
+		}
+		change := entries[i].Changes["rating"]
+		if string(change.Before) != last {
+			t.Errorf("an update of the review went from rating %s, want %s where the one before it left off", change.Before, last)
+		}
+		last = string(change.After)
+	}
+
+	// Units deleted and restored along with their property get entries of their own
+	lodge := Entities.Property{OwnerID: f.Landlord.UserID, Name: "Dead Sea Lodge", Type: "Villa", TimeZone: "Asia/Amman"}
+	lodge.SetScheduleDefaults()
+	if err := s.app.Repositories.Properties.Create(ctx, &lodge); err != nil {
+		t.Fatal(err)
+	}
+	var units []Entities.Unit
+	for _, name := range []string{"Cabin", "Tent"} {
+		unit := Entities.Unit{PropertyID: lodge.PropertyID, Name: name, RentalPrice: 50, Attributes: Entities.UnitAttributes{MaxGuests: 2}}
+		if err := s.app.Repositories.Units.Create(ctx, &unit); err != nil {
+			t.Fatal(err)
+		}
+		units = append(units, unit)
+	}
+	s.do(http.MethodDelete, "/property/"+lodge.PropertyID, nil, http.StatusOK, nil)
+	s.do(http.MethodPost, "/property/"+lodge.PropertyID+"/restore", nil, http.StatusOK, nil)
+	for _, unit := range units {
+		var actions []string
+		for _, entry := range auditLog("Unit", unit.UnitID) {
+			actions = append(actions, entry.Action)
+		}
+		if want := []string{Entities.AuditRestore, Entities.AuditDelete, Entities.AuditCreate}; fmt.Sprint(actions) != fmt.Sprint(want) {
+			t.Errorf("got audit actions %v on unit %s, want %v", actions, unit.UnitID, want)
+		}
+	}
+
+	// A chat is recorded when the first message creates it
+	var message Entities.Message
+	for _, content := range []string{"Is parking included?", "And breakfast?"} {
+		s.do(http.MethodPost, "/message/send", map[string]string{"senderID": f.Tenant.UserID, "receiverID": f.Landlord.UserID, "content": content},
+			http.StatusCreated, &message)
+	}
+	if entries := auditLog("Chat", message.ChatID); len(entries) != 1 || entries[0].Action != Entities.AuditCreate {
+		t.Errorf("got audit entries %+v of the chat, want its create", entries)
+	}
+}
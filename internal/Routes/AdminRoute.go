@@ -0,0 +1,14 @@
+package Routes
+
+import (
+	handler "GraduationProject.com/m/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes sets up the routes that need the admin token
+func RegisterAdminRoutes(router *gin.Engine, adminToken string, AuditHandler *handler.AuditHandler) {
+	admin := router.Group("/admin", handler.RequireAdminToken(adminToken))
+	{
+		admin.GET("/audit", AuditHandler.GetAuditLog)
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterBookingRoutes(router *gin.Engine, BookingHandler *handler.BookingHandler) {
+func RegisterBookingRoutes(router *gin.Engine, BookingHandler *handler.BookingHandler, idempotent gin.HandlerFunc) {
 	bookingGroup := router.Group("/booking")
 	{
-		bookingGroup.POST("/create", BookingHandler.CreateBooking)
+		bookingGroup.POST("/create", idempotent, BookingHandler.CreateBooking)
 		bookingGroup.GET("/:id", BookingHandler.GetBooking)
 		bookingGroup.PUT("/:id", BookingHandler.UpdateBooking)
+		bookingGroup.PATCH("/:id", BookingHandler.PatchBooking)
 		bookingGroup.DELETE("/:id", BookingHandler.DeleteBooking)
 		bookingGroup.GET("/unit/:id", BookingHandler.GetActiveBookings)
 		bookingGroup.GET("/user/:id", BookingHandler.GetBookingsByUserID)
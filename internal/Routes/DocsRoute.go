@@ -0,0 +1,12 @@
+package Routes
+
+import (
+	"GraduationProject.com/m/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDocsRoutes serves the OpenAPI document and the docs UI on top of it
+func RegisterDocsRoutes(router *gin.Engine) {
+	router.GET("/openapi.json", openapi.ServeSpec)
+	router.GET("/docs", openapi.ServeDocs)
+}
@@ -5,8 +5,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterFinancialTransactionRoutes(router *gin.Engine, FinancialTransactionHandler *handler.FinancialTransactionHandler) {
-	router.POST("/financialTransaction/create", FinancialTransactionHandler.CreateTransaction)
+func RegisterFinancialTransactionRoutes(router *gin.Engine, FinancialTransactionHandler *handler.FinancialTransactionHandler, idempotent gin.HandlerFunc) {
+	router.POST("/financialTransaction/create", idempotent, FinancialTransactionHandler.CreateTransaction)
 	router.GET("/financialTransaction/:id", FinancialTransactionHandler.GetTransaction)
 	router.PUT("/financialTransaction/:id", FinancialTransactionHandler.UpdateTransaction)
 	router.DELETE("/financialTransaction/:id", FinancialTransactionHandler.DeleteTransaction)
@@ -0,0 +1,12 @@
+package Routes
+
+import (
+	handler "GraduationProject.com/m/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterHealthRoutes sets up the probes of the platform running the API
+func RegisterHealthRoutes(router *gin.Engine, HealthHandler *handler.HealthHandler) {
+	router.GET("/healthz", HealthHandler.Healthz)
+	router.GET("/readyz", HealthHandler.Readyz)
+}
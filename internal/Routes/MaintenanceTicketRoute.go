@@ -2,12 +2,13 @@ package Routes
 
 import (
 	handler "GraduationProject.com/m/internal/handler"
-	"github.com/gorilla/mux"
+	"github.com/gin-gonic/gin"
 )
 
-func RegisterMaintenanceTicketRoutes(router *mux.Router, MaintenanceTicketHandler *handler.MaintenanceTicketHandler) {
-	router.HandleFunc("/maintenanceTicket/create", MaintenanceTicketHandler.CreateMaintenanceTicket).Methods("POST")
-	router.HandleFunc("/maintenanceTicket/{id}", MaintenanceTicketHandler.GetMaintenanceTicket).Methods("GET")
-	router.HandleFunc("/maintenanceTicket/{id}", MaintenanceTicketHandler.UpdateMaintenanceTicket).Methods("PUT")
-	router.HandleFunc("/maintenanceTicket/{id}", MaintenanceTicketHandler.DeleteMaintenanceTicket).Methods("DELETE")
+func RegisterMaintenanceTicketRoutes(router *gin.Engine, MaintenanceTicketHandler *handler.MaintenanceTicketHandler, idempotent gin.HandlerFunc) {
+	router.POST("/maintenanceTicket/create", idempotent, MaintenanceTicketHandler.CreateMaintenanceTicket)
+	router.GET("/maintenanceTicket/:id", MaintenanceTicketHandler.GetMaintenanceTicket)
+	router.PUT("/maintenanceTicket/:id", MaintenanceTicketHandler.UpdateMaintenanceTicket)
+	router.PATCH("/maintenanceTicket/:id", MaintenanceTicketHandler.PatchMaintenanceTicket)
+	router.DELETE("/maintenanceTicket/:id", MaintenanceTicketHandler.DeleteMaintenanceTicket)
 }
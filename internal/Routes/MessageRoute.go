@@ -5,8 +5,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterMessageRoutes(router *gin.Engine, MessageHandler *handler.MessageHandler) {
-	router.POST("/message/send", MessageHandler.SendMessage)
+// RegisterMessageRoutes sets up the chat routes, sendLimit goes in front of sending messages
+func RegisterMessageRoutes(router *gin.Engine, MessageHandler *handler.MessageHandler, sendLimit, idempotent gin.HandlerFunc) {
+	router.POST("/message/send", sendLimit, idempotent, MessageHandler.SendMessage)
 	//router.GET("/chat/:id", MessageHandler.GetChat)
 	router.GET("/chat/:id", MessageHandler.GetChatByID)
 	router.GET("/user/chat/:id", MessageHandler.GetChatBySenderID)
@@ -0,0 +1,11 @@
+package Routes
+
+import (
+	handler "GraduationProject.com/m/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterNotificationRoutes(router *gin.Engine, NotificationHandler *handler.NotificationHandler) {
+	router.GET("/notifications/user/:id", NotificationHandler.GetNotificationsByUserID)
+	router.PUT("/notifications/:id/read", NotificationHandler.MarkNotificationRead)
+}
@@ -5,17 +5,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterPropertyRoutes(router *gin.Engine, PropertyHandler *handler.PropertyHandler) {
+func RegisterPropertyRoutes(router *gin.Engine, PropertyHandler *handler.PropertyHandler, idempotent gin.HandlerFunc) {
 	propertyRoutes := router.Group("/property")
 	{
-		propertyRoutes.POST("/create", PropertyHandler.CreateProperty)
+		propertyRoutes.POST("/create", idempotent, PropertyHandler.CreateProperty)
 		propertyRoutes.GET("/:id", PropertyHandler.GetProperty)
 		propertyRoutes.GET("/", PropertyHandler.GetProperties)
 		propertyRoutes.GET("/owner/:id", PropertyHandler.GetPropertiesByUserID)
 		propertyRoutes.GET("/AllUnits/:id", PropertyHandler.GetUnitsByPropertyID)
 		propertyRoutes.GET("/ByType/:type", PropertyHandler.GetPropertiesByType)
 		propertyRoutes.PUT("/:id", PropertyHandler.UpdateProperty)
+		propertyRoutes.PATCH("/:id", PropertyHandler.PatchProperty)
 		propertyRoutes.DELETE("/:id", PropertyHandler.DeleteProperty)
+		propertyRoutes.POST("/:id/restore", PropertyHandler.RestoreProperty)
+		propertyRoutes.POST("/:id/archive", PropertyHandler.ArchiveProperty)
 		propertyRoutes.POST("/proof/add/:id", PropertyHandler.UpdateOrInsertProof)
 		propertyRoutes.GET("/proof/get/:id", PropertyHandler.GetProof)
 	}
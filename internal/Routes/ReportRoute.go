@@ -2,12 +2,12 @@ package Routes
 
 import (
 	handler "GraduationProject.com/m/internal/handler"
-	"github.com/gorilla/mux"
+	"github.com/gin-gonic/gin"
 )
 
-func RegisterReportRoutes(router *mux.Router, ReportHandler *handler.ReportHandler) {
-	router.HandleFunc("/report/create", ReportHandler.CreateReport).Methods("POST")
-	router.HandleFunc("/report/{id}", ReportHandler.GetReport).Methods("GET")
-	router.HandleFunc("/report/{id}", ReportHandler.UpdateReport).Methods("PUT")
-	router.HandleFunc("/report/{id}", ReportHandler.DeleteReport).Methods("DELETE")
+func RegisterReportRoutes(router *gin.Engine, ReportHandler *handler.ReportHandler, idempotent gin.HandlerFunc) {
+	router.POST("/report/create", idempotent, ReportHandler.CreateReport)
+	router.GET("/report/:id", ReportHandler.GetReport)
+	router.PUT("/report/:id", ReportHandler.UpdateReport)
+	router.DELETE("/report/:id", ReportHandler.DeleteReport)
 }
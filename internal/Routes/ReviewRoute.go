@@ -5,10 +5,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterReviewRoutes(router *gin.Engine, ReviewHandler *handler.ReviewHandler) {
-	router.POST("/reviews/create", ReviewHandler.CreateReview)
+func RegisterReviewRoutes(router *gin.Engine, ReviewHandler *handler.ReviewHandler, idempotent gin.HandlerFunc) {
+	router.POST("/reviews/create", idempotent, ReviewHandler.CreateReview)
 	router.GET("/reviews/:id", ReviewHandler.GetReview)
 	router.PUT("/reviews/:id", ReviewHandler.UpdateReview)
+	router.PATCH("/reviews/:id", ReviewHandler.PatchReview)
 	router.DELETE("/reviews/:id", ReviewHandler.DeleteReview)
 	router.GET("/reviews/ByUnit/:id", ReviewHandler.GetReviewsByUnitID)
 }
@@ -0,0 +1,10 @@
+package Routes
+
+import (
+	handler "GraduationProject.com/m/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSearchRoutes(router *gin.Engine, SearchHandler *handler.SearchHandler) {
+	router.GET("/search", SearchHandler.Search)
+}
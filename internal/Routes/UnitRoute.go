@@ -5,19 +5,23 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterUnitRoutes(router *gin.Engine, UnitHandler *handler.UnitHandler) {
+func RegisterUnitRoutes(router *gin.Engine, UnitHandler *handler.UnitHandler, idempotent gin.HandlerFunc) {
 	units := router.Group("/units")
 	{
-		units.POST("/create", UnitHandler.CreateUnit)
+		units.POST("/create", idempotent, UnitHandler.CreateUnit)
 		units.GET("/:id", UnitHandler.GetUnit)
 		units.GET("/", UnitHandler.GetUnits)
 		units.PUT("/:id", UnitHandler.UpdateUnit)
+		units.PATCH("/:id", UnitHandler.PatchUnit)
 		units.DELETE("/:id", UnitHandler.DeleteUnit)
+		units.POST("/:id/restore", UnitHandler.RestoreUnit)
 		// units.GET("/Available", UnitHandler.GetAllAvailableUnits)
 		// units.GET("/Occupied", UnitHandler.GetAllOccupiedUnits)
 		units.POST("/images/add/:id", UnitHandler.UpdateOrInsertImage)
 		units.GET("/images/get/:id", UnitHandler.GetImages)
 		units.POST("/SearchByName", UnitHandler.SearchUnitsByName)
 		units.POST("/SearchByAddress", UnitHandler.SearchUnitsByAddress)
+		units.GET("/nearby", UnitHandler.GetNearbyUnits)
+		units.GET("/map", UnitHandler.GetUnitsInBox)
 	}
 }
@@ -5,16 +5,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterUserRoutes sets up the routes for the application
-func RegisterUserRoutes(router *gin.Engine, UserHandler *handler.UserHandler) {
+// RegisterUserRoutes sets up the routes for the application, loginLimit goes in front of logins
+func RegisterUserRoutes(router *gin.Engine, UserHandler *handler.UserHandler, loginLimit, idempotent gin.HandlerFunc) {
 	users := router.Group("/users")
 	{
-		users.POST("/create", UserHandler.CreateUserHandler)
-		users.POST("/login", UserHandler.LoginHandler)
+		users.POST("/create", idempotent, UserHandler.CreateUserHandler)
+		users.POST("/login", loginLimit, UserHandler.LoginHandler)
 		users.GET("/", UserHandler.GetUsersHandler)
 		users.GET("/:id", UserHandler.GetUserHandler)
 		users.PUT("/:id", UserHandler.UpdateUserHandler)
+		users.PATCH("/:id", UserHandler.PatchUserHandler)
 		users.DELETE("/:id", UserHandler.DeleteUserHandler)
+		users.POST("/:id/restore", UserHandler.RestoreUserHandler)
 		users.GET("/report/:id", UserHandler.GetReports)
 	}
 }
@@ -0,0 +1,28 @@
+package Routes
+
+import (
+	handler "GraduationProject.com/m/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterWishlistRoutes(router *gin.Engine, WishlistHandler *handler.WishlistHandler, idempotent gin.HandlerFunc) {
+	wishlists := router.Group("/wishlist")
+	{
+		wishlists.POST("/create", idempotent, WishlistHandler.CreateWishlist)
+		wishlists.GET("/:id", WishlistHandler.GetWishlist)
+		wishlists.PUT("/:id", WishlistHandler.RenameWishlist)
+		wishlists.DELETE("/:id", WishlistHandler.DeleteWishlist)
+		wishlists.GET("/user/:id", WishlistHandler.GetWishlistsByUserID)
+		wishlists.POST("/:id/units", WishlistHandler.AddUnit)
+		wishlists.DELETE("/:id/units/:unitID", WishlistHandler.RemoveUnit)
+		wishlists.POST("/:id/share", WishlistHandler.ShareWishlist)
+		wishlists.DELETE("/:id/share", WishlistHandler.UnshareWishlist)
+		wishlists.GET("/shared/:token", WishlistHandler.GetSharedWishlist)
+	}
+	savedSearches := router.Group("/savedSearch")
+	{
+		savedSearches.POST("/create", idempotent, WishlistHandler.CreateSavedSearch)
+		savedSearches.GET("/user/:id", WishlistHandler.GetSavedSearchesByUserID)
+		savedSearches.DELETE("/:id", WishlistHandler.DeleteSavedSearch)
+	}
+}
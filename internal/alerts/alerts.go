@@ -0,0 +1,120 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"GraduationProject.com/m/internal/listing"
+	"GraduationProject.com/m/internal/logging"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+// Alerts turns changes to units into notifications for the tenants that saved a matching search or
+// wishlisted the unit. Failures are logged rather than returned, an alert must never fail the change itself.
+type Alerts struct {
+	savedSearches repository.SavedSearchRepository
+	wishlists     repository.WishlistRepository
+	notifications repository.NotificationRepository
+	pending       sync.WaitGroup // Alerts still running after the request that caused them
+}
+
+func New(savedSearches repository.SavedSearchRepository, wishlists repository.WishlistRepository, notifications repository.NotificationRepository) *Alerts {
+	return &Alerts{
+		savedSearches: savedSearches,
+		wishlists:     wishlists,
+		notifications: notifications,
+	}
+}
+
+// UnitCreated notifies every user with a saved search the new unit matches. Every saved search is checked,
+// so it runs in the background and returns right away, Wait waits for it.
+// Like every method, it does nothing on a nil *Alerts, which is how the feature is turned off.
+func (alerts *Alerts) UnitCreated(ctx context.Context, unit Entities.Unit) {
+	if alerts == nil {
+		return
+	}
+	// The request is over before the alerts are, but they still log with its request ID
+	ctx = context.WithoutCancel(ctx)
+	alerts.pending.Add(1)
+	go func() {
+		defer alerts.pending.Done()
+		alerts.notifyMatches(ctx, unit)
+	}()
+}
+
+// Wait returns once the alerts running in the background are done
+func (alerts *Alerts) Wait() {
+	if alerts == nil {
+		return
+	}
+	alerts.pending.Wait()
+}
+
+func (alerts *Alerts) notifyMatches(ctx context.Context, unit Entities.Unit) {
+	searches, err := alerts.savedSearches.List(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Alerts could not load saved searches", slog.Any("error", err))
+		return
+	}
+	notified := make(map[string]bool)
+	for _, search := range searches {
+		var filter listing.Filter
+		if err := json.Unmarshal([]byte(search.Criteria), &filter); err != nil {
+			logging.FromContext(ctx).Warn("Saved search has invalid criteria", slog.String("savedSearchID", search.SavedSearchID), slog.Any("error", err))
+			continue
+		}
+		// One notification per user even when several of their searches match
+		if filter.Matches(unit) && !notified[search.UserID] {
+			notified[search.UserID] = true
+			alerts.notify(ctx, search.UserID, Entities.NotificationNewMatch, unit.UnitID,
+				fmt.Sprintf("%s matches your saved search %q", unitName(unit), search.Name))
+		}
+	}
+}
+
+// PriceChanged notifies the users that wishlisted the unit when its price went down
+func (alerts *Alerts) PriceChanged(ctx context.Context, unit Entities.Unit, oldPrice int) {
+	if alerts == nil || unit.RentalPrice >= oldPrice {
+		return
+	}
+	alerts.notifyWishlisters(ctx, unit.UnitID, Entities.NotificationPriceDrop,
+		fmt.Sprintf("%s dropped from %d to %d", unitName(unit), oldPrice, unit.RentalPrice))
+}
+
+// DatesOpened notifies the users that wishlisted the unit when a booking of it no longer blocks those dates
+func (alerts *Alerts) DatesOpened(ctx context.Context, unitID string, checkIn, checkOut Entities.Date) {
+	if alerts == nil {
+		return
+	}
+	alerts.notifyWishlisters(ctx, unitID, Entities.NotificationDatesAvailable,
+		fmt.Sprintf("A unit on your wishlist is available again from %s to %s", checkIn, checkOut))
+}
+
+func (alerts *Alerts) notifyWishlisters(ctx context.Context, unitID, notificationType, message string) {
+	userIDs, err := alerts.wishlists.ListUserIDsByUnit(ctx, unitID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Alerts could not load wishlists", slog.String("unitID", unitID), slog.Any("error", err))
+		return
+	}
+	for _, userID := range userIDs {
+		alerts.notify(ctx, userID, notificationType, unitID, message)
+	}
+}
+
+func (alerts *Alerts) notify(ctx context.Context, userID, notificationType, unitID, message string) {
+	notification := Entities.Notification{UserID: userID, Type: notificationType, UnitID: unitID, Message: message}
+	if err := alerts.notifications.Create(ctx, &notification); err != nil {
+		logging.FromContext(ctx).Error("Alerts could not notify user", slog.String("userID", userID), slog.Any("error", err))
+	}
+}
+
+func unitName(unit Entities.Unit) string {
+	if unit.Name != "" {
+		return unit.Name
+	}
+	return "Unit " + unit.UnitID
+}
@@ -0,0 +1,117 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code tells clients what kind of error they got, codes never change once published
+type Code string
+
+const (
+	NotFound         Code = "not_found"
+	ValidationFailed Code = "validation_failed"
+	Conflict         Code = "conflict"
+	Unauthorized     Code = "unauthorized"
+	Internal         Code = "internal"
+	Unavailable      Code = "unavailable"       // The server cannot take requests right now, like while it shuts down
+	TooManyRequests  Code = "too_many_requests" // Rate limited or locked out, Retry-After says for how long
+	// The entity changed since the client read it, the If-Match of an update is not its current ETag
+	PreconditionFailed Code = "precondition_failed"
+)
+
+// Codes lists every code, in the order they are documented
+var Codes = []Code{NotFound, ValidationFailed, Conflict, Unauthorized, Internal, Unavailable, TooManyRequests, PreconditionFailed}
+
+// Status is the HTTP status code errors of the code are sent with
+func (code Code) Status() int {
+	switch code {
+	case NotFound:
+		return http.StatusNotFound
+	case ValidationFailed:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case TooManyRequests:
+		return http.StatusTooManyRequests
+	case PreconditionFailed:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError is a problem with one field of a request, Field is its JSON name
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string { return e.Message }
+
+// Field returns the error validation reports for a bad field, message is a whole sentence about it
+func Field(field, message string) error {
+	return FieldError{Field: field, Message: message}
+}
+
+// Error is an error with a message that is safe to show to clients. The cause, if any, is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	cause   error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.cause }
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap keeps cause for the logs, clients only see message
+func Wrap(code Code, message string, cause error) *Error {
+	return &Error{Code: code, Message: message, cause: cause}
+}
+
+// Invalid turns a validation error into a validation_failed one. Field errors anywhere in err's chain,
+// including joined ones, are listed as details.
+func Invalid(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	invalid := &Error{Code: ValidationFailed, Message: err.Error(), Fields: fieldErrors(err)}
+	if len(invalid.Fields) == 1 {
+		invalid.Message = invalid.Fields[0].Message
+	} else if len(invalid.Fields) > 1 {
+		invalid.Message = "Some fields are not valid"
+	}
+	return invalid
+}
+
+func fieldErrors(err error) []FieldError {
+	switch e := err.(type) {
+	case FieldError:
+		return []FieldError{e}
+	case interface{ Unwrap() []error }:
+		var fields []FieldError
+		for _, inner := range e.Unwrap() {
+			fields = append(fields, fieldErrors(inner)...)
+		}
+		return fields
+	case interface{ Unwrap() error }:
+		return fieldErrors(e.Unwrap())
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+const (
+	// Anonymous is the actor of requests that did not say which user made them
+	Anonymous = "anonymous"
+	// System is the actor of writes made outside of a request, like startup and background jobs
+	System = "system"
+)
+
+// Origin is who made a write and in which request
+type Origin struct {
+	Actor     string
+	RequestID string
+}
+
+type originKey struct{}
+
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, origin)
+}
+
+// OriginFrom returns the origin stored in ctx, writes without one are made by System
+func OriginFrom(ctx context.Context) Origin {
+	origin, ok := ctx.Value(originKey{}).(Origin)
+	if !ok || origin.Actor == "" {
+		origin.Actor = System
+	}
+	return origin
+}
+
+var (
+	// redacted fields are recorded as changed without their values
+	redacted = map[string]bool{"password": true, "shareToken": true}
+	// omitted fields are not recorded at all, images are too large to keep a copy of on every write
+	omitted = map[string]bool{"images": true}
+)
+
+const redactedValue = `"[REDACTED]"`
+
+// Diff compares the JSON fields of before and after, either of which can be nil for a create or a delete.
+// Only the fields whose JSON differs are returned.
+func Diff(before, after interface{}) (map[string]Entities.AuditChange, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	current, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]Entities.AuditChange)
+	for name, value := range old {
+		if next, ok := current[name]; !ok || string(next) != string(value) {
+			changes[name] = Entities.AuditChange{Before: value, After: next}
+		}
+	}
+	for name, value := range current {
+		if _, ok := old[name]; !ok {
+			changes[name] = Entities.AuditChange{After: value}
+		}
+	}
+	for name, change := range changes {
+		if redacted[name] {
+			if change.Before != nil {
+				change.Before = json.RawMessage(redactedValue)
+			}
+			if change.After != nil {
+				change.After = json.RawMessage(redactedValue)
+			}
+			changes[name] = change
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the top level JSON fields of value, which must marshal to an object or null
+func fields(value interface{}) (map[string]json.RawMessage, error) {
+	result := make(map[string]json.RawMessage)
+	if value == nil {
+		return result, nil
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	for name := range omitted {
+		delete(result, name)
+	}
+	return result, nil
+}
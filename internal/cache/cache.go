@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// stats publishes the counters of every cache under /debug/vars, as "cache": {"<name>": {"hits": ..}}
+var stats = expvar.NewMap("cache")
+
+type Options struct {
+	TTL        time.Duration // Zero keeps entries until they are evicted or invalidated
+	MaxEntries int           // Zero means unbounded, otherwise the least recently used entries are evicted
+	Shards     int           // Number of independently locked shards, defaults to 16
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+type shard[V any] struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is the most recently used
+	max     int
+	// generation is bumped by every invalidation, so a load that started before it is not stored
+	generation uint64
+}
+
+// Cache is a concurrency-safe in-memory cache keyed by string IDs
+type Cache[V any] struct {
+	ttl    time.Duration
+	shards []*shard[V]
+	group  singleflight.Group
+
+	hits, misses, loads, evictions *expvar.Int
+}
+
+// New creates a cache, name is the key its counters are published under
+func New[V any](name string, options Options) *Cache[V] {
+	if options.Shards <= 0 {
+		options.Shards = 16
+	}
+	perShard := 0
+	if options.MaxEntries > 0 {
+		perShard = (options.MaxEntries + options.Shards - 1) / options.Shards
+	}
+	cache := &Cache[V]{ttl: options.TTL}
+	for i := 0; i < options.Shards; i++ {
+		cache.shards = append(cache.shards, &shard[V]{entries: make(map[string]*list.Element), order: list.New(), max: perShard})
+	}
+
+	// Caches created again under the same name, like in tests, share their counters
+	counters, ok := stats.Get(name).(*expvar.Map)
+	if !ok {
+		counters = new(expvar.Map).Init()
+		stats.Set(name, counters)
+	}
+	cache.hits = counter(counters, "hits")
+	cache.misses = counter(counters, "misses")
+	cache.loads = counter(counters, "loads")
+	cache.evictions = counter(counters, "evictions")
+	return cache
+}
+
+func counter(counters *expvar.Map, name string) *expvar.Int {
+	if value, ok := counters.Get(name).(*expvar.Int); ok {
+		return value
+	}
+	value := new(expvar.Int)
+	counters.Set(name, value)
+	return value
+}
+
+func (cache *Cache[V]) shard(key string) *shard[V] {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return cache.shards[hash.Sum32()%uint32(len(cache.shards))]
+}
+
+// Get returns the cached value of key, if there is one that has not expired
+func (cache *Cache[V]) Get(key string) (V, bool) {
+	s := cache.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.get(key)
+	if ok {
+		cache.hits.Add(1)
+	} else {
+		cache.misses.Add(1)
+	}
+	return value, ok
+}
+
+func (s *shard[V]) get(key string) (V, bool) {
+	element, ok := s.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	e := element.Value.(*entry[V])
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		s.order.Remove(element)
+		delete(s.entries, key)
+		var zero V
+		return zero, false
+	}
+	s.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry when the shard is full
+func (cache *Cache[V]) Set(key string, value V) {
+	s := cache.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cache.set(s, key, value)
+}
+
+func (cache *Cache[V]) set(s *shard[V], key string, value V) {
+	var expires time.Time
+	if cache.ttl > 0 {
+		expires = time.Now().Add(cache.ttl)
+	}
+	if element, ok := s.entries[key]; ok {
+		e := element.Value.(*entry[V])
+		e.value, e.expires = value, expires
+		s.order.MoveToFront(element)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&entry[V]{key: key, value: value, expires: expires})
+	for s.max > 0 && s.order.Len() > s.max {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*entry[V]).key)
+		cache.evictions.Add(1)
+	}
+}
+
+// GetOrLoad returns the cached value of key, or calls load once for all the callers missing the same key at the same time.
+// The load runs with the values of the first caller's ctx but without its cancellation, so a caller that gives up does
+// not fail the others. Each caller still returns as soon as its own ctx is done. Errors are not cached.
+func (cache *Cache[V]) GetOrLoad(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	if value, ok := cache.Get(key); ok {
+		return value, nil
+	}
+	s := cache.shard(key)
+	s.mu.Lock()
+	generation := s.generation
+	s.mu.Unlock()
+
+	loadCtx := context.WithoutCancel(ctx)
+	result := cache.group.DoChan(key, func() (interface{}, error) {
+		cache.loads.Add(1)
+		value, err := load(loadCtx)
+		if err != nil {
+			return value, err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// A write that went through while loading may have made the value stale
+		if s.generation == generation {
+			cache.set(s, key, value)
+		}
+		return value, nil
+	})
+	var zero V
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case loaded := <-result:
+		if loaded.Err != nil {
+			return zero, loaded.Err
+		}
+		return loaded.Val.(V), nil
+	}
+}
+
+// Delete invalidates key, loads of it that are in flight are not stored
+func (cache *Cache[V]) Delete(key string) {
+	s := cache.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+	cache.group.Forget(key)
+}
+
+// Clear invalidates every entry
+func (cache *Cache[V]) Clear() {
+	for _, s := range cache.shards {
+		s.mu.Lock()
+		s.generation++
+		s.entries = make(map[string]*list.Element)
+		s.order.Init()
+		s.mu.Unlock()
+	}
+}
+
+// Len returns the number of stored entries, including the ones that expired but were not read since
+func (cache *Cache[V]) Len() int {
+	total := 0
+	for _, s := range cache.shards {
+		s.mu.Lock()
+		total += s.order.Len()
+		s.mu.Unlock()
+	}
+	return total
+}
+
+// Counters are the totals of the caches created under one name
+type Counters struct {
+	Name                           string
+	Hits, Misses, Loads, Evictions int64
+}
+
+// Stats returns the counters of every cache name, as published under /debug/vars
+func Stats() []Counters {
+	var all []Counters
+	stats.Do(func(kv expvar.KeyValue) {
+		counters, ok := kv.Value.(*expvar.Map)
+		if !ok {
+			return
+		}
+		all = append(all, Counters{
+			Name:      kv.Key,
+			Hits:      counterValue(counters, "hits"),
+			Misses:    counterValue(counters, "misses"),
+			Loads:     counterValue(counters, "loads"),
+			Evictions: counterValue(counters, "evictions"),
+		})
+	})
+	return all
+}
+
+func counterValue(counters *expvar.Map, name string) int64 {
+	if counter, ok := counters.Get(name).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
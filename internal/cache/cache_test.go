@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvictsTheLeastRecentlyUsed(t *testing.T) {
+	cache := New[int]("test-eviction", Options{MaxEntries: 2, Shards: 1})
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	cache.Get("a") // b is now the least recently used
+	cache.Set("c", 3)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, ok := cache.Get(tt.key); ok != tt.want {
+			t.Errorf("Get(%q) found %v, want %v", tt.key, ok, tt.want)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("got %d entries, want 2", cache.Len())
+	}
+}
+
+func TestExpires(t *testing.T) {
+	cache := New[int]("test-expiry", Options{TTL: time.Millisecond})
+	cache.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("an expired entry was returned")
+	}
+}
+
+func TestGetOrLoadCoalescesConcurrentLoads(t *testing.T) {
+	cache := New[int]("test-coalescing", Options{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	values := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = cache.GetOrLoad(context.Background(), "answer", load)
+		}(i)
+	}
+	// Give the callers time to wait on the same load
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("load was called %d times, want once", calls.Load())
+	}
+	for i, value := range values {
+		if value != 42 {
+			t.Errorf("caller %d got %d, want 42", i, value)
+		}
+	}
+	if value, ok := cache.Get("answer"); !ok || value != 42 {
+		t.Errorf("got cached %d, %v, want 42", value, ok)
+	}
+}
+
+func TestGetOrLoadOutlivesACanceledCaller(t *testing.T) {
+	cache := New[int]("test-cancel", Options{})
+	started, release := make(chan struct{}), make(chan struct{})
+	load := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		return 7, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.GetOrLoad(ctx, "key", load)
+		first <- err
+	}()
+	<-started
+	second := make(chan int, 1)
+	go func() {
+		value, _ := cache.GetOrLoad(context.Background(), "key", load)
+		second <- value
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("the canceled caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if value := <-second; value != 7 {
+		t.Errorf("the other caller got %d, want 7 from a load that was not canceled", value)
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(cache *Cache[int])
+	}{
+		{"delete", func(cache *Cache[int]) { cache.Delete("key") }},
+		{"clear", func(cache *Cache[int]) { cache.Clear() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := New[int]("test-invalidation", Options{})
+			cache.Set("key", 1)
+			tt.invalidate(cache)
+			if _, ok := cache.Get("key"); ok {
+				t.Fatal("the entry is still cached")
+			}
+
+			// A load that started before the invalidation returns its value but does not store it
+			started, release := make(chan struct{}), make(chan struct{})
+			done := make(chan int, 1)
+			go func() {
+				value, _ := cache.GetOrLoad(context.Background(), "key", func(context.Context) (int, error) {
+					close(started)
+					<-release
+					return 2, nil
+				})
+				done <- value
+			}()
+			<-started
+			tt.invalidate(cache)
+			close(release)
+			if value := <-done; value != 2 {
+				t.Errorf("the load returned %d, want 2", value)
+			}
+			if value, ok := cache.Get("key"); ok {
+				t.Errorf("a load from before the invalidation stored %d", value)
+			}
+		})
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	cache := New[int]("test-errors", Options{})
+	failure := errors.New("unavailable")
+	if _, err := cache.GetOrLoad(context.Background(), "key", func(context.Context) (int, error) { return 0, failure }); !errors.Is(err, failure) {
+		t.Fatalf("got error %v, want %v", err, failure)
+	}
+	value, err := cache.GetOrLoad(context.Background(), "key", func(context.Context) (int, error) { return 3, nil })
+	if err != nil || value != 3 {
+		t.Errorf("got %d, %v after a failed load, want 3", value, err)
+	}
+}
@@ -0,0 +1,482 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Secret is a string that never shows up in logs or JSON, Value returns it
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) Value() string { return string(s) }
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string { return strconv.Quote(s.String()) }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+// Duration is written like "5m" or "30s" in the config file
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("durations are strings like \"5m\": %v", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// RateLimit allows Requests at once and as many more every Per. It is written like "20/1m" in the config
+// file, and "off" is no limit.
+type RateLimit struct {
+	Requests int
+	Per      Duration
+}
+
+func (l RateLimit) String() string {
+	if l.Requests <= 0 {
+		return "off"
+	}
+	return strconv.Itoa(l.Requests) + "/" + l.Per.String()
+}
+
+func (l RateLimit) MarshalJSON() ([]byte, error) { return json.Marshal(l.String()) }
+
+func (l *RateLimit) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("rate limits are strings like \"20/1m\": %v", err)
+	}
+	limit, err := ParseRateLimit(value)
+	if err != nil {
+		return err
+	}
+	*l = limit
+	return nil
+}
+
+// ParseRateLimit reads a limit written like "20/1m", or "off"
+func ParseRateLimit(value string) (RateLimit, error) {
+	if value == "off" {
+		return RateLimit{}, nil
+	}
+	requests, per, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n < 0 {
+		return RateLimit{}, fmt.Errorf("rate limits are written like 20/1m or off, got %q", value)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limits are written like 20/1m or off, got %q", value)
+	}
+	return RateLimit{Requests: n, Per: Duration{d}}, nil
+}
+
+// RateLimitGroup limits the requests to a group of routes from each client IP and each user
+type RateLimitGroup struct {
+	PerIP   RateLimit `json:"perIP"`
+	PerUser RateLimit `json:"perUser"` // The user in X-User-ID
+}
+
+type RateLimits struct {
+	Default  RateLimitGroup `json:"default"`  // Every route
+	Login    RateLimitGroup `json:"login"`    // POST /users/login, on top of the default
+	Messages RateLimitGroup `json:"messages"` // POST /message/send, on top of the default
+	Lockout  Lockout        `json:"lockout"`
+}
+
+// Lockout slows down failed logins to the same email, then locks it
+type Lockout struct {
+	Threshold int      `json:"threshold"` // Failures in a row that lock the email, zero turns lockouts and delays off
+	Duration  Duration `json:"duration"`  // How long a lockout lasts, and how long failures are remembered
+	Delay     Duration `json:"delay"`     // Wait after the first failure, doubled by every further one
+}
+
+type Database struct {
+	Driver          string   `json:"driver"` // mysql or sqlite
+	Path            string   `json:"path"`   // SQLite file, or :memory:
+	User            string   `json:"user"`
+	Password        Secret   `json:"password"`
+	Address         string   `json:"address"` // host:port
+	Name            string   `json:"name"`
+	MaxOpenConns    int      `json:"maxOpenConns"`
+	MaxIdleConns    int      `json:"maxIdleConns"`
+	ConnMaxLifetime Duration `json:"connMaxLifetime"`
+}
+
+// DSN builds the data source name for the driver, it contains the password so it must not be logged.
+// Times are stored in UTC and scanned as time.Time by both drivers.
+func (db Database) DSN() string {
+	if db.Driver == "sqlite" {
+		// Foreign keys are off by default in SQLite, and writers wait for each other instead of failing
+		return "file:" + db.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+	}
+	dsn := mysql.NewConfig()
+	dsn.User = db.User
+	dsn.Passwd = db.Password.Value()
+	dsn.Net = "tcp"
+	dsn.Addr = db.Address
+	dsn.DBName = db.Name
+	dsn.ParseTime = true
+	dsn.Loc = time.UTC
+	// CURRENT_TIMESTAMP defaults follow the session time zone
+	dsn.Params = map[string]string{"time_zone": "'+00:00'"}
+	return dsn.FormatDSN()
+}
+
+type Cache struct {
+	TTL        Duration `json:"ttl"`
+	MaxEntries int      `json:"maxEntries"`
+}
+
+// Server holds the timeouts of the HTTP server
+type Server struct {
+	ReadTimeout     Duration `json:"readTimeout"`     // Reading a whole request, body included
+	WriteTimeout    Duration `json:"writeTimeout"`    // From the end of the request headers to the end of the response
+	IdleTimeout     Duration `json:"idleTimeout"`     // Keep-alive connections waiting for the next request
+	ShutdownTimeout Duration `json:"shutdownTimeout"` // Draining in-flight requests on SIGTERM before they are cut off
+}
+
+// Tracing picks where OpenTelemetry spans go
+type Tracing struct {
+	Exporter    string  `json:"exporter"`    // none, stdout or otlp
+	Endpoint    string  `json:"endpoint"`    // OTLP over HTTP, host:port. The OTEL_EXPORTER_OTLP_* variables apply when it is empty.
+	Insecure    bool    `json:"insecure"`    // Plain HTTP to the OTLP endpoint, for a collector next to the app
+	SampleRatio float64 `json:"sampleRatio"` // Share of the traces started here that are recorded, callers decide for their own
+}
+
+type Features struct {
+	Cache     bool `json:"cache"`     // Read cache in front of unit and property lookups
+	Alerts    bool `json:"alerts"`    // Notifications for saved searches and wishlists
+	DebugVars bool `json:"debugVars"` // Serve /debug/vars
+	Metrics   bool `json:"metrics"`   // Serve /metrics for Prometheus
+}
+
+type Config struct {
+	Port           string     `json:"port"`
+	CORSOrigins    []string   `json:"corsOrigins"`    // Empty or "*" allows every origin
+	TrustedProxies []string   `json:"trustedProxies"` // Addresses whose X-Forwarded-For gives the client IP, none by default
+	AdminToken     Secret     `json:"adminToken"`     // Required in X-Admin-Token by the /admin routes, which are off without it
+	StorageBackend string     `json:"storageBackend"`
+	LogLevel       string     `json:"logLevel"`
+	LogFormat      string     `json:"logFormat"`
+	Server         Server     `json:"server"`
+	Database       Database   `json:"database"`
+	Cache          Cache      `json:"cache"`
+	Tracing        Tracing    `json:"tracing"`
+	RateLimits     RateLimits `json:"rateLimits"`
+	IdempotencyTTL Duration   `json:"idempotencyTTL"` // How long responses are replayed to retries with the same Idempotency-Key
+	Features       Features   `json:"features"`
+}
+
+// Default is the configuration before the file and the environment are applied
+func Default() Config {
+	return Config{
+		Port:           "8080",
+		StorageBackend: "database",
+		LogLevel:       "info",
+		LogFormat:      "json",
+		Server: Server{
+			ReadTimeout:     Duration{30 * time.Second},
+			WriteTimeout:    Duration{60 * time.Second},
+			IdleTimeout:     Duration{2 * time.Minute},
+			ShutdownTimeout: Duration{20 * time.Second},
+		},
+		Database: Database{
+			Driver:          "mysql",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: Duration{5 * time.Minute},
+		},
+		Cache:   Cache{TTL: Duration{5 * time.Minute}, MaxEntries: 10000},
+		Tracing: Tracing{Exporter: "none", SampleRatio: 1},
+		RateLimits: RateLimits{
+			Default:  RateLimitGroup{PerIP: RateLimit{300, Duration{time.Minute}}, PerUser: RateLimit{300, Duration{time.Minute}}},
+			Login:    RateLimitGroup{PerIP: RateLimit{20, Duration{time.Minute}}},
+			Messages: RateLimitGroup{PerIP: RateLimit{60, Duration{time.Minute}}, PerUser: RateLimit{20, Duration{time.Minute}}},
+			Lockout:  Lockout{Threshold: 5, Duration: Duration{15 * time.Minute}, Delay: Duration{time.Second}},
+		},
+		IdempotencyTTL: Duration{24 * time.Hour},
+		Features:       Features{Cache: true, Alerts: true, Metrics: true},
+	}
+}
+
+// Load reads the configuration: the defaults, then the JSON file at path if there is one, then the environment.
+// The result is validated.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("could not read config file: %v", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("could not parse config file %s: %v", path, err)
+		}
+	}
+	if err := applyEnv(&cfg, os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// applyEnv overrides the configuration with the environment variables that are set
+func applyEnv(cfg *Config, lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(name string, target *string) {
+		if value, ok := lookup(name); ok {
+			*target = value
+		}
+	}
+	secret := func(name string, target *Secret) {
+		if value, ok := lookup(name); ok {
+			*target = Secret(value)
+		}
+	}
+	number := func(name string, target *int) {
+		if value, ok := lookup(name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a whole number, got %q", name, value))
+				return
+			}
+			*target = n
+		}
+	}
+	duration := func(name string, target *Duration) {
+		if value, ok := lookup(name); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration like 5m, got %q", name, value))
+				return
+			}
+			target.Duration = d
+		}
+	}
+	ratio := func(name string, target *float64) {
+		if value, ok := lookup(name); ok {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a number like 0.25, got %q", name, value))
+				return
+			}
+			*target = f
+		}
+	}
+	// Lists are comma separated
+	list := func(name string, target *[]string) {
+		if value, ok := lookup(name); ok {
+			*target = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*target = append(*target, item)
+				}
+			}
+		}
+	}
+	rateLimit := func(name string, target *RateLimit) {
+		if value, ok := lookup(name); ok {
+			limit, err := ParseRateLimit(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", name, err))
+				return
+			}
+			*target = limit
+		}
+	}
+	flag := func(name string, target *bool) {
+		if value, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be true or false, got %q", name, value))
+				return
+			}
+			*target = b
+		}
+	}
+
+	str("PORT", &cfg.Port)
+	list("CORS_ORIGINS", &cfg.CORSOrigins)
+	list("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	secret("ADMIN_TOKEN", &cfg.AdminToken)
+	str("STORAGE_BACKEND", &cfg.StorageBackend)
+	str("LOG_LEVEL", &cfg.LogLevel)
+	str("LOG_FORMAT", &cfg.LogFormat)
+
+	duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	duration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	str("DB_DRIVER", &cfg.Database.Driver)
+	str("DB_PATH", &cfg.Database.Path)
+	str("DB_USER", &cfg.Database.User)
+	secret("DB_PASSWORD", &cfg.Database.Password)
+	str("DB_ADDRESS", &cfg.Database.Address)
+	str("DB_NAME", &cfg.Database.Name)
+	number("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	number("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	duration("DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+
+	duration("CACHE_TTL", &cfg.Cache.TTL)
+	number("CACHE_MAX_ENTRIES", &cfg.Cache.MaxEntries)
+
+	rateLimit("RATE_LIMIT_DEFAULT_PER_IP", &cfg.RateLimits.Default.PerIP)
+	rateLimit("RATE_LIMIT_DEFAULT_PER_USER", &cfg.RateLimits.Default.PerUser)
+	rateLimit("RATE_LIMIT_LOGIN_PER_IP", &cfg.RateLimits.Login.PerIP)
+	rateLimit("RATE_LIMIT_LOGIN_PER_USER", &cfg.RateLimits.Login.PerUser)
+	rateLimit("RATE_LIMIT_MESSAGES_PER_IP", &cfg.RateLimits.Messages.PerIP)
+	rateLimit("RATE_LIMIT_MESSAGES_PER_USER", &cfg.RateLimits.Messages.PerUser)
+	number("LOGIN_LOCKOUT_THRESHOLD", &cfg.RateLimits.Lockout.Threshold)
+	duration("LOGIN_LOCKOUT_DURATION", &cfg.RateLimits.Lockout.Duration)
+	duration("LOGIN_LOCKOUT_DELAY", &cfg.RateLimits.Lockout.Delay)
+
+	duration("IDEMPOTENCY_KEY_TTL", &cfg.IdempotencyTTL)
+
+	str("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	str("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	flag("TRACING_INSECURE", &cfg.Tracing.Insecure)
+	ratio("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+
+	flag("FEATURE_CACHE", &cfg.Features.Cache)
+	flag("FEATURE_ALERTS", &cfg.Features.Alerts)
+	flag("FEATURE_DEBUG_VARS", &cfg.Features.DebugVars)
+	flag("FEATURE_METRICS", &cfg.Features.Metrics)
+	return errors.Join(errs...)
+}
+
+var (
+	logLevels       = []string{"debug", "info", "warn", "error"}
+	logFormats      = []string{"json", "text"}
+	traceExporters  = []string{"none", "stdout", "otlp"}
+	storageBackends = []string{"database"} // Images and proofs are stored in the Images table
+)
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate reports every problem with the configuration at once
+func (cfg Config) Validate() error {
+	var errs []error
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %q", cfg.Port))
+	}
+	timeouts := []struct {
+		name  string
+		value Duration
+	}{
+		{"SERVER_READ_TIMEOUT", cfg.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", cfg.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", cfg.Server.IdleTimeout},
+		{"SERVER_SHUTDOWN_TIMEOUT", cfg.Server.ShutdownTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", timeout.name, timeout.value))
+		}
+	}
+	switch cfg.Database.Driver {
+	case "mysql":
+		if cfg.Database.User == "" {
+			errs = append(errs, errors.New("DB_USER is required"))
+		}
+		if cfg.Database.Address == "" {
+			errs = append(errs, errors.New("DB_ADDRESS is required"))
+		} else if !strings.Contains(cfg.Database.Address, ":") {
+			errs = append(errs, fmt.Errorf("DB_ADDRESS must be host:port, got %q", cfg.Database.Address))
+		}
+		if cfg.Database.Name == "" {
+			errs = append(errs, errors.New("DB_NAME is required"))
+		}
+	case "sqlite":
+		if cfg.Database.Path == "" {
+			errs = append(errs, errors.New("DB_PATH is required for sqlite, use :memory: for a database that lives as long as the process"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be mysql or sqlite, got %q", cfg.Database.Driver))
+	}
+	if cfg.Database.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS must be at least 1"))
+	}
+	if cfg.Database.MaxIdleConns < 0 || cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS"))
+	}
+	if cfg.Database.ConnMaxLifetime.Duration < 0 {
+		errs = append(errs, errors.New("DB_CONN_MAX_LIFETIME must not be negative"))
+	}
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < 32 {
+		errs = append(errs, errors.New("ADMIN_TOKEN must be at least 32 characters"))
+	}
+	if !oneOf(cfg.StorageBackend, storageBackends) {
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND must be one of %s, got %q", strings.Join(storageBackends, ", "), cfg.StorageBackend))
+	}
+	if !oneOf(cfg.LogLevel, logLevels) {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of %s, got %q", strings.Join(logLevels, ", "), cfg.LogLevel))
+	}
+	if !oneOf(cfg.LogFormat, logFormats) {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be one of %s, got %q", strings.Join(logFormats, ", "), cfg.LogFormat))
+	}
+	if !oneOf(cfg.Tracing.Exporter, traceExporters) {
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER must be one of %s, got %q", strings.Join(traceExporters, ", "), cfg.Tracing.Exporter))
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", cfg.Tracing.SampleRatio))
+	}
+	if cfg.IdempotencyTTL.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("IDEMPOTENCY_KEY_TTL must be positive, got %s", cfg.IdempotencyTTL))
+	}
+	if lockout := cfg.RateLimits.Lockout; lockout.Threshold < 0 || lockout.Threshold > 0 && (lockout.Duration.Duration <= 0 || lockout.Delay.Duration < 0) {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT_THRESHOLD must not be negative, and with a threshold LOGIN_LOCKOUT_DURATION must be positive and LOGIN_LOCKOUT_DELAY not negative"))
+	}
+	if cfg.Features.Cache && (cfg.Cache.TTL.Duration < 0 || cfg.Cache.MaxEntries < 0) {
+		errs = append(errs, errors.New("CACHE_TTL and CACHE_MAX_ENTRIES must not be negative"))
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES entries must be IP addresses or CIDR ranges, got %q", proxy))
+		}
+	}
+	for _, origin := range cfg.CORSOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("CORS_ORIGINS entries must start with http:// or https://, got %q", origin))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const baselineFile = "migrations/mysql/0001_baseline.up.sql"
+
+// numericText matches the text of a number, the values of a text column that survive becoming DECIMAL
+const numericText = `'^[-+]?([0-9]+([.][0-9]*)?|[.][0-9]+)$'`
+
+// MigrateBaseline creates the tables of the baseline that are missing, then brings the ones that were set up
+// by hand to the baseline: columns are added or changed to their type, and missing indexes and foreign keys
+// are added. It fails without changing more when rows stand in the way, like orphans of a foreign key or
+// duplicates of a unique key, so they can be cleaned up by hand before it is run again.
+func MigrateBaseline(db *sql.DB) error {
+	content, err := migrationFiles.ReadFile(baselineFile)
+	if err != nil {
+		return err
+	}
+	if err := execStatements(db, string(content)); err != nil {
+		return err
+	}
+	return adoptBaseline(db, string(content))
+}
+
+// AdoptBaseline is migration 12, for databases that ran the baseline before it adopted existing tables
+func AdoptBaseline(db *sql.DB) error {
+	content, err := migrationFiles.ReadFile(baselineFile)
+	if err != nil {
+		return err
+	}
+	return adoptBaseline(db, string(content))
+}
+
+type baselineTable struct {
+	name        string
+	columns     []baselineColumn
+	indexes     map[string]string // Name to the line that declares it
+	foreignKeys map[string]string
+	order       []string // Index and foreign key names in the order they are declared
+}
+
+type baselineColumn struct {
+	name, kind, definition string
+	nullable               bool
+}
+
+var createTable = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \($`)
+
+// parseBaseline reads the tables out of the baseline, which declares one column, key or constraint per line
+func parseBaseline(content string) []baselineTable {
+	var tables []baselineTable
+	var table *baselineTable
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if match := createTable.FindStringSubmatch(line); match != nil {
+			tables = append(tables, baselineTable{name: match[1], indexes: map[string]string{}, foreignKeys: map[string]string{}})
+			table = &tables[len(tables)-1]
+			continue
+		}
+		if table == nil || line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		if strings.HasPrefix(line, ")") {
+			table = nil
+			continue
+		}
+		line = strings.TrimSuffix(line, ",")
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "PRIMARY KEY"):
+			// Tables set up by hand have their primary key, changing it would rewrite every reference
+		case fields[0] == "KEY":
+			table.indexes[fields[1]] = line
+			table.order = append(table.order, fields[1])
+		case fields[0] == "UNIQUE":
+			table.indexes[fields[2]] = line
+			table.order = append(table.order, fields[2])
+		case fields[0] == "CONSTRAINT":
+			table.foreignKeys[fields[1]] = line
+			table.order = append(table.order, fields[1])
+		default:
+			definition := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+			table.columns = append(table.columns, baselineColumn{
+				name:       fields[0],
+				kind:       strings.ToLower(strings.Fields(definition)[0]),
+				definition: definition,
+				nullable:   !strings.Contains(definition, "NOT NULL"),
+			})
+		}
+	}
+	return tables
+}
+
+func adoptBaseline(db *sql.DB, content string) error {
+	for _, table := range parseBaseline(content) {
+		var changes []string
+		for _, column := range table.columns {
+			var columnType, nullable string
+			err := db.QueryRow(`SELECT COLUMN_TYPE, IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+				table.name, column.name).Scan(&columnType, &nullable)
+			switch {
+			case err == sql.ErrNoRows:
+				changes = append(changes, "ADD COLUMN "+column.name+" "+column.definition)
+				continue
+			case err != nil:
+				return err
+			}
+			if sameType(columnType, column.kind) && (nullable == "YES") == column.nullable {
+				continue
+			}
+			if column.kind == "json" && isText(columnType) {
+				// Free-form text that is not JSON would fail the change, the app reads either the same way
+				continue
+			}
+			if strings.HasPrefix(column.kind, "decimal") && column.nullable && isText(columnType) {
+				// Text that is not a number cannot be converted, like migration 9 it is cleared
+				_, err := db.Exec(fmt.Sprintf(`UPDATE %s SET %s = NULL WHERE TRIM(%s) NOT REGEXP %s`, table.name, column.name, column.name, numericText))
+				if err != nil {
+					return fmt.Errorf("could not clear the values of %s.%s that are not numbers: %v", table.name, column.name, err)
+				}
+			}
+			changes = append(changes, "MODIFY COLUMN "+column.name+" "+column.definition)
+		}
+		if len(changes) > 0 {
+			if err := alterTable(db, table.name, changes); err != nil {
+				return err
+			}
+		}
+
+		// Keys go after the columns they are on, foreign keys after the keys they can use
+		changes = nil
+		for _, name := range table.order {
+			if line, ok := table.indexes[name]; ok {
+				exists, err := indexExists(db, table.name, name)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					changes = append(changes, "ADD "+line)
+				}
+			}
+		}
+		for _, name := range table.order {
+			if line, ok := table.foreignKeys[name]; ok {
+				exists, err := foreignKeyExists(db, table.name, name)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					changes = append(changes, "ADD "+line)
+				}
+			}
+		}
+		if len(changes) > 0 {
+			if err := alterTable(db, table.name, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func alterTable(db *sql.DB, table string, changes []string) error {
+	slog.Info("Adopting table into the baseline", slog.String("table", table), slog.Any("changes", changes))
+	if _, err := db.Exec("ALTER TABLE " + table + " " + strings.Join(changes, ", ")); err != nil {
+		return fmt.Errorf("could not bring %s to the baseline: %v", table, err)
+	}
+	return nil
+}
+
+// sameType compares the COLUMN_TYPE of information_schema with a type of the baseline. Older servers show
+// the display width of integers, like int(11), which does not change what the column holds.
+func sameType(columnType, kind string) bool {
+	columnType = strings.ToLower(columnType)
+	if open := strings.Index(columnType, "("); open > 0 && strings.HasSuffix(columnType[:open], "int") {
+		columnType = columnType[:open] + strings.TrimPrefix(columnType[strings.Index(columnType, ")")+1:], " ")
+	}
+	return strings.TrimSpace(columnType) == kind
+}
+
+func isText(columnType string) bool {
+	columnType = strings.ToLower(columnType)
+	return strings.HasPrefix(columnType, "varchar") || strings.HasPrefix(columnType, "char") || strings.HasSuffix(columnType, "text")
+}
+
+func foreignKeyExists(db *sql.DB, table, name string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = ? AND CONSTRAINT_TYPE = 'FOREIGN KEY'`, table, name).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,64 @@
+package db
+
+import "testing"
+
+func TestParseBaseline(t *testing.T) {
+	content, err := migrationFiles.ReadFile(baselineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]baselineTable{}
+	for _, table := range parseBaseline(string(content)) {
+		tables[table.name] = table
+	}
+
+	for _, name := range []string{"Address", "User", "Property", "Unit", "Booking"} {
+		table, ok := tables[name]
+		if !ok {
+			t.Errorf("the baseline has no %s table", name)
+			continue
+		}
+		if len(table.columns) == 0 {
+			t.Errorf("%s has no columns", name)
+		}
+		for _, column := range table.columns {
+			if column.name == "PRIMARY" || column.name == "KEY" || column.name == "CONSTRAINT" {
+				t.Errorf("%s.%s is a key, not a column", name, column.name)
+			}
+		}
+	}
+	for _, table := range tables {
+		for _, name := range table.order {
+			_, index := table.indexes[name]
+			_, foreignKey := table.foreignKeys[name]
+			if index == foreignKey {
+				t.Errorf("%s.%s should be either an index or a foreign key", table.name, name)
+			}
+		}
+	}
+	for _, column := range tables["Address"].columns {
+		if (column.name == "Latitude" || column.name == "Longitude") && (column.kind != "decimal(9,6)" || !column.nullable) {
+			t.Errorf("got %s %s nullable %v, want a nullable decimal(9,6)", column.name, column.kind, column.nullable)
+		}
+	}
+}
+
+func TestSameType(t *testing.T) {
+	tests := []struct {
+		columnType, kind string
+		want             bool
+	}{
+		{"int", "int", true},
+		{"int(11)", "int", true},
+		{"bigint(20)", "bigint", true},
+		{"DECIMAL(9,6)", "decimal(9,6)", true},
+		{"varchar(255)", "decimal(9,6)", false},
+		{"varchar(255)", "varchar(100)", false},
+		{"datetime", "datetime", true},
+	}
+	for _, tt := range tests {
+		if got := sameType(tt.columnType, tt.kind); got != tt.want {
+			t.Errorf("sameType(%q, %q) = %v, want %v", tt.columnType, tt.kind, got, tt.want)
+		}
+	}
+}
@@ -1,30 +1,77 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"GraduationProject.com/m/internal/config"
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+// Dialect is the SQL flavour of the database, it picks the migrations to run
+type Dialect string
+
+const (
+	MySQL  Dialect = "mysql"
+	SQLite Dialect = "sqlite"
 )
 
 type DBExecutor struct {
-	Db *sql.DB
+	Db      *sql.DB
+	Dialect Dialect
 }
 
-// InitDB initializes the database with the given connection string.
-func InitDB(dataSourceName string) (*DBExecutor, error) {
-	db, err := sql.Open("mysql", dataSourceName)
+// InitDB initializes the database with the given configuration.
+// Statements run within a traced request are spans of it, with the statement but not its arguments.
+func InitDB(cfg config.Database) (*DBExecutor, error) {
+	dialect := Dialect(cfg.Driver)
+	system := semconv.DBSystemMySQL
+	if dialect == SQLite {
+		system = semconv.DBSystemSqlite
+	}
+	db, err := otelsql.Open(string(dialect), cfg.DSN(), otelsql.WithAttributes(system), otelsql.WithSpanOptions(otelsql.SpanOptions{
+		DisableErrSkip:       true,
+		OmitConnResetSession: true,
+		OmitRows:             true,
+		SpanFilter:           traced,
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to the database: %v", err)
 	}
 
+	if dialect == SQLite {
+		// SQLite allows one writer at a time, and an in-memory database only lives as long as its connection
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	} else {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+		db.SetConnMaxLifetime(cfg.ConnMaxLifetime.Duration)
+	}
+
 	err = db.Ping()
 	if err != nil {
 		return nil, fmt.Errorf("could not ping the database: %v", err)
 	}
 
-	log.Println("Successfully connected to the database")
+	if dialect == SQLite {
+		slog.Info("Connected to the SQLite database", slog.String("path", cfg.Path))
+	} else {
+		slog.Info("Connected to the database", slog.String("name", cfg.Name), slog.String("address", cfg.Address))
+	}
+
+	return &DBExecutor{Db: db, Dialect: dialect}, nil
+}
 
-	return &DBExecutor{Db: db}, nil
+// traced leaves out statements outside of a trace, like migrations and indexing on startup
+func traced(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
 }
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Ping checks that the database answers
+func (database *DBExecutor) Ping(ctx context.Context) error {
+	return database.Db.PingContext(ctx)
+}
+
+// Reachable checks that the table can be read. table is one of the schema's own names, never user input.
+func (database *DBExecutor) Reachable(ctx context.Context, table string) error {
+	var one int
+	err := database.Db.QueryRowContext(ctx, `SELECT 1 FROM `+table+` LIMIT 1`).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+// PendingMigrations lists the migrations shipped with the binary that the database does not have yet
+func PendingMigrations(database *DBExecutor) ([]MigrationStatus, error) {
+	statuses, err := MigrationStatuses(database)
+	if err != nil {
+		return nil, err
+	}
+	var pending []MigrationStatus
+	for _, status := range statuses {
+		if status.AppliedTime == nil {
+			pending = append(pending, status)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,248 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations live in migrations/<dialect>/ as <version>_<name>.up.sql and <version>_<name>.down.sql, and are applied in version order.
+// Both dialects have the same versions.
+//
+//go:embed migrations/mysql/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// goMigrations are up migrations that need more than SQL, their down migration is still a file
+var goMigrations = map[Dialect]map[int]func(*sql.DB) error{
+	MySQL: {1: MigrateBaseline, 2: MigrateUnitAttributes, 12: AdoptBaseline},
+}
+
+// irreversible are the versions MigrateDown refuses to roll back. Rolling back the baseline would drop every
+// table, and with the tables of databases that were set up by hand, all of their data.
+var irreversible = map[int]bool{1: true}
+
+// ErrIrreversible is returned by MigrateDown for a migration that cannot be rolled back
+var ErrIrreversible = errors.New("cannot be rolled back")
+
+const migrationHistory = `
+    CREATE TABLE IF NOT EXISTS SchemaMigration (
+        Version INT NOT NULL,
+        Name VARCHAR(255) NOT NULL,
+        AppliedTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (Version)
+    )`
+
+type Migration struct {
+	Version int
+	Name    string
+	up      func(*sql.DB) error
+	down    string
+}
+
+type MigrationStatus struct {
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	AppliedTime *time.Time `json:"appliedTime"` // Nil when the migration is pending
+}
+
+// Migrations returns every migration of the dialect shipped with the binary, oldest first
+func Migrations(dialect Dialect) ([]Migration, error) {
+	names, err := fs.Glob(migrationFiles, path.Join("migrations", string(dialect), "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("there are no migrations for %s", dialect)
+	}
+	byVersion := make(map[int]*Migration)
+	ups := make(map[int]string)
+	for _, name := range names {
+		base := path.Base(name)
+		direction := ""
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction, base = "up", strings.TrimSuffix(base, ".up.sql")
+		case strings.HasSuffix(base, ".down.sql"):
+			direction, base = "down", strings.TrimSuffix(base, ".down.sql")
+		default:
+			return nil, fmt.Errorf("migration %s is neither .up.sql nor .down.sql", name)
+		}
+		prefix, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s does not start with a version number", name)
+		}
+		content, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		} else if migration.Name != label {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, label)
+		}
+		if direction == "up" {
+			ups[version] = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, migration := range byVersion {
+		if migration.down == "" {
+			return nil, fmt.Errorf("migration %d has no down migration", version)
+		}
+		if up, ok := goMigrations[dialect][version]; ok {
+			migration.up = up
+		} else if statements, ok := ups[version]; ok {
+			migration.up = func(db *sql.DB) error { return execStatements(db, statements) }
+		} else {
+			return nil, fmt.Errorf("migration %d has no up migration", version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execStatements runs a migration file one statement at a time, since the driver does not allow several per query.
+// Statements end with a semicolon at the end of a line, lines starting with -- are comments.
+func execStatements(db *sql.DB, statements string) error {
+	var statement strings.Builder
+	for _, line := range strings.Split(statements, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if _, err := db.Exec(statement.String()); err != nil {
+				return err
+			}
+			statement.Reset()
+		}
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		_, err := db.Exec(statement.String())
+		return err
+	}
+	return nil
+}
+
+func appliedMigrations(db *sql.DB) (map[int]time.Time, error) {
+	if _, err := db.Exec(migrationHistory); err != nil {
+		return nil, fmt.Errorf("could not create the migration history: %v", err)
+	}
+	rows, err := db.Query(`SELECT Version, AppliedTime FROM SchemaMigration`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedTime time.Time
+		if err := rows.Scan(&version, &appliedTime); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedTime
+	}
+	return applied, rows.Err()
+}
+
+// MigrateUp applies every pending migration in order, stopping at the first one that fails.
+// MySQL commits schema changes as they run, so a failed migration may need cleaning up by hand before it is retried.
+func MigrateUp(database *DBExecutor) ([]Migration, error) {
+	db := database.Db
+	migrations, err := Migrations(database.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		slog.Info("Applying migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+		if err := migration.up(db); err != nil {
+			return done, fmt.Errorf("migration %d %s failed: %v", migration.Version, migration.Name, err)
+		}
+		if _, err := db.Exec(`INSERT INTO SchemaMigration (Version, Name) VALUES (?, ?)`, migration.Version, migration.Name); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrateDown rolls back the given number of applied migrations, newest first. It stops at the baseline,
+// which is never rolled back.
+func MigrateDown(database *DBExecutor, steps int) ([]Migration, error) {
+	db := database.Db
+	migrations, err := Migrations(database.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if irreversible[migration.Version] {
+			return done, fmt.Errorf("migration %d %s %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		slog.Info("Rolling back migration", slog.Int("version", migration.Version), slog.String("name", migration.Name))
+		if err := execStatements(db, migration.down); err != nil {
+			return done, fmt.Errorf("rolling back migration %d %s failed: %v", migration.Version, migration.Name, err)
+		}
+		if _, err := db.Exec(`DELETE FROM SchemaMigration WHERE Version = ?`, migration.Version); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrationStatuses lists every migration shipped with the binary and when it was applied
+func MigrationStatuses(database *DBExecutor) ([]MigrationStatus, error) {
+	db := database.Db
+	migrations, err := Migrations(database.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedTime, ok := applied[migration.Version]; ok {
+			status.AppliedTime = &appliedTime
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
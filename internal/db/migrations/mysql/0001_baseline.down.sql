@@ -0,0 +1,2 @@
+-- The baseline is never rolled back, MigrateDown refuses it. Rolling it back would drop every table, with all
+-- the data of databases that were set up by hand before they adopted the migrations.
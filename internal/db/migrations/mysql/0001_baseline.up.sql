@@ -0,0 +1,177 @@
+-- The schema as it was before migrations were versioned. It is run by MigrateBaseline, which creates the
+-- tables that are missing and then brings the ones that were set up by hand to these definitions, so those
+-- databases adopt the migration history. Each column, key and constraint is declared on a line of its own.
+
+CREATE TABLE IF NOT EXISTS Address (
+    AddressID INT NOT NULL AUTO_INCREMENT,
+    Country VARCHAR(100) NULL,
+    City VARCHAR(100) NULL,
+    State VARCHAR(100) NULL,
+    Street VARCHAR(255) NULL,
+    PostalCode VARCHAR(20) NULL,
+    AdditionalNumber VARCHAR(20) NULL,
+    MapLocation VARCHAR(255) NULL,
+    Latitude DECIMAL(9,6) NULL,
+    Longitude DECIMAL(9,6) NULL,
+    PRIMARY KEY (AddressID),
+    KEY idx_address_city (City),
+    KEY idx_address_location (Latitude, Longitude)
+);
+
+CREATE TABLE IF NOT EXISTS User (
+    UserID INT NOT NULL AUTO_INCREMENT,
+    AddressID INT NULL,
+    Name VARCHAR(100) NOT NULL,
+    PhoneNumber VARCHAR(20) NULL,
+    Email VARCHAR(255) NOT NULL,
+    Password VARCHAR(255) NOT NULL,
+    UserRole VARCHAR(32) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (UserID),
+    UNIQUE KEY uq_user_email (Email),
+    CONSTRAINT fk_user_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS Property (
+    PropertyID INT NOT NULL AUTO_INCREMENT,
+    OwnerID INT NULL,
+    AddressID INT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Description TEXT NULL,
+    Type VARCHAR(50) NULL,
+    Rules TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (PropertyID),
+    KEY idx_property_owner (OwnerID),
+    KEY idx_property_type (Type),
+    CONSTRAINT fk_property_owner FOREIGN KEY (OwnerID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_property_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS Unit (
+    UnitID INT NOT NULL AUTO_INCREMENT,
+    PropertyID INT NOT NULL,
+    AddressID INT NULL,
+    Name VARCHAR(100) NULL,
+    RentalPrice INT NOT NULL DEFAULT 0,
+    Description TEXT NULL,
+    Rating FLOAT NOT NULL DEFAULT 0,
+    StructuralProperties JSON NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (UnitID),
+    KEY idx_unit_property (PropertyID),
+    KEY idx_unit_price (RentalPrice),
+    CONSTRAINT fk_unit_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE,
+    CONSTRAINT fk_unit_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS Images (
+    ImageID INT NOT NULL AUTO_INCREMENT,
+    UnitID INT NULL,
+    UserID INT NULL,
+    PropertyID INT NULL,
+    Image MEDIUMBLOB NOT NULL,
+    Type VARCHAR(16) NOT NULL,
+    PRIMARY KEY (ImageID),
+    KEY idx_images_unit (UnitID, Type),
+    KEY idx_images_property (PropertyID, Type),
+    CONSTRAINT fk_images_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE,
+    CONSTRAINT fk_images_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_images_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Booking (
+    BookingID INT NOT NULL AUTO_INCREMENT,
+    UnitID INT NOT NULL,
+    UserID INT NOT NULL,
+    StartDate DATETIME NOT NULL,
+    EndDate DATETIME NOT NULL,
+    Summary TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (BookingID),
+    KEY idx_booking_unit_dates (UnitID, StartDate, EndDate),
+    KEY idx_booking_user (UserID),
+    CONSTRAINT fk_booking_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE,
+    CONSTRAINT fk_booking_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Chat (
+    ChatID INT NOT NULL AUTO_INCREMENT,
+    SenderID INT NOT NULL,
+    ReceiverID INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ChatID),
+    KEY idx_chat_sender (SenderID, ReceiverID),
+    KEY idx_chat_receiver (ReceiverID),
+    CONSTRAINT fk_chat_sender FOREIGN KEY (SenderID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_chat_receiver FOREIGN KEY (ReceiverID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Message (
+    MessageID INT NOT NULL AUTO_INCREMENT,
+    ChatID INT NOT NULL,
+    SenderID INT NOT NULL,
+    Content TEXT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (MessageID),
+    KEY idx_message_chat (ChatID, CreateTime),
+    CONSTRAINT fk_message_chat FOREIGN KEY (ChatID) REFERENCES Chat (ChatID) ON DELETE CASCADE,
+    CONSTRAINT fk_message_sender FOREIGN KEY (SenderID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Review (
+    ReviewID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    UnitID INT NOT NULL,
+    Review TEXT NULL,
+    Rating INT NOT NULL,
+    Comment TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ReviewID),
+    KEY idx_review_unit (UnitID),
+    KEY idx_review_user (UserID),
+    CONSTRAINT fk_review_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_review_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Report (
+    ReportID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    Type VARCHAR(50) NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Data TEXT NOT NULL,
+    PRIMARY KEY (ReportID),
+    KEY idx_report_user (UserID),
+    CONSTRAINT fk_report_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS MaintenanceTicket (
+    TicketID INT NOT NULL AUTO_INCREMENT,
+    MaintenancePresenterID INT NULL,
+    TenantID INT NOT NULL,
+    PropertyID INT NOT NULL,
+    Description TEXT NOT NULL,
+    UrgencyLevel VARCHAR(16) NOT NULL,
+    Status VARCHAR(32) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TicketID),
+    KEY idx_ticket_property (PropertyID, Status),
+    KEY idx_ticket_tenant (TenantID),
+    CONSTRAINT fk_ticket_presenter FOREIGN KEY (MaintenancePresenterID) REFERENCES User (UserID) ON DELETE SET NULL,
+    CONSTRAINT fk_ticket_tenant FOREIGN KEY (TenantID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_ticket_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS FinancialTransaction (
+    TransactionID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    BookingID INT NOT NULL,
+    PaymentMethod VARCHAR(32) NOT NULL,
+    Amount INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TransactionID),
+    KEY idx_transaction_user (UserID),
+    KEY idx_transaction_booking (BookingID),
+    CONSTRAINT fk_transaction_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_booking FOREIGN KEY (BookingID) REFERENCES Booking (BookingID) ON DELETE CASCADE
+);
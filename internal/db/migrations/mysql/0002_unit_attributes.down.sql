@@ -0,0 +1,4 @@
+-- The up migration is MigrateUnitAttributes, in Go, since it parses the legacy StructuralProperties of every unit
+DROP TABLE IF EXISTS UnitAmenity;
+DROP INDEX idx_unit_maxguests ON Unit;
+ALTER TABLE Unit DROP COLUMN Bedrooms, DROP COLUMN Beds, DROP COLUMN Bathrooms, DROP COLUMN MaxGuests, DROP COLUMN Size, DROP COLUMN Floor;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS Notification;
+DROP TABLE IF EXISTS SavedSearch;
+DROP TABLE IF EXISTS WishlistUnit;
+DROP TABLE IF EXISTS Wishlist;
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS Wishlist (
+    WishlistID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    ShareToken CHAR(36) NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (WishlistID),
+    UNIQUE KEY uq_wishlist_sharetoken (ShareToken),
+    KEY idx_wishlist_user (UserID),
+    CONSTRAINT fk_wishlist_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS WishlistUnit (
+    WishlistID INT NOT NULL,
+    UnitID INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (WishlistID, UnitID),
+    KEY idx_wishlistunit_unit (UnitID),
+    CONSTRAINT fk_wishlistunit_wishlist FOREIGN KEY (WishlistID) REFERENCES Wishlist (WishlistID) ON DELETE CASCADE,
+    CONSTRAINT fk_wishlistunit_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS SavedSearch (
+    SavedSearchID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Criteria JSON NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (SavedSearchID),
+    KEY idx_savedsearch_user (UserID),
+    CONSTRAINT fk_savedsearch_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Notification (
+    NotificationID INT NOT NULL AUTO_INCREMENT,
+    UserID INT NOT NULL,
+    Type VARCHAR(32) NOT NULL,
+    UnitID INT NOT NULL,
+    Message VARCHAR(255) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ReadTime DATETIME NULL,
+    PRIMARY KEY (NotificationID),
+    KEY idx_notification_user (UserID, ReadTime, CreateTime),
+    CONSTRAINT fk_notification_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE Booking DROP COLUMN CheckInDate, DROP COLUMN CheckOutDate;
+ALTER TABLE Property DROP COLUMN TimeZone, DROP COLUMN CheckInTime, DROP COLUMN CheckOutTime;
@@ -0,0 +1,14 @@
+-- Properties get a time zone and check-in and check-out times, bookings the local dates they were made for.
+-- Times were already stored in UTC, so existing bookings keep their days in UTC.
+ALTER TABLE Property
+    ADD COLUMN TimeZone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN CheckInTime CHAR(5) NOT NULL DEFAULT '15:00',
+    ADD COLUMN CheckOutTime CHAR(5) NOT NULL DEFAULT '11:00';
+
+ALTER TABLE Booking
+    ADD COLUMN CheckInDate DATE NULL AFTER UserID,
+    ADD COLUMN CheckOutDate DATE NULL AFTER CheckInDate;
+UPDATE Booking SET CheckInDate = DATE(StartDate), CheckOutDate = DATE(EndDate);
+ALTER TABLE Booking
+    MODIFY CheckInDate DATE NOT NULL,
+    MODIFY CheckOutDate DATE NOT NULL;
@@ -0,0 +1,4 @@
+-- Rows that were soft deleted come back, delete them by hand first if they should not
+ALTER TABLE Unit DROP COLUMN DeleteTime, DROP COLUMN ArchiveTime;
+ALTER TABLE Property DROP COLUMN DeleteTime, DROP COLUMN ArchiveTime;
+ALTER TABLE User DROP COLUMN DeleteTime;
@@ -0,0 +1,4 @@
+-- Deleted users, properties and units are kept with the time they were deleted, so bookings and transactions keep their history
+ALTER TABLE User ADD COLUMN DeleteTime DATETIME NULL;
+ALTER TABLE Property ADD COLUMN DeleteTime DATETIME NULL, ADD COLUMN ArchiveTime DATETIME NULL;
+ALTER TABLE Unit ADD COLUMN DeleteTime DATETIME NULL, ADD COLUMN ArchiveTime DATETIME NULL;
@@ -0,0 +1,15 @@
+-- Every write made through the API. The repository only ever inserts into it, triggers that refuse updates
+-- and deletes would need SUPER on servers with binary logging, so revoke UPDATE and DELETE on it instead.
+CREATE TABLE IF NOT EXISTS AuditLog (
+    AuditID BIGINT NOT NULL AUTO_INCREMENT,
+    Actor VARCHAR(64) NOT NULL,
+    EntityType VARCHAR(32) NOT NULL,
+    EntityID VARCHAR(64) NOT NULL,
+    Action VARCHAR(16) NOT NULL,
+    Changes JSON NOT NULL,
+    RequestID VARCHAR(64) NOT NULL DEFAULT '',
+    CreateTime DATETIME(6) NOT NULL,
+    PRIMARY KEY (AuditID),
+    KEY idx_auditlog_entity (EntityType, EntityID),
+    KEY idx_auditlog_actor (Actor)
+);
@@ -0,0 +1,15 @@
+-- The response to the first request sent with each Idempotency-Key, replayed to retries until ExpireTime.
+-- Status is 0 while that request is still running.
+CREATE TABLE IF NOT EXISTS IdempotencyKey (
+    Actor VARCHAR(64) NOT NULL,
+    IdempotencyKey VARCHAR(255) NOT NULL,
+    Route VARCHAR(255) NOT NULL,
+    RequestHash CHAR(64) NOT NULL,
+    Status INT NOT NULL DEFAULT 0,
+    ContentType VARCHAR(255) NOT NULL DEFAULT '',
+    Body MEDIUMBLOB,
+    CreateTime DATETIME NOT NULL,
+    ExpireTime DATETIME NOT NULL,
+    PRIMARY KEY (Actor, IdempotencyKey),
+    KEY idx_idempotencykey_expire (ExpireTime)
+);
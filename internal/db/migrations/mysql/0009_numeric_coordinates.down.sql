@@ -0,0 +1 @@
+-- The baseline already declares the coordinates DECIMAL, so they stay numeric
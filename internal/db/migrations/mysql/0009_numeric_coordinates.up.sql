@@ -0,0 +1,7 @@
+-- Databases set up by hand before migrations kept Latitude and Longitude as text, and the baseline adopted
+-- them as they were. Values that are not numbers, or not on the globe, cannot be converted and are cleared.
+UPDATE Address SET Latitude = NULL WHERE TRIM(Latitude) NOT REGEXP '^[-+]?([0-9]+([.][0-9]*)?|[.][0-9]+)$';
+UPDATE Address SET Longitude = NULL WHERE TRIM(Longitude) NOT REGEXP '^[-+]?([0-9]+([.][0-9]*)?|[.][0-9]+)$';
+UPDATE Address SET Latitude = NULL WHERE ABS(Latitude) > 90;
+UPDATE Address SET Longitude = NULL WHERE ABS(Longitude) > 180;
+ALTER TABLE Address MODIFY Latitude DECIMAL(9,6) NULL, MODIFY Longitude DECIMAL(9,6) NULL;
@@ -0,0 +1 @@
+-- Nothing to roll back, a guest count of zero was never valid
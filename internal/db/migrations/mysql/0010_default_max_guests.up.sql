@@ -0,0 +1,3 @@
+-- Units the attribute migration parsed before it defaulted the guest count still take no guests, which
+-- their validation rejects, so they are given one guest per bed and at least one
+UPDATE Unit SET MaxGuests = LEAST(GREATEST(Beds, 1), 100) WHERE MaxGuests = 0;
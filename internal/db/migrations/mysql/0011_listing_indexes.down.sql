@@ -0,0 +1,4 @@
+DROP INDEX idx_property_type_lower ON Property;
+DROP INDEX idx_address_city_lower ON Address;
+DROP INDEX idx_unit_rating ON Unit;
+DROP INDEX idx_unit_created ON Unit;
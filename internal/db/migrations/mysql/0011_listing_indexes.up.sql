@@ -0,0 +1,7 @@
+-- The unit listing filters, sorts and counts in SQL. Types and cities are compared in lower case, so they
+-- get expression indexes, and the sort orders that had none get one. Units are ordered by UnitID on ties,
+-- which InnoDB keeps in every secondary index.
+CREATE INDEX idx_unit_created ON Unit (CreateTime);
+CREATE INDEX idx_unit_rating ON Unit (Rating);
+CREATE INDEX idx_address_city_lower ON Address ((LOWER(City)));
+CREATE INDEX idx_property_type_lower ON Property ((LOWER(Type)));
@@ -0,0 +1 @@
+-- The up migration is AdoptBaseline, in Go. It only brought tables to the baseline, which stays.
@@ -0,0 +1,6 @@
+-- Fails while a deleted user shares their email with another user
+ALTER TABLE User
+    ADD UNIQUE KEY uq_user_email (Email),
+    DROP INDEX idx_user_email,
+    DROP INDEX uq_user_live_email,
+    DROP COLUMN LiveEmail;
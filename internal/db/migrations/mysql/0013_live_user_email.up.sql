@@ -0,0 +1,7 @@
+-- Deleted users keep their row, so only the emails of live users are unique. LiveEmail is NULL for deleted
+-- users, and a unique key allows any number of NULLs. Email keeps a plain index for lookups.
+ALTER TABLE User
+    ADD COLUMN LiveEmail VARCHAR(255) GENERATED ALWAYS AS (IF(DeleteTime IS NULL, Email, NULL)) STORED,
+    ADD UNIQUE KEY uq_user_live_email (LiveEmail),
+    ADD KEY idx_user_email (Email),
+    DROP INDEX uq_user_email;
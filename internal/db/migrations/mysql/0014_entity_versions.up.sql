@@ -0,0 +1,6 @@
+-- Users, reviews, tickets, transactions and reports get a version like units, properties and bookings
+ALTER TABLE User ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE Review ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE MaintenanceTicket ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE FinancialTransaction ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE Report ADD COLUMN Version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,163 @@
+-- The MySQL baseline for SQLite: AUTOINCREMENT keys, indexes as separate statements and TEXT for JSON.
+
+CREATE TABLE IF NOT EXISTS Address (
+    AddressID INTEGER PRIMARY KEY AUTOINCREMENT,
+    Country VARCHAR(100) NULL,
+    City VARCHAR(100) NULL,
+    State VARCHAR(100) NULL,
+    Street VARCHAR(255) NULL,
+    PostalCode VARCHAR(20) NULL,
+    AdditionalNumber VARCHAR(20) NULL,
+    MapLocation VARCHAR(255) NULL,
+    Latitude DECIMAL(9,6) NULL,
+    Longitude DECIMAL(9,6) NULL
+);
+CREATE INDEX IF NOT EXISTS idx_address_city ON Address (City);
+CREATE INDEX IF NOT EXISTS idx_address_location ON Address (Latitude, Longitude);
+
+CREATE TABLE IF NOT EXISTS User (
+    UserID INTEGER PRIMARY KEY AUTOINCREMENT,
+    AddressID INT NULL,
+    Name VARCHAR(100) NOT NULL,
+    PhoneNumber VARCHAR(20) NULL,
+    Email VARCHAR(255) NOT NULL,
+    Password VARCHAR(255) NOT NULL,
+    UserRole VARCHAR(32) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_email ON User (Email);
+
+CREATE TABLE IF NOT EXISTS Property (
+    PropertyID INTEGER PRIMARY KEY AUTOINCREMENT,
+    OwnerID INT NULL,
+    AddressID INT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Description TEXT NULL,
+    Type VARCHAR(50) NULL,
+    Rules TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_property_owner FOREIGN KEY (OwnerID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_property_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_property_owner ON Property (OwnerID);
+CREATE INDEX IF NOT EXISTS idx_property_type ON Property (Type);
+
+CREATE TABLE IF NOT EXISTS Unit (
+    UnitID INTEGER PRIMARY KEY AUTOINCREMENT,
+    PropertyID INT NOT NULL,
+    AddressID INT NULL,
+    Name VARCHAR(100) NULL,
+    RentalPrice INT NOT NULL DEFAULT 0,
+    Description TEXT NULL,
+    Rating FLOAT NOT NULL DEFAULT 0,
+    StructuralProperties TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_unit_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE,
+    CONSTRAINT fk_unit_address FOREIGN KEY (AddressID) REFERENCES Address (AddressID) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_unit_property ON Unit (PropertyID);
+CREATE INDEX IF NOT EXISTS idx_unit_price ON Unit (RentalPrice);
+
+CREATE TABLE IF NOT EXISTS Images (
+    ImageID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UnitID INT NULL,
+    UserID INT NULL,
+    PropertyID INT NULL,
+    Image BLOB NOT NULL,
+    Type VARCHAR(16) NOT NULL,
+    CONSTRAINT fk_images_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE,
+    CONSTRAINT fk_images_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_images_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_images_unit ON Images (UnitID, Type);
+CREATE INDEX IF NOT EXISTS idx_images_property ON Images (PropertyID, Type);
+
+CREATE TABLE IF NOT EXISTS Booking (
+    BookingID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UnitID INT NOT NULL,
+    UserID INT NOT NULL,
+    StartDate DATETIME NOT NULL,
+    EndDate DATETIME NOT NULL,
+    Summary TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_booking_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE,
+    CONSTRAINT fk_booking_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_booking_unit_dates ON Booking (UnitID, StartDate, EndDate);
+CREATE INDEX IF NOT EXISTS idx_booking_user ON Booking (UserID);
+
+CREATE TABLE IF NOT EXISTS Chat (
+    ChatID INTEGER PRIMARY KEY AUTOINCREMENT,
+    SenderID INT NOT NULL,
+    ReceiverID INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_chat_sender FOREIGN KEY (SenderID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_chat_receiver FOREIGN KEY (ReceiverID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_chat_sender ON Chat (SenderID, ReceiverID);
+CREATE INDEX IF NOT EXISTS idx_chat_receiver ON Chat (ReceiverID);
+
+CREATE TABLE IF NOT EXISTS Message (
+    MessageID INTEGER PRIMARY KEY AUTOINCREMENT,
+    ChatID INT NOT NULL,
+    SenderID INT NOT NULL,
+    Content TEXT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_message_chat FOREIGN KEY (ChatID) REFERENCES Chat (ChatID) ON DELETE CASCADE,
+    CONSTRAINT fk_message_sender FOREIGN KEY (SenderID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_message_chat ON Message (ChatID, CreateTime);
+
+CREATE TABLE IF NOT EXISTS Review (
+    ReviewID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    UnitID INT NOT NULL,
+    Review TEXT NULL,
+    Rating INT NOT NULL,
+    Comment TEXT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_review_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_review_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_review_unit ON Review (UnitID);
+CREATE INDEX IF NOT EXISTS idx_review_user ON Review (UserID);
+
+CREATE TABLE IF NOT EXISTS Report (
+    ReportID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    Type VARCHAR(50) NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    Data TEXT NOT NULL,
+    CONSTRAINT fk_report_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_report_user ON Report (UserID);
+
+CREATE TABLE IF NOT EXISTS MaintenanceTicket (
+    TicketID INTEGER PRIMARY KEY AUTOINCREMENT,
+    MaintenancePresenterID INT NULL,
+    TenantID INT NOT NULL,
+    PropertyID INT NOT NULL,
+    Description TEXT NOT NULL,
+    UrgencyLevel VARCHAR(16) NOT NULL,
+    Status VARCHAR(32) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_ticket_presenter FOREIGN KEY (MaintenancePresenterID) REFERENCES User (UserID) ON DELETE SET NULL,
+    CONSTRAINT fk_ticket_tenant FOREIGN KEY (TenantID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_ticket_property FOREIGN KEY (PropertyID) REFERENCES Property (PropertyID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_property ON MaintenanceTicket (PropertyID, Status);
+CREATE INDEX IF NOT EXISTS idx_ticket_tenant ON MaintenanceTicket (TenantID);
+
+CREATE TABLE IF NOT EXISTS FinancialTransaction (
+    TransactionID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    BookingID INT NOT NULL,
+    PaymentMethod VARCHAR(32) NOT NULL,
+    Amount INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_transaction_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_booking FOREIGN KEY (BookingID) REFERENCES Booking (BookingID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_transaction_user ON FinancialTransaction (UserID);
+CREATE INDEX IF NOT EXISTS idx_transaction_booking ON FinancialTransaction (BookingID);
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS UnitAmenity;
+DROP INDEX IF EXISTS idx_unit_maxguests;
+ALTER TABLE Unit DROP COLUMN Bedrooms;
+ALTER TABLE Unit DROP COLUMN Beds;
+ALTER TABLE Unit DROP COLUMN Bathrooms;
+ALTER TABLE Unit DROP COLUMN MaxGuests;
+ALTER TABLE Unit DROP COLUMN Size;
+ALTER TABLE Unit DROP COLUMN Floor;
@@ -0,0 +1,17 @@
+-- SQLite databases are only used for local development and tests and start empty, so there is no
+-- StructuralProperties to parse like MigrateUnitAttributes does on MySQL
+ALTER TABLE Unit ADD COLUMN Bedrooms INT NOT NULL DEFAULT 0;
+ALTER TABLE Unit ADD COLUMN Beds INT NOT NULL DEFAULT 0;
+ALTER TABLE Unit ADD COLUMN Bathrooms INT NOT NULL DEFAULT 0;
+ALTER TABLE Unit ADD COLUMN MaxGuests INT NOT NULL DEFAULT 0;
+ALTER TABLE Unit ADD COLUMN Size DECIMAL(10,2) NOT NULL DEFAULT 0;
+ALTER TABLE Unit ADD COLUMN Floor INT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_unit_maxguests ON Unit (MaxGuests, Bedrooms);
+
+CREATE TABLE IF NOT EXISTS UnitAmenity (
+    UnitID INT NOT NULL,
+    Amenity VARCHAR(32) NOT NULL,
+    PRIMARY KEY (UnitID, Amenity),
+    CONSTRAINT fk_unitamenity_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_unitamenity_amenity ON UnitAmenity (Amenity, UnitID);
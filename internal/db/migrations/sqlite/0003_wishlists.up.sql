@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS Wishlist (
+    WishlistID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    ShareToken CHAR(36) NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_wishlist_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_wishlist_sharetoken ON Wishlist (ShareToken);
+CREATE INDEX IF NOT EXISTS idx_wishlist_user ON Wishlist (UserID);
+
+CREATE TABLE IF NOT EXISTS WishlistUnit (
+    WishlistID INT NOT NULL,
+    UnitID INT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (WishlistID, UnitID),
+    CONSTRAINT fk_wishlistunit_wishlist FOREIGN KEY (WishlistID) REFERENCES Wishlist (WishlistID) ON DELETE CASCADE,
+    CONSTRAINT fk_wishlistunit_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_wishlistunit_unit ON WishlistUnit (UnitID);
+
+CREATE TABLE IF NOT EXISTS SavedSearch (
+    SavedSearchID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Criteria TEXT NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_savedsearch_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_savedsearch_user ON SavedSearch (UserID);
+
+CREATE TABLE IF NOT EXISTS Notification (
+    NotificationID INTEGER PRIMARY KEY AUTOINCREMENT,
+    UserID INT NOT NULL,
+    Type VARCHAR(32) NOT NULL,
+    UnitID INT NOT NULL,
+    Message VARCHAR(255) NOT NULL,
+    CreateTime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ReadTime DATETIME NULL,
+    CONSTRAINT fk_notification_user FOREIGN KEY (UserID) REFERENCES User (UserID) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_notification_user ON Notification (UserID, ReadTime, CreateTime);
@@ -0,0 +1,5 @@
+ALTER TABLE Booking DROP COLUMN CheckInDate;
+ALTER TABLE Booking DROP COLUMN CheckOutDate;
+ALTER TABLE Property DROP COLUMN TimeZone;
+ALTER TABLE Property DROP COLUMN CheckInTime;
+ALTER TABLE Property DROP COLUMN CheckOutTime;
@@ -0,0 +1,8 @@
+-- SQLite cannot add a NOT NULL column without a default, the booking dates are always written by the application
+ALTER TABLE Property ADD COLUMN TimeZone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE Property ADD COLUMN CheckInTime CHAR(5) NOT NULL DEFAULT '15:00';
+ALTER TABLE Property ADD COLUMN CheckOutTime CHAR(5) NOT NULL DEFAULT '11:00';
+
+ALTER TABLE Booking ADD COLUMN CheckInDate DATE NULL;
+ALTER TABLE Booking ADD COLUMN CheckOutDate DATE NULL;
+UPDATE Booking SET CheckInDate = DATE(StartDate), CheckOutDate = DATE(EndDate);
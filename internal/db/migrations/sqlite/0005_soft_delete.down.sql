@@ -0,0 +1,5 @@
+ALTER TABLE Unit DROP COLUMN DeleteTime;
+ALTER TABLE Unit DROP COLUMN ArchiveTime;
+ALTER TABLE Property DROP COLUMN DeleteTime;
+ALTER TABLE Property DROP COLUMN ArchiveTime;
+ALTER TABLE User DROP COLUMN DeleteTime;
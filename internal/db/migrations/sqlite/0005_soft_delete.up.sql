@@ -0,0 +1,5 @@
+ALTER TABLE User ADD COLUMN DeleteTime DATETIME NULL;
+ALTER TABLE Property ADD COLUMN DeleteTime DATETIME NULL;
+ALTER TABLE Property ADD COLUMN ArchiveTime DATETIME NULL;
+ALTER TABLE Unit ADD COLUMN DeleteTime DATETIME NULL;
+ALTER TABLE Unit ADD COLUMN ArchiveTime DATETIME NULL;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS auditlog_no_delete;
+DROP TRIGGER IF EXISTS auditlog_no_update;
+DROP TABLE IF EXISTS AuditLog;
@@ -0,0 +1,15 @@
+-- Every write made through the API, the triggers keep the log append-only
+CREATE TABLE IF NOT EXISTS AuditLog (
+    AuditID INTEGER PRIMARY KEY AUTOINCREMENT,
+    Actor VARCHAR(64) NOT NULL,
+    EntityType VARCHAR(32) NOT NULL,
+    EntityID VARCHAR(64) NOT NULL,
+    Action VARCHAR(16) NOT NULL,
+    Changes TEXT NOT NULL,
+    RequestID VARCHAR(64) NOT NULL DEFAULT '',
+    CreateTime DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_auditlog_entity ON AuditLog (EntityType, EntityID);
+CREATE INDEX IF NOT EXISTS idx_auditlog_actor ON AuditLog (Actor);
+CREATE TRIGGER IF NOT EXISTS auditlog_no_update BEFORE UPDATE ON AuditLog BEGIN SELECT RAISE(ABORT, 'AuditLog is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS auditlog_no_delete BEFORE DELETE ON AuditLog BEGIN SELECT RAISE(ABORT, 'AuditLog is append-only'); END;
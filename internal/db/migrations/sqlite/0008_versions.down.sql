@@ -0,0 +1,3 @@
+ALTER TABLE Booking DROP COLUMN Version;
+ALTER TABLE Unit DROP COLUMN Version;
+ALTER TABLE Property DROP COLUMN Version;
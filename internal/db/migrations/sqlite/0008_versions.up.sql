@@ -0,0 +1,4 @@
+-- Every update bumps the version, updates based on an older one are refused instead of overwriting
+ALTER TABLE Property ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE Unit ADD COLUMN Version INT NOT NULL DEFAULT 1;
+ALTER TABLE Booking ADD COLUMN Version INT NOT NULL DEFAULT 1;
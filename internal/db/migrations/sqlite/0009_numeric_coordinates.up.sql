@@ -0,0 +1 @@
+-- SQLite databases were created by the baseline with numeric coordinates, there is nothing to convert
@@ -0,0 +1,2 @@
+-- Units created before the guest count was required take one guest per bed and at least one
+UPDATE Unit SET MaxGuests = MIN(MAX(Beds, 1), 100) WHERE MaxGuests = 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_property_type_lower;
+DROP INDEX IF EXISTS idx_address_city_lower;
+DROP INDEX IF EXISTS idx_unit_rating;
+DROP INDEX IF EXISTS idx_unit_created;
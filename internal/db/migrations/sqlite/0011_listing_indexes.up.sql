@@ -0,0 +1,5 @@
+-- The unit listing filters, sorts and counts in SQL, types and cities are compared in lower case
+CREATE INDEX IF NOT EXISTS idx_unit_created ON Unit (CreateTime);
+CREATE INDEX IF NOT EXISTS idx_unit_rating ON Unit (Rating);
+CREATE INDEX IF NOT EXISTS idx_address_city_lower ON Address (LOWER(City));
+CREATE INDEX IF NOT EXISTS idx_property_type_lower ON Property (LOWER(Type));
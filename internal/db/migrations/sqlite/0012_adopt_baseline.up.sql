@@ -0,0 +1 @@
+-- SQLite databases were always created by the baseline, there are no tables set up by hand to adopt
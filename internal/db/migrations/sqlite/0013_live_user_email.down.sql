@@ -0,0 +1,4 @@
+-- Fails while a deleted user shares their email with another user
+DROP INDEX IF EXISTS idx_user_email;
+DROP INDEX IF EXISTS uq_user_live_email;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_email ON User (Email);
@@ -0,0 +1,4 @@
+-- Deleted users keep their row, so only the emails of live users are unique
+DROP INDEX IF EXISTS uq_user_email;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_live_email ON User (Email) WHERE DeleteTime IS NULL;
+CREATE INDEX IF NOT EXISTS idx_user_email ON User (Email);
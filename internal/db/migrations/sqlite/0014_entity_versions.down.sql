@@ -0,0 +1,5 @@
+ALTER TABLE Report DROP COLUMN Version;
+ALTER TABLE FinancialTransaction DROP COLUMN Version;
+ALTER TABLE MaintenanceTicket DROP COLUMN Version;
+ALTER TABLE Review DROP COLUMN Version;
+ALTER TABLE User DROP COLUMN Version;
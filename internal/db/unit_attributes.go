@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+var unitAttributeColumns = []struct{ name, definition string }{
+	{"Bedrooms", "INT NOT NULL DEFAULT 0"},
+	{"Beds", "INT NOT NULL DEFAULT 0"},
+	{"Bathrooms", "INT NOT NULL DEFAULT 0"},
+	{"MaxGuests", "INT NOT NULL DEFAULT 0"},
+	{"Size", "DECIMAL(10,2) NOT NULL DEFAULT 0"},
+	{"Floor", "INT NOT NULL DEFAULT 0"},
+}
+
+// MigrateUnitAttributes adds the attribute columns to Unit and the UnitAmenity table, then parses the
+// StructuralProperties of every unit that has no attributes yet into them. Every unit it visits ends up with
+// a MaxGuests of at least one, units whose StructuralProperties cannot be parsed too, so a unit is only parsed
+// once and it is safe to run more than once.
+func MigrateUnitAttributes(db *sql.DB) error {
+	for _, column := range unitAttributeColumns {
+		exists, err := columnExists(db, "Unit", column.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE Unit ADD COLUMN %s %s", column.name, column.definition)); err != nil {
+			return fmt.Errorf("could not add column Unit.%s: %v", column.name, err)
+		}
+	}
+	if exists, err := indexExists(db, "Unit", "idx_unit_maxguests"); err != nil {
+		return err
+	} else if !exists {
+		if _, err := db.Exec(`CREATE INDEX idx_unit_maxguests ON Unit (MaxGuests, Bedrooms)`); err != nil {
+			return fmt.Errorf("could not index unit attributes: %v", err)
+		}
+	}
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS UnitAmenity (
+            UnitID INT NOT NULL,
+            Amenity VARCHAR(32) NOT NULL,
+            PRIMARY KEY (UnitID, Amenity),
+            KEY idx_unitamenity_amenity (Amenity, UnitID),
+            CONSTRAINT fk_unitamenity_unit FOREIGN KEY (UnitID) REFERENCES Unit (UnitID) ON DELETE CASCADE
+        )`)
+	if err != nil {
+		return fmt.Errorf("could not create UnitAmenity: %v", err)
+	}
+
+	migrated, skipped, err := backfillUnitAttributes(db)
+	if err != nil {
+		return err
+	}
+	slog.Info("Migrated unit attributes", slog.Int("migrated", migrated), slog.Int("skipped", skipped))
+	return nil
+}
+
+func backfillUnitAttributes(db *sql.DB) (migrated, skipped int, err error) {
+	rows, err := db.Query(`SELECT UnitID, StructuralProperties FROM Unit WHERE MaxGuests = 0`)
+	if err != nil {
+		return 0, 0, err
+	}
+	type pending struct {
+		unitID     string
+		attributes Entities.UnitAttributes
+		amenities  []string
+		skipped    bool
+	}
+	var units []pending
+	for rows.Next() {
+		var unitID string
+		var raw sql.NullString
+		if err := rows.Scan(&unitID, &raw); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		attributes, amenities, err := Entities.ParseStructuralProperties(raw.String)
+		if err != nil {
+			// The unit keeps no attributes, but is marked as visited with the least guests it can take
+			slog.Warn("Skipping unit", slog.String("unitID", unitID), slog.Any("error", err))
+			units = append(units, pending{unitID, Entities.UnitAttributes{MaxGuests: 1}, nil, true})
+			continue
+		}
+		units = append(units, pending{unitID, attributes, amenities, false})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, unit := range units {
+		tx, err := db.Begin()
+		if err != nil {
+			return migrated, skipped, err
+		}
+		a := unit.attributes
+		_, err = tx.Exec(`UPDATE Unit SET Bedrooms = ?, Beds = ?, Bathrooms = ?, MaxGuests = ?, Size = ?, Floor = ? WHERE UnitID = ?`,
+			a.Bedrooms, a.Beds, a.Bathrooms, a.MaxGuests, a.Size, a.Floor, unit.unitID)
+		for _, amenity := range unit.amenities {
+			if err != nil {
+				break
+			}
+			_, err = tx.Exec(`INSERT IGNORE INTO UnitAmenity (UnitID, Amenity) VALUES (?, ?)`, unit.unitID, amenity)
+		}
+		if err != nil {
+			tx.Rollback()
+			return migrated, skipped, fmt.Errorf("could not migrate unit %s: %v", unit.unitID, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return migrated, skipped, err
+		}
+		if unit.skipped {
+			skipped++
+		} else {
+			migrated++
+		}
+	}
+	return migrated, skipped, nil
+}
+
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, table, column).Scan(&count)
+	return count > 0, err
+}
+
+func indexExists(db *sql.DB, table, index string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`, table, index).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,51 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// AddressRequest is the address of a user, property or unit. Latitude and Longitude are given together.
+type AddressRequest struct {
+	Country          string              `json:"Country" binding:"max=100"`
+	City             string              `json:"city" binding:"max=100"`
+	State            string              `json:"state" binding:"max=100"`
+	Street           string              `json:"street" binding:"max=255"`
+	PostalCode       string              `json:"PostalCode" binding:"max=20"`
+	AdditionalNumber string              `json:"additionalNumber" binding:"max=20"`
+	MapLocation      string              `json:"mapLocation" binding:"max=255"`
+	Latitude         Entities.Coordinate `json:"Latitude"`
+	Longitude        Entities.Coordinate `json:"Longitude"`
+}
+
+func (r AddressRequest) Model() Entities.Address {
+	return Entities.Address{
+		Country:          r.Country,
+		City:             r.City,
+		State:            r.State,
+		Street:           r.Street,
+		PostalCode:       r.PostalCode,
+		AdditionalNumber: r.AdditionalNumber,
+		MapLocation:      r.MapLocation,
+		Latitude:         r.Latitude,
+		Longitude:        r.Longitude,
+	}
+}
+
+func NewAddressRequest(address Entities.Address) AddressRequest {
+	return AddressRequest{
+		Country:          address.Country,
+		City:             address.City,
+		State:            address.State,
+		Street:           address.Street,
+		PostalCode:       address.PostalCode,
+		AdditionalNumber: address.AdditionalNumber,
+		MapLocation:      address.MapLocation,
+		Latitude:         address.Latitude,
+		Longitude:        address.Longitude,
+	}
+}
+
+// apply copies the fields onto address, which keeps its ID
+func (r AddressRequest) apply(address *Entities.Address) {
+	id := address.AddressID
+	*address = r.Model()
+	address.AddressID = id
+}
@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// CreateBookingRequest is the body of POST /booking/create. The stay is given by checkIn and checkOut dates,
+// or by startDate and endDate instants that are turned into dates in the property's time zone.
+type CreateBookingRequest struct {
+	UnitID    string        `json:"unitID" binding:"required"`
+	UserID    string        `json:"userID" binding:"required"`
+	CheckIn   Entities.Date `json:"checkIn"`
+	CheckOut  Entities.Date `json:"checkOut"`
+	StartDate time.Time     `json:"startDate"`
+	EndDate   time.Time     `json:"endDate"`
+	Summary   string        `json:"summary"`
+}
+
+func (r CreateBookingRequest) Model() Entities.Booking {
+	return Entities.Booking{
+		UnitID:    r.UnitID,
+		UserID:    r.UserID,
+		CheckIn:   r.CheckIn,
+		CheckOut:  r.CheckOut,
+		StartDate: r.StartDate,
+		EndDate:   r.EndDate,
+		Summary:   r.Summary,
+	}
+}
+
+// UpdateBookingRequest is the body of PUT /booking/:id, fields that are left out keep their value.
+// The unit and the guest of a booking cannot be changed.
+type UpdateBookingRequest struct {
+	CheckIn   Entities.Date `json:"checkIn"`
+	CheckOut  Entities.Date `json:"checkOut"`
+	StartDate time.Time     `json:"startDate"`
+	EndDate   time.Time     `json:"endDate"`
+	Summary   string        `json:"summary"`
+}
+
+func (r UpdateBookingRequest) Model() Entities.Booking {
+	return Entities.Booking{
+		CheckIn:   r.CheckIn,
+		CheckOut:  r.CheckOut,
+		StartDate: r.StartDate,
+		EndDate:   r.EndDate,
+		Summary:   r.Summary,
+	}
+}
+
+// BookingPatch holds the fields of a booking PATCH /booking/:id can change, its body is a JSON Merge Patch
+// of them. The stay is only given by its dates here, the instants follow from them.
+type BookingPatch struct {
+	CheckIn  Entities.Date `json:"checkIn"`
+	CheckOut Entities.Date `json:"checkOut"`
+	Summary  string        `json:"summary"`
+}
+
+func NewBookingPatch(booking Entities.Booking) BookingPatch {
+	return BookingPatch{
+		CheckIn:  booking.CheckIn,
+		CheckOut: booking.CheckOut,
+		Summary:  booking.Summary,
+	}
+}
+
+// Apply copies the fields onto the booking, whose instants have to be scheduled again
+func (p BookingPatch) Apply(booking *Entities.Booking) {
+	booking.CheckIn = p.CheckIn
+	booking.CheckOut = p.CheckOut
+	booking.StartDate, booking.EndDate = time.Time{}, time.Time{}
+	booking.Summary = p.Summary
+}
@@ -0,0 +1,24 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// SendMessageRequest is the body of POST /message/send, the chat between the two users is created if needed
+type SendMessageRequest struct {
+	SenderID   string `json:"senderID" binding:"required"`
+	ReceiverID string `json:"receiverID" binding:"required,nefield=SenderID"`
+	Content    string `json:"content" binding:"required"`
+}
+
+func (r SendMessageRequest) Model() Entities.Message {
+	return Entities.Message{
+		SenderID:   r.SenderID,
+		ReceiverID: r.ReceiverID,
+		Content:    r.Content,
+	}
+}
+
+// ChatRequest names the two users of a chat
+type ChatRequest struct {
+	SenderID   string `json:"senderID" binding:"required"`
+	ReceiverID string `json:"receiverID" binding:"required"`
+}
@@ -0,0 +1,94 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// CreatePropertyRequest is the body of POST /property/create. The time zone and the check-in and check-out
+// times default to the ones in the model.
+type CreatePropertyRequest struct {
+	OwnerID      string         `json:"ownerID" binding:"required"`
+	Name         string         `json:"name" binding:"required,max=100"`
+	Type         string         `json:"type" binding:"required,max=50"`
+	Description  string         `json:"description" binding:"required"`
+	Rules        string         `json:"rules" binding:"required"`
+	TimeZone     string         `json:"timeZone" binding:"omitempty,timezone"`
+	CheckInTime  string         `json:"checkInTime" binding:"omitempty,datetime=15:04"`
+	CheckOutTime string         `json:"checkOutTime" binding:"omitempty,datetime=15:04"`
+	Address      AddressRequest `json:"address"`
+}
+
+func (r CreatePropertyRequest) Model() Entities.Property {
+	return Entities.Property{
+		OwnerID:      r.OwnerID,
+		Name:         r.Name,
+		Type:         r.Type,
+		Description:  r.Description,
+		Rules:        r.Rules,
+		TimeZone:     r.TimeZone,
+		CheckInTime:  r.CheckInTime,
+		CheckOutTime: r.CheckOutTime,
+		Address:      r.Address.Model(),
+	}
+}
+
+// UpdatePropertyRequest is the body of PUT /property/:id, fields that are left out keep their value
+type UpdatePropertyRequest struct {
+	Name         string         `json:"name" binding:"max=100"`
+	Type         string         `json:"type" binding:"max=50"`
+	Description  string         `json:"description"`
+	Rules        string         `json:"rules"`
+	TimeZone     string         `json:"timeZone" binding:"omitempty,timezone"`
+	CheckInTime  string         `json:"checkInTime" binding:"omitempty,datetime=15:04"`
+	CheckOutTime string         `json:"checkOutTime" binding:"omitempty,datetime=15:04"`
+	Address      AddressRequest `json:"address"`
+}
+
+func (r UpdatePropertyRequest) Model() Entities.Property {
+	return Entities.Property{
+		Name:         r.Name,
+		Type:         r.Type,
+		Description:  r.Description,
+		Rules:        r.Rules,
+		TimeZone:     r.TimeZone,
+		CheckInTime:  r.CheckInTime,
+		CheckOutTime: r.CheckOutTime,
+		Address:      r.Address.Model(),
+	}
+}
+
+// PropertyPatch holds the fields of a property PATCH /property/:id can change, its body is a JSON Merge
+// Patch of them. The rules are those of the property after the patch.
+type PropertyPatch struct {
+	Name         string         `json:"name" binding:"required,max=100"`
+	Type         string         `json:"type" binding:"required,max=50"`
+	Description  string         `json:"description"`
+	Rules        string         `json:"rules"`
+	TimeZone     string         `json:"timeZone" binding:"required,timezone"`
+	CheckInTime  string         `json:"checkInTime" binding:"required,datetime=15:04"`
+	CheckOutTime string         `json:"checkOutTime" binding:"required,datetime=15:04"`
+	Address      AddressRequest `json:"address"`
+}
+
+func NewPropertyPatch(property Entities.Property) PropertyPatch {
+	return PropertyPatch{
+		Name:         property.Name,
+		Type:         property.Type,
+		Description:  property.Description,
+		Rules:        property.Rules,
+		TimeZone:     property.TimeZone,
+		CheckInTime:  property.CheckInTime,
+		CheckOutTime: property.CheckOutTime,
+		Address:      NewAddressRequest(property.Address),
+	}
+}
+
+// Apply copies the fields onto the property
+func (p PropertyPatch) Apply(property *Entities.Property) {
+	property.Name = p.Name
+	property.Type = p.Type
+	property.Description = p.Description
+	property.Rules = p.Rules
+	property.TimeZone = p.TimeZone
+	property.CheckInTime = p.CheckInTime
+	property.CheckOutTime = p.CheckOutTime
+	p.Address.apply(&property.Address)
+}
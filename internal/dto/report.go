@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"database/sql"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// CreateReportRequest is the body of POST /report/create
+type CreateReportRequest struct {
+	UserID string `json:"userID" binding:"required"`
+	Type   string `json:"type" binding:"max=50"`
+	Data   string `json:"data" binding:"required"`
+}
+
+func (r CreateReportRequest) Model() Entities.Report {
+	return Entities.Report{
+		UserID: r.UserID,
+		Type:   sql.NullString{String: r.Type, Valid: r.Type != ""},
+		Data:   r.Data,
+	}
+}
+
+// UpdateReportRequest is the body of PUT /report/:id, fields that are left out keep their value
+type UpdateReportRequest struct {
+	Type string `json:"type" binding:"max=50"`
+	Data string `json:"data"`
+}
+
+func (r UpdateReportRequest) Model() Entities.Report {
+	return Entities.Report{
+		Type: sql.NullString{String: r.Type, Valid: r.Type != ""},
+		Data: r.Data,
+	}
+}
+
+// ReportResponse is a report as clients see it, with the type as a plain string
+type ReportResponse struct {
+	ReportID   string    `json:"reportID"`
+	UserID     string    `json:"userID"`
+	Type       string    `json:"type,omitempty"`
+	CreateTime time.Time `json:"createTime"`
+	Data       string    `json:"data"`
+	Version    int       `json:"version"`
+}
+
+func NewReportResponse(report Entities.Report) ReportResponse {
+	return ReportResponse{
+		ReportID:   report.ReportID,
+		UserID:     report.UserID,
+		Type:       report.Type.String,
+		CreateTime: report.CreateTime,
+		Data:       report.Data,
+		Version:    report.Version,
+	}
+}
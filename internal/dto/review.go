@@ -0,0 +1,61 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// CreateReviewRequest is the body of POST /reviews/create
+type CreateReviewRequest struct {
+	UserID  string `json:"userID" binding:"required"`
+	UnitID  string `json:"unitID" binding:"required"`
+	Review  string `json:"review"`
+	Rating  int    `json:"rating" binding:"required,gte=1,lte=5"`
+	Comment string `json:"comment"`
+}
+
+func (r CreateReviewRequest) Model() Entities.Review {
+	return Entities.Review{
+		UserID:  r.UserID,
+		UnitID:  r.UnitID,
+		Review:  r.Review,
+		Rating:  r.Rating,
+		Comment: r.Comment,
+	}
+}
+
+// UpdateReviewRequest is the body of PUT /reviews/:id, fields that are left out keep their value.
+// The author and the unit of a review cannot be changed.
+type UpdateReviewRequest struct {
+	Review  string `json:"review"`
+	Rating  int    `json:"rating" binding:"omitempty,gte=1,lte=5"`
+	Comment string `json:"comment"`
+}
+
+func (r UpdateReviewRequest) Model() Entities.Review {
+	return Entities.Review{
+		Review:  r.Review,
+		Rating:  r.Rating,
+		Comment: r.Comment,
+	}
+}
+
+// ReviewPatch holds the fields of a review PATCH /reviews/:id can change, its body is a JSON Merge Patch of
+// them. The rules are those of the review after the patch.
+type ReviewPatch struct {
+	Review  string `json:"review"`
+	Rating  int    `json:"rating" binding:"required,gte=1,lte=5"`
+	Comment string `json:"comment"`
+}
+
+func NewReviewPatch(review Entities.Review) ReviewPatch {
+	return ReviewPatch{
+		Review:  review.Review,
+		Rating:  review.Rating,
+		Comment: review.Comment,
+	}
+}
+
+// Apply copies the fields onto the review
+func (p ReviewPatch) Apply(review *Entities.Review) {
+	review.Review = p.Review
+	review.Rating = p.Rating
+	review.Comment = p.Comment
+}
@@ -0,0 +1,73 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// CreateTicketRequest is the body of POST /maintenanceTicket/create. A maintenance presenter can be
+// assigned later, new tickets are open unless a status is given.
+type CreateTicketRequest struct {
+	MaintenancePresenterID string `json:"maintenancePresenterID"`
+	TenantID               string `json:"tenantID" binding:"required"`
+	PropertyID             string `json:"propertyID" binding:"required"`
+	Description            string `json:"description" binding:"required"`
+	UrgencyLevel           string `json:"urgencyLevel" binding:"required,oneof=low medium high"`
+	Status                 string `json:"status" binding:"max=32"`
+}
+
+func (r CreateTicketRequest) Model() Entities.MaintenanceTicket {
+	ticket := Entities.MaintenanceTicket{
+		MaintenancePresenterID: r.MaintenancePresenterID,
+		TenantID:               r.TenantID,
+		PropertyID:             r.PropertyID,
+		Description:            r.Description,
+		UrgencyLevel:           r.UrgencyLevel,
+		Status:                 r.Status,
+	}
+	if ticket.Status == "" {
+		ticket.Status = "open"
+	}
+	return ticket
+}
+
+// UpdateTicketRequest is the body of PUT /maintenanceTicket/:id, fields that are left out keep their value
+type UpdateTicketRequest struct {
+	MaintenancePresenterID string `json:"maintenancePresenterID"`
+	Description            string `json:"description"`
+	UrgencyLevel           string `json:"urgencyLevel" binding:"omitempty,oneof=low medium high"`
+	Status                 string `json:"status" binding:"max=32"`
+}
+
+func (r UpdateTicketRequest) Model() Entities.MaintenanceTicket {
+	return Entities.MaintenanceTicket{
+		MaintenancePresenterID: r.MaintenancePresenterID,
+		Description:            r.Description,
+		UrgencyLevel:           r.UrgencyLevel,
+		Status:                 r.Status,
+	}
+}
+
+// TicketPatch holds the fields of a ticket PATCH /maintenanceTicket/:id can change, its body is a JSON Merge
+// Patch of them. The rules are those of the ticket after the patch, clearing the maintenance presenter
+// unassigns the ticket.
+type TicketPatch struct {
+	MaintenancePresenterID string `json:"maintenancePresenterID"`
+	Description            string `json:"description" binding:"required"`
+	UrgencyLevel           string `json:"urgencyLevel" binding:"required,oneof=low medium high"`
+	Status                 string `json:"status" binding:"required,max=32"`
+}
+
+func NewTicketPatch(ticket Entities.MaintenanceTicket) TicketPatch {
+	return TicketPatch{
+		MaintenancePresenterID: ticket.MaintenancePresenterID,
+		Description:            ticket.Description,
+		UrgencyLevel:           ticket.UrgencyLevel,
+		Status:                 ticket.Status,
+	}
+}
+
+// Apply copies the fields onto the ticket
+func (p TicketPatch) Apply(ticket *Entities.MaintenanceTicket) {
+	ticket.MaintenancePresenterID = p.MaintenancePresenterID
+	ticket.Description = p.Description
+	ticket.UrgencyLevel = p.UrgencyLevel
+	ticket.Status = p.Status
+}
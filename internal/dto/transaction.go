@@ -0,0 +1,33 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// CreateTransactionRequest is the body of POST /financialTransaction/create
+type CreateTransactionRequest struct {
+	UserID        string `json:"userID" binding:"required"`
+	BookingID     string `json:"BookingID" binding:"required"`
+	PaymentMethod string `json:"paymentMethod" binding:"required,max=32"`
+	Amount        int    `json:"amount" binding:"required,gt=0"`
+}
+
+func (r CreateTransactionRequest) Model() Entities.FinancialTransaction {
+	return Entities.FinancialTransaction{
+		UserID:        r.UserID,
+		BookingID:     r.BookingID,
+		PaymentMethod: r.PaymentMethod,
+		Amount:        r.Amount,
+	}
+}
+
+// UpdateTransactionRequest is the body of PUT /financialTransaction/:id, fields that are left out keep their value
+type UpdateTransactionRequest struct {
+	PaymentMethod string `json:"paymentMethod" binding:"max=32"`
+	Amount        int    `json:"amount" binding:"omitempty,gt=0"`
+}
+
+func (r UpdateTransactionRequest) Model() Entities.FinancialTransaction {
+	return Entities.FinancialTransaction{
+		PaymentMethod: r.PaymentMethod,
+		Amount:        r.Amount,
+	}
+}
@@ -0,0 +1,100 @@
+package dto
+
+import Entities "GraduationProject.com/m/internal/model"
+
+// CreateUnitRequest is the body of POST /units/create. The attributes can also come from the legacy
+// structuralProperties JSON, so their ranges are checked by the model once they are filled in.
+type CreateUnitRequest struct {
+	PropertyID           string                  `json:"propertyID" binding:"required"`
+	Name                 string                  `json:"name" binding:"max=100"`
+	Description          string                  `json:"description"`
+	RentalPrice          int                     `json:"rentalPrice" binding:"gte=0"`
+	StructuralProperties string                  `json:"structuralProperties"`
+	Attributes           Entities.UnitAttributes `json:"attributes"`
+	Amenities            []string                `json:"amenities"`
+	Images               [][]byte                `json:"images"`
+	Address              AddressRequest          `json:"address"`
+}
+
+func (r CreateUnitRequest) Model() Entities.Unit {
+	return Entities.Unit{
+		PropertyID:           r.PropertyID,
+		Name:                 r.Name,
+		Description:          r.Description,
+		RentalPrice:          r.RentalPrice,
+		StructuralProperties: r.StructuralProperties,
+		Attributes:           r.Attributes,
+		Amenities:            r.Amenities,
+		Images:               r.Images,
+		Address:              r.Address.Model(),
+	}
+}
+
+// UpdateUnitRequest is the body of PUT /units/:id, fields that are left out keep their value. The rating
+// is not among them, it comes from reviews.
+type UpdateUnitRequest struct {
+	PropertyID           string                  `json:"propertyID"`
+	Name                 string                  `json:"name" binding:"max=100"`
+	Description          string                  `json:"description"`
+	RentalPrice          int                     `json:"rentalPrice" binding:"gte=0"`
+	StructuralProperties string                  `json:"structuralProperties"`
+	Attributes           Entities.UnitAttributes `json:"attributes"`
+	Amenities            []string                `json:"amenities"`
+	Images               [][]byte                `json:"images"`
+	Address              AddressRequest          `json:"address"`
+}
+
+func (r UpdateUnitRequest) Model() Entities.Unit {
+	return Entities.Unit{
+		PropertyID:           r.PropertyID,
+		Name:                 r.Name,
+		Description:          r.Description,
+		RentalPrice:          r.RentalPrice,
+		StructuralProperties: r.StructuralProperties,
+		Attributes:           r.Attributes,
+		Amenities:            r.Amenities,
+		Images:               r.Images,
+		Address:              r.Address.Model(),
+	}
+}
+
+// UnitNameSearchRequest is the body of POST /units/SearchByName
+type UnitNameSearchRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UnitPatch holds the fields of a unit PATCH /units/:id can change, its body is a JSON Merge Patch of them.
+// The rules are those of the unit after the patch, the legacy structuralProperties and the images cannot
+// be patched.
+type UnitPatch struct {
+	PropertyID  string                  `json:"propertyID" binding:"required"`
+	Name        string                  `json:"name" binding:"max=100"`
+	Description string                  `json:"description"`
+	RentalPrice int                     `json:"rentalPrice" binding:"gte=0"`
+	Attributes  Entities.UnitAttributes `json:"attributes"`
+	Amenities   []string                `json:"amenities"`
+	Address     AddressRequest          `json:"address"`
+}
+
+func NewUnitPatch(unit Entities.Unit) UnitPatch {
+	return UnitPatch{
+		PropertyID:  unit.PropertyID,
+		Name:        unit.Name,
+		Description: unit.Description,
+		RentalPrice: unit.RentalPrice,
+		Attributes:  unit.Attributes,
+		Amenities:   unit.Amenities,
+		Address:     NewAddressRequest(unit.Address),
+	}
+}
+
+// Apply copies the fields onto the unit
+func (p UnitPatch) Apply(unit *Entities.Unit) {
+	unit.PropertyID = p.PropertyID
+	unit.Name = p.Name
+	unit.Description = p.Description
+	unit.RentalPrice = p.RentalPrice
+	unit.Attributes = p.Attributes
+	unit.Amenities = p.Amenities
+	p.Address.apply(&unit.Address)
+}
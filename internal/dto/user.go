@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// CreateUserRequest is the body of POST /users/create
+type CreateUserRequest struct {
+	Name        string         `json:"name" binding:"required,max=100"`
+	Email       string         `json:"email" binding:"required,email,max=255"`
+	PhoneNumber string         `json:"phoneNumber" binding:"max=20"`
+	Password    string         `json:"password" binding:"required,min=8,max=255"`
+	UserRole    string         `json:"userRole" binding:"required,oneof=LandLord Tenant MaintenancePresenter"`
+	Address     AddressRequest `json:"address"`
+}
+
+func (r CreateUserRequest) Model() Entities.User {
+	return Entities.User{
+		Name:        r.Name,
+		Email:       r.Email,
+		PhoneNumber: r.PhoneNumber,
+		Password:    r.Password,
+		UserRole:    r.UserRole,
+		Address:     r.Address.Model(),
+	}
+}
+
+// UpdateUserRequest is the body of PUT /users/:id, fields that are left out keep their value
+type UpdateUserRequest struct {
+	Name        string         `json:"name" binding:"max=100"`
+	Email       string         `json:"email" binding:"omitempty,email,max=255"`
+	PhoneNumber string         `json:"phoneNumber" binding:"max=20"`
+	UserRole    string         `json:"userRole" binding:"omitempty,oneof=LandLord Tenant MaintenancePresenter"`
+	Address     AddressRequest `json:"address"`
+}
+
+func (r UpdateUserRequest) Model() Entities.User {
+	return Entities.User{
+		Name:        r.Name,
+		Email:       r.Email,
+		PhoneNumber: r.PhoneNumber,
+		UserRole:    r.UserRole,
+		Address:     r.Address.Model(),
+	}
+}
+
+// UserPatch holds the fields of a user PATCH /users/:id can change, its body is a JSON Merge Patch of them.
+// The rules are those of the user after the patch, the password is not among the fields.
+type UserPatch struct {
+	Name        string         `json:"name" binding:"required,max=100"`
+	Email       string         `json:"email" binding:"required,email,max=255"`
+	PhoneNumber string         `json:"phoneNumber" binding:"max=20"`
+	UserRole    string         `json:"userRole" binding:"required,oneof=LandLord Tenant MaintenancePresenter"`
+	Address     AddressRequest `json:"address"`
+}
+
+func NewUserPatch(user Entities.User) UserPatch {
+	return UserPatch{
+		Name:        user.Name,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		UserRole:    user.UserRole,
+		Address:     NewAddressRequest(user.Address),
+	}
+}
+
+// Apply copies the fields onto the user
+func (p UserPatch) Apply(user *Entities.User) {
+	user.Name = p.Name
+	user.Email = p.Email
+	user.PhoneNumber = p.PhoneNumber
+	user.UserRole = p.UserRole
+	p.Address.apply(&user.Address)
+}
+
+// LoginRequest is the body of POST /users/login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// UserResponse is a user as clients see it, the password never leaves the server
+type UserResponse struct {
+	UserID      string           `json:"userID"`
+	AddressID   string           `json:"addressID,omitempty"`
+	Name        string           `json:"name"`
+	Email       string           `json:"email,omitempty"`
+	PhoneNumber string           `json:"phoneNumber,omitempty"`
+	CreateTime  time.Time        `json:"createTime"`
+	UserRole    string           `json:"userRole"`
+	Address     Entities.Address `json:"address"`
+	Version     int              `json:"version"`
+}
+
+func NewUserResponse(user Entities.User) UserResponse {
+	return UserResponse{
+		UserID:      user.UserID,
+		AddressID:   user.AddressID,
+		Name:        user.Name,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		CreateTime:  user.CreateTime,
+		UserRole:    user.UserRole,
+		Address:     user.Address,
+		Version:     user.Version,
+	}
+}
+
+func NewUserResponses(users []Entities.User) []UserResponse {
+	responses := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, NewUserResponse(user))
+	}
+	return responses
+}
@@ -0,0 +1,81 @@
+// Package dto holds what clients send to and get back from the API, separate from the model.
+// Requests are checked by gin's validator from their binding tags when they are bound.
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Errors name fields the way clients write them
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(jsonName)
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Errors turns the errors of the validator into field errors. Fields of nested objects are named by their
+// path, like "address.city".
+func Errors(errs validator.ValidationErrors) error {
+	fields := make([]error, 0, len(errs))
+	for _, e := range errs {
+		// The namespace starts with the name of the request type
+		_, path, _ := strings.Cut(e.Namespace(), ".")
+		fields = append(fields, apperror.Field(path, message(e)))
+	}
+	return errors.Join(fields...)
+}
+
+func message(e validator.FieldError) string {
+	field := e.Field()
+	switch e.Tag() {
+	case "required":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(e.Param()), ", "))
+	case "min", "gte":
+		if isLength(e.Kind()) {
+			return fmt.Sprintf("%s must be at least %s characters long", field, e.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, e.Param())
+	case "max", "lte":
+		if isLength(e.Kind()) {
+			return fmt.Sprintf("%s must be at most %s characters long", field, e.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, e.Param())
+	case "nefield":
+		other := e.Param()
+		return fmt.Sprintf("%s must be different from %s", field, strings.ToLower(other[:1])+other[1:])
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, e.Param())
+	case "timezone":
+		return field + " must be an IANA time zone like Asia/Riyadh"
+	case "datetime":
+		return field + " must be a time of day like 15:00"
+	default:
+		return field + " is not valid"
+	}
+}
+
+func isLength(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Map
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"GraduationProject.com/m/internal/listing"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// CreateWishlistRequest is the body of POST /wishlist/create, the wishlist belongs to the user in X-User-ID
+type CreateWishlistRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+func (r CreateWishlistRequest) Model(userID string) Entities.Wishlist {
+	return Entities.Wishlist{UserID: userID, Name: r.Name}
+}
+
+// RenameWishlistRequest is the body of PUT /wishlist/:id
+type RenameWishlistRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// AddWishlistUnitRequest is the body of POST /wishlist/:id/units
+type AddWishlistUnitRequest struct {
+	UnitID string `json:"unitID" binding:"required"`
+}
+
+// CreateSavedSearchRequest is the body of POST /savedSearch/create. The criteria are checked by the listing
+// package, which also normalizes the amenities in them.
+type CreateSavedSearchRequest struct {
+	UserID   string         `json:"userID" binding:"required"`
+	Name     string         `json:"name" binding:"required,max=100"`
+	Criteria listing.Filter `json:"criteria"`
+}
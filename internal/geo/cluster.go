@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// Pins are clustered on maps zoomed out further than this, closer in every pin is shown on its own
+const ClusterMaxZoom = 13
+
+type Cluster struct {
+	Center     Point
+	DistanceKm float64 // From the center of the box that was clustered
+	Members    []Match
+}
+
+// Clusters groups matches that fall into the same quarter of a map tile at the given zoom level.
+// Clusters come back closest to the center of the box first.
+func Clusters(box BoundingBox, zoom int, matches []Match) []Cluster {
+	if zoom < 0 {
+		zoom = 0
+	}
+	size := 360 / math.Pow(2, float64(zoom)) / 4
+	groups := make(map[cell]*Cluster)
+	var order []cell
+	for _, match := range matches {
+		c := cell{int(math.Floor(match.Point.Lat / size)), int(math.Floor(match.Point.Lng / size))}
+		group, ok := groups[c]
+		if !ok {
+			group = &Cluster{}
+			groups[c] = group
+			order = append(order, c)
+		}
+		group.Members = append(group.Members, match)
+	}
+
+	center := box.Center()
+	clusters := make([]Cluster, 0, len(order))
+	for _, c := range order {
+		group := groups[c]
+		for _, member := range group.Members {
+			group.Center.Lat += member.Point.Lat
+			group.Center.Lng += member.Point.Lng
+		}
+		group.Center.Lat /= float64(len(group.Members))
+		group.Center.Lng /= float64(len(group.Members))
+		group.DistanceKm = Distance(center, group.Center)
+		clusters = append(clusters, *group)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return clusters[i].DistanceKm < clusters[j].DistanceKm
+	})
+	return clusters
+}
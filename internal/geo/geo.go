@@ -0,0 +1,114 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+const earthRadiusKm = 6371.0
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return errors.New("latitude must be between -90 and 90")
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return errors.New("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// BoundingBox is the area shown on a map, from its south-west to its north-east corner. A box that crosses
+// the antimeridian has its west edge east of its east edge, like MinLng 170 and MaxLng -170.
+type BoundingBox struct {
+	MinLat float64 `json:"minLat"`
+	MinLng float64 `json:"minLng"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLng float64 `json:"maxLng"`
+}
+
+func (b BoundingBox) Validate() error {
+	if err := (Point{b.MinLat, b.MinLng}).Validate(); err != nil {
+		return err
+	}
+	if err := (Point{b.MaxLat, b.MaxLng}).Validate(); err != nil {
+		return err
+	}
+	if b.MinLat > b.MaxLat {
+		return errors.New("the bounding box minimum must be south of its maximum")
+	}
+	return nil
+}
+
+// CrossesAntimeridian reports whether the box spans the ±180° meridian
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.MinLng > b.MaxLng
+}
+
+func (b BoundingBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return p.Lng >= b.MinLng || p.Lng <= b.MaxLng
+	}
+	return p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+func (b BoundingBox) Center() Point {
+	maxLng := b.MaxLng
+	if b.CrossesAntimeridian() {
+		maxLng += 360
+	}
+	return Point{(b.MinLat + b.MaxLat) / 2, normalizeLng((b.MinLng + maxLng) / 2)}
+}
+
+// split returns the box as boxes that do not cross the antimeridian
+func (b BoundingBox) split() []BoundingBox {
+	if !b.CrossesAntimeridian() {
+		return []BoundingBox{b}
+	}
+	return []BoundingBox{
+		{MinLat: b.MinLat, MinLng: b.MinLng, MaxLat: b.MaxLat, MaxLng: 180},
+		{MinLat: b.MinLat, MinLng: -180, MaxLat: b.MaxLat, MaxLng: b.MaxLng},
+	}
+}
+
+// Around returns the smallest box that holds every point within radiusKm of the center. Its longitudes wrap
+// around the antimeridian, and a circle that reaches a pole holds every longitude.
+func Around(center Point, radiusKm float64) BoundingBox {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	box := BoundingBox{MinLat: math.Max(center.Lat-dLat, -90), MinLng: -180, MaxLat: math.Min(center.Lat+dLat, 90), MaxLng: 180}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	if dLng := dLat / math.Cos(center.Lat*math.Pi/180); dLng < 180 {
+		box.MinLng, box.MaxLng = normalizeLng(center.Lng-dLng), normalizeLng(center.Lng+dLng)
+	}
+	return box
+}
+
+// normalizeLng brings a longitude that went past the antimeridian back between -180 and 180
+func normalizeLng(lng float64) float64 {
+	switch {
+	case lng > 180:
+		return lng - 360
+	case lng < -180:
+		return lng + 360
+	}
+	return lng
+}
+
+// Distance returns the great-circle distance between a and b in kilometres
+func Distance(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
@@ -0,0 +1,126 @@
+package geo
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestBoundingBoxAcrossTheAntimeridian(t *testing.T) {
+	fiji := BoundingBox{MinLat: -20, MinLng: 175, MaxLat: -15, MaxLng: -178}
+	if err := fiji.Validate(); err != nil {
+		t.Fatalf("a box across the antimeridian was rejected: %v", err)
+	}
+	if err := (BoundingBox{MinLat: 10, MinLng: 0, MaxLat: 5, MaxLng: 1}).Validate(); err == nil {
+		t.Error("a box with its minimum north of its maximum was accepted")
+	}
+
+	tests := []struct {
+		name  string
+		point Point
+		want  bool
+	}{
+		{"west of the antimeridian", Point{-17.7, 178.0}, true},
+		{"east of the antimeridian", Point{-17.7, -179.5}, true},
+		{"on the antimeridian", Point{-17.7, 180}, true},
+		{"past the east edge", Point{-17.7, -177}, false},
+		{"past the west edge", Point{-17.7, 174}, false},
+		{"the other side of the world", Point{-17.7, 0}, false},
+		{"south of the box", Point{-21, 178}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fiji.Contains(tt.point); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+
+	if center := fiji.Center(); math.Abs(center.Lng-178.5) > 1e-9 || math.Abs(center.Lat+17.5) > 1e-9 {
+		t.Errorf("got center %v, want -17.5, 178.5", center)
+	}
+}
+
+func TestAround(t *testing.T) {
+	tests := []struct {
+		name        string
+		center      Point
+		radiusKm    float64
+		wantCrosses bool
+		wantFull    bool
+	}{
+		{"away from the antimeridian", Point{31.95, 35.93}, 10, false, false},
+		{"east edge past 180", Point{-17.7, 179.9}, 50, true, false},
+		{"west edge past -180", Point{-17.7, -179.9}, 50, true, false},
+		{"reaching the north pole", Point{89.9, 10}, 50, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := Around(tt.center, tt.radiusKm)
+			if err := box.Validate(); err != nil {
+				t.Fatalf("Around returned an invalid box %+v: %v", box, err)
+			}
+			if box.CrossesAntimeridian() != tt.wantCrosses {
+				t.Errorf("box %+v crosses the antimeridian: %v, want %v", box, box.CrossesAntimeridian(), tt.wantCrosses)
+			}
+			if full := box.MinLng == -180 && box.MaxLng == 180; full != tt.wantFull {
+				t.Errorf("box %+v holds every longitude: %v, want %v", box, full, tt.wantFull)
+			}
+			if !box.Contains(tt.center) {
+				t.Errorf("box %+v does not contain its center %v", box, tt.center)
+			}
+		})
+	}
+}
+
+func TestNearbyAcrossTheAntimeridian(t *testing.T) {
+	index := NewIndex()
+	index.Put(Item{ID: "west", Point: Point{-17.7, 179.95}})
+	index.Put(Item{ID: "east", Point: Point{-17.7, -179.95}})
+	index.Put(Item{ID: "far", Point: Point{-17.7, -178}})
+
+	matches := index.Nearby(Point{-17.7, 179.99}, 20)
+	if len(matches) != 2 || matches[0].ID != "west" || matches[1].ID != "east" {
+		t.Fatalf("got matches %+v, want west then east", matches)
+	}
+	if matches[1].DistanceKm > 10 {
+		t.Errorf("got %.1f km to the point across the antimeridian, want under 10", matches[1].DistanceKm)
+	}
+
+	within := index.Within(BoundingBox{MinLat: -18, MinLng: 179.9, MaxLat: -17, MaxLng: -179.9})
+	if len(within) != 2 {
+		t.Errorf("got %d items in the box across the antimeridian, want 2", len(within))
+	}
+}
+
+func TestReplaceIsAtomic(t *testing.T) {
+	items := make([]Item, 200)
+	for i := range items {
+		items[i] = Item{ID: strconv.Itoa(i), Point: Point{Lat: 51.5 + float64(i)/1000, Lng: -0.1}}
+	}
+	index := NewIndex()
+	index.Replace(items)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			index.Replace(items)
+		}
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if found := len(index.Nearby(Point{Lat: 51.6, Lng: -0.1}, 50)); found != len(items) {
+			t.Fatalf("a search during Replace found %d items, want %d", found, len(items))
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,158 @@
+package geo
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Size of a grid cell in degrees, roughly 5.5 km north to south
+const cellDegrees = 0.05
+
+type Item struct {
+	ID    string
+	Point Point
+	Data  interface{}
+}
+
+type Match struct {
+	Item
+	DistanceKm float64
+}
+
+type cell struct {
+	lat, lng int
+}
+
+func cellOf(p Point) cell {
+	return cell{int(math.Floor(p.Lat / cellDegrees)), int(math.Floor(p.Lng / cellDegrees))}
+}
+
+// Index is a grid based spatial index, safe for concurrent use
+type Index struct {
+	mu    sync.RWMutex
+	items map[string]Item
+	cells map[cell]map[string]bool
+}
+
+func NewIndex() *Index {
+	return &Index{
+		items: make(map[string]Item),
+		cells: make(map[cell]map[string]bool),
+	}
+}
+
+func (index *Index) Put(item Item) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.put(item)
+}
+
+func (index *Index) put(item Item) {
+	index.remove(item.ID)
+	index.items[item.ID] = item
+	c := cellOf(item.Point)
+	if index.cells[c] == nil {
+		index.cells[c] = make(map[string]bool)
+	}
+	index.cells[c][item.ID] = true
+}
+
+func (index *Index) Delete(id string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(id)
+}
+
+// Replace drops everything in the index and adds items instead. Searches see either the old items or
+// the new ones, never an index in between.
+func (index *Index) Replace(items []Item) {
+	// Nobody else sees the new index until it is swapped in, so it is built without the lock
+	fresh := NewIndex()
+	for _, item := range items {
+		fresh.put(item)
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.items, index.cells = fresh.items, fresh.cells
+}
+
+func (index *Index) remove(id string) {
+	old, ok := index.items[id]
+	if !ok {
+		return
+	}
+	c := cellOf(old.Point)
+	delete(index.cells[c], id)
+	if len(index.cells[c]) == 0 {
+		delete(index.cells, c)
+	}
+	delete(index.items, id)
+}
+
+// Nearby returns the items within radiusKm of center, closest first
+func (index *Index) Nearby(center Point, radiusKm float64) []Match {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	var matches []Match
+	index.scan(Around(center, radiusKm), func(item Item) {
+		if d := Distance(center, item.Point); d <= radiusKm {
+			matches = append(matches, Match{item, d})
+		}
+	})
+	sortMatches(matches)
+	return matches
+}
+
+// Within returns the items inside the box, closest to its center first
+func (index *Index) Within(box BoundingBox) []Match {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	center := box.Center()
+	var matches []Match
+	index.scan(box, func(item Item) {
+		matches = append(matches, Match{item, Distance(center, item.Point)})
+	})
+	sortMatches(matches)
+	return matches
+}
+
+// scan calls fn for every item inside the box, a box across the antimeridian is scanned as its two halves.
+// When a half covers more cells than there are occupied cells it walks the occupied ones instead, so a
+// zoomed out map does not visit empty ocean.
+func (index *Index) scan(box BoundingBox, fn func(Item)) {
+	visit := func(ids map[string]bool) {
+		for id := range ids {
+			if item := index.items[id]; box.Contains(item.Point) {
+				fn(item)
+			}
+		}
+	}
+	// Every item is in one cell and the halves share no cells, so no item is visited twice
+	for _, part := range box.split() {
+		from, to := cellOf(Point{part.MinLat, part.MinLng}), cellOf(Point{part.MaxLat, part.MaxLng})
+		if float64(to.lat-from.lat+1)*float64(to.lng-from.lng+1) > float64(len(index.cells)) {
+			for c, ids := range index.cells {
+				if c.lat >= from.lat && c.lat <= to.lat && c.lng >= from.lng && c.lng <= to.lng {
+					visit(ids)
+				}
+			}
+			continue
+		}
+		for lat := from.lat; lat <= to.lat; lat++ {
+			for lng := from.lng; lng <= to.lng; lng++ {
+				visit(index.cells[cell{lat, lng}])
+			}
+		}
+	}
+}
+
+func sortMatches(matches []Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].DistanceKm != matches[j].DistanceKm {
+			return matches[i].DistanceKm < matches[j].DistanceKm
+		}
+		return matches[i].ID < matches[j].ID
+	})
+}
@@ -0,0 +1,93 @@
+package Handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// headerValue matches the user and request IDs clients may send, anything else is ignored
+var headerValue = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// AuditOrigin stores who is making the request and its ID in the request context, for the audit log and the logs.
+// The API has no authentication yet, so the actor is the user ID the client sends in X-User-ID.
+// The request ID is taken from X-Request-ID or generated, and sent back in the same header.
+func AuditOrigin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := audit.Origin{Actor: audit.Anonymous, RequestID: uuid.NewString()}
+		if actor := c.GetHeader("X-User-ID"); headerValue.MatchString(actor) {
+			origin.Actor = actor
+		}
+		if requestID := c.GetHeader("X-Request-ID"); headerValue.MatchString(requestID) {
+			origin.RequestID = requestID
+		}
+		c.Header("X-Request-ID", origin.RequestID)
+		c.Request = c.Request.WithContext(audit.WithOrigin(c.Request.Context(), origin))
+		c.Next()
+	}
+}
+
+// RequireAdminToken only lets through requests that send token in X-Admin-Token
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(token)) != 1 {
+			respondError(c, apperror.New(apperror.Unauthorized, "A valid X-Admin-Token is required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+type AuditHandler struct {
+	audit repository.AuditRepository
+}
+
+func NewAuditHandler(audit repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		audit: audit,
+	}
+}
+
+// GET /admin/audit?entityType=&entityID=&actor=&page=&pageSize=
+func (handler *AuditHandler) GetAuditLog(c *gin.Context) {
+	filter := repository.AuditFilter{
+		EntityType: c.Query("entityType"),
+		EntityID:   c.Query("entityID"),
+		Actor:      c.Query("actor"),
+	}
+	if filter.EntityID != "" && filter.EntityType == "" {
+		respondError(c, invalid(apperror.Field("entityType", "entityType is required with entityID")))
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("pageSize"))
+	if pageSize < 1 {
+		pageSize = defaultAuditPageSize
+	}
+	if pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+	filter.Offset, filter.Limit = (page-1)*pageSize, pageSize
+
+	entries, err := handler.audit.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve the audit log"))
+		return
+	}
+	respond(c, http.StatusOK, "Audit log retrieved successfully", entries)
+}
@@ -1,181 +1,215 @@
 package Handlers
 
 import (
-	"database/sql"
-	"fmt"
+	"errors"
 	"net/http"
-	"strconv"
-	"time"
 
+	"GraduationProject.com/m/internal/alerts"
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
 	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 type BookingHandler struct {
-	db    *sql.DB
-	cache map[string]Entities.Booking // Cache to hold bookings in memory
+	bookings   repository.BookingRepository
+	units      repository.UnitRepository
+	properties repository.PropertyRepository
+	alerts     *alerts.Alerts
 }
 
-func NewBookingHandler(db *sql.DB) *BookingHandler {
+func NewBookingHandler(bookings repository.BookingRepository, units repository.UnitRepository, properties repository.PropertyRepository, alerts *alerts.Alerts) *BookingHandler {
 	return &BookingHandler{
-		db:    db,
-		cache: make(map[string]Entities.Booking),
+		bookings:   bookings,
+		units:      units,
+		properties: properties,
+		alerts:     alerts,
 	}
 }
 
-func (BookingHandler *BookingHandler) LoadBookings() error {
-	BookingHandler.cache = make(map[string]Entities.Booking)
-	rows, err := BookingHandler.db.Query(`SELECT BookingID, UnitID, UserID, EndDate, CreateTime, StartDate, Summary FROM Booking`)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var createTime []byte
-		var StartDate []byte
-		var EndDate []byte
-		var booking Entities.Booking
-		if err := rows.Scan(&booking.BookingID, &booking.UnitID, &booking.UserID, &EndDate, &createTime, &StartDate, &booking.Summary); err != nil {
-			fmt.Println(err.Error())
-			return err
-		}
-		booking.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		booking.StartDate, _ = time.Parse("2006-01-02 15:04:05", string(StartDate))
-		booking.EndDate, _ = time.Parse("2006-01-02 15:04:05", string(EndDate))
-		fmt.Println(booking)
-		BookingHandler.cache[booking.BookingID] = booking
-	}
-	return rows.Err()
+// respondBookingError writes the response for a failed booking lookup
+func respondBookingError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Booking not found", "Failed to retrieve booking"))
 }
 
-// Method takes date as an argument and check in the cache if there is an active booking in that date returns true otherwise false
-func (BookingHandler *BookingHandler) CheckActiveBooking(UnitId string, date time.Time) bool {
-	for _, booking := range BookingHandler.cache {
-		if booking.UnitID == UnitId && ((booking.StartDate.Before(date) || booking.StartDate.Equal(date)) && (booking.EndDate.After(date) || booking.EndDate.Equal(date))) {
-			return true
-		}
+// errBookingOverlap is returned when the unit is already booked for some of the dates
+var errBookingOverlap = apperror.New(apperror.Conflict, "There is an active booking in this date")
+
+// schedule sets the booking's StartDate and EndDate from its check-in and check-out dates in the time zone of the unit's property.
+// It writes the response and returns false when the booking cannot be scheduled.
+func (BookingHandler *BookingHandler) schedule(c *gin.Context, booking *Entities.Booking) bool {
+	ctx := c.Request.Context()
+	unit, err := BookingHandler.units.GetByID(ctx, booking.UnitID)
+	if errors.Is(err, repository.ErrNotFound) {
+		respondError(c, invalid(apperror.Field("unitID", "Unit not found")))
+		return false
+	}
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve unit"))
 		return false
 	}
-	return false
+	property, err := BookingHandler.properties.GetByID(ctx, unit.PropertyID)
+	if err != nil {
+		respondError(c, apperror.Wrap(apperror.Internal, "Failed to retrieve property", err))
+		return false
+	}
+	if err := booking.Schedule(property); err != nil {
+		respondError(c, invalid(err))
+		return false
+	}
+	return true
 }
-func (BookingHandler *BookingHandler) CreateBooking(c *gin.Context) {
-	var booking Entities.Booking
-	BookingHandler.LoadBookings()
 
-	err := c.BindJSON(&booking)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+func (BookingHandler *BookingHandler) CreateBooking(c *gin.Context) {
+	var request dto.CreateBookingRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	if BookingHandler.CheckActiveBooking(booking.UnitID, booking.StartDate) {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "There is an active booking in this date"})
+
+	booking := request.Model()
+	if !BookingHandler.schedule(c, &booking) {
 		return
 	}
-	query := `INSERT INTO Booking (UnitID, UserID, EndDate, StartDate, Summary) VALUES (?, ?, ?, ?, ?)`
-	result, err := BookingHandler.db.Exec(query, booking.UnitID, booking.UserID, booking.EndDate, booking.StartDate, booking.Summary)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create booking" + err.Error()})
+
+	ctx := c.Request.Context()
+	if err := BookingHandler.bookings.Create(ctx, &booking); err != nil {
+		respondError(c, failed(err, "Failed to create booking"))
 		return
 	}
-	id, _ := result.LastInsertId()
-	booking.BookingID = strconv.FormatInt(id, 10)
-	BookingHandler.LoadBookings()
-	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Booking created successfully", "data": BookingHandler.cache[booking.BookingID]})
+	if created, err := BookingHandler.bookings.GetByID(ctx, booking.BookingID); err == nil {
+		booking = created
+	}
+	respond(c, http.StatusCreated, "Booking created successfully", booking)
 }
 
 func (BookingHandler *BookingHandler) GetBooking(c *gin.Context) {
-	bookingID := c.Param("id")
-	BookingHandler.LoadBookings()
-
-	booking, exists := BookingHandler.cache[bookingID]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Not found"})
+	booking, err := BookingHandler.bookings.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondBookingError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Booking retrieved successfully", "data": booking})
+	setETag(c, booking.Version)
+	respond(c, http.StatusOK, "Booking retrieved successfully", booking)
 }
 
 func (BookingHandler *BookingHandler) UpdateBooking(c *gin.Context) {
-	bookingID := c.Param("id")
-	BookingHandler.LoadBookings()
-
-	var newInfoBooking Entities.Booking
-	oldInfoBooking := BookingHandler.cache[bookingID]
-
-	err := c.BindJSON(&newInfoBooking)
+	ctx := c.Request.Context()
+	oldInfoBooking, err := BookingHandler.bookings.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		respondBookingError(c, err)
+		return
+	}
+	if !ifMatch(c, oldInfoBooking.Version) {
+		return
+	}
+	previous := oldInfoBooking
+
+	var request dto.UpdateBookingRequest
+	if !bindJSON(c, &request) {
 		return
 	}
+	newInfoBooking := request.Model()
 
-	if !newInfoBooking.StartDate.IsZero() {
-		oldInfoBooking.StartDate = newInfoBooking.StartDate
+	// New dates win over new instants, which are turned into dates by schedule
+	if !newInfoBooking.CheckIn.IsZero() {
+		oldInfoBooking.CheckIn = newInfoBooking.CheckIn
+	} else if !newInfoBooking.StartDate.IsZero() {
+		oldInfoBooking.CheckIn, oldInfoBooking.StartDate = Entities.Date{}, newInfoBooking.StartDate
 	}
-	if !newInfoBooking.EndDate.IsZero() {
-		oldInfoBooking.EndDate = newInfoBooking.EndDate
+	if !newInfoBooking.CheckOut.IsZero() {
+		oldInfoBooking.CheckOut = newInfoBooking.CheckOut
+	} else if !newInfoBooking.EndDate.IsZero() {
+		oldInfoBooking.CheckOut, oldInfoBooking.EndDate = Entities.Date{}, newInfoBooking.EndDate
 	}
 	if newInfoBooking.Summary != "" {
 		oldInfoBooking.Summary = newInfoBooking.Summary
 	}
 
-	query := `UPDATE Booking SET StartDate = ?, EndDate = ?, Summary = ? WHERE BookingID = ?`
-	_, err = BookingHandler.db.Exec(query, oldInfoBooking.StartDate, oldInfoBooking.EndDate, oldInfoBooking.Summary, oldInfoBooking.BookingID)
+	BookingHandler.save(c, previous, oldInfoBooking)
+}
+
+// PatchBooking changes a booking by a JSON Merge Patch of dto.BookingPatch, unlike UpdateBooking it can
+// clear the summary
+func (BookingHandler *BookingHandler) PatchBooking(c *gin.Context) {
+	booking, err := BookingHandler.bookings.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update booking" + err.Error()})
+		respondBookingError(c, err)
+		return
+	}
+	if !ifMatch(c, booking.Version) {
+		return
+	}
+
+	previous := booking
+	patch := dto.NewBookingPatch(booking)
+	if !mergePatch(c, &patch) {
 		return
 	}
+	patch.Apply(&booking)
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Booking updated successfully", "Data": oldInfoBooking})
+	BookingHandler.save(c, previous, booking)
 }
 
-func (BookingHandler *BookingHandler) DeleteBooking(c *gin.Context) {
-	bookingID := c.Param("id")
-	BookingHandler.LoadBookings()
+// save schedules the changed booking again and stores it, the repository refuses it when it overlaps another
+// booking, then responds with it. The users who wishlisted the unit hear about the nights previous held that are free now.
+func (BookingHandler *BookingHandler) save(c *gin.Context, previous, booking Entities.Booking) {
+	if !BookingHandler.schedule(c, &booking) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := BookingHandler.bookings.Update(ctx, booking); err != nil {
+		respondError(c, failed(err, "Failed to update booking"))
+		return
+	}
+	booking.Version++
+
+	setETag(c, booking.Version)
+	respond(c, http.StatusOK, "Booking updated successfully", booking)
+	if !previous.IsPastBooking() {
+		for _, stay := range previous.FreedBy(booking) {
+			BookingHandler.alerts.DatesOpened(ctx, previous.UnitID, stay.CheckIn, stay.CheckOut)
+		}
+	}
+}
 
-	query := `DELETE FROM Booking WHERE BookingID = ?`
-	_, err := BookingHandler.db.Exec(query, bookingID)
+func (BookingHandler *BookingHandler) DeleteBooking(c *gin.Context) {
+	ctx := c.Request.Context()
+	booking, err := BookingHandler.bookings.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to delete booking" + err.Error()})
+		respondBookingError(c, err)
+		return
+	}
+
+	if err := BookingHandler.bookings.Delete(ctx, booking.BookingID); err != nil {
+		respondError(c, failed(err, "Failed to delete booking"))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Booking deleted successfully", "data": BookingHandler.cache[bookingID]})
-	BookingHandler.LoadBookings()
+	respond(c, http.StatusOK, "Booking deleted successfully", booking)
+	if !booking.IsPastBooking() {
+		BookingHandler.alerts.DatesOpened(ctx, booking.UnitID, booking.CheckIn, booking.CheckOut)
+	}
 }
 
 // A function that gets unitid and returns all the active bookings for that unit
 func (BookingHandler *BookingHandler) GetActiveBookings(c *gin.Context) {
-	unitID := c.Param("id")
-	BookingHandler.LoadBookings()
-	var activeBookings []Entities.Booking
-	for _, booking := range BookingHandler.cache {
-		if booking.UnitID == unitID {
-			activeBookings = append(activeBookings, booking)
-		}
-	}
-	if len(activeBookings) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "No active bookings found"})
+	activeBookings, err := BookingHandler.bookings.ListByUnit(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondBookingError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Active bookings retrieved successfully", "data": activeBookings})
+	respond(c, http.StatusOK, "Active bookings retrieved successfully", activeBookings)
 }
 
 // GET all the bookings for a user
 func (BookingHandler *BookingHandler) GetBookingsByUserID(c *gin.Context) {
-	userID := c.Param("id")
-	BookingHandler.LoadBookings()
-	var userBookings []Entities.Booking
-	for _, booking := range BookingHandler.cache {
-		if booking.UserID == userID {
-			userBookings = append(userBookings, booking)
-		}
-	}
-	if len(userBookings) == 0 {
-		c.JSON(http.StatusOK, gin.H{"status": "sucess", "message": "No bookings found for this user"})
+	userBookings, err := BookingHandler.bookings.ListByUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondBookingError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Bookings retrieved successfully", "data": userBookings})
+	respond(c, http.StatusOK, "Bookings retrieved successfully", userBookings)
 }
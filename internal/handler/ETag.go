@@ -0,0 +1,39 @@
+package Handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// errStale is the answer to an update based on a version that is no longer the current one
+var errStale = apperror.New(apperror.PreconditionFailed, "It was changed since you read it, get it again and redo your change")
+
+// etag is the entity tag of a version, it is strong so If-Match can compare it
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag tells clients the version of the entity in the response, to send back in If-Match
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatch checks the If-Match header of an update against the current version of the entity. Without the
+// header the update goes ahead. It writes 412 with the current ETag and returns false when no tag matches.
+func ifMatch(c *gin.Context, version int) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	setETag(c, version)
+	respondError(c, errStale)
+	return false
+}
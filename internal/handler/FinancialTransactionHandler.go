@@ -1,96 +1,72 @@
 package Handlers
 
 import (
-	"database/sql"
-	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
-	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 type FinancialTransactionHandler struct {
-	db    *sql.DB
-	cache map[string]Entities.FinancialTransaction // Cache to hold transactions in memory
+	transactions repository.TransactionRepository
 }
 
-func NewFinancialTransactionHandler(db *sql.DB) *FinancialTransactionHandler {
+func NewFinancialTransactionHandler(transactions repository.TransactionRepository) *FinancialTransactionHandler {
 	return &FinancialTransactionHandler{
-		db:    db,
-		cache: make(map[string]Entities.FinancialTransaction),
+		transactions: transactions,
 	}
 }
 
-func (handler *FinancialTransactionHandler) LoadTransactions() error {
-	handler.cache = make(map[string]Entities.FinancialTransaction)
-	rows, err := handler.db.Query(`SELECT TransactionID, UserID, BookingID, PaymentMethod, Amount, CreateTime FROM FinancialTransaction`)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var transaction Entities.FinancialTransaction
-		var createTime []byte
-		if err := rows.Scan(&transaction.TransactionID, &transaction.UserID, &transaction.BookingID, &transaction.PaymentMethod, &transaction.Amount, &createTime); err != nil {
-			fmt.Println(err.Error())
-			return err
-		}
-		transaction.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		handler.cache[transaction.TransactionID] = transaction
-	}
-	return rows.Err()
+// respondTransactionError writes the response for a failed transaction lookup
+func respondTransactionError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Transaction not found", "Failed to retrieve transaction"))
 }
 
 func (handler *FinancialTransactionHandler) CreateTransaction(c *gin.Context) {
-	var transaction Entities.FinancialTransaction
-	handler.LoadTransactions()
-
-	err := c.BindJSON(&transaction)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+	var request dto.CreateTransactionRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	query := `INSERT INTO FinancialTransaction (UserID, BookingID, PaymentMethod, Amount) VALUES (?, ?, ?, ?)`
-	result, err := handler.db.Exec(query, transaction.UserID, transaction.BookingID, transaction.PaymentMethod, transaction.Amount)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create transaction" + err.Error()})
+	transaction := request.Model()
+	ctx := c.Request.Context()
+	if err := handler.transactions.Create(ctx, &transaction); err != nil {
+		respondError(c, failed(err, "Failed to create transaction"))
 		return
 	}
-	id, _ := result.LastInsertId()
-	transaction.TransactionID = strconv.FormatInt(id, 10)
-	handler.LoadTransactions()
-	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Transaction created successfully", "data": handler.cache[transaction.TransactionID]})
+	if created, err := handler.transactions.GetByID(ctx, transaction.TransactionID); err == nil {
+		transaction = created
+	}
+	respond(c, http.StatusCreated, "Transaction created successfully", transaction)
 }
 
 func (handler *FinancialTransactionHandler) GetTransaction(c *gin.Context) {
-	transactionID := c.Param("id")
-	handler.LoadTransactions()
-
-	transaction, exists := handler.cache[transactionID]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Not found"})
+	transaction, err := handler.transactions.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTransactionError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transaction retrieved successfully", "data": transaction})
+	setETag(c, transaction.Version)
+	respond(c, http.StatusOK, "Transaction retrieved successfully", transaction)
 }
 
 func (handler *FinancialTransactionHandler) UpdateTransaction(c *gin.Context) {
-	transactionID := c.Param("id")
-	handler.LoadTransactions()
-
-	var newInfoTransaction Entities.FinancialTransaction
-	oldInfoTransaction := handler.cache[transactionID]
-
-	err := c.BindJSON(&newInfoTransaction)
+	ctx := c.Request.Context()
+	oldInfoTransaction, err := handler.transactions.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		respondTransactionError(c, err)
 		return
 	}
+	if !ifMatch(c, oldInfoTransaction.Version) {
+		return
+	}
+
+	var request dto.UpdateTransactionRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	newInfoTransaction := request.Model()
 
 	if newInfoTransaction.PaymentMethod != "" {
 		oldInfoTransaction.PaymentMethod = newInfoTransaction.PaymentMethod
@@ -99,44 +75,38 @@ func (handler *FinancialTransactionHandler) UpdateTransaction(c *gin.Context) {
 		oldInfoTransaction.Amount = newInfoTransaction.Amount
 	}
 
-	query := `UPDATE FinancialTransaction SET PaymentMethod = ?, Amount = ? WHERE TransactionID = ?`
-	_, err = handler.db.Exec(query, oldInfoTransaction.PaymentMethod, oldInfoTransaction.Amount, oldInfoTransaction.TransactionID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update transaction" + err.Error()})
+	if err := handler.transactions.Update(ctx, oldInfoTransaction); err != nil {
+		respondError(c, failed(err, "Failed to update transaction"))
 		return
 	}
+	oldInfoTransaction.Version++
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transaction updated successfully", "Data": oldInfoTransaction})
+	setETag(c, oldInfoTransaction.Version)
+	respond(c, http.StatusOK, "Transaction updated successfully", oldInfoTransaction)
 }
 
 func (handler *FinancialTransactionHandler) DeleteTransaction(c *gin.Context) {
-	transactionID := c.Param("id")
-	handler.LoadTransactions()
-
-	query := `DELETE FROM FinancialTransaction WHERE TransactionID = ?`
-	_, err := handler.db.Exec(query, transactionID)
+	ctx := c.Request.Context()
+	transaction, err := handler.transactions.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to delete transaction" + err.Error()})
+		respondTransactionError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transaction deleted successfully", "data": handler.cache[transactionID]})
-	handler.LoadTransactions()
+	if err := handler.transactions.Delete(ctx, transaction.TransactionID); err != nil {
+		respondError(c, failed(err, "Failed to delete transaction"))
+		return
+	}
+
+	respond(c, http.StatusOK, "Transaction deleted successfully", transaction)
 }
 
 // GET all the transactions for a user
 func (handler *FinancialTransactionHandler) GetTransactionsByUserID(c *gin.Context) {
-	userID := c.Param("id")
-	handler.LoadTransactions()
-	var userTransactions []Entities.FinancialTransaction
-	for _, transaction := range handler.cache {
-		if transaction.UserID == userID {
-			userTransactions = append(userTransactions, transaction)
-		}
-	}
-	if len(userTransactions) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "No transactions found for this user"})
+	userTransactions, err := handler.transactions.ListByUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTransactionError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Transactions retrieved successfully", "data": userTransactions})
+	respond(c, http.StatusOK, "Transactions retrieved successfully", userTransactions)
 }
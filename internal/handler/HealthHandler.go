@@ -0,0 +1,70 @@
+package Handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each readiness check, probes give up after a few seconds anyway
+const readinessTimeout = 2 * time.Second
+
+// HealthCheck is something the API needs to serve requests, Check returns why it is not there
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	checks   []HealthCheck
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks: checks,
+	}
+}
+
+// Drain makes the API report that it is not ready, so no new requests are sent to it while it shuts down
+func (handler *HealthHandler) Drain() {
+	handler.draining.Store(true)
+}
+
+// GET /healthz answers as long as the process serves requests, it checks nothing else
+func (handler *HealthHandler) Healthz(c *gin.Context) {
+	respond(c, http.StatusOK, "Alive", nil)
+}
+
+// GET /readyz runs every check and reports each of them. The causes of failed checks are only logged,
+// clients see which checks failed.
+func (handler *HealthHandler) Readyz(c *gin.Context) {
+	if handler.draining.Load() {
+		respondError(c, apperror.New(apperror.Unavailable, "Shutting down"))
+		return
+	}
+	checks := make(map[string]string, len(handler.checks))
+	var failures []apperror.FieldError
+	for _, check := range handler.checks {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		err := check.Check(ctx)
+		cancel()
+		if err != nil {
+			logging.FromContext(ctx).Warn("Readiness check failed", slog.String("check", check.Name), slog.Any("error", err))
+			failures = append(failures, apperror.FieldError{Field: check.Name, Message: check.Name + " is not ready"})
+			continue
+		}
+		checks[check.Name] = "ok"
+	}
+	if len(failures) > 0 {
+		respondError(c, &apperror.Error{Code: apperror.Unavailable, Message: "Not ready", Fields: failures})
+		return
+	}
+	respond(c, http.StatusOK, "Ready", checks)
+}
@@ -0,0 +1,135 @@
+package Handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/logging"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKey matches the keys clients may send, a UUID is the usual choice
+var idempotencyKey = regexp.MustCompile(`^[\x21-\x7E]{1,255}$`)
+
+// Idempotent makes a create safe to retry. The first response to a request with an Idempotency-Key header is
+// stored and replayed, with Idempotent-Replayed: true, to retries with the key until it expires after
+// retention. A retry with another body, or while the first request is still running, is a conflict. Keys
+// belong to the user in X-User-ID, it goes after AuditOrigin. Server errors are not stored, the key can be
+// retried after one.
+func Idempotent(keys repository.IdempotencyRepository, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader("Idempotency-Key")
+		if value == "" {
+			c.Next()
+			return
+		}
+		if !idempotencyKey.MatchString(value) {
+			respondError(c, invalid(apperror.Field("Idempotency-Key", "Idempotency-Key must be 1 to 255 printable ASCII characters without spaces")))
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondError(c, invalid(apperror.Field("body", "The request body could not be read")))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := Entities.IdempotencyKey{
+			Actor:       audit.OriginFrom(ctx).Actor,
+			Key:         value,
+			Route:       c.Request.Method + " " + c.FullPath(),
+			RequestHash: requestHash(body),
+			ExpireTime:  time.Now().Add(retention),
+		}
+		stored, err := keys.Reserve(ctx, &key)
+		if errors.Is(err, repository.ErrDuplicate) {
+			replay(c, key, stored)
+			return
+		}
+		if err != nil {
+			respondError(c, failed(err, "Failed to check the idempotency key"))
+			return
+		}
+
+		// The key is stored whatever happens to the client, and released when the handler fails or panics
+		ctx = context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if !completed {
+				if err := keys.Release(ctx, key.Actor, key.Key); err != nil {
+					logging.FromContext(ctx).Error("Failed to release an idempotency key", slog.Any("error", err))
+				}
+			}
+		}()
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if writer.Status() >= 500 {
+			return
+		}
+		key.Status, key.ContentType, key.Body = writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes()
+		if err := keys.Complete(ctx, key); err != nil {
+			// Retries keep getting a conflict rather than running the request twice
+			logging.FromContext(ctx).Error("Failed to store the response to an idempotent request", slog.Any("error", err))
+		}
+		completed = true
+	}
+}
+
+// replay answers a retry with the stored response, or with a conflict when it cannot be replayed
+func replay(c *gin.Context, key, stored Entities.IdempotencyKey) {
+	switch {
+	case stored.Route != key.Route || stored.RequestHash != key.RequestHash:
+		respondError(c, apperror.New(apperror.Conflict, "The Idempotency-Key was already used for a different request"))
+	case stored.Status == 0:
+		respondError(c, apperror.New(apperror.Conflict, "A request with this Idempotency-Key is still running, retry later"))
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+		c.Abort()
+	}
+}
+
+// requestHash identifies a body. JSON is compared by its content, so retries may order the fields differently.
+func requestHash(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var content interface{}
+	if err := decoder.Decode(&content); err == nil && !decoder.More() {
+		if canonical, err := json.Marshal(content); err == nil {
+			body = canonical
+		}
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -1,140 +1,118 @@
 package Handlers
 
 import (
-	"database/sql"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
+	"GraduationProject.com/m/internal/dto"
 	Entities "GraduationProject.com/m/internal/model"
-	"github.com/gorilla/mux"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
 )
 
 type MaintenanceTicketHandler struct {
-	db                *sql.DB
-	TicketIdReference int64
-	cache             map[string]Entities.MaintenanceTicket // Cache to hold tickets in memory
+	tickets repository.TicketRepository
 }
 
-func NewMaintenanceTicketHandler(db *sql.DB) *MaintenanceTicketHandler {
+func NewMaintenanceTicketHandler(tickets repository.TicketRepository) *MaintenanceTicketHandler {
 	return &MaintenanceTicketHandler{
-		db:                db,
-		TicketIdReference: 0,
-		cache:             make(map[string]Entities.MaintenanceTicket),
+		tickets: tickets,
 	}
 }
 
-func (handler *MaintenanceTicketHandler) GenerateUniqueTicketID() string {
-	handler.TicketIdReference++
-	return fmt.Sprintf("%d", handler.TicketIdReference)
-}
+func (handler *MaintenanceTicketHandler) CreateMaintenanceTicket(c *gin.Context) {
+	var request dto.CreateTicketRequest
+	if !bindJSON(c, &request) {
+		return
+	}
 
-func (handler *MaintenanceTicketHandler) SetHighestTicketID() {
-	highestID := int64(0)
-	for _, ticket := range handler.cache {
-		ticketID, err := strconv.ParseInt(ticket.TicketID, 10, 64)
-		if err != nil {
-			continue // Skip if the TicketID is not a valid integer
-		}
-		if ticketID > highestID {
-			highestID = ticketID
-		}
-	}
-	handler.TicketIdReference = highestID
+	ticket := request.Model()
+	if err := handler.tickets.Create(c.Request.Context(), &ticket); err != nil {
+		respondError(c, failed(err, "Failed to create maintenance ticket"))
+		return
+	}
+	respond(c, http.StatusCreated, "Maintenance ticket created successfully", ticket)
 }
 
-func (handler *MaintenanceTicketHandler) LoadTickets() error {
-	rows, err := handler.db.Query(`SELECT TicketID, MaintenancePresenterID, TenantID, PropertyID, Description, UrgencyLevel, CreateTime, Status FROM MaintenanceTicket`)
+func (handler *MaintenanceTicketHandler) GetMaintenanceTicket(c *gin.Context) {
+	ticket, err := handler.tickets.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		return err
+		respondError(c, lookupFailed(err, "Maintenance ticket not found", "Failed to retrieve maintenance ticket"))
+		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var ticket Entities.MaintenanceTicket
-		if err := rows.Scan(&ticket.TicketID, &ticket.MaintenancePresenterID, &ticket.TenantID, &ticket.PropertyID, &ticket.Description, &ticket.UrgencyLevel, &ticket.CreateTime, &ticket.Status); err != nil {
-			return err
-		}
-		handler.cache[ticket.TicketID] = ticket
-	}
-	handler.SetHighestTicketID()
-	return rows.Err()
+	setETag(c, ticket.Version)
+	respond(c, http.StatusOK, "Maintenance ticket retrieved successfully", ticket)
 }
 
-func (handler *MaintenanceTicketHandler) CreateMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
-	var ticket Entities.MaintenanceTicket
-	err := json.NewDecoder(r.Body).Decode(&ticket)
+func (handler *MaintenanceTicketHandler) UpdateMaintenanceTicket(c *gin.Context) {
+	ctx := c.Request.Context()
+	ticket, err := handler.tickets.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(c, lookupFailed(err, "Maintenance ticket not found", "Failed to retrieve maintenance ticket"))
 		return
 	}
-	handler.LoadTickets()
-
-	query := `INSERT INTO MaintenanceTicket (TicketID, MaintenancePresenterID, TenantID, PropertyID, Description, UrgencyLevel, CreateTime, Status) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = handler.db.Exec(query, ticket.TicketID, ticket.MaintenancePresenterID, ticket.TenantID, ticket.PropertyID, ticket.Description, ticket.UrgencyLevel, time.Now(), ticket.Status)
-	if err != nil {
-		http.Error(w, "Failed to create maintenance ticket", http.StatusInternalServerError)
+	if !ifMatch(c, ticket.Version) {
 		return
 	}
-	handler.LoadTickets()
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(handler.cache[ticket.TicketID]) // Respond with the created ticket object
-}
 
-func (handler *MaintenanceTicketHandler) GetMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	ticketID := params["id"]
-
-	var ticket Entities.MaintenanceTicket
-	query := `SELECT TicketID, MaintenancePresenterID, TenantID, PropertyID, Description, UrgencyLevel, CreateTime, Status FROM MaintenanceTicket WHERE TicketID = ?`
-	err := handler.db.QueryRow(query, ticketID).Scan(&ticket.TicketID, &ticket.MaintenancePresenterID, &ticket.TenantID, &ticket.PropertyID, &ticket.Description, &ticket.UrgencyLevel, &ticket.CreateTime, &ticket.Status)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.NotFound(w, r)
-			return
-		}
-		http.Error(w, "Failed to retrieve maintenance ticket", http.StatusInternalServerError)
+	var request dto.UpdateTicketRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(ticket)
+	update := request.Model()
+	if update.MaintenancePresenterID != "" {
+		ticket.MaintenancePresenterID = update.MaintenancePresenterID
+	}
+	if update.Description != "" {
+		ticket.Description = update.Description
+	}
+	if update.UrgencyLevel != "" {
+		ticket.UrgencyLevel = update.UrgencyLevel
+	}
+	if update.Status != "" {
+		ticket.Status = update.Status
+	}
+
+	handler.save(c, ticket)
 }
 
-func (handler *MaintenanceTicketHandler) UpdateMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	ticketID := params["id"]
-	handler.LoadTickets()
-	var ticket Entities.MaintenanceTicket
-	err := json.NewDecoder(r.Body).Decode(&ticket)
+// PatchMaintenanceTicket changes a ticket by a JSON Merge Patch of dto.TicketPatch, unlike
+// UpdateMaintenanceTicket it can unassign the maintenance presenter
+func (handler *MaintenanceTicketHandler) PatchMaintenanceTicket(c *gin.Context) {
+	ticket, err := handler.tickets.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(c, lookupFailed(err, "Maintenance ticket not found", "Failed to retrieve maintenance ticket"))
+		return
+	}
+	if !ifMatch(c, ticket.Version) {
 		return
 	}
 
-	query := `UPDATE MaintenanceTicket SET MaintenancePresenterID = ?, TenantID = ?, PropertyID = ?, Description = ?, UrgencyLevel = ?, Status = ? WHERE TicketID = ?`
-	_, err = handler.db.Exec(query, ticket.MaintenancePresenterID, ticket.TenantID, ticket.PropertyID, ticket.Description, ticket.UrgencyLevel, ticket.Status, ticketID)
-	if err != nil {
-		http.Error(w, "Failed to update maintenance ticket", http.StatusInternalServerError)
+	patch := dto.NewTicketPatch(ticket)
+	if !mergePatch(c, &patch) {
 		return
 	}
-	handler.LoadTickets()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Maintenance ticket updated successfully")
+	patch.Apply(&ticket)
+
+	handler.save(c, ticket)
 }
 
-func (handler *MaintenanceTicketHandler) DeleteMaintenanceTicket(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	ticketID := params["id"]
-	handler.LoadTickets()
-	query := `DELETE FROM MaintenanceTicket WHERE TicketID = ?`
-	_, err := handler.db.Exec(query, ticketID)
-	if err != nil {
-		http.Error(w, "Failed to delete maintenance ticket", http.StatusInternalServerError)
+// save stores the changed ticket and responds with it
+func (handler *MaintenanceTicketHandler) save(c *gin.Context, ticket Entities.MaintenanceTicket) {
+	if err := handler.tickets.Update(c.Request.Context(), ticket); err != nil {
+		respondError(c, failed(err, "Failed to update maintenance ticket"))
+		return
+	}
+	ticket.Version++
+	setETag(c, ticket.Version)
+	respond(c, http.StatusOK, "Maintenance ticket updated successfully", ticket)
+}
+
+func (handler *MaintenanceTicketHandler) DeleteMaintenanceTicket(c *gin.Context) {
+	if err := handler.tickets.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Maintenance ticket not found", "Failed to delete maintenance ticket"))
 		return
 	}
-	handler.LoadTickets()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Maintenance ticket deleted successfully")
+	respond(c, http.StatusOK, "Maintenance ticket deleted successfully", nil)
 }
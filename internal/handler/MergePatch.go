@@ -0,0 +1,82 @@
+package Handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/mergepatch"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// mergePatch merges the JSON Merge Patch (RFC 7396) in the request body into patch, which starts out with
+// the current fields of the entity, like a dto.UnitPatch. Members of the body that are left out keep their
+// value and members that are null clear theirs. The result is checked by the binding rules of patch. It
+// writes a validation error and returns false when the body is not a patch of those fields or the result
+// is not valid.
+func mergePatch(c *gin.Context, patch interface{}) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, invalid(apperror.Field("body", "The request body could not be read")))
+		return false
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		respondError(c, bindingError(io.EOF))
+		return false
+	}
+	// A patch that is not an object would replace the whole entity
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		respondError(c, bindingError(err))
+		return false
+	}
+	if members == nil {
+		respondError(c, apperror.New(apperror.ValidationFailed, "The request body must be an object"))
+		return false
+	}
+
+	current, err := json.Marshal(patch)
+	if err != nil {
+		respondError(c, apperror.Wrap(apperror.Internal, "Failed to apply the patch", err))
+		return false
+	}
+	merged, err := mergepatch.Apply(current, body)
+	if err != nil {
+		respondError(c, bindingError(err))
+		return false
+	}
+
+	// Cleared fields are missing from the result, so it is decoded into an empty patch
+	target := reflect.ValueOf(patch).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		if name, ok := unknownField(err); ok {
+			respondError(c, invalid(apperror.Field(name, name+" cannot be changed")))
+			return false
+		}
+		respondError(c, bindingError(err))
+		return false
+	}
+	if err := binding.Validator.ValidateStruct(patch); err != nil {
+		respondError(c, bindingError(err))
+		return false
+	}
+	return true
+}
+
+// unknownField returns the member a decoder with DisallowUnknownFields did not know, which has no error type
+func unknownField(err error) (string, bool) {
+	quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	name, err := strconv.Unquote(quoted)
+	return name, err == nil
+}
@@ -1,188 +1,80 @@
 package Handlers
 
 import (
-	"database/sql"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 
-	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/repository"
 )
 
 type MessageHandler struct {
-	db    *sql.DB
-	cache map[string]Entities.Chat // Cache to hold messages in memory
+	messages repository.MessageRepository
 }
 
-func NewMessageHandler(db *sql.DB) *MessageHandler {
+func NewMessageHandler(messages repository.MessageRepository) *MessageHandler {
 	return &MessageHandler{
-		db:    db,
-		cache: make(map[string]Entities.Chat),
+		messages: messages,
 	}
 }
 
-func (handler *MessageHandler) LoadMessages() error {
-	handler.cache = make(map[string]Entities.Chat)
-	rows, err := handler.db.Query(`SELECT * FROM Chat`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var createTime []byte
-		var Chat Entities.Chat
-		if err := rows.Scan(&Chat.ChatID, &Chat.SenderID, &Chat.ReceiverID, &createTime); err != nil {
-			return err
-		}
-		Chat.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		rows, err := handler.db.Query(`SELECT MessageID, ChatID, SenderID, Content, CreateTime FROM Message WHERE ChatID = ?`, Chat.ChatID)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var createTime []byte
-			var message Entities.Message
-			if err := rows.Scan(&message.MessageID, &message.ChatID, &message.SenderID, &message.Content, &createTime); err != nil {
-				return err
-			}
-			message.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-			Chat.Messages = append(Chat.Messages, message)
-		}
-		handler.cache[Chat.ChatID] = Chat
-	}
-	return rows.Err()
-
-}
-
-// function to check if there is a chat between two users, if there is return the chatID otherwise return an empty string
-func (handler *MessageHandler) GetChatID(senderID string, receiverID string) string {
-	handler.LoadMessages()
-	for _, chat := range handler.cache {
-		if (chat.SenderID == senderID && chat.ReceiverID == receiverID) || (chat.SenderID == receiverID && chat.ReceiverID == senderID) {
-			return chat.ChatID
-		}
-	}
-	//Create a new chat
-	query := `INSERT INTO Chat (SenderID, ReceiverID) VALUES (?, ?)`
-	_, err := handler.db.Exec(query, senderID, receiverID)
-	if err != nil {
-		return ""
-	}
-	handler.LoadMessages()
-	for _, chat := range handler.cache {
-		if (chat.SenderID == senderID && chat.ReceiverID == receiverID) || (chat.SenderID == receiverID && chat.ReceiverID == senderID) {
-			return chat.ChatID
-		}
-	}
-	return ""
-}
 func (handler *MessageHandler) SendMessage(c *gin.Context) {
-	var message Entities.Message
-	err := c.BindJSON(&message)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+	var request dto.SendMessageRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	handler.LoadMessages()
-	message.ChatID = handler.GetChatID(message.SenderID, message.ReceiverID)
-	if message.ChatID == "" {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "error",
-			Message: "Failed to create chat",
-		})
+	message := request.Model()
+	ctx := c.Request.Context()
+	chat, _, err := handler.messages.GetOrCreateChat(ctx, message.SenderID, message.ReceiverID)
+	if err != nil {
+		respondError(c, failed(err, "Failed to create chat"))
 		return
 	}
-	query := `INSERT INTO Message (ChatID, SenderID, Content) VALUES (?, ?, ?)`
-	_, err = handler.db.Exec(query, message.ChatID, message.SenderID, message.Content)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "error",
-			Message: "Failed to create message",
-		})
+	message.ChatID = chat.ChatID
+	if err := handler.messages.CreateMessage(ctx, &message); err != nil {
+		respondError(c, failed(err, "Failed to create message"))
 		return
 	}
-	handler.LoadMessages()
-	c.JSON(http.StatusCreated, Response{
-		Status:  "success",
-		Message: "Message created successfully",
-		Data:    handler.cache[message.MessageID],
-	})
+	respond(c, http.StatusCreated, "Message created successfully", message)
 }
 
 func (handler *MessageHandler) GetChat(c *gin.Context) {
-	var chatRequest struct {
-		SenderID   string `json:"senderID"`
-		ReceiverID string `json:"receiverID"`
+	var chatRequest dto.ChatRequest
+	if !bindJSON(c, &chatRequest) {
+		return
 	}
-
-	if err := c.ShouldBindJSON(&chatRequest); err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+	chats, err := handler.messages.ListChatsByUser(c.Request.Context(), chatRequest.SenderID)
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve chat"))
 		return
 	}
-	chatID := ""
-	for _, chat := range handler.cache {
-		if (chat.SenderID == chatRequest.SenderID && chat.ReceiverID == chatRequest.ReceiverID) || (chat.SenderID == chatRequest.ReceiverID && chat.ReceiverID == chatRequest.SenderID) {
-			chatID = chat.ChatID
+	for _, chat := range chats {
+		if chat.SenderID == chatRequest.ReceiverID || chat.ReceiverID == chatRequest.ReceiverID {
+			respond(c, http.StatusOK, "Chat retrieved successfully", chat)
+			return
 		}
 	}
-	if chatID == "" {
-		c.JSON(http.StatusNotFound, Response{
-			Status:  "error",
-			Message: "Chat not found",
-		})
-		return
-	}
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Chat retrieved successfully",
-		Data:    handler.cache[chatID],
-	})
+	respondError(c, apperror.New(apperror.NotFound, "Chat not found"))
 }
 
 // Get chat by Chat ID
 func (handler *MessageHandler) GetChatByID(c *gin.Context) {
-	chatID := c.Param("id")
-	handler.LoadMessages()
-	chat, exists := handler.cache[chatID]
-	if !exists {
-		c.JSON(http.StatusNotFound, Response{
-			Status:  "error",
-			Message: "Chat not found",
-		})
+	chat, err := handler.messages.GetChat(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, lookupFailed(err, "Chat not found", "Failed to retrieve chat"))
 		return
 	}
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Chat retrieved successfully",
-		Data:    chat,
-	})
+	respond(c, http.StatusOK, "Chat retrieved successfully", chat)
 }
 
 // Get chat by sender ID
 func (handler *MessageHandler) GetChatBySenderID(c *gin.Context) {
-	senderID := c.Param("id")
-	handler.LoadMessages()
-	var chats []Entities.Chat
-	for _, chat := range handler.cache {
-		if chat.SenderID == senderID {
-			chats = append(chats, chat)
-		}
-		if chat.ReceiverID == senderID {
-			chats = append(chats, chat)
-		}
+	chats, err := handler.messages.ListChatsByUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve chats"))
+		return
 	}
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Chats retrieved successfully",
-		Data:    chats,
-	})
+	respond(c, http.StatusOK, "Chats retrieved successfully", chats)
 }
@@ -0,0 +1,36 @@
+package Handlers
+
+import (
+	"net/http"
+
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notifications repository.NotificationRepository
+}
+
+func NewNotificationHandler(notifications repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{
+		notifications: notifications,
+	}
+}
+
+// GetNotificationsByUserID lists the newest notifications of a user first, ?unread=true leaves out the read ones
+func (handler *NotificationHandler) GetNotificationsByUserID(c *gin.Context) {
+	notifications, err := handler.notifications.ListByUser(c.Request.Context(), c.Param("id"), c.Query("unread") == "true")
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve notifications"))
+		return
+	}
+	respond(c, http.StatusOK, "Notifications retrieved successfully", notifications)
+}
+
+func (handler *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	if err := handler.notifications.MarkRead(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Unread notification not found", "Failed to update notification"))
+		return
+	}
+	respond(c, http.StatusOK, "Notification marked as read", nil)
+}
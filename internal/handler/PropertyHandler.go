@@ -1,161 +1,133 @@
 package Handlers
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"time"
 
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/logging"
 	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+	"GraduationProject.com/m/internal/search"
 	"github.com/gin-gonic/gin"
 )
 
 type PropertyHandler struct {
-	db    *sql.DB
-	cache map[string]Entities.Property // Cache to hold properties in memory
+	properties   repository.PropertyRepository
+	units        repository.UnitRepository
+	index        *search.Index
+	reindex      func(ctx context.Context)
+	reindexUnits func(ctx context.Context, propertyID string)
 }
 
-func NewPropertyHandler(db *sql.DB) *PropertyHandler {
+// reindex rebuilds the search and map indexes after a delete, restore or archive that reached the property's units.
+// reindexUnits refreshes the entries of the property's units after an update, they share its address.
+func NewPropertyHandler(properties repository.PropertyRepository, units repository.UnitRepository, index *search.Index, reindex func(ctx context.Context),
+	reindexUnits func(ctx context.Context, propertyID string)) *PropertyHandler {
 	return &PropertyHandler{
-		db:    db,
-		cache: make(map[string]Entities.Property),
+		properties:   properties,
+		units:        units,
+		index:        index,
+		reindex:      reindex,
+		reindexUnits: reindexUnits,
 	}
 }
 
-func (PropertyHandler *PropertyHandler) LoadProperties() error {
-	PropertyHandler.cache = make(map[string]Entities.Property)
-	query := `
-        SELECT 
-            p.PropertyID, p.OwnerID, p.AddressID,  p.Name, p.Description, p.Type, p.Rules, p.CreateTime,
-            a.AddressID, a.Country, a.City, a.State, a.Street, a.PostalCode, a.AdditionalNumber, a.MapLocation, a.Latitude, a.Longitude
-        FROM 
-            Property p
-        LEFT JOIN 
-            Address a ON p.AddressID = a.AddressID
-    `
-	rows, err := PropertyHandler.db.Query(query)
+// IndexProperties rebuilds the search index entries of every property
+func (PropertyHandler *PropertyHandler) IndexProperties(ctx context.Context) error {
+	properties, err := PropertyHandler.properties.List(ctx)
 	if err != nil {
-		fmt.Println(err.Error())
 		return err
 	}
-	defer rows.Close()
+	docs := make([]search.Document, 0, len(properties))
+	for _, property := range properties {
+		docs = append(docs, propertyDocument(property))
+	}
+	PropertyHandler.index.Replace(search.KindProperty, docs)
+	return nil
+}
 
-	for rows.Next() {
-		var createTime []byte
-		var property Entities.Property
-		var address Entities.Address
-		if err := rows.Scan(&property.PropertyID, &property.OwnerID, &property.AddressID, &property.Name, &property.Description, &property.Type, &property.Rules, &createTime, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			fmt.Println(err.Error())
-		}
-		property.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
+// reindexProperty refreshes the search index entry of a single property, and returns the property as stored
+func (PropertyHandler *PropertyHandler) reindexProperty(ctx context.Context, propertyID string) (Entities.Property, bool) {
+	return reindexProperty(ctx, PropertyHandler.properties, PropertyHandler.index, propertyID)
+}
 
-		// Save the grouped object to the cache
-		property.Address = address
-		fmt.Println(property)
-		PropertyHandler.cache[property.PropertyID] = property
+// reindexProperty refreshes the search index entry of the property with the given ID in index
+func reindexProperty(ctx context.Context, properties repository.PropertyRepository, index *search.Index, propertyID string) (Entities.Property, bool) {
+	property, err := properties.GetByID(ctx, propertyID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			logging.FromContext(ctx).Error("Failed to reindex property", slog.String("propertyID", propertyID), slog.Any("error", err))
+			return property, false
+		}
+		index.Delete(search.KindProperty, propertyID)
+		return property, false
 	}
-	return rows.Err()
+	index.Put(propertyDocument(property))
+	return property, true
 }
 
-func (PropertyHandler *PropertyHandler) CreateProperty(c *gin.Context) {
-	var property Entities.Property
-	PropertyHandler.LoadProperties()
+// respondPropertyError writes the response for a failed property lookup
+func respondPropertyError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Property not found", "Failed to retrieve property"))
+}
 
-	err := c.BindJSON(&property)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message here": err.Error()})
-		return
+// propertyWriteFailed describes an error from deleting or archiving a property
+func propertyWriteFailed(err error, message string) error {
+	if errors.Is(err, repository.ErrUpcomingBookings) {
+		return apperror.New(apperror.Conflict, "A unit of the property has upcoming bookings")
 	}
+	return lookupFailed(err, "Property not found", message)
+}
 
-	err = property.Validate()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+func (PropertyHandler *PropertyHandler) CreateProperty(c *gin.Context) {
+	var request dto.CreatePropertyRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	tx, err := PropertyHandler.db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create property"})
+	property := request.Model()
+	if err := property.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
 
-	// Insert into Address table
-	addressQuery := `INSERT INTO Address (Country, City, State, Street, PostalCode, AdditionalNumber, MapLocation, Latitude, Longitude) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	addressResult, err := tx.Exec(addressQuery, property.Address.Country, property.Address.City, property.Address.State, property.Address.Street, property.Address.PostalCode, property.Address.AdditionalNumber, property.Address.MapLocation, property.Address.Latitude, property.Address.Longitude)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create address" + err.Error()})
+	property.SetScheduleDefaults()
+
+	ctx := c.Request.Context()
+	if err := PropertyHandler.properties.Create(ctx, &property); err != nil {
+		respondError(c, failed(err, "Failed to create property"))
 		return
 	}
-	AddressID, _ := addressResult.LastInsertId()
-	property.AddressID = strconv.FormatInt(AddressID, 10)
-	// Insert into Property table
-	propertyQuery := `INSERT INTO Property (OwnerID, AddressID, Name, Description, Type, Rules) VALUES (?, ?, ?, ?, ?, ?)`
-	propertyResult, err := tx.Exec(propertyQuery, property.OwnerID, property.AddressID, property.Name, property.Description, property.Type, property.Rules)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create property" + err.Error()})
-		return
+	if created, ok := PropertyHandler.reindexProperty(ctx, property.PropertyID); ok {
+		property = created
 	}
-	propertyID, _ := propertyResult.LastInsertId()
-	property.PropertyID = strconv.FormatInt(propertyID, 10)
-	tx.Commit()
-	PropertyHandler.LoadProperties()
-	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Property created successfully", "data": PropertyHandler.cache[property.PropertyID]})
+	respond(c, http.StatusCreated, "Property created successfully", property)
 }
 
 func (PropertyHandler *PropertyHandler) UpdateOrInsertProof(c *gin.Context) {
-	// Get the PropertyID from the URL parameters
-	PropertyID := c.Param("id")
-
 	// Get the URL from the form data
 	url, _ := c.GetPostForm("URL")
 	if url == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get URL from form data"})
+		respondError(c, invalid(apperror.Field("URL", "URL is required")))
 		return
 	}
 
-	// Check if an image for this property and type is proof
-	query := `SELECT COUNT(*) FROM Images WHERE PropertyID = ? AND Type = 'proof'`
-	row := PropertyHandler.db.QueryRow(query, PropertyID)
-	var count int
-	err := row.Scan(&count)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := PropertyHandler.properties.SaveProof(c.Request.Context(), c.Param("id"), url); err != nil {
+		respondError(c, lookupFailed(err, "Property not found", "Failed to save proof"))
 		return
 	}
 
-	if count == 0 {
-		// If not, insert a new row
-		insertQuery := `INSERT INTO Images (PropertyID, Type, Image) VALUES (?, 'proof', ?)`
-		_, err := PropertyHandler.db.Exec(insertQuery, PropertyID, url)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		// If yes, update the existing row
-		updateQuery := `UPDATE Images SET Image = ? WHERE PropertyID = ? AND Type = 'proof'`
-		_, err := PropertyHandler.db.Exec(updateQuery, url, PropertyID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	respond(c, http.StatusOK, "Proof saved successfully", nil)
 }
 
 func (PropertyHandler *PropertyHandler) GetProof(c *gin.Context) {
-	// Get the PropertyID from the URL parameters
-	PropertyID := c.Param("id")
-
-	// Execute the SQL query
-	query := `SELECT Image FROM Images WHERE PropertyID = ? AND Type = 'proof'`
-	row := PropertyHandler.db.QueryRow(query, PropertyID)
-	var proof []byte
-	err := row.Scan(&proof)
+	proof, err := PropertyHandler.properties.GetProof(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, lookupFailed(err, "Proof not found", "Failed to retrieve proof"))
 		return
 	}
 
@@ -164,165 +136,189 @@ func (PropertyHandler *PropertyHandler) GetProof(c *gin.Context) {
 }
 
 func (PropertyHandler *PropertyHandler) GetProperty(c *gin.Context) {
-	ownerID := c.Param("id")
-	PropertyHandler.LoadProperties()
-
-	property, exists := PropertyHandler.cache[ownerID]
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Not found"})
+	property, err := PropertyHandler.properties.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondPropertyError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Property retrieved successfully", "data": property})
+	setETag(c, property.Version)
+	respond(c, http.StatusOK, "Property retrieved successfully", property)
 }
 
 func (PropertyHandler *PropertyHandler) GetProperties(c *gin.Context) {
-	PropertyHandler.LoadProperties()
-	var properties []Entities.Property
-	for _, property := range PropertyHandler.cache {
-		properties = append(properties, property)
+	properties, err := PropertyHandler.properties.List(c.Request.Context())
+	if err != nil {
+		respondPropertyError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Properties retrieved successfully", "data": properties})
+	respond(c, http.StatusOK, "Properties retrieved successfully", properties)
 }
 
 func (PropertyHandler *PropertyHandler) UpdateProperty(c *gin.Context) {
-	PropertyID := c.Param("id")
-	PropertyHandler.LoadProperties()
-
-	var newInfoProperty Entities.Property
-	oldInfoProperty := PropertyHandler.cache[PropertyID]
-
-	err := c.BindJSON(&newInfoProperty)
+	ctx := c.Request.Context()
+	property, err := PropertyHandler.properties.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		respondPropertyError(c, err)
+		return
+	}
+	if !ifMatch(c, property.Version) {
+		return
+	}
+
+	var request dto.UpdatePropertyRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	errValid := newInfoProperty.Validate()
-	if errValid != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": errValid})
+	newInfoProperty := request.Model()
+	if err := newInfoProperty.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
 
 	if newInfoProperty.Name != "" {
-		oldInfoProperty.Name = newInfoProperty.Name
+		property.Name = newInfoProperty.Name
 	}
 	if newInfoProperty.Description != "" {
-		oldInfoProperty.Description = newInfoProperty.Description
+		property.Description = newInfoProperty.Description
 	}
 	if newInfoProperty.Type != "" {
-		oldInfoProperty.Type = newInfoProperty.Type
+		property.Type = newInfoProperty.Type
 	}
 	if newInfoProperty.Rules != "" {
-		oldInfoProperty.Rules = newInfoProperty.Rules
+		property.Rules = newInfoProperty.Rules
 	}
+	if newInfoProperty.TimeZone != "" {
+		property.TimeZone = newInfoProperty.TimeZone
+	}
+	if newInfoProperty.CheckInTime != "" {
+		property.CheckInTime = newInfoProperty.CheckInTime
+	}
+	if newInfoProperty.CheckOutTime != "" {
+		property.CheckOutTime = newInfoProperty.CheckOutTime
+	}
+	mergeAddress(&property.Address, newInfoProperty.Address)
+
+	PropertyHandler.save(c, property)
+}
 
-	query := `UPDATE Property SET Name = ?, Description = ?, Type = ?, Rules = ? WHERE PropertyID = ?`
-	_, err = PropertyHandler.db.Exec(query, oldInfoProperty.Name, oldInfoProperty.Description, oldInfoProperty.Type, oldInfoProperty.Rules, PropertyID)
+// PatchProperty changes a property by a JSON Merge Patch of dto.PropertyPatch, unlike UpdateProperty it can
+// clear fields
+func (PropertyHandler *PropertyHandler) PatchProperty(c *gin.Context) {
+	property, err := PropertyHandler.properties.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update property" + err.Error()})
+		respondPropertyError(c, err)
+		return
+	}
+	if !ifMatch(c, property.Version) {
+		return
+	}
+
+	patch := dto.NewPropertyPatch(property)
+	if !mergePatch(c, &patch) {
+		return
+	}
+	patch.Apply(&property)
+	if err := property.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+
+	PropertyHandler.save(c, property)
+}
+
+// save stores the changed property and responds with it
+func (PropertyHandler *PropertyHandler) save(c *gin.Context, property Entities.Property) {
+	ctx := c.Request.Context()
+	if err := PropertyHandler.properties.Update(ctx, property); err != nil {
+		respondError(c, failed(err, "Failed to update property"))
 		return
 	}
+	property.Version++
+	if updated, ok := PropertyHandler.reindexProperty(ctx, property.PropertyID); ok {
+		property = updated
+	}
+	PropertyHandler.reindexUnits(ctx, property.PropertyID)
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Property updated successfully", "Data": oldInfoProperty})
+	setETag(c, property.Version)
+	respond(c, http.StatusOK, "Property updated successfully", property)
 }
 
 func (PropertyHandler *PropertyHandler) DeleteProperty(c *gin.Context) {
-	ownerID := c.Param("id")
-	PropertyHandler.LoadProperties()
+	ctx := c.Request.Context()
+	property, err := PropertyHandler.properties.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		respondPropertyError(c, err)
+		return
+	}
+
+	if err := PropertyHandler.properties.Delete(ctx, property.PropertyID); err != nil {
+		respondError(c, propertyWriteFailed(err, "Failed to delete property"))
+		return
+	}
+	PropertyHandler.reindex(ctx)
 
-	query := `DELETE FROM Property WHERE PropertyID = ?`
-	_, err := PropertyHandler.db.Exec(query, ownerID)
+	respond(c, http.StatusOK, "Property deleted successfully", property)
+}
+
+// RestoreProperty brings back a deleted property along with the units that were deleted with it
+func (PropertyHandler *PropertyHandler) RestoreProperty(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := PropertyHandler.properties.Restore(ctx, c.Param("id")); err != nil {
+		respondRestoreError(c, err, "Archived properties and properties of deleted owners cannot be restored")
+		return
+	}
+	PropertyHandler.reindex(ctx)
+	property, err := PropertyHandler.properties.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to delete property" + err.Error()})
+		respondPropertyError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, "Property restored successfully", property)
+}
+
+// ArchiveProperty deletes a property and its units for good and drops their images, except ownership proofs.
+// Bookings and transactions stay for reporting.
+func (PropertyHandler *PropertyHandler) ArchiveProperty(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := PropertyHandler.properties.Archive(ctx, c.Param("id")); err != nil {
+		respondError(c, propertyWriteFailed(err, "Failed to archive property"))
 		return
 	}
+	PropertyHandler.reindex(ctx)
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Property deleted successfully", "data": PropertyHandler.cache[ownerID]})
-	PropertyHandler.LoadProperties()
+	respond(c, http.StatusOK, "Property archived successfully", nil)
 }
 
 func (PropertyHandler *PropertyHandler) GetPropertiesByUserID(c *gin.Context) {
-	userID := c.Param("id")
-	PropertyHandler.LoadProperties()
-	var properties []Entities.Property
-	for _, property := range PropertyHandler.cache {
-		if property.OwnerID == userID {
-			properties = append(properties, property)
-		}
+	properties, err := PropertyHandler.properties.ListByOwner(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondPropertyError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Properties retrieved successfully", "data": properties})
+	respond(c, http.StatusOK, "Properties retrieved successfully", properties)
 }
 
 func (PropertyHandler *PropertyHandler) GetPropertiesByType(c *gin.Context) {
-	propertyType := c.Param("type")
-	PropertyHandler.LoadProperties()
-	var properties []Entities.Property
-	for _, property := range PropertyHandler.cache {
-		if property.Type == propertyType {
-			properties = append(properties, property)
-		}
+	properties, err := PropertyHandler.properties.ListByType(c.Request.Context(), c.Param("type"))
+	if err != nil {
+		respondPropertyError(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Properties retrieved successfully", "data": properties})
+	respond(c, http.StatusOK, "Properties retrieved successfully", properties)
 }
 
 func (PropertyHandler *PropertyHandler) GetUnitsByPropertyID(c *gin.Context) {
-	propertyID := c.Param("id")
-
-	query := `
-    SELECT 
-        u.UnitID, 
-        u.PropertyID, 
-        u.Name, 
-        u.RentalPrice, 
-        u.Description, 
-        u.Rating, 
-        u.StructuralProperties, 
-        u.CreateTime,
-        a.AddressID, 
-        a.Country, 
-        a.City, 
-        a.State, 
-        a.Street, 
-        a.PostalCode, 
-        a.AdditionalNumber, 
-        a.MapLocation, 
-        a.Latitude, 
-        a.Longitude
-    FROM 
-        Unit u
-    LEFT JOIN 
-        Address a ON u.AddressID = a.AddressID
-    WHERE 
-        u.PropertyID = ?
-`
-	rows, err := PropertyHandler.db.Query(query, propertyID)
+	units, err := PropertyHandler.units.ListByProperty(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to retrieve units: " + err.Error()})
+		respondError(c, failed(err, "Failed to retrieve units"))
 		return
 	}
-	defer rows.Close()
-
-	var units []Entities.Unit
-	for rows.Next() {
-		var createTime []byte
-		var unit Entities.Unit
-		var address Entities.Address
-		if err := rows.Scan(&unit.UnitID, &unit.PropertyID, &unit.Name, &unit.RentalPrice, &unit.Description, &unit.Rating, &unit.StructuralProperties, &createTime, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to scan units: " + err.Error()})
-			return
-		}
-		unit.CreateTime, err = time.Parse("2006-01-02 15:04:05", string(createTime))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to parse time: " + err.Error()})
-			return
-		}
-		unit.Address = address
-		units = append(units, unit)
-	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Units retrieved successfully", "data": units})
+	respond(c, http.StatusOK, "Units retrieved successfully", units)
 }
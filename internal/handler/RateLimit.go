@@ -0,0 +1,55 @@
+package Handlers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitPolicy limits the requests to a group of routes from each client IP and from each user
+type RateLimitPolicy struct {
+	Group   string // Keeps the buckets of the groups apart
+	PerIP   ratelimit.Limit
+	PerUser ratelimit.Limit
+}
+
+// RateLimit answers 429 with Retry-After once the client IP or the user has used up the requests of the
+// group. It goes after AuditOrigin, requests that do not say which user made them are only limited by IP.
+// A request spends from both buckets, so a made up X-User-ID still spends the budget of its IP.
+func RateLimit(store ratelimit.Store, policy RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		buckets := map[string]ratelimit.Limit{policy.Group + ":ip:" + c.ClientIP(): policy.PerIP}
+		if actor := audit.OriginFrom(ctx).Actor; actor != audit.Anonymous {
+			buckets[policy.Group+":user:"+actor] = policy.PerUser
+		}
+
+		var wait time.Duration
+		for key, limit := range buckets {
+			decision, err := store.Take(ctx, key, limit)
+			if err != nil {
+				respondError(c, failed(err, "Failed to check the rate limit"))
+				return
+			}
+			if !decision.Allowed && decision.RetryAfter > wait {
+				wait = decision.RetryAfter
+			}
+		}
+		if wait > 0 {
+			tooManyRequests(c, wait, "Too many requests, slow down")
+			return
+		}
+		c.Next()
+	}
+}
+
+// tooManyRequests answers 429 with how long to wait in Retry-After, in whole seconds rounded up
+func tooManyRequests(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondError(c, apperror.New(apperror.TooManyRequests, message))
+}
@@ -1,150 +1,85 @@
 package Handlers
 
 import (
-	"database/sql"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
 
-	Entities "GraduationProject.com/m/internal/model"
-	"github.com/gorilla/mux"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
 )
 
 type ReportHandler struct {
-	db                *sql.DB
-	ReportIdReference int64
-	cache             map[string]Entities.Report // Cache to hold reports in memory
+	reports repository.ReportRepository
 }
 
-func NewReportHandler(db *sql.DB) *ReportHandler {
+func NewReportHandler(reports repository.ReportRepository) *ReportHandler {
 	return &ReportHandler{
-		db:                db,
-		ReportIdReference: 0,
-		cache:             make(map[string]Entities.Report),
+		reports: reports,
 	}
 }
 
-func (ReportHandler *ReportHandler) GenerateUniqueReportID() string {
-	ReportHandler.ReportIdReference++
-	return fmt.Sprintf("%d", ReportHandler.ReportIdReference)
-}
+func (ReportHandler *ReportHandler) CreateReport(c *gin.Context) {
+	var request dto.CreateReportRequest
+	if !bindJSON(c, &request) {
+		return
+	}
 
-func (ReportHandler *ReportHandler) SetHighestReportID() {
-	highestID := int64(0)
-	for _, report := range ReportHandler.cache {
-		reportID, err := strconv.ParseInt(report.ReportID, 10, 64)
-		if err != nil {
-			continue // Skip if the ReportID is not a valid integer
-		}
-		if reportID > highestID {
-			highestID = reportID
-		}
+	report := request.Model()
+	if err := ReportHandler.reports.Create(c.Request.Context(), &report); err != nil {
+		respondError(c, failed(err, "Failed to create report"))
+		return
 	}
-	ReportHandler.ReportIdReference = highestID
+	respond(c, http.StatusCreated, "Report created successfully", dto.NewReportResponse(report))
 }
 
-func (ReportHandler *ReportHandler) LoadReports() error {
-	rows, err := ReportHandler.db.Query(`SELECT ReportID, UserID, Type, CreateTime, Data FROM Report`)
+func (ReportHandler *ReportHandler) GetReport(c *gin.Context) {
+	report, err := ReportHandler.reports.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		return err
+		respondError(c, lookupFailed(err, "Report not found", "Failed to retrieve report"))
+		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var report Entities.Report
-		if err := rows.Scan(&report.ReportID, &report.UserID, &report.Type, &report.CreateTime, &report.Data); err != nil {
-			return err
-		}
-		ReportHandler.cache[report.ReportID] = report
-	}
-	ReportHandler.SetHighestReportID()
-	return rows.Err()
+	setETag(c, report.Version)
+	respond(c, http.StatusOK, "Report retrieved successfully", dto.NewReportResponse(report))
 }
 
-func (ReportHandler *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
-	var report Entities.Report
-	err := json.NewDecoder(r.Body).Decode(&report)
+func (ReportHandler *ReportHandler) UpdateReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	report, err := ReportHandler.reports.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(c, lookupFailed(err, "Report not found", "Failed to retrieve report"))
 		return
 	}
-	ReportHandler.LoadReports()
-	err = report.Validate()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !ifMatch(c, report.Version) {
 		return
 	}
 
-	query := `INSERT INTO Report (ReportID, UserID, Type, CreateTime, Data) VALUES (?, ?, ?, ?, ?)`
-	_, err = ReportHandler.db.Exec(query, report.ReportID, report.UserID, report.Type, report.CreateTime, report.Data)
-	if err != nil {
-		http.Error(w, "Failed to create report", http.StatusInternalServerError)
+	var request dto.UpdateReportRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	ReportHandler.LoadReports()
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(ReportHandler.cache[report.ReportID]) // Respond with the created report object
-}
 
-func (ReportHandler *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	reportID := params["id"]
-
-	var report Entities.Report
-	query := `SELECT ReportID, UserID, Type, CreateTime, Data FROM Report WHERE ReportID = ?`
-	err := ReportHandler.db.QueryRow(query, reportID).Scan(&report.ReportID, &report.UserID, &report.Type, &report.CreateTime, &report.Data)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.NotFound(w, r)
-			return
-		}
-		http.Error(w, "Failed to retrieve report", http.StatusInternalServerError)
-		return
+	update := request.Model()
+	if update.Type.Valid {
+		report.Type = update.Type
 	}
-
-	json.NewEncoder(w).Encode(report)
-}
-
-func (ReportHandler *ReportHandler) UpdateReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	reportID := params["id"]
-	ReportHandler.LoadReports()
-	var report Entities.Report
-	err := json.NewDecoder(r.Body).Decode(&report)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	if update.Data != "" {
+		report.Data = update.Data
 	}
 
-	err = report.Validate()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := ReportHandler.reports.Update(ctx, report); err != nil {
+		respondError(c, failed(err, "Failed to update report"))
 		return
 	}
-
-	query := `UPDATE Report SET UserID = ?, Type = ?, CreateTime = ?, Data = ? WHERE ReportID = ?`
-	_, err = ReportHandler.db.Exec(query, report.UserID, report.Type, report.CreateTime, report.Data, reportID)
-	if err != nil {
-		http.Error(w, "Failed to update report", http.StatusInternalServerError)
-		return
-	}
-	ReportHandler.LoadReports()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Report updated successfully")
+	report.Version++
+	setETag(c, report.Version)
+	respond(c, http.StatusOK, "Report updated successfully", dto.NewReportResponse(report))
 }
 
-func (ReportHandler *ReportHandler) DeleteReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	reportID := params["id"]
-	ReportHandler.LoadReports()
-	query := `DELETE FROM Report WHERE ReportID = ?`
-	_, err := ReportHandler.db.Exec(query, reportID)
-	if err != nil {
-		http.Error(w, "Failed to delete report", http.StatusInternalServerError)
+func (ReportHandler *ReportHandler) DeleteReport(c *gin.Context) {
+	if err := ReportHandler.reports.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Report not found", "Failed to delete report"))
 		return
 	}
-	ReportHandler.LoadReports()
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode("Report deleted successfully")
+	respond(c, http.StatusOK, "Report deleted successfully", nil)
 }
@@ -0,0 +1,61 @@
+package Handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestLog gives every request a logger tagged with its request ID, and its trace ID when it is traced,
+// for the handlers to find in the request context, and writes an access log line when the request is done.
+// It goes after AuditOrigin, which picks the request ID and the user ID, and the span is tagged with both.
+func RequestLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		origin := audit.OriginFrom(c.Request.Context())
+		requestLogger := logger.With(slog.String("requestID", origin.RequestID))
+		span := trace.SpanFromContext(c.Request.Context())
+		if span.SpanContext().IsValid() {
+			requestLogger = requestLogger.With(slog.String("traceID", span.SpanContext().TraceID().String()))
+			span.SetAttributes(attribute.String("request.id", origin.RequestID), attribute.String("enduser.id", origin.Actor))
+		}
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		requestLogger.LogAttrs(c.Request.Context(), level, "Request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", loggedPath(c)),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Float64("latencyMs", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("userID", origin.Actor),
+			slog.String("clientIP", c.ClientIP()),
+		)
+	}
+}
+
+// loggedPath is the path of the request with the values of sensitive parameters, like share tokens, redacted.
+// The query string is left out, clients may put anything in it.
+func loggedPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	for _, param := range c.Params {
+		if logging.Sensitive(param.Key) && param.Value != "" {
+			path = strings.Replace(path, "/"+param.Value, "/"+logging.Redacted, 1)
+		}
+	}
+	return path
+}
@@ -0,0 +1,158 @@
+package Handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime/debug"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/logging"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Response is the envelope of every response. Successful ones carry Data, failed ones a Code and, when
+// fields of the request were not valid, Details about each of them.
+type Response struct {
+	Status  string                `json:"status"`
+	Message string                `json:"message"`
+	Data    interface{}           `json:"data,omitempty"`
+	Code    apperror.Code         `json:"code,omitempty"`
+	Details []apperror.FieldError `json:"details,omitempty"`
+}
+
+// respond writes a successful response
+func respond(c *gin.Context, status int, message string, data interface{}) {
+	c.JSON(status, Response{Status: "success", Message: message, Data: data})
+}
+
+// respondError writes err as an error response. Only the message of an *apperror.Error reaches the client,
+// internal errors are logged with their cause instead.
+func respondError(c *gin.Context, err error) {
+	appErr := classify(err)
+	if appErr.Code == apperror.Internal {
+		logging.FromContext(c.Request.Context()).Error("Request failed",
+			slog.String("method", c.Request.Method), slog.String("path", loggedPath(c)), slog.Any("error", err))
+	}
+	c.AbortWithStatusJSON(appErr.Code.Status(), Response{
+		Status:  "error",
+		Message: appErr.Message,
+		Code:    appErr.Code,
+		Details: appErr.Fields,
+	})
+}
+
+// classify gives the errors of the repositories and model validation their codes, anything else is internal
+func classify(err error) *apperror.Error {
+	var appErr *apperror.Error
+	var fieldErr apperror.FieldError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.As(err, &fieldErr):
+		return apperror.Invalid(err)
+	case errors.Is(err, repository.ErrNotFound):
+		return apperror.New(apperror.NotFound, "Not found")
+	case errors.Is(err, repository.ErrDuplicate):
+		return apperror.New(apperror.Conflict, "Already exists")
+	case errors.Is(err, repository.ErrUpcomingBookings):
+		return apperror.New(apperror.Conflict, "There are bookings that have not ended yet")
+	case errors.Is(err, repository.ErrNotRestorable):
+		return apperror.New(apperror.Conflict, "Cannot be restored")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return errStale
+	case errors.Is(err, repository.ErrOverlap):
+		return errBookingOverlap
+	default:
+		return apperror.New(apperror.Internal, "Something went wrong")
+	}
+}
+
+// failed describes an error from a repository call for the client. Errors the repositories define keep
+// their code, anything else is internal and reported with message.
+func failed(err error, message string) error {
+	if appErr := classify(err); appErr.Code != apperror.Internal {
+		return appErr
+	}
+	return apperror.Wrap(apperror.Internal, message, err)
+}
+
+// lookupFailed describes an error from looking up an entity, notFound is the message when there is none
+func lookupFailed(err error, notFound, message string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.New(apperror.NotFound, notFound)
+	}
+	return failed(err, message)
+}
+
+// invalid reports err from validating the request, field errors in it are listed as details
+func invalid(err error) error {
+	return apperror.Invalid(err)
+}
+
+// bindJSON decodes the request body into target and checks its binding rules, or writes a validation error
+// and returns false
+func bindJSON(c *gin.Context, target interface{}) bool {
+	if err := c.ShouldBindJSON(target); err != nil {
+		respondError(c, bindingError(err))
+		return false
+	}
+	return true
+}
+
+// bindingError describes why the request body could not be decoded, without the decoder's Go type names
+func bindingError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, io.EOF):
+		return apperror.New(apperror.ValidationFailed, "The request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.New(apperror.ValidationFailed, "The request body is not valid JSON")
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return apperror.New(apperror.ValidationFailed, fmt.Sprintf("The request body must be %s", jsonType(typeErr.Type)))
+		}
+		return apperror.Invalid(apperror.Field(typeErr.Field, fmt.Sprintf("%s must be %s", typeErr.Field, jsonType(typeErr.Type))))
+	case errors.As(err, &validationErrs):
+		return apperror.Invalid(dto.Errors(validationErrs))
+	default:
+		// The remaining errors come from the UnmarshalJSON methods of the model, which are written for clients
+		return apperror.Invalid(err)
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
+
+// RouteNotFound answers requests for routes that do not exist
+func RouteNotFound(c *gin.Context) {
+	respondError(c, apperror.New(apperror.NotFound, "There is no such endpoint"))
+}
+
+// Recovered answers requests whose handler panicked, the panic is logged with its stack
+func Recovered(c *gin.Context, recovered interface{}) {
+	respondError(c, apperror.Wrap(apperror.Internal, "Something went wrong", fmt.Errorf("panic: %v\n%s", recovered, debug.Stack())))
+}
@@ -1,195 +1,133 @@
 package Handlers
 
 import (
-	"database/sql"
 	"net/http"
-	"time"
 
+	"GraduationProject.com/m/internal/dto"
 	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
 type ReviewHandler struct {
-	db                *sql.DB
-	ReviewIdReference int64
-	cache             map[string]Entities.Review // Cache to hold users in memory
+	reviews repository.ReviewRepository
 }
 
-func NewReviewHandler(db *sql.DB) *ReviewHandler {
+func NewReviewHandler(reviews repository.ReviewRepository) *ReviewHandler {
 	return &ReviewHandler{
-		db:    db,
-		cache: make(map[string]Entities.Review),
+		reviews: reviews,
 	}
 }
 
-func (ReviewHandler *ReviewHandler) LoadReviews() error {
-	ReviewHandler.cache = make(map[string]Entities.Review)
-	rows, err := ReviewHandler.db.Query(`SELECT ReviewID, UserID, UnitID, Review, Rating, Comment, CreateTime FROM Review`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var createTime []byte
-		var review Entities.Review
-		if err := rows.Scan(&review.ReviewID, &review.UserID, &review.UnitID, &review.Review, &review.Rating, &review.Comment, &createTime); err != nil {
-			return err
-		}
-		//fmt.Println(review)
-		review.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		ReviewHandler.cache[review.ReviewID] = review
-	}
-	return rows.Err()
+// respondReviewError writes the response for a failed review lookup
+func respondReviewError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Review not found", "Failed to retrieve review"))
 }
 
 func (ReviewHandler *ReviewHandler) CreateReview(c *gin.Context) {
-	var review Entities.Review
-	ReviewHandler.LoadReviews()
-	err := c.BindJSON(&review)
-	if err != nil {
-
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "success",
-			Message: "Review created successfully",
-			Data:    review,
-		})
+	var request dto.CreateReviewRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	err = review.Validate()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+	review := request.Model()
+	ctx := c.Request.Context()
+	if err := ReviewHandler.reviews.Create(ctx, &review); err != nil {
+		respondError(c, failed(err, "Failed to create review"))
 		return
 	}
-
-	query := `INSERT INTO Review (UserID, UnitID, Review, Rating, Comment) VALUES (?, ?, ?, ?, ?)`
-	_, err = ReviewHandler.db.Exec(query, review.UserID, review.UnitID, review.Review, review.Rating, review.Comment)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "error",
-			Message: "Failed to create review",
-		})
-		return
+	if created, err := ReviewHandler.reviews.GetByID(ctx, review.ReviewID); err == nil {
+		review = created
 	}
-	ReviewHandler.LoadReviews()
-	c.JSON(http.StatusCreated, ReviewHandler.cache[review.ReviewID]) // Respond with the created review object
+	respond(c, http.StatusCreated, "Review created successfully", review)
 }
 
 func (ReviewHandler *ReviewHandler) GetReview(c *gin.Context) {
-	reviewID := c.Param("id")
-
-	var review Entities.Review
-
-	// Load the cache
-	ReviewHandler.LoadReviews()
-
-	// Check if the review is in the cache
-	if cachedReview, ok := ReviewHandler.cache[reviewID]; ok {
-		review = cachedReview
-	} else {
-		// If the review is not in the cache, return an error
-		c.JSON(http.StatusNotFound, Response{
-			Status:  "error",
-			Message: "Review not found",
-		})
+	review, err := ReviewHandler.reviews.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondReviewError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, review)
+	setETag(c, review.Version)
+	respond(c, http.StatusOK, "Review retrieved successfully", review)
 }
 
 func (ReviewHandler *ReviewHandler) UpdateReview(c *gin.Context) {
-	reviewID := c.Param("id")
-	ReviewHandler.LoadReviews()
-	var review Entities.Review
-	err := c.BindJSON(&review)
+	ctx := c.Request.Context()
+	oldReview, err := ReviewHandler.reviews.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		respondReviewError(c, err)
 		return
 	}
-
-	err = review.Validate()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+	if !ifMatch(c, oldReview.Version) {
 		return
 	}
 
-	query := `UPDATE Review SET `
-	setValues := []interface{}{}
-	if review.UserID != "" {
-		query += `UserID = ?, `
-		setValues = append(setValues, review.UserID)
+	var request dto.UpdateReviewRequest
+	if !bindJSON(c, &request) {
+		return
 	}
-	if review.UnitID != "" {
-		query += `UnitID = ?, `
-		setValues = append(setValues, review.UnitID)
+
+	review := request.Model()
+	if review.Review != "" {
+		oldReview.Review = review.Review
 	}
 	if review.Rating != 0 {
-		query += `Rating = ?, `
-		setValues = append(setValues, review.Rating)
+		oldReview.Rating = review.Rating
 	}
 	if review.Comment != "" {
-		query += `Comment = ?, `
-		setValues = append(setValues, review.Comment)
+		oldReview.Comment = review.Comment
 	}
 
-	// Remove the last comma and space
-	query = query[:len(query)-2]
-
-	query += ` WHERE ReviewID = ?`
-	setValues = append(setValues, reviewID)
+	ReviewHandler.save(c, oldReview)
+}
 
-	_, err = ReviewHandler.db.Exec(query, setValues...)
+// PatchReview changes a review by a JSON Merge Patch of dto.ReviewPatch, unlike UpdateReview it can clear
+// the text and the comment
+func (ReviewHandler *ReviewHandler) PatchReview(c *gin.Context) {
+	review, err := ReviewHandler.reviews.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "error",
-			Message: "Failed to update review",
-		})
+		respondReviewError(c, err)
+		return
+	}
+	if !ifMatch(c, review.Version) {
+		return
+	}
+
+	patch := dto.NewReviewPatch(review)
+	if !mergePatch(c, &patch) {
+		return
+	}
+	patch.Apply(&review)
+
+	ReviewHandler.save(c, review)
+}
+
+// save stores the changed review and responds with it
+func (ReviewHandler *ReviewHandler) save(c *gin.Context, review Entities.Review) {
+	if err := ReviewHandler.reviews.Update(c.Request.Context(), review); err != nil {
+		respondError(c, failed(err, "Failed to update review"))
 		return
 	}
-	ReviewHandler.LoadReviews()
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Review updated successfully",
-	})
+	review.Version++
+	setETag(c, review.Version)
+	respond(c, http.StatusOK, "Review updated successfully", review)
 }
+
 func (ReviewHandler *ReviewHandler) DeleteReview(c *gin.Context) {
-	reviewID := c.Param("id")
-	ReviewHandler.LoadReviews()
-	query := `DELETE FROM Review WHERE ReviewID = ?`
-	_, err := ReviewHandler.db.Exec(query, reviewID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Status:  "error",
-			Message: "Failed to delete review",
-		})
+	if err := ReviewHandler.reviews.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Review not found", "Failed to delete review"))
 		return
 	}
-	ReviewHandler.LoadReviews()
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Review deleted successfully",
-	})
+	respond(c, http.StatusOK, "Review deleted successfully", nil)
 }
 
 func (ReviewHandler *ReviewHandler) GetReviewsByUnitID(c *gin.Context) {
-	unitID := c.Param("id")
-	ReviewHandler.LoadReviews()
-	var reviews []Entities.Review
-	for _, review := range ReviewHandler.cache {
-		if review.UnitID == unitID {
-			reviews = append(reviews, review)
-		}
-	}
-	c.JSON(http.StatusOK, reviews)
+	reviews, err := ReviewHandler.reviews.ListByUnit(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondReviewError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, "Reviews retrieved successfully", reviews)
 }
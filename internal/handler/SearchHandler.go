@@ -0,0 +1,109 @@
+package Handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/search"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	index *search.Index
+}
+
+func NewSearchHandler(index *search.Index) *SearchHandler {
+	return &SearchHandler{
+		index: index,
+	}
+}
+
+// GET /search?q=&kind=unit|property&page=&pageSize=
+func (handler *SearchHandler) Search(c *gin.Context) {
+	query := search.Query{Text: strings.TrimSpace(c.Query("q"))}
+	if query.Text == "" {
+		respondError(c, invalid(apperror.Field("q", "q is required")))
+		return
+	}
+	switch kind := search.Kind(c.Query("kind")); kind {
+	case "":
+	case search.KindUnit, search.KindProperty:
+		query.Kinds = []search.Kind{kind}
+	default:
+		respondError(c, invalid(apperror.Field("kind", "kind must be unit or property")))
+		return
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("pageSize"))
+
+	respond(c, http.StatusOK, "Search results retrieved successfully", handler.index.Search(query))
+}
+
+func unitDocument(unit Entities.Unit) search.Document {
+	return search.Document{
+		Kind: search.KindUnit,
+		ID:   unit.UnitID,
+		Fields: map[string]string{
+			search.FieldName:        unit.Name,
+			search.FieldDescription: unit.Description,
+			search.FieldCity:        unit.Address.City,
+			search.FieldAmenities:   strings.Join(unit.Amenities, " "),
+		},
+		Source: unit,
+	}
+}
+
+func propertyDocument(property Entities.Property) search.Document {
+	return search.Document{
+		Kind: search.KindProperty,
+		ID:   property.PropertyID,
+		Fields: map[string]string{
+			search.FieldName:        property.Name,
+			search.FieldDescription: property.Description,
+			search.FieldCity:        property.Address.City,
+			search.FieldRules:       structuralText(property.Rules),
+		},
+		Source: property,
+	}
+}
+
+// structuralText turns free-form JSON like the property rules into searchable words.
+// Keys set to true become words ({"wifi": true} -> "wifi"), other values are kept as text.
+// Anything that is not a JSON object is indexed as-is.
+func structuralText(raw string) string {
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return raw
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var words []string
+	for _, key := range keys {
+		switch value := values[key].(type) {
+		case bool:
+			if value {
+				words = append(words, key)
+			}
+		case string:
+			words = append(words, key, value)
+		case []interface{}:
+			words = append(words, key)
+			for _, item := range value {
+				words = append(words, fmt.Sprint(item))
+			}
+		default:
+			words = append(words, key)
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -1,389 +1,373 @@
 package Handlers
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
-	"time"
 
+	"GraduationProject.com/m/internal/alerts"
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
+	"GraduationProject.com/m/internal/geo"
+	"GraduationProject.com/m/internal/listing"
+	"GraduationProject.com/m/internal/logging"
 	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+	"GraduationProject.com/m/internal/search"
 	"github.com/gin-gonic/gin"
 )
 
 type UnitHandler struct {
-	db    *sql.DB
-	cache map[string]Entities.Unit // Cache to hold users in memory
+	units      repository.UnitRepository
+	properties repository.PropertyRepository
+	index      *search.Index
+	geo        *geo.Index
+	alerts     *alerts.Alerts
 }
 
-func NewUnitHandler(db *sql.DB) *UnitHandler {
+// properties is read to reindex a property when one of its units changes the address they share
+func NewUnitHandler(units repository.UnitRepository, properties repository.PropertyRepository, index *search.Index, geoIndex *geo.Index, alerts *alerts.Alerts) *UnitHandler {
 	return &UnitHandler{
-		db:    db,
-		cache: make(map[string]Entities.Unit),
+		units:      units,
+		properties: properties,
+		index:      index,
+		geo:        geoIndex,
+		alerts:     alerts,
 	}
 }
 
-func (UnitHandler *UnitHandler) LoadUnits() error {
-	UnitHandler.cache = make(map[string]Entities.Unit)
-	query := `
-    SELECT 
-        u.UnitID, u.PropertyID, u.AddressID, u.Name, u.RentalPrice, u.Description, u.Rating, u.StructuralProperties, u.CreateTime,
-        a.AddressID, a.Country, a.City, a.State, a.Street, a.PostalCode, a.AdditionalNumber, a.MapLocation, a.Latitude, a.Longitude
-    FROM 
-        Unit u
-    LEFT JOIN 
-        Address a ON u.AddressID = a.AddressID
-`
-	rows, err := UnitHandler.db.Query(query)
+// IndexUnits rebuilds the search and map index entries of every unit
+func (UnitHandler *UnitHandler) IndexUnits(ctx context.Context) error {
+	units, err := UnitHandler.units.List(ctx)
 	if err != nil {
-		fmt.Println(err.Error())
 		return err
 	}
-	defer rows.Close()
+	docs := make([]search.Document, 0, len(units))
+	var items []geo.Item
+	for _, unit := range units {
+		docs = append(docs, unitDocument(unit))
+		if point, ok := unit.Address.Point(); ok {
+			items = append(items, geo.Item{ID: unit.UnitID, Point: point, Data: unit})
+		}
+	}
+	UnitHandler.index.Replace(search.KindUnit, docs)
+	UnitHandler.geo.Replace(items)
+	return nil
+}
 
-	ownerQuery, err := UnitHandler.db.Prepare(`SELECT p.OwnerID, u.Name FROM Property p JOIN User u ON p.OwnerID = u.UserID WHERE p.PropertyID = ?`)
+// ReindexPropertyUnits refreshes the search and map index entries of the units of a property, which share
+// its address
+func (UnitHandler *UnitHandler) ReindexPropertyUnits(ctx context.Context, propertyID string) {
+	units, err := UnitHandler.units.ListByProperty(ctx, propertyID)
 	if err != nil {
-		return err
+		logging.FromContext(ctx).Error("Failed to reindex the units of a property", slog.String("propertyID", propertyID), slog.Any("error", err))
+		return
 	}
-	defer ownerQuery.Close()
-
-	for rows.Next() {
-		var createTime []byte
-		var unit Entities.Unit
-		var address Entities.Address
-		if err := rows.Scan(&unit.UnitID, &unit.PropertyID, &unit.AddressID, &unit.Name, &unit.RentalPrice, &unit.Description, &unit.Rating, &unit.StructuralProperties, &createTime, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
-		unit.CreateTime, err = time.Parse("2006-01-02 15:04:05", string(createTime))
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
+	for _, unit := range units {
+		UnitHandler.index.Put(unitDocument(unit))
+		if point, ok := unit.Address.Point(); ok {
+			UnitHandler.geo.Put(geo.Item{ID: unit.UnitID, Point: point, Data: unit})
+		} else {
+			UnitHandler.geo.Delete(unit.UnitID)
 		}
+	}
+}
 
-		// Get OwnerID and OwnerName
-		var OwnerID, OwnerName string
-		err = ownerQuery.QueryRow(unit.PropertyID).Scan(&OwnerID, &OwnerName)
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
+// reindexUnit refreshes the search and map index entries of a single unit, and returns the unit as stored
+func (UnitHandler *UnitHandler) reindexUnit(ctx context.Context, unitID string) (Entities.Unit, bool) {
+	unit, err := UnitHandler.units.GetByID(ctx, unitID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			logging.FromContext(ctx).Error("Failed to reindex unit", slog.String("unitID", unitID), slog.Any("error", err))
+			return unit, false
 		}
-
-		// Save the grouped object to the cache
-		unit.Address = address
-		unit.OwnerName = OwnerName
-		fmt.Println(unit)
-		UnitHandler.cache[unit.UnitID] = unit
+		UnitHandler.index.Delete(search.KindUnit, unitID)
+		UnitHandler.geo.Delete(unitID)
+		return unit, false
+	}
+	UnitHandler.index.Put(unitDocument(unit))
+	if point, ok := unit.Address.Point(); ok {
+		UnitHandler.geo.Put(geo.Item{ID: unit.UnitID, Point: point, Data: unit})
+	} else {
+		UnitHandler.geo.Delete(unitID)
 	}
-	return rows.Err()
+	return unit, true
 }
 
-func (UnitHandler *UnitHandler) CreateUnit(c *gin.Context) {
-	var unit Entities.Unit
-	UnitHandler.LoadUnits()
-	err := c.BindJSON(&unit)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+// respondRestoreError writes the response for a failed restore, notRestorable explains ErrNotRestorable
+func respondRestoreError(c *gin.Context, err error, notRestorable string) {
+	if errors.Is(err, repository.ErrNotRestorable) {
+		respondError(c, apperror.New(apperror.Conflict, notRestorable))
 		return
 	}
+	respondError(c, lookupFailed(err, "Nothing deleted with this ID", "Failed to restore"))
+}
 
-	err = unit.Validate()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+// respondUnitError writes the response for a failed unit lookup
+func respondUnitError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Unit not found", "Failed to retrieve unit"))
+}
+
+func (UnitHandler *UnitHandler) CreateUnit(c *gin.Context) {
+	var request dto.CreateUnitRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	tx, _ := UnitHandler.db.Begin()
-	addressQuery := `SELECT AddressID FROM Property WHERE PropertyID = ?`
-	row := tx.QueryRow(addressQuery, unit.PropertyID)
-
-	var address Entities.Address
-	err = row.Scan(&address.AddressID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, Response{
-				Status:  "error",
-				Message: "Address not found",
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, Response{
-				Status:  "error",
-				Message: "Failed to retrieve address",
-			})
-		}
+	unit := request.Model()
+	if err := unit.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
-	unit.AddressID = address.AddressID
-	query := `INSERT INTO Unit (PropertyID, AddressID, Name, RentalPrice, Description, Rating, StructuralProperties) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	result, err := tx.Exec(query, unit.PropertyID, unit.AddressID, unit.Name, unit.RentalPrice, unit.Description, unit.Rating, unit.StructuralProperties)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create unit" + err.Error()})
+	unit.FillFromStructuralProperties()
+	if err := unit.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
 
-	if unit.Images != nil {
-		for _, image := range unit.Images {
-			_, err = tx.Exec(`INSERT INTO Images (UnitID, Image, Type) VALUES (?, ?, ?)`, unit.UnitID, image, "Unit")
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to insert image" + err.Error()})
-				return
-			}
-		}
-	}
-	id, _ := result.LastInsertId()
-	unit.UnitID = strconv.FormatInt(id, 10)
-	err = tx.Commit()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create unit" + err.Error()})
+	ctx := c.Request.Context()
+	if err := UnitHandler.units.Create(ctx, &unit); err != nil {
+		respondError(c, lookupFailed(err, "Property not found", "Failed to create unit"))
 		return
 	}
-	UnitHandler.LoadUnits()
-	c.JSON(http.StatusCreated, gin.H{"status": "success", "message": "Unit created successfully", "data": UnitHandler.cache[unit.UnitID]})
+	created, ok := UnitHandler.reindexUnit(ctx, unit.UnitID)
+	if ok {
+		UnitHandler.alerts.UnitCreated(ctx, created)
+	}
+	respond(c, http.StatusCreated, "Unit created successfully", created)
 }
 
 func (UnitHandler *UnitHandler) UpdateOrInsertImage(c *gin.Context) {
-	// Get the UnitID from the URL parameters
-	UnitID := c.Param("id")
-
 	// Get the URL from the form data
 	newImages := c.PostFormArray("Images")
 	if len(newImages) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get Images from form data"})
+		respondError(c, invalid(apperror.Field("Images", "Images is required")))
 		return
 	}
 
-	// Fetch existing images from the database
-	query := `SELECT ImageID FROM Images WHERE UnitID = ? AND Type = 'Unit' LIMIT 4`
-	rows, err := UnitHandler.db.Query(query, UnitID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := UnitHandler.units.SaveImages(c.Request.Context(), c.Param("id"), newImages); err != nil {
+		respondError(c, lookupFailed(err, "Unit not found", "Failed to save images"))
 		return
 	}
-	defer rows.Close()
-
-	var existingImages []string
-	for rows.Next() {
-		var imageID string
-		if err := rows.Scan(&imageID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		existingImages = append(existingImages, imageID)
-	}
-
-	// Iterate over the newImages array
-	for i, newImage := range newImages {
-		if i < len(existingImages) {
-			// If an existing image exists, update it
-			updateQuery := `UPDATE Images SET Image = ? WHERE UnitID = ? AND ImageID = ? AND Type = 'Unit'`
-			_, err := UnitHandler.db.Exec(updateQuery, newImage, UnitID, existingImages[i])
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-		} else {
-			// If no existing image exists, insert a new one
-			insertQuery := `INSERT INTO Images (UnitID, Type, Image) VALUES (?, 'Unit', ?)`
-			_, err := UnitHandler.db.Exec(insertQuery, UnitID, newImage)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-		}
-	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	respond(c, http.StatusOK, "Images saved successfully", nil)
 }
 
 // Get the images of the unit from the images table
 func (UnitHandler *UnitHandler) GetImages(c *gin.Context) {
-	UnitID := c.Param("id")
-	query := `SELECT ImageID, UnitID, Image, Type FROM Images WHERE UnitID = ? AND Type = 'Unit'`
-	rows, err := UnitHandler.db.Query(query, UnitID)
+	images, err := UnitHandler.units.ListImages(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, failed(err, "Failed to retrieve images"))
 		return
 	}
-	defer rows.Close()
-
-	var images []Entities.Image
-	for rows.Next() {
-		var image Entities.Image
-		err := rows.Scan(&image.ImageID, &image.UnitID, &image.Image, &image.Type)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		images = append(images, image)
-	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "data": images})
+	respond(c, http.StatusOK, "Images retrieved successfully", images)
 }
 
 func (UnitHandler *UnitHandler) GetUnit(c *gin.Context) {
-	unitID := c.Param("id")
-	UnitHandler.LoadUnits()
-
-	unit, ok := UnitHandler.cache[unitID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Not found"})
+	unit, err := UnitHandler.units.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondUnitError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Units retrieved successfully", "data": unit})
+	setETag(c, unit.Version)
+	respond(c, http.StatusOK, "Unit retrieved successfully", unit)
+}
+
+// UnitPage is one page of the unit listing
+type UnitPage struct {
+	Units      []Entities.Unit `json:"units"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"nextCursor"`
+	Facets     listing.Facets  `json:"facets"`
 }
 
+// GetUnits lists units with optional filters (minPrice, maxPrice, type, city, minRating, guests, amenities),
+// sorted by newest, price, -price or rating, one page at a time. Pass nextCursor back as cursor for the next page.
 func (UnitHandler *UnitHandler) GetUnits(c *gin.Context) {
-	UnitHandler.LoadUnits()
-	var units []Entities.Unit
-	for _, unit := range UnitHandler.cache {
-		units = append(units, unit)
+	filter, err := listing.ParseFilter(c.Request.URL.Query())
+	if err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			respondError(c, invalid(apperror.Field("limit", "limit must be a positive whole number")))
+			return
+		}
+	}
+
+	page, err := UnitHandler.units.ListPage(c.Request.Context(), listing.Request{
+		Filter: filter,
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	})
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve units"))
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Units retrieved successfully", "data": units})
+	respond(c, http.StatusOK, "Units retrieved successfully", UnitPage{
+		Units:      page.Units,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		Facets:     page.Facets,
+	})
 }
 
 func (UnitHandler *UnitHandler) UpdateUnit(c *gin.Context) {
-	unitID := c.Param("id")
-	UnitHandler.LoadUnits()
-	var NewInfoUnit Entities.Unit
-	OldInfoUnit, ok := UnitHandler.cache[unitID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Unit not found"})
+	ctx := c.Request.Context()
+	unit, err := UnitHandler.units.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		respondUnitError(c, err)
+		return
+	}
+	if !ifMatch(c, unit.Version) {
 		return
 	}
 
-	_ = c.BindJSON(&NewInfoUnit)
-
-	// Prepare dynamic SQL for unit update
-	updateUnitQuery := "UPDATE Unit SET "
-	updateUnitParams := []interface{}{}
-	fields := []string{}
+	previous := unit
+	var request dto.UpdateUnitRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	NewInfoUnit := request.Model()
+	if err := NewInfoUnit.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	NewInfoUnit.FillFromStructuralProperties()
 
 	if NewInfoUnit.PropertyID != "" {
-		fields = append(fields, "PropertyID = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.PropertyID)
-		OldInfoUnit.PropertyID = NewInfoUnit.PropertyID
+		unit.PropertyID = NewInfoUnit.PropertyID
 	}
 	if NewInfoUnit.Name != "" {
-		fields = append(fields, "Name = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.Name)
-		OldInfoUnit.Name = NewInfoUnit.Name
+		unit.Name = NewInfoUnit.Name
 	}
 	if NewInfoUnit.Description != "" {
-		fields = append(fields, "Description = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.Description)
-		OldInfoUnit.Description = NewInfoUnit.Description
+		unit.Description = NewInfoUnit.Description
 	}
 	if NewInfoUnit.StructuralProperties != "" {
-		fields = append(fields, "StructuralProperties = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.StructuralProperties)
-		OldInfoUnit.StructuralProperties = NewInfoUnit.StructuralProperties
+		unit.StructuralProperties = NewInfoUnit.StructuralProperties
+	}
+	if NewInfoUnit.Attributes != (Entities.UnitAttributes{}) {
+		unit.Attributes = NewInfoUnit.Attributes
 	}
-	if NewInfoUnit.Rating != 0 {
-		fields = append(fields, "Rating = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.Rating)
-		OldInfoUnit.Rating = NewInfoUnit.Rating
+	if NewInfoUnit.Amenities != nil {
+		unit.Amenities = NewInfoUnit.Amenities
 	}
 	if NewInfoUnit.RentalPrice != 0 {
-		fields = append(fields, "RentalPrice = ?")
-		updateUnitParams = append(updateUnitParams, NewInfoUnit.RentalPrice)
-		OldInfoUnit.RentalPrice = NewInfoUnit.RentalPrice
+		unit.RentalPrice = NewInfoUnit.RentalPrice
+	}
+	unit.Images = NewInfoUnit.Images
+	mergeAddress(&unit.Address, NewInfoUnit.Address)
+	if err := unit.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
 	}
 
-	updateUnitQuery += strings.Join(fields, ", ") + " WHERE UnitID = ?"
-	updateUnitParams = append(updateUnitParams, OldInfoUnit.UnitID)
+	UnitHandler.save(c, unit, previous)
+}
 
-	_, err := UnitHandler.db.Exec(updateUnitQuery, updateUnitParams...)
+// PatchUnit changes a unit by a JSON Merge Patch of dto.UnitPatch, unlike UpdateUnit it can clear fields
+func (UnitHandler *UnitHandler) PatchUnit(c *gin.Context) {
+	unit, err := UnitHandler.units.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update unit" + err.Error()})
+		respondUnitError(c, err)
 		return
 	}
-
-	// Handle images
-	if NewInfoUnit.Images != nil {
-		_, err = UnitHandler.db.Exec(`DELETE FROM Images WHERE UnitID = ?`, unitID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update unit" + err.Error()})
-			return
-		}
-
-		for _, image := range NewInfoUnit.Images {
-			_, err = UnitHandler.db.Exec(`INSERT INTO Images (UnitID, Image, Type) VALUES (?, ?, ?)`, OldInfoUnit.UnitID, image, "Unit")
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to update unit" + err.Error()})
-				return
-			}
-		}
+	if !ifMatch(c, unit.Version) {
+		return
 	}
-	if NewInfoUnit.Address != (Entities.Address{}) {
-		// Update the address related to the unit
-		addressQuery := "UPDATE Address SET "
-		params := []interface{}{}
-
-		if NewInfoUnit.Address.Country != "" {
-			addressQuery += "Country = ?, "
-			params = append(params, NewInfoUnit.Address.Country)
-		}
-		if NewInfoUnit.Address.City != "" {
-			addressQuery += "City = ?, "
-			params = append(params, NewInfoUnit.Address.City)
-		}
-		if NewInfoUnit.Address.State != "" {
-			addressQuery += "State = ?, "
-			params = append(params, NewInfoUnit.Address.State)
-		}
-		if NewInfoUnit.Address.Street != "" {
-			addressQuery += "Street = ?, "
-			params = append(params, NewInfoUnit.Address.Street)
-		}
-		if NewInfoUnit.Address.PostalCode != "" {
-			addressQuery += "PostalCode = ?, "
-			params = append(params, NewInfoUnit.Address.PostalCode)
-		}
-		if NewInfoUnit.Address.AdditionalNumber != "" {
-			addressQuery += "AdditionalNumber = ?, "
-			params = append(params, NewInfoUnit.Address.AdditionalNumber)
-		}
-		if NewInfoUnit.Address.MapLocation != "" {
-			addressQuery += "MapLocation = ?, "
-			params = append(params, NewInfoUnit.Address.MapLocation)
-		}
-		if NewInfoUnit.Address.Latitude != "" {
-			addressQuery += "Latitude = ?, "
-			params = append(params, NewInfoUnit.Address.Latitude)
-		}
-		if NewInfoUnit.Address.Longitude != "" {
-			addressQuery += "Longitude = ?, "
-			params = append(params, NewInfoUnit.Address.Longitude)
-		}
 
-		// Remove the trailing comma and space
-		addressQuery = addressQuery[:len(addressQuery)-2]
+	patch := dto.NewUnitPatch(unit)
+	if !mergePatch(c, &patch) {
+		return
+	}
+	previous := unit
+	patch.Apply(&unit)
+	if err := unit.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	if err := unit.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
 
-		addressQuery += " WHERE AddressID = ?"
-		params = append(params, NewInfoUnit.AddressID)
+	UnitHandler.save(c, unit, previous)
+}
 
-		_, err = UnitHandler.db.Exec(addressQuery, params...)
+// save stores the changed unit and responds with it, the users who wishlisted it hear about a lower price.
+// The address belongs to the property and is shared by its units, a new one reindexes all of them. A unit
+// moved to another property takes the address of that one instead, and both properties are reindexed.
+func (UnitHandler *UnitHandler) save(c *gin.Context, unit, previous Entities.Unit) {
+	ctx := c.Request.Context()
+	moved := unit.PropertyID != previous.PropertyID
+	if moved {
+		property, err := UnitHandler.properties.GetByID(ctx, unit.PropertyID)
+		if errors.Is(err, repository.ErrNotFound) {
+			respondError(c, invalid(apperror.Field("propertyID", "Property not found")))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+			respondError(c, failed(err, "Failed to retrieve property"))
 			return
 		}
+		unit.AddressID, unit.Address = property.AddressID, property.Address
+	}
+	if err := UnitHandler.units.Update(ctx, unit); err != nil {
+		respondError(c, failed(err, "Failed to update unit"))
+		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Unit updated successfully", "Data": OldInfoUnit})
+	unit.Version++
+	if moved {
+		reindexProperty(ctx, UnitHandler.properties, UnitHandler.index, previous.PropertyID)
+	}
+	if moved || unit.Address != previous.Address {
+		UnitHandler.ReindexPropertyUnits(ctx, unit.PropertyID)
+		reindexProperty(ctx, UnitHandler.properties, UnitHandler.index, unit.PropertyID)
+	}
+	if updated, ok := UnitHandler.reindexUnit(ctx, unit.UnitID); ok {
+		unit = updated
+		UnitHandler.alerts.PriceChanged(ctx, updated, previous.RentalPrice)
+	}
+	setETag(c, unit.Version)
+	respond(c, http.StatusOK, "Unit updated successfully", unit)
 }
 
 func (UnitHandler *UnitHandler) DeleteUnit(c *gin.Context) {
-	unitID := c.Param("id")
-	UnitHandler.LoadUnits()
-	query := `DELETE FROM Unit WHERE UnitID = ?`
-	_, err := UnitHandler.db.Exec(query, unitID)
+	ctx := c.Request.Context()
+	unit, err := UnitHandler.units.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Failed to delete unit" + err.Error()})
+		respondUnitError(c, err)
+		return
+	}
+	if err := UnitHandler.units.Delete(ctx, unit.UnitID); err != nil {
+		if errors.Is(err, repository.ErrUpcomingBookings) {
+			respondError(c, apperror.New(apperror.Conflict, "The unit has upcoming bookings"))
+			return
+		}
+		respondError(c, lookupFailed(err, "Unit not found", "Failed to delete unit"))
+		return
+	}
+	UnitHandler.reindexUnit(ctx, unit.UnitID)
+	respond(c, http.StatusOK, "Unit deleted successfully", unit)
+}
+
+func (UnitHandler *UnitHandler) RestoreUnit(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := UnitHandler.units.Restore(ctx, c.Param("id")); err != nil {
+		respondRestoreError(c, err, "Archived units and units of deleted properties cannot be restored")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Unit deleted successfully", "data": UnitHandler.cache[unitID]})
-	UnitHandler.LoadUnits()
+	unit, _ := UnitHandler.reindexUnit(ctx, c.Param("id"))
+	respond(c, http.StatusOK, "Unit restored successfully", unit)
 }
 
 // GetAllUnits : Gets all the units that are available
@@ -409,60 +393,147 @@ func (UnitHandler *UnitHandler) DeleteUnit(c *gin.Context) {
 // 	c.JSON(http.StatusOK, gin.H{"status": "success", "message": "Units retrieved successfully", "data": units})
 // }
 
-// function that search units by name, let it search if there is a unit with the exact name and then units that contain the name
+// SearchUnitsByName ranks units by how well their name matches, tolerating small typos
 func (UnitHandler *UnitHandler) SearchUnitsByName(c *gin.Context) {
-	UnitHandler.LoadUnits()
-	var unit Entities.Unit
-	err := c.BindJSON(&unit)
+	var request dto.UnitNameSearchRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	result := UnitHandler.index.Search(search.Query{
+		Text:     request.Name,
+		Kinds:    []search.Kind{search.KindUnit},
+		Fields:   []string{search.FieldName},
+		PageSize: search.MaxPageSize,
+	})
+	units := make([]Entities.Unit, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		units = append(units, hit.Data.(Entities.Unit))
+	}
+	respond(c, http.StatusOK, "Units retrieved successfully", units)
+}
+
+func (UnitHandler *UnitHandler) SearchUnitsByAddress(c *gin.Context) {
+	var request dto.AddressRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	units, err := UnitHandler.units.SearchByAddress(c.Request.Context(), request.Model())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		respondError(c, failed(err, "Failed to retrieve units"))
 		return
 	}
-	var units []Entities.Unit
-	for _, unitx := range UnitHandler.cache {
-		if strings.EqualFold(unitx.Name, unit.Name) {
-			units = append(units, unitx)
-		}
+	respond(c, http.StatusOK, "Units retrieved successfully", units)
+}
+
+const (
+	defaultNearbyRadiusKm = 5
+	maxNearbyRadiusKm     = 100
+)
+
+type UnitDistance struct {
+	DistanceKm float64       `json:"distanceKm"`
+	Unit       Entities.Unit `json:"unit"`
+}
+
+type UnitCluster struct {
+	Latitude   float64        `json:"lat"`
+	Longitude  float64        `json:"lng"`
+	Count      int            `json:"count"`
+	DistanceKm float64        `json:"distanceKm"`
+	UnitIDs    []string       `json:"unitIDs"`
+	Unit       *Entities.Unit `json:"unit,omitempty"` // Only set when the cluster is a single pin
+}
+
+// GET /units/nearby?lat=&lng=&radius= returns the units within radius km of the point, closest first
+func (UnitHandler *UnitHandler) GetNearbyUnits(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, errLng := strconv.ParseFloat(c.Query("lng"), 64)
+	if errLat != nil || errLng != nil {
+		respondError(c, apperror.New(apperror.ValidationFailed, "lat and lng must be numbers"))
+		return
 	}
-	for _, unitx := range UnitHandler.cache {
-		if strings.Contains(strings.ToLower(unitx.Name), strings.ToLower(unit.Name)) {
-			units = append(units, unitx)
+	center := geo.Point{Lat: lat, Lng: lng}
+	if err := center.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	radius := float64(defaultNearbyRadiusKm)
+	if raw := c.Query("radius"); raw != "" {
+		var err error
+		radius, err = strconv.ParseFloat(raw, 64)
+		if err != nil || radius <= 0 || radius > maxNearbyRadiusKm {
+			respondError(c, invalid(apperror.Field("radius", fmt.Sprintf("radius must be a number of km between 0 and %d", maxNearbyRadiusKm))))
+			return
 		}
 	}
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Units retrieved successfully",
-		Data:    units,
-	})
+
+	respond(c, http.StatusOK, "Units retrieved successfully", unitDistances(UnitHandler.geo.Nearby(center, radius)))
 }
 
-func (UnitHandler *UnitHandler) SearchUnitsByAddress(c *gin.Context) {
-	UnitHandler.LoadUnits()
-	var Address Entities.Address
-	err := c.BindJSON(&Address)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Status:  "error",
-			Message: err.Error(),
-		})
-		return
-	}
-	var units []Entities.Unit
-	for _, unit := range UnitHandler.cache {
-		if strings.Contains(strings.ToLower(unit.Address.PostalCode), strings.ToLower(Address.PostalCode)) ||
-			strings.Contains(strings.ToLower(unit.Address.Country), strings.ToLower(Address.Country)) ||
-			strings.Contains(strings.ToLower(unit.Address.State), strings.ToLower(Address.State)) ||
-			strings.Contains(strings.ToLower(unit.Address.City), strings.ToLower(Address.City)) ||
-			strings.Contains(strings.ToLower(unit.Address.Street), strings.ToLower(Address.Street)) {
-			units = append(units, unit)
+// GET /units/map?minLat=&minLng=&maxLat=&maxLng=&zoom= returns the units inside the box shown on a map.
+// When zoom is at or below geo.ClusterMaxZoom the units are grouped into clusters instead of single pins.
+func (UnitHandler *UnitHandler) GetUnitsInBox(c *gin.Context) {
+	var box geo.BoundingBox
+	var errs [4]error
+	box.MinLat, errs[0] = strconv.ParseFloat(c.Query("minLat"), 64)
+	box.MinLng, errs[1] = strconv.ParseFloat(c.Query("minLng"), 64)
+	box.MaxLat, errs[2] = strconv.ParseFloat(c.Query("maxLat"), 64)
+	box.MaxLng, errs[3] = strconv.ParseFloat(c.Query("maxLng"), 64)
+	for _, err := range errs {
+		if err != nil {
+			respondError(c, apperror.New(apperror.ValidationFailed, "minLat, minLng, maxLat and maxLng must be numbers"))
+			return
 		}
 	}
-	c.JSON(http.StatusOK, Response{
-		Status:  "success",
-		Message: "Units retrieved successfully",
-		Data:    units,
-	})
+	if err := box.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+
+	matches := UnitHandler.geo.Within(box)
+	rawZoom := c.Query("zoom")
+	if rawZoom == "" {
+		respond(c, http.StatusOK, "Units retrieved successfully", unitDistances(matches))
+		return
+	}
+	zoom, err := strconv.Atoi(rawZoom)
+	if err != nil || zoom < 0 {
+		respondError(c, invalid(apperror.Field("zoom", "zoom must be a positive whole number")))
+		return
+	}
+	if zoom > geo.ClusterMaxZoom {
+		respond(c, http.StatusOK, "Units retrieved successfully", unitDistances(matches))
+		return
+	}
+
+	clusters := []UnitCluster{}
+	for _, cluster := range geo.Clusters(box, zoom, matches) {
+		pin := UnitCluster{
+			Latitude:   cluster.Center.Lat,
+			Longitude:  cluster.Center.Lng,
+			Count:      len(cluster.Members),
+			DistanceKm: roundKm(cluster.DistanceKm),
+		}
+		for _, member := range cluster.Members {
+			pin.UnitIDs = append(pin.UnitIDs, member.ID)
+		}
+		if len(cluster.Members) == 1 {
+			unit := cluster.Members[0].Data.(Entities.Unit)
+			pin.Unit = &unit
+		}
+		clusters = append(clusters, pin)
+	}
+	respond(c, http.StatusOK, "Unit clusters retrieved successfully", clusters)
+}
+
+func unitDistances(matches []geo.Match) []UnitDistance {
+	units := make([]UnitDistance, 0, len(matches))
+	for _, match := range matches {
+		units = append(units, UnitDistance{DistanceKm: roundKm(match.DistanceKm), Unit: match.Data.(Entities.Unit)})
+	}
+	return units
+}
+
+func roundKm(km float64) float64 {
+	return float64(int64(km*1000+0.5)) / 1000
 }
@@ -1,569 +1,379 @@
 package Handlers
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
+	"context"
+	"errors"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/dto"
 	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/ratelimit"
+	"GraduationProject.com/m/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	db              *sql.DB
-	UserIdReference int64
-	cache           map[string]Entities.User // Cache to hold users in memory
+	users        repository.UserRepository
+	properties   repository.PropertyRepository
+	units        repository.UnitRepository
+	bookings     repository.BookingRepository
+	transactions repository.TransactionRepository
+	reindex      func(ctx context.Context)
+	logins       *ratelimit.Lockout
 }
 
-func NewUserHandler(db *sql.DB) *UserHandler {
+// reindex rebuilds the search and map indexes after deleting or restoring a user took their properties along.
+// logins slows down and locks out repeated failed logins to an email, nil turns that off.
+func NewUserHandler(repos repository.Repositories, reindex func(ctx context.Context), logins *ratelimit.Lockout) *UserHandler {
 	return &UserHandler{
-		db:              db,
-		UserIdReference: 0,
-		cache:           make(map[string]Entities.User),
+		users:        repos.Users,
+		properties:   repos.Properties,
+		units:        repos.Units,
+		bookings:     repos.Bookings,
+		transactions: repos.Transactions,
+		reindex:      reindex,
+		logins:       logins,
 	}
 }
 
-type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+// respondUserError writes the response for a failed user lookup
+func respondUserError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "User not found", "Failed to retrieve user"))
 }
 
-func (UserHandler *UserHandler) LoadUsersIntoCache() error {
-	UserHandler.cache = make(map[string]Entities.User)
-	query := `
-        SELECT 
-            u.UserID, u.AddressID, u.Name, u.PhoneNumber, u.Email, u.Password, u.CreateTime, u.UserRole,
-            a.AddressID, a.Country, a.City, a.State, a.Street, a.PostalCode, a.AdditionalNumber, a.MapLocation, a.Latitude, a.Longitude
-        FROM 
-            User u
-        LEFT JOIN 
-            Address a ON u.AddressID = a.AddressID
-    `
-	rows, err := UserHandler.db.Query(query)
-	if err != nil {
-		fmt.Println(err.Error())
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var createTime []byte
-		var user Entities.User
-		var address Entities.Address
-		if err := rows.Scan(&user.UserID, &user.AddressID, &user.Name, &user.PhoneNumber, &user.Email, &user.Password, &createTime, &user.UserRole, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			fmt.Println(err.Error())
-			return err
-		}
-		user.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-
-		// Save the grouped object to the cache
-		user.Address = address
-		fmt.Println(user)
-		UserHandler.cache[user.UserID] = user
-	}
-	return rows.Err()
-}
-
-func (UserHandler *UserHandler) GetUserByID(userID string) (Entities.User, bool) {
-	user, exists := UserHandler.cache[userID]
-	return user, exists
-}
+// errUserExists is returned when the email of a new or updated user belongs to another one
+var errUserExists = apperror.New(apperror.Conflict, "User already exists")
 
 func (UserHandler *UserHandler) CreateUserHandler(c *gin.Context) {
-	UserHandler.LoadUsersIntoCache()
-	var user Entities.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request dto.CreateUserRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	// Check if user already exists in the cache
-	if !user.IsEmailValid() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is not valid"})
+	user := request.Model()
+	if err := user.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
 	// else if !user.IsPasswordStrong() {
 	// 	c.JSON(http.StatusBadRequest, gin.H{"error": "Password is not strong enough"})
 	// 	return
 	// }
-	for _, existingUser := range UserHandler.cache {
-		if existingUser.Email == user.Email {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User already exists"})
-			return
-		}
-	}
-
-	tx, err := UserHandler.db.Begin()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create property"})
+	ctx := c.Request.Context()
+	if _, err := UserHandler.users.GetByEmail(ctx, user.Email); err == nil {
+		respondError(c, errUserExists)
 		return
-	}
-
-	addressQuery := `INSERT INTO Address (Country, City, State, Street, PostalCode, AdditionalNumber, MapLocation, Latitude, Longitude) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	addressResult, err := tx.Exec(addressQuery, user.Address.Country, user.Address.City, user.Address.State, user.Address.Street, user.Address.PostalCode, user.Address.AdditionalNumber, user.Address.MapLocation, user.Address.Latitude, user.Address.Longitude)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to create address" + err.Error()})
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		respondError(c, failed(err, "Failed to create user"))
 		return
 	}
-	AddressID, _ := addressResult.LastInsertId()
-
-	user.AddressID = strconv.FormatInt(AddressID, 10)
 
-	query := `INSERT INTO User (AddressID, Name, Email, PhoneNumber, Password, UserRole) VALUES (?, ?, ?, ?, ?, ?)`
-	r, err := tx.Exec(query, AddressID, user.Name, user.Email, user.PhoneNumber, user.Password, user.UserRole)
-	if err != nil {
-		if strings.Contains(err.Error(), "Duplicate entry") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User already exists"})
+	if err := UserHandler.users.Create(ctx, &user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			respondError(c, errUserExists)
 			return
 		}
+		respondError(c, failed(err, "Failed to create user"))
+		return
 	}
-	tx.Commit()
-	// Add the new user to the cache
-	id, _ := r.LastInsertId()
-	user.UserID = strconv.FormatInt(id, 10)
-	UserHandler.LoadUsersIntoCache()
-	response := Response{
-		Status:  "success",
-		Message: "User created successfully",
-		Data:    UserHandler.cache[user.UserID],
+	created, err := UserHandler.users.GetByID(ctx, user.UserID)
+	if err != nil {
+		created = user
 	}
-
-	c.JSON(http.StatusCreated, response)
+	respond(c, http.StatusCreated, "User created successfully", dto.NewUserResponse(created))
 }
 
 func (UserHandler *UserHandler) GetUserHandler(c *gin.Context) {
-	UserHandler.LoadUsersIntoCache()
-	userID := c.Param("id")
-
-	// Try to get the user from the cache
-	user, ok := UserHandler.cache[userID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, err := UserHandler.users.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondUserError(c, err)
 		return
 	}
 
-	response := Response{
-		Status:  "success",
-		Message: "User retrieved successfully",
-		Data:    user,
-	}
-	c.JSON(http.StatusOK, response)
+	setETag(c, user.Version)
+	respond(c, http.StatusOK, "User retrieved successfully", dto.NewUserResponse(user))
 }
 
 func (UserHandler *UserHandler) GetUsersHandler(c *gin.Context) {
-	UserHandler.LoadUsersIntoCache()
-	var users []Entities.User
-	for _, user := range UserHandler.cache {
-		users = append(users, user)
-	}
-
-	response := Response{
-		Status:  "success",
-		Message: "Users retrieved successfully",
-		Data:    users,
+	users, err := UserHandler.users.List(c.Request.Context())
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve users"))
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respond(c, http.StatusOK, "Users retrieved successfully", dto.NewUserResponses(users))
 }
 
 func (UserHandler *UserHandler) UpdateUserHandler(c *gin.Context) {
-	userID := c.Param("id")
-	UserHandler.LoadUsersIntoCache()
-	oldUser, exists := UserHandler.GetUserByID(userID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	ctx := c.Request.Context()
+	user, err := UserHandler.users.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	if !ifMatch(c, user.Version) {
+		return
+	}
+	var request dto.UpdateUserRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	var newUser Entities.User
-	if err := c.ShouldBindJSON(&newUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	newUser := request.Model()
+	if err := newUser.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
-	if newUser.Name == "" {
-		newUser.Name = oldUser.Name
+	if newUser.Name != "" {
+		user.Name = newUser.Name
 	}
-	if newUser.Email == "" {
-		newUser.Email = oldUser.Email
+	if newUser.Email != "" {
+		user.Email = newUser.Email
 	}
-	if newUser.UserRole == "" {
-		newUser.UserRole = oldUser.UserRole
+	if newUser.UserRole != "" {
+		user.UserRole = newUser.UserRole
 	}
-	if newUser.PhoneNumber == "" {
-		newUser.PhoneNumber = oldUser.PhoneNumber
+	if newUser.PhoneNumber != "" {
+		user.PhoneNumber = newUser.PhoneNumber
 	}
-	query := `UPDATE User SET Name = ?, PhoneNumber = ?, Email = ?, UserRole = ? WHERE UserID = ?`
-	_, err := UserHandler.db.Exec(query, newUser.Name, newUser.PhoneNumber, newUser.Email, newUser.UserRole, userID)
+	mergeAddress(&user.Address, newUser.Address)
+
+	UserHandler.save(c, user)
+}
+
+// PatchUserHandler changes a user by a JSON Merge Patch of dto.UserPatch, unlike UpdateUserHandler it can
+// clear fields like the phone number
+func (UserHandler *UserHandler) PatchUserHandler(c *gin.Context) {
+	user, err := UserHandler.users.GetByID(c.Request.Context(), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		respondUserError(c, err)
+		return
+	}
+	if !ifMatch(c, user.Version) {
+		return
+	}
+
+	patch := dto.NewUserPatch(user)
+	if !mergePatch(c, &patch) {
+		return
+	}
+	patch.Apply(&user)
+	if err := user.Address.Validate(); err != nil {
+		respondError(c, invalid(err))
 		return
 	}
 
-	// Update the address related to the user
-	addressQuery := "UPDATE Address SET "
-	params := []interface{}{}
+	UserHandler.save(c, user)
+}
 
-	if newUser.Address.Country != "" {
-		addressQuery += "Country = ?, "
-		params = append(params, newUser.Address.Country)
+// save stores the changed user and their address and responds with them
+func (UserHandler *UserHandler) save(c *gin.Context, user Entities.User) {
+	if err := UserHandler.users.Update(c.Request.Context(), user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			respondError(c, apperror.New(apperror.Conflict, "Email is already in use"))
+			return
+		}
+		respondError(c, failed(err, "Failed to update user"))
+		return
 	}
-	if newUser.Address.City != "" {
-		addressQuery += "City = ?, "
-		params = append(params, newUser.Address.City)
+	user.Version++
+
+	setETag(c, user.Version)
+	respond(c, http.StatusOK, "User and address updated successfully", dto.NewUserResponse(user))
+}
+
+// mergeAddress copies the fields that were given in the update onto the address
+func mergeAddress(address *Entities.Address, update Entities.Address) {
+	if update.Country != "" {
+		address.Country = update.Country
 	}
-	if newUser.Address.State != "" {
-		addressQuery += "State = ?, "
-		params = append(params, newUser.Address.State)
+	if update.City != "" {
+		address.City = update.City
 	}
-	if newUser.Address.Street != "" {
-		addressQuery += "Street = ?, "
-		params = append(params, newUser.Address.Street)
+	if update.State != "" {
+		address.State = update.State
 	}
-	if newUser.Address.PostalCode != "" {
-		addressQuery += "PostalCode = ?, "
-		params = append(params, newUser.Address.PostalCode)
+	if update.Street != "" {
+		address.Street = update.Street
 	}
-	if newUser.Address.AdditionalNumber != "" {
-		addressQuery += "AdditionalNumber = ?, "
-		params = append(params, newUser.Address.AdditionalNumber)
+	if update.PostalCode != "" {
+		address.PostalCode = update.PostalCode
 	}
-	if newUser.Address.MapLocation != "" {
-		addressQuery += "MapLocation = ?, "
-		params = append(params, newUser.Address.MapLocation)
+	if update.AdditionalNumber != "" {
+		address.AdditionalNumber = update.AdditionalNumber
 	}
-	if newUser.Address.Latitude != "" {
-		addressQuery += "Latitude = ?, "
-		params = append(params, newUser.Address.Latitude)
+	if update.MapLocation != "" {
+		address.MapLocation = update.MapLocation
 	}
-	if newUser.Address.Longitude != "" {
-		addressQuery += "Longitude = ?, "
-		params = append(params, newUser.Address.Longitude)
+	if update.Latitude.Valid {
+		address.Latitude = update.Latitude
 	}
-	if len(params) > 0 {
-		// Remove the trailing comma and space
-		addressQuery = addressQuery[:len(addressQuery)-2]
-
-		addressQuery += " WHERE AddressID = ?"
-		params = append(params, oldUser.AddressID)
+	if update.Longitude.Valid {
+		address.Longitude = update.Longitude
+	}
+}
 
-		_, err = UserHandler.db.Exec(addressQuery, params...)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, Response{
-				Status:  "error",
-				Message: "Failed to update address",
-				Data:    err.Error(),
-			})
+func (UserHandler *UserHandler) DeleteUserHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := UserHandler.users.GetByID(ctx, c.Param("id"))
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+	if err := UserHandler.users.Delete(ctx, user.UserID); err != nil {
+		if errors.Is(err, repository.ErrUpcomingBookings) {
+			respondError(c, apperror.New(apperror.Conflict, "The user or one of their properties has upcoming bookings"))
 			return
 		}
+		respondError(c, lookupFailed(err, "User not found", "Failed to delete user"))
+		return
 	}
+	UserHandler.reindex(ctx)
 
-	UserHandler.LoadUsersIntoCache()
-
-	response := Response{
-		Status:  "success",
-		Message: "User and address updated successfully",
-		Data:    UserHandler.cache[userID],
-	}
-	c.JSON(http.StatusOK, response)
+	respond(c, http.StatusOK, "User deleted successfully", dto.NewUserResponse(user))
 }
 
-func (UserHandler *UserHandler) DeleteUserHandler(c *gin.Context) {
-	userID := c.Param("id")
-	UserHandler.LoadUsersIntoCache()
-	query := `DELETE FROM User WHERE UserID = ?`
-	_, err := UserHandler.db.Exec(query, userID)
+// RestoreUserHandler brings back a deleted user along with the properties and units deleted with them
+func (UserHandler *UserHandler) RestoreUserHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := UserHandler.users.Restore(ctx, c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Deleted user not found", "Failed to restore user"))
+		return
+	}
+	UserHandler.reindex(ctx)
+	user, err := UserHandler.users.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		respondUserError(c, err)
 		return
 	}
 
-	response := Response{
-		Status:  "success",
-		Message: "User deleted successfully",
-		Data:    UserHandler.cache[userID],
-	}
-	UserHandler.LoadUsersIntoCache()
-	c.JSON(http.StatusOK, response)
+	respond(c, http.StatusOK, "User restored successfully", dto.NewUserResponse(user))
 }
 
+// errInvalidLogin answers every login with a wrong email or password
+var errInvalidLogin = apperror.New(apperror.Unauthorized, "Invalid email or password")
+
 func (UserHandler *UserHandler) LoginHandler(c *gin.Context) {
-	// Parse and decode the request body into a new 'User' struct
-	var user Entities.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Parse and decode the request body
+	var user dto.LoginRequest
+	if !bindJSON(c, &user) {
 		return
 	}
+	ctx := c.Request.Context()
+	// Failures are counted per email whether or not it belongs to a user, so lockouts do not tell which do
+	account := strings.ToLower(strings.TrimSpace(user.Email))
+	if UserHandler.logins != nil {
+		// The attempt is counted as a failure before the password is checked, and forgotten when it is right
+		wait, err := UserHandler.logins.Attempt(ctx, account)
+		if err != nil {
+			respondError(c, failed(err, "Failed to check the login attempts"))
+			return
+		}
+		if wait > 0 {
+			tooManyRequests(c, wait, "Too many failed logins, try again later")
+			return
+		}
+	}
 	// Get the existing user details from the database
-	var createTime []byte
-	var existingUser Entities.User
-	query := `SELECT * FROM User WHERE Email = ?`
-	err := UserHandler.db.QueryRow(query, user.Email).Scan(&existingUser.UserID, &existingUser.AddressID, &existingUser.Name, &existingUser.PhoneNumber, &existingUser.Email, &existingUser.Password, &createTime, &existingUser.UserRole)
+	existingUser, err := UserHandler.users.GetByEmail(ctx, user.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// If the user does not exist, send an appropriate response message
-			response := Response{
-				Status:  "error",
-				Message: "User not found",
-			}
-			c.JSON(http.StatusUnauthorized, response)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The same answer as for a wrong password, so logins do not tell which emails have an account
+			respondError(c, errInvalidLogin)
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+			respondError(c, failed(err, "Failed to retrieve user"))
 		}
 		return
 	}
-	existingUser.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
 	// Compare the supplied password with the stored password
 	if user.Password != existingUser.Password {
-		// If the password does not match, send an appropriate response message
-		response := Response{
-			Status:  "error",
-			Message: "Invalid password",
-		}
-		c.JSON(http.StatusUnauthorized, response)
+		respondError(c, errInvalidLogin)
 		return
 	}
 
-	// If the password matches, send a success response
-	response := Response{
-		Status:  "success",
-		Message: "Logged in successfully",
-		Data:    existingUser,
+	if UserHandler.logins != nil {
+		if err := UserHandler.logins.Succeeded(ctx, account); err != nil {
+			respondError(c, failed(err, "Failed to reset the login attempts"))
+			return
+		}
 	}
-	c.JSON(http.StatusOK, response)
+	// If the password matches, send a success response
+	respond(c, http.StatusOK, "Logged in successfully", dto.NewUserResponse(existingUser))
 }
 
 type Report struct {
-	ReportID              string `json:"reportID"`
-	UserID                string `json:"userID"`
-	Type                  string `json:"type,omitempty"`
-	CreateTime            string `json:"createTime,omitempty"`
+	ReportID              string    `json:"reportID"`
+	UserID                string    `json:"userID"`
+	Type                  string    `json:"type,omitempty"`
+	CreateTime            time.Time `json:"createTime"`
 	Properties            []Entities.Property
 	Bookings              []Entities.Booking
 	FinancialTransactions []Entities.FinancialTransaction
 	TotalEarnings         int `json:"totalEarnings,omitempty"`
 }
 
-func (UserHandler *UserHandler) GetProperties(userID string) ([]Entities.Property, error) {
-	query := `
-    SELECT 
-        p.PropertyID, 
-        p.OwnerID, 
-        p.Name, 
-        p.CreateTime,
-        a.AddressID, 
-        a.Country, 
-        a.City, 
-        a.State, 
-        a.Street, 
-        a.PostalCode, 
-        a.AdditionalNumber, 
-        a.MapLocation, 
-        a.Latitude, 
-        a.Longitude
-    FROM 
-        Property p
-    LEFT JOIN 
-        Address a ON p.AddressID = a.AddressID
-    WHERE 
-        p.OwnerID = ?
-    `
-	rows, err := UserHandler.db.Query(query, userID)
+func (UserHandler *UserHandler) GetReports(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := UserHandler.users.GetByID(ctx, c.Param("id"))
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var properties []Entities.Property
-	for rows.Next() {
-		var createTime []byte
-		var property Entities.Property
-		var address Entities.Address
-		if err := rows.Scan(&property.PropertyID, &property.OwnerID, &property.Name, &createTime, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			return nil, err
-		}
-		property.CreateTime, err = time.Parse("2006-01-02 15:04:05", string(createTime))
-		if err != nil {
-			return nil, err
-		}
-		property.Address = address
-
-		// Get units for the property
-		units, err := UserHandler.GetUnits(property.PropertyID)
-		if err != nil {
-			return nil, err
-		}
-		property.Units = units
-
-		properties = append(properties, property)
+		respondUserError(c, err)
+		return
 	}
-
-	return properties, nil
-}
-
-func (UserHandler *UserHandler) GetReports(c *gin.Context) {
-	UserHandler.LoadUsersIntoCache()
-	userID := c.Param("id")
-	user, exists := UserHandler.GetUserByID(userID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	report, err := UserHandler.GetReport(ctx, user.UserID)
+	if err != nil {
+		respondError(c, failed(err, "Failed to build report"))
 		return
 	}
-	Report, _ := UserHandler.GetReport(user.UserID)
-	//fmt.Println(err.Error())
-	c.JSON(http.StatusOK, Report)
+	respond(c, http.StatusOK, "Report built successfully", report)
 }
 
-func (UserHandler *UserHandler) GetUnits(propertyID string) ([]Entities.Unit, error) {
-	query := `
-    SELECT 
-        u.UnitID, 
-        u.PropertyID, 
-        u.Name, 
-        u.RentalPrice, 
-        u.Description, 
-        u.Rating, 
-        u.StructuralProperties, 
-        u.CreateTime,
-        a.AddressID, 
-        a.Country, 
-        a.City, 
-        a.State, 
-        a.Street, 
-        a.PostalCode, 
-        a.AdditionalNumber, 
-        a.MapLocation, 
-        a.Latitude, 
-        a.Longitude
-    FROM 
-        Unit u
-    LEFT JOIN 
-        Address a ON u.AddressID = a.AddressID
-    WHERE 
-        u.PropertyID = ?
-    `
-	rows, err := UserHandler.db.Query(query, propertyID)
+// GetReport builds the owner's report from their properties with units, the bookings of those units and the
+// transactions of those bookings, one query each
+func (UserHandler *UserHandler) GetReport(ctx context.Context, userID string) (Report, error) {
+	properties, err := UserHandler.properties.ListByOwner(ctx, userID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var units []Entities.Unit
-	for rows.Next() {
-		var createTime []byte
-		var unit Entities.Unit
-		var address Entities.Address
-		if err := rows.Scan(&unit.UnitID, &unit.PropertyID, &unit.Name, &unit.RentalPrice, &unit.Description, &unit.Rating, &unit.StructuralProperties, &createTime, &address.AddressID, &address.Country, &address.City, &address.State, &address.Street, &address.PostalCode, &address.AdditionalNumber, &address.MapLocation, &address.Latitude, &address.Longitude); err != nil {
-			return nil, err
-		}
-		unit.CreateTime, err = time.Parse("2006-01-02 15:04:05", string(createTime))
-		if err != nil {
-			return nil, err
-		}
-		unit.Address = address
-		units = append(units, unit)
+		return Report{}, err
 	}
-
-	return units, nil
-}
-
-// Create a report and return it for a userid
-func (UserHandler *UserHandler) GetReport(userID string) (Report, error) {
-	// Get the properties for the user
-	properties, err := UserHandler.GetProperties(userID)
+	units, err := UserHandler.units.ListByOwner(ctx, userID)
 	if err != nil {
 		return Report{}, err
 	}
+	unitsByProperty := make(map[string][]Entities.Unit)
+	for _, unit := range units {
+		unitsByProperty[unit.PropertyID] = append(unitsByProperty[unit.PropertyID], unit)
+	}
+	for i := range properties {
+		properties[i].Units = unitsByProperty[properties[i].PropertyID]
+	}
 
-	// Get the bookings for the user
-	var bookings []Entities.Booking
-
-	for _, property := range properties {
-		for _, unit := range property.Units {
-			unitBookings, err := UserHandler.GetBookings(unit.UnitID)
-			if err != nil {
-				continue
-			}
-			bookings = append(bookings, unitBookings...)
-		}
+	bookings, err := UserHandler.bookings.ListByOwner(ctx, userID)
+	if err != nil {
+		return Report{}, err
 	}
-	var FinancialTransactions []Entities.FinancialTransaction
-	for _, booking := range bookings {
-		transactions, err := UserHandler.GetFinancialTransactions(booking.BookingID)
-		if err != nil {
-			continue
-		}
-		FinancialTransactions = append(FinancialTransactions, transactions...)
+	transactions, err := UserHandler.transactions.ListByOwner(ctx, userID)
+	if err != nil {
+		return Report{}, err
 	}
 
 	// Calculate the total earnings for the user
 	var totalEarnings int
-	for _, Transaction := range FinancialTransactions {
-		totalEarnings += Transaction.Amount
+	for _, transaction := range transactions {
+		totalEarnings += transaction.Amount
 	}
 
 	report := Report{
 		ReportID:              uuid.New().String(),
 		UserID:                userID,
 		Type:                  "Report",
-		CreateTime:            time.Now().Format("2006-01-02 15:04:05"),
+		CreateTime:            time.Now().UTC(),
 		Properties:            properties,
 		Bookings:              bookings,
-		FinancialTransactions: FinancialTransactions,
+		FinancialTransactions: transactions,
 		TotalEarnings:         totalEarnings,
 	}
 
 	return report, nil
 }
-
-func (UserHandler *UserHandler) GetBookings(UnitID string) ([]Entities.Booking, error) {
-	query := `SELECT * FROM Booking WHERE UnitID = ?`
-	rows, err := UserHandler.db.Query(query, UnitID)
-	if err != nil {
-		log.Fatalf("Failed to execute query: %v", err)
-	}
-	defer rows.Close()
-	var bookings []Entities.Booking
-	for rows.Next() {
-		var booking Entities.Booking
-		var createTime []byte
-		var StartDate []byte
-		var EndDate []byte
-		if err := rows.Scan(&booking.BookingID, &booking.UnitID, &booking.UserID, &EndDate, &createTime, &StartDate, &booking.Summary); err != nil {
-			return nil, err
-		}
-		booking.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		booking.StartDate, _ = time.Parse("2006-01-02 15:04:05", string(StartDate))
-		booking.EndDate, _ = time.Parse("2006-01-02 15:04:05", string(EndDate))
-		bookings = append(bookings, booking)
-	}
-	return bookings, nil
-}
-
-// Gets all the FinancialTransactions for the bookings for the units of a property
-func (UserHandler *UserHandler) GetFinancialTransactions(BookingID string) ([]Entities.FinancialTransaction, error) {
-	var transactions []Entities.FinancialTransaction
-
-	query := `SELECT TransactionID, UserID, BookingID, PaymentMethod, Amount, CreateTime FROM FinancialTransaction WHERE BookingID = ?`
-	rows, err := UserHandler.db.Query(query, BookingID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var transaction Entities.FinancialTransaction
-		var createTime []byte
-		if err := rows.Scan(&transaction.TransactionID, &transaction.UserID, &transaction.BookingID, &transaction.PaymentMethod, &transaction.Amount, &createTime); err != nil {
-			return nil, err
-		}
-		transaction.CreateTime, _ = time.Parse("2006-01-02 15:04:05", string(createTime))
-		transactions = append(transactions, transaction)
-	}
-
-	return transactions, nil
-}
@@ -0,0 +1,265 @@
+package Handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/dto"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WishlistHandler struct {
+	wishlists     repository.WishlistRepository
+	savedSearches repository.SavedSearchRepository
+	units         repository.UnitRepository
+}
+
+func NewWishlistHandler(wishlists repository.WishlistRepository, savedSearches repository.SavedSearchRepository, units repository.UnitRepository) *WishlistHandler {
+	return &WishlistHandler{
+		wishlists:     wishlists,
+		savedSearches: savedSearches,
+		units:         units,
+	}
+}
+
+// respondWishlistError writes the response for a failed wishlist lookup
+func respondWishlistError(c *gin.Context, err error) {
+	respondError(c, lookupFailed(err, "Wishlist not found", "Failed to retrieve wishlist"))
+}
+
+// visibleTo returns the wishlist as the user making the request may see it. The owner, the user in
+// X-User-ID, sees all of it. Anyone else only sees a wishlist that is shared, and never its share token.
+func visibleTo(c *gin.Context, wishlist Entities.Wishlist) (Entities.Wishlist, bool) {
+	if wishlist.UserID == audit.OriginFrom(c.Request.Context()).Actor {
+		return wishlist, true
+	}
+	shared := wishlist.IsShared()
+	wishlist.ShareToken = ""
+	return wishlist, shared
+}
+
+// errNoOwner is the answer to creating a wishlist without saying whose it is
+var errNoOwner = apperror.New(apperror.Unauthorized, "Send your user ID in X-User-ID to create a wishlist")
+
+// owned reports whether the wishlist exists and belongs to the user making the request. Otherwise it writes
+// a 404, the same answer as for a wishlist that does not exist, and returns false.
+func (handler *WishlistHandler) owned(c *gin.Context, wishlistID string) bool {
+	wishlist, err := handler.wishlists.GetByID(c.Request.Context(), wishlistID)
+	if err == nil && wishlist.UserID != audit.OriginFrom(c.Request.Context()).Actor {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		respondWishlistError(c, err)
+		return false
+	}
+	return true
+}
+
+// respondWishlist writes the wishlist with the given ID after a change to it, or the matching error.
+// The share token is left out unless the owner made the change.
+func (handler *WishlistHandler) respondWishlist(c *gin.Context, status int, message, wishlistID string) {
+	wishlist, err := handler.wishlists.GetByID(c.Request.Context(), wishlistID)
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+	wishlist, _ = visibleTo(c, wishlist)
+	respond(c, status, message, wishlist)
+}
+
+func (handler *WishlistHandler) CreateWishlist(c *gin.Context) {
+	var request dto.CreateWishlistRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	owner := audit.OriginFrom(c.Request.Context()).Actor
+	if owner == audit.Anonymous {
+		respondError(c, errNoOwner)
+		return
+	}
+	wishlist := request.Model(owner)
+	if err := handler.wishlists.Create(c.Request.Context(), &wishlist); err != nil {
+		respondError(c, failed(err, "Failed to create wishlist"))
+		return
+	}
+	handler.respondWishlist(c, http.StatusCreated, "Wishlist created successfully", wishlist.WishlistID)
+}
+
+// GetWishlist lets the owner see their wishlist, anyone else gets a 404 unless it is shared
+func (handler *WishlistHandler) GetWishlist(c *gin.Context) {
+	wishlist, err := handler.wishlists.GetByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+	wishlist, visible := visibleTo(c, wishlist)
+	if !visible {
+		respondWishlistError(c, repository.ErrNotFound)
+		return
+	}
+	respond(c, http.StatusOK, "Wishlist retrieved successfully", wishlist)
+}
+
+// GetSharedWishlist lets anyone with the share link see the wishlist
+func (handler *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := handler.wishlists.GetByShareToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondWishlistError(c, err)
+		return
+	}
+	wishlist, _ = visibleTo(c, wishlist)
+	respond(c, http.StatusOK, "Wishlist retrieved successfully", wishlist)
+}
+
+// GetWishlistsByUserID lists all wishlists of the user to themselves, and only the shared ones to anyone else
+func (handler *WishlistHandler) GetWishlistsByUserID(c *gin.Context) {
+	wishlists, err := handler.wishlists.ListByUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve wishlists"))
+		return
+	}
+	visible := make([]Entities.Wishlist, 0, len(wishlists))
+	for _, wishlist := range wishlists {
+		if wishlist, ok := visibleTo(c, wishlist); ok {
+			visible = append(visible, wishlist)
+		}
+	}
+	respond(c, http.StatusOK, "Wishlists retrieved successfully", visible)
+}
+
+// RenameWishlist and the other changes below are for the owner only, anyone else gets a 404
+func (handler *WishlistHandler) RenameWishlist(c *gin.Context) {
+	var request dto.RenameWishlistRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if !handler.owned(c, c.Param("id")) {
+		return
+	}
+	if err := handler.wishlists.Rename(c.Request.Context(), c.Param("id"), request.Name); err != nil {
+		respondError(c, failed(err, "Failed to update wishlist"))
+		return
+	}
+	// No affected rows can also mean the name did not change, so the 404 comes from reading the wishlist back
+	handler.respondWishlist(c, http.StatusOK, "Wishlist updated successfully", c.Param("id"))
+}
+
+func (handler *WishlistHandler) DeleteWishlist(c *gin.Context) {
+	if !handler.owned(c, c.Param("id")) {
+		return
+	}
+	if err := handler.wishlists.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Wishlist not found", "Failed to delete wishlist"))
+		return
+	}
+	respond(c, http.StatusOK, "Wishlist deleted successfully", nil)
+}
+
+func (handler *WishlistHandler) AddUnit(c *gin.Context) {
+	wishlistID := c.Param("id")
+	var request dto.AddWishlistUnitRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	if !handler.owned(c, wishlistID) {
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := handler.units.GetByID(ctx, request.UnitID); err != nil {
+		respondError(c, lookupFailed(err, "Unit not found", "Failed to add unit"))
+		return
+	}
+	// Adding a unit twice keeps the first one
+	if err := handler.wishlists.AddUnit(ctx, wishlistID, request.UnitID); err != nil {
+		respondError(c, failed(err, "Failed to add unit"))
+		return
+	}
+	handler.respondWishlist(c, http.StatusOK, "Unit added to wishlist", wishlistID)
+}
+
+func (handler *WishlistHandler) RemoveUnit(c *gin.Context) {
+	wishlistID := c.Param("id")
+	if !handler.owned(c, wishlistID) {
+		return
+	}
+	if err := handler.wishlists.RemoveUnit(c.Request.Context(), wishlistID, c.Param("unitID")); err != nil {
+		respondError(c, failed(err, "Failed to remove unit"))
+		return
+	}
+	handler.respondWishlist(c, http.StatusOK, "Unit removed from wishlist", wishlistID)
+}
+
+// ShareWishlist gives the wishlist a share token, so it can be opened through /wishlist/shared/:token.
+// Sharing again keeps the existing link.
+func (handler *WishlistHandler) ShareWishlist(c *gin.Context) {
+	wishlistID := c.Param("id")
+	if !handler.owned(c, wishlistID) {
+		return
+	}
+	if err := handler.wishlists.SetShareToken(c.Request.Context(), wishlistID, uuid.New().String()); err != nil {
+		respondError(c, failed(err, "Failed to share wishlist"))
+		return
+	}
+	handler.respondWishlist(c, http.StatusOK, "Wishlist shared successfully", wishlistID)
+}
+
+// UnshareWishlist revokes the share link
+func (handler *WishlistHandler) UnshareWishlist(c *gin.Context) {
+	wishlistID := c.Param("id")
+	if !handler.owned(c, wishlistID) {
+		return
+	}
+	if err := handler.wishlists.SetShareToken(c.Request.Context(), wishlistID, ""); err != nil {
+		respondError(c, failed(err, "Failed to unshare wishlist"))
+		return
+	}
+	handler.respondWishlist(c, http.StatusOK, "Wishlist is no longer shared", wishlistID)
+}
+
+// CreateSavedSearch saves unit listing criteria, the user is notified when a new unit matches them
+func (handler *WishlistHandler) CreateSavedSearch(c *gin.Context) {
+	var request dto.CreateSavedSearchRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+	amenities, err := Entities.NormalizeAmenities(request.Criteria.Amenities)
+	if err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	request.Criteria.Amenities = amenities
+	if err := request.Criteria.Validate(); err != nil {
+		respondError(c, invalid(err))
+		return
+	}
+	criteria, _ := json.Marshal(request.Criteria)
+	search := Entities.SavedSearch{UserID: request.UserID, Name: request.Name, Criteria: string(criteria)}
+
+	if err := handler.savedSearches.Create(c.Request.Context(), &search); err != nil {
+		respondError(c, failed(err, "Failed to save search"))
+		return
+	}
+	respond(c, http.StatusCreated, "Search saved successfully", search)
+}
+
+func (handler *WishlistHandler) GetSavedSearchesByUserID(c *gin.Context) {
+	searches, err := handler.savedSearches.ListByUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, failed(err, "Failed to retrieve saved searches"))
+		return
+	}
+	respond(c, http.StatusOK, "Saved searches retrieved successfully", searches)
+}
+
+func (handler *WishlistHandler) DeleteSavedSearch(c *gin.Context) {
+	if err := handler.savedSearches.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, lookupFailed(err, "Saved search not found", "Failed to delete saved search"))
+		return
+	}
+	respond(c, http.StatusOK, "Saved search deleted successfully", nil)
+}
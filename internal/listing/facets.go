@@ -0,0 +1,72 @@
+package listing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	FacetPrice     = "price"
+	FacetType      = "type"
+	FacetCity      = "city"
+	FacetRating    = "rating"
+	FacetGuests    = "guests"
+	FacetAmenities = "amenities"
+)
+
+// FacetValue is one filter chip: the value to filter by and how many units the listing would have with it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets count, for every facet, the units that match all other filters. That way selecting a city still
+// shows how many units the other cities have instead of collapsing the chips to one.
+type Facets map[string][]FacetValue
+
+// PriceBuckets are the price ranges shown as chips, each runs up to the next one and the last one has no upper bound
+var PriceBuckets = []int{0, 250, 500, 1000, 2000}
+
+// RatingSteps and GuestSteps are the minimums shown as chips, a unit counts towards every step it reaches
+var (
+	RatingSteps = []int{4, 3, 2, 1}
+	GuestSteps  = []int{1, 2, 4, 6, 8}
+)
+
+// PriceFacet labels the counts of units in each of PriceBuckets
+func PriceFacet(counts []int) []FacetValue {
+	values := make([]FacetValue, 0, len(PriceBuckets))
+	for i, from := range PriceBuckets {
+		value := fmt.Sprintf("%d-", from)
+		if i+1 < len(PriceBuckets) {
+			value += fmt.Sprint(PriceBuckets[i+1] - 1)
+		}
+		values = append(values, FacetValue{Value: value, Count: counts[i]})
+	}
+	return values
+}
+
+// StepFacet labels the counts of units that reach each of steps
+func StepFacet(steps, counts []int) []FacetValue {
+	values := make([]FacetValue, 0, len(steps))
+	for i, step := range steps {
+		values = append(values, FacetValue{Value: fmt.Sprintf("%d+", step), Count: counts[i]})
+	}
+	return values
+}
+
+// SortedCounts lists the most common values first, ties in alphabetical order
+func SortedCounts(counts map[string]int) []FacetValue {
+	values := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return strings.ToLower(values[i].Value) < strings.ToLower(values[j].Value)
+	})
+	return values
+}
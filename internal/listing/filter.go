@@ -0,0 +1,139 @@
+package listing
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// Filter holds the criteria a unit listing can be narrowed down by. Every criterion that is set must match.
+type Filter struct {
+	MinPrice  *int     `json:"minPrice,omitempty"`
+	MaxPrice  *int     `json:"maxPrice,omitempty"`
+	Types     []string `json:"types,omitempty"`  // Property type, any of them
+	Cities    []string `json:"cities,omitempty"` // Any of them
+	MinRating *float64 `json:"minRating,omitempty"`
+	Guests    *int     `json:"guests,omitempty"`    // Units that fit at least this many guests
+	Amenities []string `json:"amenities,omitempty"` // All of them
+}
+
+// ParseFilter reads the filter from query parameters. Parameters that take several values can be
+// repeated or comma separated, e.g. ?amenities=wifi,pool or ?amenities=wifi&amenities=pool.
+func ParseFilter(query url.Values) (Filter, error) {
+	var filter Filter
+	var err error
+	if filter.MinPrice, err = intParam(query, "minPrice"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPrice, err = intParam(query, "maxPrice"); err != nil {
+		return filter, err
+	}
+	if filter.Guests, err = intParam(query, "guests"); err != nil {
+		return filter, err
+	}
+	if raw := query.Get("minRating"); raw != "" {
+		rating, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return filter, apperror.Field("minRating", "minRating must be a number")
+		}
+		filter.MinRating = &rating
+	}
+	filter.Types = listParam(query, "type")
+	filter.Cities = listParam(query, "city")
+	filter.Amenities, err = Entities.NormalizeAmenities(listParam(query, "amenities"))
+	if err != nil {
+		return filter, err
+	}
+	return filter, filter.Validate()
+}
+
+func (f *Filter) Validate() error {
+	if f.MinPrice != nil && *f.MinPrice < 0 {
+		return apperror.Field("minPrice", "minPrice cannot be negative")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return apperror.Field("minPrice", "minPrice cannot be greater than maxPrice")
+	}
+	if f.MinRating != nil && (*f.MinRating < 0 || *f.MinRating > 5) {
+		return apperror.Field("minRating", "minRating must be between 0 and 5")
+	}
+	if f.Guests != nil && *f.Guests < 1 {
+		return apperror.Field("guests", "guests must be at least 1")
+	}
+	return nil
+}
+
+// Matches reports whether the unit satisfies every criterion of the filter. Listings apply the same
+// criteria in SQL, this is for checking a single unit, like a new one against saved searches.
+func (f *Filter) Matches(unit Entities.Unit) bool {
+	if f.MinPrice != nil && unit.RentalPrice < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && unit.RentalPrice > *f.MaxPrice {
+		return false
+	}
+	if len(f.Types) > 0 && !containsFold(f.Types, unit.PropertyType) {
+		return false
+	}
+	if len(f.Cities) > 0 && !containsFold(f.Cities, unit.Address.City) {
+		return false
+	}
+	if f.MinRating != nil && float64(unit.Rating) < *f.MinRating {
+		return false
+	}
+	if f.Guests != nil && Guests(unit) < *f.Guests {
+		return false
+	}
+	amenities := Amenities(unit)
+	for _, amenity := range f.Amenities {
+		if !containsFold(amenities, amenity) {
+			return false
+		}
+	}
+	return true
+}
+
+// Guests returns how many guests the unit fits, 0 when unknown
+func Guests(unit Entities.Unit) int {
+	return unit.Attributes.MaxGuests
+}
+
+func Amenities(unit Entities.Unit) []string {
+	return unit.Amenities
+}
+
+func intParam(query url.Values, name string) (*int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, apperror.Field(name, name+" must be a whole number")
+	}
+	return &value, nil
+}
+
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, raw := range query[name] {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"GraduationProject.com/m/internal/apperror"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+const (
+	SortNewest    = "newest"
+	SortPriceAsc  = "price"
+	SortPriceDesc = "-price"
+	SortRating    = "rating"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Request struct {
+	Filter Filter
+	Sort   string
+	Cursor string
+	Limit  int
+}
+
+type Page struct {
+	Units      []Entities.Unit
+	Total      int // Units matching the filter, across every page
+	NextCursor string
+	Facets     Facets
+}
+
+// Cursor remembers where the previous page ended, so the next page starts right after that unit even
+// when units are added or removed in between. Units are ordered by the sort key, then by UnitID.
+type Cursor struct {
+	Sort   string  `json:"s"`
+	Price  int     `json:"p,omitempty"`
+	Rating float32 `json:"r,omitempty"`
+	Time   int64   `json:"t,omitempty"` // CreateTime in nanoseconds since the epoch
+	UnitID string  `json:"id"`
+}
+
+func ValidSort(sortBy string) bool {
+	switch sortBy {
+	case SortNewest, SortPriceAsc, SortPriceDesc, SortRating:
+		return true
+	}
+	return false
+}
+
+// Normalize fills in the default sort and limit and checks the sort order. It returns the cursor the page
+// starts after, nil for the first page, or an error when it is not a cursor of this sort order.
+func (r *Request) Normalize() (*Cursor, error) {
+	if r.Sort == "" {
+		r.Sort = SortNewest
+	}
+	if !ValidSort(r.Sort) {
+		return nil, apperror.Field("sort", fmt.Sprintf("sort must be one of %s, %s, %s or %s", SortNewest, SortPriceAsc, SortPriceDesc, SortRating))
+	}
+	if r.Limit <= 0 {
+		r.Limit = DefaultLimit
+	}
+	if r.Limit > MaxLimit {
+		r.Limit = MaxLimit
+	}
+	if r.Cursor == "" {
+		return nil, nil
+	}
+	after, err := DecodeCursor(r.Cursor)
+	if err != nil || after.Sort != r.Sort || after.UnitID == "" {
+		return nil, apperror.Field("cursor", "cursor is not valid for this sort order")
+	}
+	return &after, nil
+}
+
+// CursorOf returns the cursor of a page that ends with unit
+func CursorOf(sortBy string, unit Entities.Unit) Cursor {
+	c := Cursor{Sort: sortBy, UnitID: unit.UnitID}
+	switch sortBy {
+	case SortPriceAsc, SortPriceDesc:
+		c.Price = unit.RentalPrice
+	case SortRating:
+		c.Rating = unit.Rating
+	default:
+		c.Time = unit.CreateTime.UnixNano()
+	}
+	return c
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(raw string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
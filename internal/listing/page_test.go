@@ -0,0 +1,68 @@
+package listing
+
+import (
+	"testing"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	unit := Entities.Unit{
+		UnitID:      "42",
+		RentalPrice: 180,
+		Rating:      4.5,
+		CreateTime:  time.Date(2024, 3, 1, 12, 30, 15, 123456789, time.UTC),
+	}
+	tests := []struct {
+		sort string
+		want Cursor
+	}{
+		{SortNewest, Cursor{Sort: SortNewest, Time: unit.CreateTime.UnixNano(), UnitID: "42"}},
+		{SortPriceAsc, Cursor{Sort: SortPriceAsc, Price: 180, UnitID: "42"}},
+		{SortPriceDesc, Cursor{Sort: SortPriceDesc, Price: 180, UnitID: "42"}},
+		{SortRating, Cursor{Sort: SortRating, Rating: 4.5, UnitID: "42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			request := Request{Sort: tt.sort, Cursor: CursorOf(tt.sort, unit).Encode()}
+			after, err := request.Normalize()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after == nil || *after != tt.want {
+				t.Errorf("got cursor %+v, want %+v", after, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	priceCursor := CursorOf(SortPriceAsc, Entities.Unit{UnitID: "1", RentalPrice: 100}).Encode()
+	tests := []struct {
+		name      string
+		request   Request
+		wantSort  string
+		wantLimit int
+		wantErr   bool
+	}{
+		{name: "defaults", request: Request{}, wantSort: SortNewest, wantLimit: DefaultLimit},
+		{name: "limit is capped", request: Request{Sort: SortRating, Limit: MaxLimit + 1}, wantSort: SortRating, wantLimit: MaxLimit},
+		{name: "unknown sort", request: Request{Sort: "name"}, wantErr: true},
+		{name: "cursor of another sort", request: Request{Sort: SortPriceDesc, Cursor: priceCursor}, wantErr: true},
+		{name: "cursor that is not base64", request: Request{Cursor: "not a cursor!"}, wantErr: true},
+		{name: "cursor without a unit", request: Request{Cursor: Cursor{Sort: SortNewest}.Encode()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			_, err := request.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want one: %v", err, tt.wantErr)
+			}
+			if err == nil && (request.Sort != tt.wantSort || request.Limit != tt.wantLimit) {
+				t.Errorf("got sort %q and limit %d, want %q and %d", request.Sort, request.Limit, tt.wantSort, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package logging sets up the structured logger. Requests carry their own logger in the context, tagged
+// with the request ID, and sensitive fields are redacted wherever they appear in a record.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+const Redacted = "[REDACTED]"
+
+// sensitive are the keys whose values never reach the logs, compared case-insensitively
+var sensitive = map[string]bool{
+	"password":      true,
+	"passwd":        true,
+	"secret":        true,
+	"token":         true,
+	"tokensecret":   true,
+	"admintoken":    true,
+	"sharetoken":    true,
+	"authorization": true,
+	"cookie":        true,
+	"x-admin-token": true,
+}
+
+// Sensitive reports whether values named key are redacted
+func Sensitive(key string) bool {
+	return sensitive[strings.ToLower(key)]
+}
+
+// New returns a logger writing records of level and above to w, as JSON lines or as text.
+// Levels are debug, info, warn and error, anything else is info.
+func New(w io.Writer, level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: parseLevel(level), ReplaceAttr: redact}
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, options))
+	}
+	return slog.New(slog.NewJSONHandler(w, options))
+}
+
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// redact hides sensitive attributes, and the sensitive fields of structs and maps logged as a whole
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if Sensitive(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+	value := attr.Value.Any()
+	if _, ok := value.(error); ok {
+		return attr
+	}
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		if clean, ok := redactJSON(value); ok {
+			return slog.Any(attr.Key, clean)
+		}
+	}
+	return attr
+}
+
+// redactJSON returns value as its JSON decodes, with the sensitive fields at any depth redacted.
+// Types with their own JSON marshaling, like config.Secret, are already safe and stay as they are.
+func redactJSON(value interface{}) (interface{}, bool) {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(content, &decoded); err != nil {
+		return nil, false
+	}
+	return redactValue(decoded), true
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if Sensitive(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = redactValue(element)
+		}
+	}
+	return value
+}
+
+type loggerKey struct{}
+
+// WithLogger stores the logger of a request in ctx
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the default logger outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,53 @@
+// Package mergepatch applies JSON Merge Patches (RFC 7396). A patch looks like the document it changes:
+// members it leaves out keep their value, members set to null are removed, objects are merged member by
+// member and every other value, arrays included, replaces the one in the document.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Apply returns document with patch merged into it, both are JSON texts
+func Apply(document, patch []byte) ([]byte, error) {
+	target, err := decode(document)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Merge(target, changes))
+}
+
+// Merge merges a decoded patch into a decoded document. Objects of the document are changed in place.
+func Merge(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = make(map[string]interface{}, len(changes))
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = Merge(object[name], value)
+	}
+	return object
+}
+
+// decode keeps numbers as they were written, so large integers are not rounded through float64
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
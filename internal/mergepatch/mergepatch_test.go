@@ -0,0 +1,66 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// The examples of RFC 7396, appendix A, and a few of the cases the handlers depend on
+func TestApply(t *testing.T) {
+	tests := []struct {
+		document, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Removing a member that is not there changes nothing, and large numbers are not rounded
+		{`{"a":"b"}`, `{"z":null}`, `{"a":"b"}`},
+		{`{"id":9007199254740993}`, `{"name":"x"}`, `{"id":9007199254740993,"name":"x"}`},
+		{`{"a":{"b":1}}`, `{}`, `{"a":{"b":1}}`},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.document), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Apply(%s, %s) failed: %v", tt.document, tt.patch, err)
+			continue
+		}
+		if canonical(t, got) != canonical(t, []byte(tt.want)) {
+			t.Errorf("Apply(%s, %s) = %s, want %s", tt.document, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRejectsInvalidJSON(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":`), []byte(`{}`)); err == nil {
+		t.Error("an invalid document was accepted")
+	}
+	if _, err := Apply([]byte(`{}`), []byte(`{"a"}`)); err == nil {
+		t.Error("an invalid patch was accepted")
+	}
+}
+
+// canonical re-encodes a JSON text, objects marshal with their members sorted
+func canonical(t *testing.T, text []byte) string {
+	t.Helper()
+	value, err := decode(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,146 @@
+// Package metrics collects the Prometheus metrics served on /metrics: requests per route, the database
+// connection pool, the read caches and the business events behind writes.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"GraduationProject.com/m/internal/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rentals"
+
+// Metrics holds the collectors of one app. Each app has its own registry, so apps created again, like in
+// tests, do not register twice.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+
+	BookingsCreated   prometheus.Counter
+	BookingsCancelled prometheus.Counter
+	Payments          *prometheus.CounterVec // By result, succeeded or failed
+	MessagesSent      prometheus.Counter
+	TicketsOpened     prometheus.Counter
+}
+
+// New registers the runtime, process and cache collectors along with the app's own
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "http", Name: "requests_total",
+			Help: "Requests served, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help:    "Time to serve a request, by method and route template.",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"method", "route"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "http", Name: "requests_in_flight",
+			Help: "Requests being served.",
+		}),
+		BookingsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "bookings_created_total", Help: "Bookings created.",
+		}),
+		BookingsCancelled: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "bookings_cancelled_total", Help: "Bookings deleted before their check-out.",
+		}),
+		Payments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "payments_total", Help: "Payments recorded as financial transactions, by whether they were stored.",
+		}, []string{"result"}),
+		MessagesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "messages_sent_total", Help: "Chat messages sent.",
+		}),
+		TicketsOpened: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "maintenance_tickets_opened_total", Help: "Maintenance tickets opened.",
+		}),
+	}
+	// Both results are exported from the start, so rates can be taken before the first failure
+	m.Payments.WithLabelValues("succeeded")
+	m.Payments.WithLabelValues("failed")
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		cacheCollector{},
+		m.requests, m.duration, m.inFlight,
+		m.BookingsCreated, m.BookingsCancelled, m.Payments, m.MessagesSent, m.TicketsOpened,
+	)
+	return m
+}
+
+// CollectDB adds the statistics of the connection pool of db, labeled with dbName
+func (m *Metrics) CollectDB(db *sql.DB, dbName string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware measures every request. Routes are labeled with their template, like /units/:id, so IDs do not
+// make a series each, and requests no route matched share the "unmatched" label.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.duration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Payment counts a payment by whether storing it failed
+func (m *Metrics) Payment(err error) {
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+	m.Payments.WithLabelValues(result).Inc()
+}
+
+var (
+	cacheRequests = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "requests_total"),
+		"Cache lookups, by cache and whether they hit.", []string{"cache", "result"}, nil)
+	cacheLoads = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "loads_total"),
+		"Values loaded into the cache after a miss.", []string{"cache"}, nil)
+	cacheEvictions = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "evictions_total"),
+		"Entries evicted to stay under the size limit.", []string{"cache"}, nil)
+)
+
+// cacheCollector exports the counters the caches already keep, the hit rate is hits over all requests
+type cacheCollector struct{}
+
+func (cacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- cacheRequests
+	descs <- cacheLoads
+	descs <- cacheEvictions
+}
+
+func (cacheCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, counters := range cache.Stats() {
+		metrics <- prometheus.MustNewConstMetric(cacheRequests, prometheus.CounterValue, float64(counters.Hits), counters.Name, "hit")
+		metrics <- prometheus.MustNewConstMetric(cacheRequests, prometheus.CounterValue, float64(counters.Misses), counters.Name, "miss")
+		metrics <- prometheus.MustNewConstMetric(cacheLoads, prometheus.CounterValue, float64(counters.Loads), counters.Name)
+		metrics <- prometheus.MustNewConstMetric(cacheEvictions, prometheus.CounterValue, float64(counters.Evictions), counters.Name)
+	}
+}
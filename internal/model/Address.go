@@ -1,14 +1,130 @@
 package model
 
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+	"GraduationProject.com/m/internal/geo"
+)
+
 type Address struct {
-	AddressID        string `json:"addressID"`
-	Country          string `json:"Country"`
-	City             string `json:"city"`
-	State            string `json:"state"`
-	Street           string `json:"street"`
-	PostalCode       string `json:"PostalCode"`
-	AdditionalNumber string `json:"additionalNumber"`
-	MapLocation      string `json:"mapLocation"`
-	Latitude         string `json:"Latitude"`
-	Longitude        string `json:"Longitude"`
+	AddressID        string     `json:"addressID"`
+	Country          string     `json:"Country"`
+	City             string     `json:"city"`
+	State            string     `json:"state"`
+	Street           string     `json:"street"`
+	PostalCode       string     `json:"PostalCode"`
+	AdditionalNumber string     `json:"additionalNumber"`
+	MapLocation      string     `json:"mapLocation"`
+	Latitude         Coordinate `json:"Latitude"`
+	Longitude        Coordinate `json:"Longitude"`
+}
+
+func (a *Address) Validate() error {
+	if a.Latitude.Valid != a.Longitude.Valid {
+		return apperror.Field("address.Latitude", "latitude and longitude must be given together")
+	}
+	if point, ok := a.Point(); ok {
+		if err := point.Validate(); err != nil {
+			return apperror.Field("address", err.Error())
+		}
+	}
+	return nil
+}
+
+// Point returns the location of the address, ok is false when it has no coordinates
+func (a *Address) Point() (geo.Point, bool) {
+	if !a.Latitude.Valid || !a.Longitude.Valid {
+		return geo.Point{}, false
+	}
+	return geo.Point{Lat: a.Latitude.Float64, Lng: a.Longitude.Float64}, true
+}
+
+// Coordinate is a latitude or longitude in degrees. Older clients send it as a string and the MySQL driver
+// reads DECIMAL columns as text, so both numbers and numeric strings are accepted, and an empty value means unset.
+type Coordinate struct {
+	Float64 float64
+	Valid   bool
+}
+
+func NewCoordinate(value float64) Coordinate {
+	return Coordinate{Float64: value, Valid: true}
+}
+
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	if !c.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(c.Float64)
+}
+
+func (c *Coordinate) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*c = Coordinate{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return c.parse(s)
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("coordinate must be a number: %v", err)
+	}
+	*c = NewCoordinate(value)
+	return nil
+}
+
+// Scan implements the sql.Scanner interface
+func (c *Coordinate) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = Coordinate{}
+		return nil
+	case float64:
+		*c = NewCoordinate(v)
+		return nil
+	case float32:
+		*c = NewCoordinate(float64(v))
+		return nil
+	case int64:
+		*c = NewCoordinate(float64(v))
+		return nil
+	case []byte:
+		return c.parse(string(v))
+	case string:
+		return c.parse(v)
+	}
+	return fmt.Errorf("cannot scan %T into a coordinate", value)
+}
+
+// Value implements the driver.Valuer interface
+func (c Coordinate) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	return c.Float64, nil
+}
+
+func (c *Coordinate) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*c = Coordinate{}
+		return nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("coordinate %q is not a number", s)
+	}
+	*c = NewCoordinate(value)
+	return nil
 }
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditArchive = "archive"
+)
+
+// AuditEntry represents the 'AuditLog' table, one write to an entity. Entries are never changed or removed.
+type AuditEntry struct {
+	AuditID    string                 `json:"auditID"`
+	Actor      string                 `json:"actor"` // The user ID the request was made as, or "anonymous" and "system"
+	EntityType string                 `json:"entityType"`
+	EntityID   string                 `json:"entityID"`
+	Action     string                 `json:"action"`
+	Changes    map[string]AuditChange `json:"changes"` // Field name -> its value before and after, as JSON
+	RequestID  string                 `json:"requestID,omitempty"`
+	CreateTime time.Time              `json:"createTime"`
+}
+
+// AuditChange is a field that changed, Before is missing for created entities and After for deleted ones
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
@@ -1,44 +1,70 @@
 package model
 
 import (
-	"errors"
 	"time"
+
+	"GraduationProject.com/m/internal/apperror"
 )
 
 // Booking represents the 'Booking' table in your database.
+// CheckIn and CheckOut are days in the property's time zone, StartDate and EndDate are the instants they
+// stand for at the property's check-in and check-out times, in UTC.
 type Booking struct {
 	BookingID  string    `json:"bookingID"`
 	UnitID     string    `json:"unitID"`
 	UserID     string    `json:"userID"`
+	CheckIn    Date      `json:"checkIn"`
+	CheckOut   Date      `json:"checkOut"`
 	EndDate    time.Time `json:"endDate"`
 	CreateTime time.Time `json:"createTime"`
 	StartDate  time.Time `json:"startDate"`
 	Summary    string    `json:"summary"` // Assuming JSON data as a string; adjust according to your needs
+	Version    int       `json:"version"` // Bumped by every update, the ETag of the booking
 }
 
-func (b *Booking) Validate() error {
-	if b.BookingID == "" {
-		return errors.New("BookingID is required")
+// Schedule sets StartDate and EndDate from the check-in and check-out dates at the property.
+// Older clients only send StartDate and EndDate, their days in the property's time zone are used as the dates.
+func (b *Booking) Schedule(property Property) error {
+	loc, err := property.Location()
+	if err != nil {
+		return err
+	}
+	if b.CheckIn.IsZero() && !b.StartDate.IsZero() {
+		b.CheckIn = DateOf(b.StartDate.In(loc))
 	}
-	if b.UnitID == "" {
-		return errors.New("UnitID is required")
+	if b.CheckOut.IsZero() && !b.EndDate.IsZero() {
+		b.CheckOut = DateOf(b.EndDate.In(loc))
 	}
-	if b.UserID == "" {
-		return errors.New("UserID is required")
+	if b.CheckIn.IsZero() || b.CheckOut.IsZero() {
+		return apperror.Field("checkIn", "checkIn and checkOut are required")
 	}
-	if b.EndDate.IsZero() {
-		return errors.New("EndDate is required")
+	if !b.CheckIn.Before(b.CheckOut) {
+		return apperror.Field("checkOut", "checkOut must be after checkIn")
 	}
-	if b.StartDate.IsZero() {
-		return errors.New("StartDate is required")
+	b.StartDate, b.EndDate, err = property.StayTimes(b.CheckIn, b.CheckOut)
+	return err
+}
+
+// Stay is the nights from CheckIn up to CheckOut
+type Stay struct {
+	CheckIn  Date
+	CheckOut Date
+}
+
+// FreedBy returns the nights b holds that changed, the same booking after an update, no longer holds. A booking
+// that moved to other dates frees all of its nights, one that got shorter frees the nights it lost.
+func (b *Booking) FreedBy(changed Booking) []Stay {
+	if changed.UnitID != b.UnitID || !changed.CheckIn.Before(b.CheckOut) || !b.CheckIn.Before(changed.CheckOut) {
+		return []Stay{{b.CheckIn, b.CheckOut}}
 	}
-	if b.StartDate.After(b.EndDate) {
-		return errors.New("StartDate cannot be after EndDate")
+	var freed []Stay
+	if b.CheckIn.Before(changed.CheckIn) {
+		freed = append(freed, Stay{b.CheckIn, changed.CheckIn})
 	}
-	if b.Summary == "" {
-		return errors.New("summary is required")
+	if changed.CheckOut.Before(b.CheckOut) {
+		freed = append(freed, Stay{changed.CheckOut, b.CheckOut})
 	}
-	return nil
+	return freed
 }
 
 func (b *Booking) IsPastBooking() bool {
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyKey represents the 'IdempotencyKey' table, the response to the first request a client sent with
+// an Idempotency-Key header. Retries with the key get that response instead of running the request again.
+type IdempotencyKey struct {
+	Actor       string // Keys belong to the user in X-User-ID, or to "anonymous"
+	Key         string
+	Route       string // Method and route template, like "POST /booking/create"
+	RequestHash string // SHA-256 of the body, retries have to send the same one
+	Status      int    // Zero while the first request is still running
+	ContentType string
+	Body        []byte
+	CreateTime  time.Time
+	ExpireTime  time.Time
+}
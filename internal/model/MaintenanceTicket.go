@@ -1,9 +1,6 @@
 package model
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 // MaintenanceTicket represents the 'MaintenanceTicket' table in your database.
 type MaintenanceTicket struct {
@@ -15,31 +12,7 @@ type MaintenanceTicket struct {
 	UrgencyLevel           string    `json:"urgencyLevel"`
 	CreateTime             time.Time `json:"createTime"`
 	Status                 string    `json:"status"`
-}
-
-func (m *MaintenanceTicket) Validate() error {
-	if m.TicketID == "" {
-		return errors.New("TicketID is required")
-	}
-	if m.MaintenancePresenterID == "" {
-		return errors.New("MaintenancePresenterID is required")
-	}
-	if m.TenantID == "" {
-		return errors.New("TenantID is required")
-	}
-	if m.PropertyID == "" {
-		return errors.New("PropertyID is required")
-	}
-	if m.Description == "" {
-		return errors.New("description is required")
-	}
-	if m.UrgencyLevel == "" {
-		return errors.New("UrgencyLevel is required")
-	}
-	if m.Status == "" {
-		return errors.New("status is required")
-	}
-	return nil
+	Version                int       `json:"version"` // Bumped by every update, the ETag of the ticket
 }
 
 func (m *MaintenanceTicket) IsUrgent() bool {
@@ -1,9 +1,6 @@
 package model
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 // Message represents the 'Message' table in your database.
 type Message struct {
@@ -15,20 +12,6 @@ type Message struct {
 	ReceiverID string    `json:"receiverID"`
 }
 
-func (m *Message) Validate() error {
-	if m.MessageID == "" {
-		return errors.New("MessageID is required")
-	}
-	if m.Content == "" {
-		return errors.New("content is required")
-	}
-
-	if m.SenderID == "" {
-		return errors.New("senderID is required")
-	}
-	return nil
-}
-
 func (m *Message) IsFrom(senderID string) bool {
 	return m.SenderID == senderID
 }
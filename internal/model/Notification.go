@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const (
+	NotificationNewMatch       = "NewMatch"       // A new unit matches a saved search
+	NotificationPriceDrop      = "PriceDrop"      // A wishlisted unit got cheaper
+	NotificationDatesAvailable = "DatesAvailable" // A booking of a wishlisted unit was cancelled
+)
+
+// Notification represents the 'Notification' table in your database.
+type Notification struct {
+	NotificationID string     `json:"notificationID"`
+	UserID         string     `json:"userID"`
+	Type           string     `json:"type"`
+	UnitID         string     `json:"unitID"`
+	Message        string     `json:"message"`
+	CreateTime     time.Time  `json:"createTime"`
+	ReadTime       *time.Time `json:"readTime,omitempty"`
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadTime != nil
+}
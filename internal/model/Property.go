@@ -1,8 +1,13 @@
 package model
 
-import (
-	"errors"
-	"time"
+import "time"
+
+const (
+	DefaultTimeZone     = "UTC"
+	DefaultCheckInTime  = "15:00"
+	DefaultCheckOutTime = "11:00"
+
+	clockLayout = "15:04"
 )
 
 // Property represents the 'Property' table in your database.
@@ -11,29 +16,61 @@ type Property struct {
 	AddressID   string    `json:"addressID"`
 	Name        string    `json:"name"`
 	CreateTime  time.Time `json:"createTime"`
+	Version     int       `json:"version"` // Bumped by every update, the ETag of the property
 	Type        string    `json:"type"`
 	Photos      [][]byte  `json:"images,omitempty"`
 	OwnerID     string    `json:"ownerID"`
 	Description string    `json:"description"`
 	Rules       string    `json:"rules"` // Assuming JSON data as a string; adjust according to your needs
-	Address     Address   `json:"address"`
-	Units       []Unit    `json:"units,omitempty"`
+	// TimeZone is an IANA name like "Asia/Riyadh", check-in and check-out dates of bookings are days in it
+	TimeZone     string     `json:"timeZone"`
+	CheckInTime  string     `json:"checkInTime"`           // Local time guests can arrive, like "15:00"
+	CheckOutTime string     `json:"checkOutTime"`          // Local time guests leave, like "11:00"
+	DeleteTime   *time.Time `json:"deleteTime,omitempty"`  // Set on deleted properties, which the repositories do not return
+	ArchiveTime  *time.Time `json:"archiveTime,omitempty"` // Archived properties stay deleted, their bookings are kept for reporting
+	Address      Address    `json:"address"`
+	Units        []Unit     `json:"units,omitempty"`
 }
 
-func (p *Property) Validate() error {
-	if p.Name == "" {
-		return errors.New("name is required")
+// SetScheduleDefaults fills in the time zone and the check-in and check-out times that are not set
+func (p *Property) SetScheduleDefaults() {
+	if p.TimeZone == "" {
+		p.TimeZone = DefaultTimeZone
+	}
+	if p.CheckInTime == "" {
+		p.CheckInTime = DefaultCheckInTime
 	}
-	if p.Type == "" {
-		return errors.New("type is required")
+	if p.CheckOutTime == "" {
+		p.CheckOutTime = DefaultCheckOutTime
+	}
+}
+
+// Location returns the property's time zone
+func (p *Property) Location() (*time.Location, error) {
+	if p.TimeZone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(p.TimeZone)
+}
+
+// StayTimes returns the instants, in UTC, a stay from the check-in date to the check-out date starts and ends
+// at the property's check-in and check-out times
+func (p *Property) StayTimes(checkIn, checkOut Date) (time.Time, time.Time, error) {
+	property := *p
+	property.SetScheduleDefaults()
+	loc, err := property.Location()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
-	if p.Description == "" {
-		return errors.New("description is required")
+	in, err := time.Parse(clockLayout, property.CheckInTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
-	if p.Rules == "" {
-		return errors.New("rules are required")
+	out, err := time.Parse(clockLayout, property.CheckOutTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
-	return nil
+	return checkIn.At(in.Hour(), in.Minute(), loc).UTC(), checkOut.At(out.Hour(), out.Minute(), loc).UTC(), nil
 }
 
 func (p *Property) HasRules() bool {
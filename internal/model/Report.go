@@ -2,7 +2,6 @@ package model
 
 import (
 	"database/sql"
-	"errors"
 	"time"
 )
 
@@ -12,20 +11,8 @@ type Report struct {
 	UserID     string         `json:"userID"`
 	Type       sql.NullString `json:"type,omitempty"`
 	CreateTime time.Time      `json:"createTime,omitempty"`
-	Data       string         `json:"data"` // Assuming JSON data as a string; adjust according to your needs
-}
-
-func (r *Report) Validate() error {
-	if r.ReportID == "" {
-		return errors.New("ReportID is required")
-	}
-	if r.UserID == "" {
-		return errors.New("UserID is required")
-	}
-	if r.Data == "" {
-		return errors.New("data is required")
-	}
-	return nil
+	Data       string         `json:"data"`    // Assuming JSON data as a string; adjust according to your needs
+	Version    int            `json:"version"` // Bumped by every update, the ETag of the report
 }
 
 func (r *Report) HasType() bool {
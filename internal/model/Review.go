@@ -1,9 +1,6 @@
 package model
 
-import (
-	"errors"
-	"time"
-)
+import "time"
 
 // Review represents the 'Review' table in your database.
 type Review struct {
@@ -14,19 +11,7 @@ type Review struct {
 	Rating     int       `json:"rating"`
 	Comment    string    `json:"comment,omitempty"`
 	CreateTime time.Time `json:"createTime"`
-}
-
-func (r *Review) Validate() error {
-	if r.UserID == "" {
-		return errors.New("UserID is required")
-	}
-	if r.UnitID == "" {
-		return errors.New("UnitID is required")
-	}
-	if r.Rating < 1 || r.Rating > 5 {
-		return errors.New("rating must be between 1 and 5")
-	}
-	return nil
+	Version    int       `json:"version"` // Bumped by every update, the ETag of the review
 }
 
 func (r *Review) HasComment() bool {
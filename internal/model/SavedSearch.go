@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// SavedSearch represents the 'SavedSearch' table in your database.
+type SavedSearch struct {
+	SavedSearchID string    `json:"savedSearchID"`
+	UserID        string    `json:"userID"`
+	Name          string    `json:"name"`
+	Criteria      string    `json:"criteria"` // The unit listing filter as JSON
+	CreateTime    time.Time `json:"createTime"`
+}
@@ -1,35 +1,71 @@
 package model
 
 import (
-	"errors"
 	"time"
+
+	"GraduationProject.com/m/internal/apperror"
 )
 
 type Unit struct {
-	UnitID               string    `json:"unitID"`
-	OwnerName            string    `json:"ownerName,omitempty"` // Optional field
-	AddressID            string    `json:"addressID"`
-	Name                 string    `json:"name,omitempty"` // Optional field
-	Images               [][]byte  `json:"images,omitempty"`
-	Description          string    `json:"description,omitempty"`
-	Rating               float32   `json:"rating,omitempty"`
-	PropertyID           string    `json:"propertyID"`
-	RentalPrice          int       `json:"rentalPrice"`
-	StructuralProperties string    `json:"structuralProperties"` // Assuming JSON data as a string; adjust according to your needs
-	CreateTime           time.Time `json:"createTime"`
-	Address              Address   `json:"address"`
+	UnitID               string         `json:"unitID"`
+	OwnerName            string         `json:"ownerName,omitempty"` // Optional field
+	AddressID            string         `json:"addressID"`
+	Name                 string         `json:"name,omitempty"` // Optional field
+	Images               [][]byte       `json:"images,omitempty"`
+	Description          string         `json:"description,omitempty"`
+	Rating               float32        `json:"rating,omitempty"`
+	PropertyID           string         `json:"propertyID"`
+	PropertyType         string         `json:"propertyType,omitempty"`
+	RentalPrice          int            `json:"rentalPrice"`
+	StructuralProperties string         `json:"structuralProperties,omitempty"` // Legacy free-form JSON, superseded by Attributes and Amenities
+	Attributes           UnitAttributes `json:"attributes"`
+	Amenities            []string       `json:"amenities"`
+	CreateTime           time.Time      `json:"createTime"`
+	Version              int            `json:"version"`               // Bumped by every update, the ETag of the unit
+	DeleteTime           *time.Time     `json:"deleteTime,omitempty"`  // Set on deleted units, which the repositories do not return
+	ArchiveTime          *time.Time     `json:"archiveTime,omitempty"` // Set when the unit's property was archived
+	Address              Address        `json:"address"`
 }
 
 func (u *Unit) Validate() error {
 	if u.PropertyID == "" {
-		return errors.New("PropertyID is required")
+		return apperror.Field("propertyID", "PropertyID is required")
 	}
 	if u.RentalPrice < 0 {
-		return errors.New("RentalPrice must be greater than 0")
+		return apperror.Field("rentalPrice", "RentalPrice must be greater than 0")
 	}
 
-	if u.StructuralProperties == "" {
-		return errors.New("StructuralProperties is required")
+	return u.validateStructure()
+}
+
+// validateStructure checks the attributes and amenities, and normalizes the amenity names to the taxonomy.
+// Units that do not say how many guests they take get one per bed and at least one, like migration 0010.
+func (u *Unit) validateStructure() error {
+	u.Attributes.defaultMaxGuests()
+	if err := u.Attributes.Validate(); err != nil {
+		return err
 	}
+	amenities, err := NormalizeAmenities(u.Amenities)
+	if err != nil {
+		return err
+	}
+	u.Amenities = amenities
 	return nil
 }
+
+// FillFromStructuralProperties lets clients that still only send StructuralProperties create units,
+// by parsing the attributes and amenities out of it when they were not given. JSON that cannot be parsed
+// gives no attributes, like it does in migration 0002, and the unit keeps it as it is.
+func (u *Unit) FillFromStructuralProperties() {
+	if u.StructuralProperties == "" || u.Attributes != (UnitAttributes{}) {
+		return
+	}
+	attributes, amenities, err := ParseStructuralProperties(u.StructuralProperties)
+	if err != nil {
+		return
+	}
+	u.Attributes = attributes
+	if u.Amenities == nil {
+		u.Amenities = amenities
+	}
+}
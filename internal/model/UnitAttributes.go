@@ -0,0 +1,191 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"GraduationProject.com/m/internal/apperror"
+)
+
+// UnitAttributes are the structural facts about a unit that guests filter on
+type UnitAttributes struct {
+	Bedrooms  int     `json:"bedrooms"`
+	Beds      int     `json:"beds"`
+	Bathrooms int     `json:"bathrooms"`
+	MaxGuests int     `json:"maxGuests"`
+	Size      float64 `json:"size"` // Square metres
+	Floor     int     `json:"floor"`
+}
+
+func (a *UnitAttributes) Validate() error {
+	if a.Bedrooms < 0 || a.Bedrooms > 50 {
+		return apperror.Field("attributes.bedrooms", "bedrooms must be between 0 and 50")
+	}
+	if a.Beds < 0 || a.Beds > 100 {
+		return apperror.Field("attributes.beds", "beds must be between 0 and 100")
+	}
+	if a.Bathrooms < 0 || a.Bathrooms > 50 {
+		return apperror.Field("attributes.bathrooms", "bathrooms must be between 0 and 50")
+	}
+	if a.MaxGuests < 1 || a.MaxGuests > 100 {
+		return apperror.Field("attributes.maxGuests", "maxGuests must be between 1 and 100")
+	}
+	if a.Size < 0 || a.Size > 100000 {
+		return apperror.Field("attributes.size", "size must be between 0 and 100000 square metres")
+	}
+	if a.Floor < -10 || a.Floor > 200 {
+		return apperror.Field("attributes.floor", "floor must be between -10 and 200")
+	}
+	return nil
+}
+
+// Amenities is the taxonomy units can pick from, stored by these names
+var Amenities = []string{
+	"ac",
+	"balcony",
+	"bbq",
+	"dryer",
+	"elevator",
+	"gym",
+	"heating",
+	"kitchen",
+	"parking",
+	"pets",
+	"pool",
+	"security",
+	"tv",
+	"washer",
+	"wifi",
+	"workspace",
+}
+
+// Other spellings clients have been using, mapped to the taxonomy name
+var amenityAliases = map[string]string{
+	"air conditioning": "ac",
+	"airconditioning":  "ac",
+	"aircon":           "ac",
+	"a/c":              "ac",
+	"barbecue":         "bbq",
+	"garage":           "parking",
+	"internet":         "wifi",
+	"lift":             "elevator",
+	"pets allowed":     "pets",
+	"petsallowed":      "pets",
+	"swimming pool":    "pool",
+	"swimmingpool":     "pool",
+	"television":       "tv",
+	"washing machine":  "washer",
+	"washingmachine":   "washer",
+	"wi-fi":            "wifi",
+	"wireless":         "wifi",
+}
+
+// NormalizeAmenity maps a name to its taxonomy entry, ok is false when it is not a known amenity
+func NormalizeAmenity(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := amenityAliases[name]; ok {
+		return alias, true
+	}
+	for _, amenity := range Amenities {
+		if amenity == name {
+			return amenity, true
+		}
+	}
+	return "", false
+}
+
+// NormalizeAmenities maps every name to its taxonomy entry and removes duplicates
+func NormalizeAmenities(names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	amenities := []string{}
+	for _, name := range names {
+		amenity, ok := NormalizeAmenity(name)
+		if !ok {
+			return nil, apperror.Field("amenities", fmt.Sprintf("unknown amenity %q, must be one of %s", name, strings.Join(Amenities, ", ")))
+		}
+		if !seen[amenity] {
+			seen[amenity] = true
+			amenities = append(amenities, amenity)
+		}
+	}
+	sort.Strings(amenities)
+	return amenities, nil
+}
+
+// ParseStructuralProperties reads the attributes and amenities out of the free-form JSON that units used
+// to store in StructuralProperties. Amenities come from an "amenities" array or from keys set to true,
+// names that are not in the taxonomy are dropped. Units that never said how many guests they take are given
+// one per bed and at least one, so they pass Validate.
+func ParseStructuralProperties(raw string) (UnitAttributes, []string, error) {
+	var attributes UnitAttributes
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return attributes, nil, fmt.Errorf("structural properties are not a JSON object: %v", err)
+	}
+
+	var names []string
+	for key, value := range values {
+		switch strings.ToLower(key) {
+		case "bedrooms", "rooms":
+			attributes.Bedrooms = jsonInt(value)
+		case "beds":
+			attributes.Beds = jsonInt(value)
+		case "bathrooms", "baths":
+			attributes.Bathrooms = jsonInt(value)
+		case "maxguests", "guests", "capacity":
+			attributes.MaxGuests = jsonInt(value)
+		case "size", "area", "sqm":
+			attributes.Size = jsonFloat(value)
+		case "floor":
+			attributes.Floor = jsonInt(value)
+		case "amenities":
+			if list, ok := value.([]interface{}); ok {
+				for _, item := range list {
+					names = append(names, fmt.Sprint(item))
+				}
+			}
+		default:
+			if enabled, ok := value.(bool); ok && enabled {
+				names = append(names, key)
+			}
+		}
+	}
+
+	var amenities []string
+	for _, name := range names {
+		if amenity, ok := NormalizeAmenity(name); ok {
+			amenities = append(amenities, amenity)
+		}
+	}
+	amenities, _ = NormalizeAmenities(amenities)
+	if attributes.MaxGuests < 0 {
+		attributes.MaxGuests = 0
+	}
+	attributes.defaultMaxGuests()
+	return attributes, amenities, nil
+}
+
+// defaultMaxGuests gives a unit that does not say how many guests it takes one per bed and at least one
+func (a *UnitAttributes) defaultMaxGuests() {
+	if a.MaxGuests == 0 {
+		a.MaxGuests = min(max(a.Beds, 1), 100)
+	}
+}
+
+func jsonFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		var f float64
+		fmt.Sscan(v, &f)
+		return f
+	}
+	return 0
+}
+
+func jsonInt(value interface{}) int {
+	return int(jsonFloat(value))
+}
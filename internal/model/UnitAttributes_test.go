@@ -0,0 +1,54 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStructuralProperties(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		wantAttrs     UnitAttributes
+		wantAmenities []string
+	}{
+		{"guests given", `{"rooms": 2, "beds": 3, "guests": 4, "wifi": true}`, UnitAttributes{Bedrooms: 2, Beds: 3, MaxGuests: 4}, []string{"wifi"}},
+		{"guests from beds", `{"beds": 3, "amenities": ["Pool", "sauna"]}`, UnitAttributes{Beds: 3, MaxGuests: 3}, []string{"pool"}},
+		{"no beds", `{"floor": 2}`, UnitAttributes{Floor: 2, MaxGuests: 1}, []string{}},
+		{"more beds than guests allowed", `{"beds": 100}`, UnitAttributes{Beds: 100, MaxGuests: 100}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes, amenities, err := ParseStructuralProperties(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attributes != tt.wantAttrs {
+				t.Errorf("got attributes %+v, want %+v", attributes, tt.wantAttrs)
+			}
+			if err := attributes.Validate(); err != nil {
+				t.Errorf("the parsed attributes are not valid: %v", err)
+			}
+			if !reflect.DeepEqual(amenities, tt.wantAmenities) {
+				t.Errorf("got amenities %v, want %v", amenities, tt.wantAmenities)
+			}
+		})
+	}
+
+	if _, _, err := ParseStructuralProperties(`"not an object"`); err == nil {
+		t.Error("a JSON string was parsed as structural properties")
+	}
+}
+
+func TestUnitStructureDefaults(t *testing.T) {
+	unit := Unit{PropertyID: "1", Attributes: UnitAttributes{Beds: 3}}
+	if err := unit.Validate(); err != nil || unit.Attributes.MaxGuests != 3 {
+		t.Errorf("got maxGuests %d, %v for a unit with 3 beds that left it out, want 3", unit.Attributes.MaxGuests, err)
+	}
+
+	legacy := Unit{PropertyID: "1", StructuralProperties: "two rooms, one bed"}
+	legacy.FillFromStructuralProperties()
+	if err := legacy.Validate(); err != nil || legacy.Attributes != (UnitAttributes{MaxGuests: 1}) {
+		t.Errorf("got attributes %+v, %v from structural properties that are not JSON, want one guest", legacy.Attributes, err)
+	}
+}
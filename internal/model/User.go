@@ -1,47 +1,23 @@
 package model
 
 import (
-	"errors"
 	"net/mail"
 	"regexp"
 	"time"
 )
 
 type User struct {
-	UserID      string    `json:"userID"`
-	AddressID   string    `json:"addressID,omitempty"`
-	Name        string    `json:"name"`
-	Email       string    `json:"email,omitempty"`
-	PhoneNumber string    `json:"phoneNumber,omitempty"`
-	Password    string    `json:"password"`
-	CreateTime  time.Time `json:"createTime"`
-	UserRole    string    `json:"userRole"`
-	Address     Address   `json:"address,omitempty"`
-}
-
-func (u *User) Validate() error {
-	if u.UserID == "" {
-		return errors.New("UserID is required")
-	}
-	if u.Name == "" {
-		return errors.New("name is required")
-	}
-	if u.Email == "" {
-		return errors.New("email is required")
-	}
-	if _, err := mail.ParseAddress(u.Email); err != nil {
-		return errors.New("email is not valid")
-	}
-	if u.Password == "" {
-		return errors.New("password is required")
-	}
-	if len(u.Password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
-	if u.UserRole == "" {
-		return errors.New("UserRole is required")
-	}
-	return nil
+	UserID      string     `json:"userID"`
+	AddressID   string     `json:"addressID,omitempty"`
+	Name        string     `json:"name"`
+	Email       string     `json:"email,omitempty"`
+	PhoneNumber string     `json:"phoneNumber,omitempty"`
+	Password    string     `json:"password"`
+	CreateTime  time.Time  `json:"createTime"`
+	UserRole    string     `json:"userRole"`
+	DeleteTime  *time.Time `json:"deleteTime,omitempty"` // Set on deleted users, which the repositories do not return
+	Address     Address    `json:"address,omitempty"`
+	Version     int        `json:"version"` // Bumped by every update, the ETag of the user
 }
 
 func (u *User) IsEmailValid() bool {
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Wishlist represents the 'Wishlist' table in your database.
+type Wishlist struct {
+	WishlistID string         `json:"wishlistID"`
+	UserID     string         `json:"userID"`
+	Name       string         `json:"name"`
+	ShareToken string         `json:"shareToken,omitempty"` // Empty until the owner shares the wishlist
+	CreateTime time.Time      `json:"createTime"`
+	Items      []WishlistItem `json:"items"`
+}
+
+// WishlistItem represents the 'WishlistUnit' table, a unit saved to a wishlist
+type WishlistItem struct {
+	UnitID      string    `json:"unitID"`
+	Name        string    `json:"name"`
+	RentalPrice int       `json:"rentalPrice"`
+	CreateTime  time.Time `json:"createTime"`
+}
+
+func (w *Wishlist) IsShared() bool {
+	return w.ShareToken != ""
+}
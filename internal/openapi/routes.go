@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"GraduationProject.com/m/internal/dto"
+	Handlers "GraduationProject.com/m/internal/handler"
+	"GraduationProject.com/m/internal/listing"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/search"
+)
+
+// Route is one route as registered in internal/Routes
+type Route struct {
+	Method      string
+	Path        string // Written the way gin registers it, like /users/:id
+	Tag         string
+	Summary     string
+	Query       []Parameter
+	Body        interface{} // The JSON body the handler binds, for PATCH the fields its merge patch can change
+	Form        []FormField // Form fields, for handlers that read a form instead of JSON
+	Status      int         // Of a successful response, 200 when zero
+	Data        interface{} // What the envelope carries on success, nothing when nil
+	ContentType string      // Set when a successful response is not the JSON envelope
+	Admin       bool        // Needs X-Admin-Token
+	RateLimited bool        // Has a rate limit of its own, on top of the default one
+	Idempotent  bool        // Takes an Idempotency-Key header to be retried safely
+	Versioned   bool        // Responds with the ETag of the entity, and updates take If-Match
+}
+
+type FormField struct {
+	Name     string
+	Required bool
+	Schema   *Schema
+}
+
+// OneOf is Data for handlers that return one of several types
+type OneOf []interface{}
+
+func query(name, kind, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: kind}}
+}
+
+func page(maxPageSize int) []Parameter {
+	return []Parameter{
+		query("page", "integer", "Starts at 1"),
+		query("pageSize", "integer", "At most "+strconv.Itoa(maxPageSize)),
+	}
+}
+
+// routes must list every route the app registers, the tests fail otherwise
+var routes = []Route{
+	// Users
+	{Method: http.MethodPost, Path: "/users/create", Tag: "Users", Summary: "Create a user", Body: dto.CreateUserRequest{}, Status: http.StatusCreated, Data: dto.UserResponse{}, Idempotent: true},
+	{Method: http.MethodPost, Path: "/users/login", Tag: "Users", Summary: "Log in with email and password", Body: dto.LoginRequest{}, Data: dto.UserResponse{}, RateLimited: true},
+	{Method: http.MethodGet, Path: "/users/", Tag: "Users", Summary: "List users", Data: []dto.UserResponse{}},
+	{Method: http.MethodGet, Path: "/users/:id", Tag: "Users", Summary: "Get a user", Data: dto.UserResponse{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/users/:id", Tag: "Users", Summary: "Update a user and their address", Body: dto.UpdateUserRequest{}, Data: dto.UserResponse{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/users/:id", Tag: "Users", Summary: "Change or clear fields of a user and their address", Body: dto.UserPatch{}, Data: dto.UserResponse{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/users/:id", Tag: "Users", Summary: "Delete a user with their properties and units", Data: dto.UserResponse{}},
+	{Method: http.MethodPost, Path: "/users/:id/restore", Tag: "Users", Summary: "Restore a deleted user", Data: dto.UserResponse{}},
+	{Method: http.MethodGet, Path: "/users/report/:id", Tag: "Users", Summary: "Build the owner's report of properties, bookings and earnings", Data: Handlers.Report{}},
+
+	// Properties
+	{Method: http.MethodPost, Path: "/property/create", Tag: "Properties", Summary: "Create a property", Body: dto.CreatePropertyRequest{}, Status: http.StatusCreated, Data: Entities.Property{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/property/:id", Tag: "Properties", Summary: "Get a property", Data: Entities.Property{}, Versioned: true},
+	{Method: http.MethodGet, Path: "/property/", Tag: "Properties", Summary: "List properties", Data: []Entities.Property{}},
+	{Method: http.MethodGet, Path: "/property/owner/:id", Tag: "Properties", Summary: "List the properties of an owner", Data: []Entities.Property{}},
+	{Method: http.MethodGet, Path: "/property/AllUnits/:id", Tag: "Properties", Summary: "List the units of a property", Data: []Entities.Unit{}},
+	{Method: http.MethodGet, Path: "/property/ByType/:type", Tag: "Properties", Summary: "List the properties of a type", Data: []Entities.Property{}},
+	{Method: http.MethodPut, Path: "/property/:id", Tag: "Properties", Summary: "Update a property", Body: dto.UpdatePropertyRequest{}, Data: Entities.Property{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/property/:id", Tag: "Properties", Summary: "Change or clear fields of a property", Body: dto.PropertyPatch{}, Data: Entities.Property{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/property/:id", Tag: "Properties", Summary: "Delete a property with its units", Data: Entities.Property{}},
+	{Method: http.MethodPost, Path: "/property/:id/restore", Tag: "Properties", Summary: "Restore a deleted property", Data: Entities.Property{}},
+	{Method: http.MethodPost, Path: "/property/:id/archive", Tag: "Properties", Summary: "Archive a property, its units stop being listed"},
+	{Method: http.MethodPost, Path: "/property/proof/add/:id", Tag: "Properties", Summary: "Save the proof of ownership", Form: []FormField{{Name: "URL", Required: true, Schema: &Schema{Type: "string"}}}},
+	{Method: http.MethodGet, Path: "/property/proof/get/:id", Tag: "Properties", Summary: "Download the proof of ownership", ContentType: "application/octet-stream"},
+
+	// Units
+	{Method: http.MethodPost, Path: "/units/create", Tag: "Units", Summary: "Create a unit", Body: dto.CreateUnitRequest{}, Status: http.StatusCreated, Data: Entities.Unit{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/units/:id", Tag: "Units", Summary: "Get a unit", Data: Entities.Unit{}, Versioned: true},
+	{Method: http.MethodGet, Path: "/units/", Tag: "Units", Summary: "List units a page at a time, filtered and sorted, with facet counts", Data: Handlers.UnitPage{}, Query: []Parameter{
+		query("minPrice", "integer", ""),
+		query("maxPrice", "integer", ""),
+		query("type", "string", "Property types, repeated or comma separated"),
+		query("city", "string", "Cities, repeated or comma separated"),
+		query("minRating", "number", "Between 0 and 5"),
+		query("guests", "integer", "Units that fit at least this many guests"),
+		query("amenities", "string", "Amenities the unit must all have, repeated or comma separated"),
+		{Name: "sort", In: "query", Schema: &Schema{Type: "string", Enum: []string{listing.SortNewest, listing.SortPriceAsc, listing.SortPriceDesc, listing.SortRating}}},
+		query("cursor", "string", "nextCursor of the previous page"),
+		query("limit", "integer", "At most "+strconv.Itoa(listing.MaxLimit)+", "+strconv.Itoa(listing.DefaultLimit)+" when not given"),
+	}},
+	{Method: http.MethodPut, Path: "/units/:id", Tag: "Units", Summary: "Update a unit", Body: dto.UpdateUnitRequest{}, Data: Entities.Unit{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/units/:id", Tag: "Units", Summary: "Change or clear fields of a unit", Body: dto.UnitPatch{}, Data: Entities.Unit{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/units/:id", Tag: "Units", Summary: "Delete a unit", Data: Entities.Unit{}},
+	{Method: http.MethodPost, Path: "/units/:id/restore", Tag: "Units", Summary: "Restore a deleted unit", Data: Entities.Unit{}},
+	{Method: http.MethodPost, Path: "/units/images/add/:id", Tag: "Units", Summary: "Add images to a unit", Form: []FormField{{Name: "Images", Required: true, Schema: &Schema{Type: "array", Items: &Schema{Type: "string"}}}}},
+	{Method: http.MethodGet, Path: "/units/images/get/:id", Tag: "Units", Summary: "List the images of a unit", Data: []Entities.Image{}},
+	{Method: http.MethodPost, Path: "/units/SearchByName", Tag: "Units", Summary: "Find units by name", Body: dto.UnitNameSearchRequest{}, Data: []Entities.Unit{}},
+	{Method: http.MethodPost, Path: "/units/SearchByAddress", Tag: "Units", Summary: "Find units matching any part of an address", Body: dto.AddressRequest{}, Data: []Entities.Unit{}},
+	{Method: http.MethodGet, Path: "/units/nearby", Tag: "Units", Summary: "List the units around a point, closest first", Data: []Handlers.UnitDistance{}, Query: []Parameter{
+		{Name: "lat", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		{Name: "lng", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		query("radius", "number", "In km, 5 when not given"),
+	}},
+	{Method: http.MethodGet, Path: "/units/map", Tag: "Units", Summary: "List the units inside a map box, clustered at low zoom levels", Data: OneOf{[]Handlers.UnitDistance{}, []Handlers.UnitCluster{}}, Query: []Parameter{
+		{Name: "minLat", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		{Name: "minLng", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		{Name: "maxLat", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		{Name: "maxLng", In: "query", Required: true, Schema: &Schema{Type: "number"}},
+		query("zoom", "integer", "Units are clustered at this zoom level and below it"),
+	}},
+
+	// Bookings
+	{Method: http.MethodPost, Path: "/booking/create", Tag: "Bookings", Summary: "Book a unit", Body: dto.CreateBookingRequest{}, Status: http.StatusCreated, Data: Entities.Booking{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/booking/:id", Tag: "Bookings", Summary: "Get a booking", Data: Entities.Booking{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/booking/:id", Tag: "Bookings", Summary: "Change the dates or summary of a booking", Body: dto.UpdateBookingRequest{}, Data: Entities.Booking{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/booking/:id", Tag: "Bookings", Summary: "Change the dates or summary of a booking, or clear the summary", Body: dto.BookingPatch{}, Data: Entities.Booking{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/booking/:id", Tag: "Bookings", Summary: "Cancel a booking", Data: Entities.Booking{}},
+	{Method: http.MethodGet, Path: "/booking/unit/:id", Tag: "Bookings", Summary: "List the bookings of a unit", Data: []Entities.Booking{}},
+	{Method: http.MethodGet, Path: "/booking/user/:id", Tag: "Bookings", Summary: "List the bookings of a user", Data: []Entities.Booking{}},
+
+	// Financial transactions
+	{Method: http.MethodPost, Path: "/financialTransaction/create", Tag: "Financial transactions", Summary: "Record a payment", Body: dto.CreateTransactionRequest{}, Status: http.StatusCreated, Data: Entities.FinancialTransaction{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/financialTransaction/:id", Tag: "Financial transactions", Summary: "Get a transaction", Data: Entities.FinancialTransaction{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/financialTransaction/:id", Tag: "Financial transactions", Summary: "Update a transaction", Body: dto.UpdateTransactionRequest{}, Data: Entities.FinancialTransaction{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/financialTransaction/:id", Tag: "Financial transactions", Summary: "Delete a transaction", Data: Entities.FinancialTransaction{}},
+
+	// Reviews
+	{Method: http.MethodPost, Path: "/reviews/create", Tag: "Reviews", Summary: "Review a unit", Body: dto.CreateReviewRequest{}, Status: http.StatusCreated, Data: Entities.Review{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/reviews/:id", Tag: "Reviews", Summary: "Get a review", Data: Entities.Review{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/reviews/:id", Tag: "Reviews", Summary: "Update a review", Body: dto.UpdateReviewRequest{}, Data: Entities.Review{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/reviews/:id", Tag: "Reviews", Summary: "Change or clear fields of a review", Body: dto.ReviewPatch{}, Data: Entities.Review{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/reviews/:id", Tag: "Reviews", Summary: "Delete a review"},
+	{Method: http.MethodGet, Path: "/reviews/ByUnit/:id", Tag: "Reviews", Summary: "List the reviews of a unit", Data: []Entities.Review{}},
+
+	// Maintenance tickets
+	{Method: http.MethodPost, Path: "/maintenanceTicket/create", Tag: "Maintenance tickets", Summary: "Open a maintenance ticket", Body: dto.CreateTicketRequest{}, Status: http.StatusCreated, Data: Entities.MaintenanceTicket{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/maintenanceTicket/:id", Tag: "Maintenance tickets", Summary: "Get a maintenance ticket", Data: Entities.MaintenanceTicket{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/maintenanceTicket/:id", Tag: "Maintenance tickets", Summary: "Update a maintenance ticket", Body: dto.UpdateTicketRequest{}, Data: Entities.MaintenanceTicket{}, Versioned: true},
+	{Method: http.MethodPatch, Path: "/maintenanceTicket/:id", Tag: "Maintenance tickets", Summary: "Change or clear fields of a maintenance ticket", Body: dto.TicketPatch{}, Data: Entities.MaintenanceTicket{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/maintenanceTicket/:id", Tag: "Maintenance tickets", Summary: "Delete a maintenance ticket"},
+
+	// Reports
+	{Method: http.MethodPost, Path: "/report/create", Tag: "Reports", Summary: "Create a report", Body: dto.CreateReportRequest{}, Status: http.StatusCreated, Data: dto.ReportResponse{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/report/:id", Tag: "Reports", Summary: "Get a report", Data: dto.ReportResponse{}, Versioned: true},
+	{Method: http.MethodPut, Path: "/report/:id", Tag: "Reports", Summary: "Update a report", Body: dto.UpdateReportRequest{}, Data: dto.ReportResponse{}, Versioned: true},
+	{Method: http.MethodDelete, Path: "/report/:id", Tag: "Reports", Summary: "Delete a report"},
+
+	// Messages
+	{Method: http.MethodPost, Path: "/message/send", Tag: "Messages", Summary: "Send a message, the chat between the two users is started when there is none", Body: dto.SendMessageRequest{}, Status: http.StatusCreated, Data: Entities.Message{},
+		RateLimited: true, Idempotent: true},
+	{Method: http.MethodGet, Path: "/chat/:id", Tag: "Messages", Summary: "Get a chat with its messages", Data: Entities.Chat{}},
+	{Method: http.MethodGet, Path: "/user/chat/:id", Tag: "Messages", Summary: "List the chats of a user", Data: []Entities.Chat{}},
+
+	// Search
+	{Method: http.MethodGet, Path: "/search", Tag: "Search", Summary: "Full text search over units and properties", Data: search.Result{}, Query: append([]Parameter{
+		{Name: "q", In: "query", Required: true, Schema: &Schema{Type: "string"}},
+		{Name: "kind", In: "query", Schema: &Schema{Type: "string", Enum: []string{string(search.KindUnit), string(search.KindProperty)}}},
+	}, page(search.MaxPageSize)...)},
+
+	// Wishlists and saved searches
+	{Method: http.MethodPost, Path: "/wishlist/create", Tag: "Wishlists", Summary: "Create a wishlist", Body: dto.CreateWishlistRequest{}, Status: http.StatusCreated, Data: Entities.Wishlist{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/wishlist/:id", Tag: "Wishlists", Summary: "Get a wishlist with its units", Data: Entities.Wishlist{}},
+	{Method: http.MethodPut, Path: "/wishlist/:id", Tag: "Wishlists", Summary: "Rename a wishlist", Body: dto.RenameWishlistRequest{}, Data: Entities.Wishlist{}},
+	{Method: http.MethodDelete, Path: "/wishlist/:id", Tag: "Wishlists", Summary: "Delete a wishlist"},
+	{Method: http.MethodGet, Path: "/wishlist/user/:id", Tag: "Wishlists", Summary: "List the wishlists of a user", Data: []Entities.Wishlist{}},
+	{Method: http.MethodPost, Path: "/wishlist/:id/units", Tag: "Wishlists", Summary: "Add a unit to a wishlist", Body: dto.AddWishlistUnitRequest{}, Data: Entities.Wishlist{}},
+	{Method: http.MethodDelete, Path: "/wishlist/:id/units/:unitID", Tag: "Wishlists", Summary: "Remove a unit from a wishlist", Data: Entities.Wishlist{}},
+	{Method: http.MethodPost, Path: "/wishlist/:id/share", Tag: "Wishlists", Summary: "Share a wishlist by link", Data: Entities.Wishlist{}},
+	{Method: http.MethodDelete, Path: "/wishlist/:id/share", Tag: "Wishlists", Summary: "Revoke the share link of a wishlist", Data: Entities.Wishlist{}},
+	{Method: http.MethodGet, Path: "/wishlist/shared/:token", Tag: "Wishlists", Summary: "Open a shared wishlist", Data: Entities.Wishlist{}},
+	{Method: http.MethodPost, Path: "/savedSearch/create", Tag: "Wishlists", Summary: "Save unit listing criteria to be notified about new matches", Body: dto.CreateSavedSearchRequest{}, Status: http.StatusCreated, Data: Entities.SavedSearch{}, Idempotent: true},
+	{Method: http.MethodGet, Path: "/savedSearch/user/:id", Tag: "Wishlists", Summary: "List the saved searches of a user", Data: []Entities.SavedSearch{}},
+	{Method: http.MethodDelete, Path: "/savedSearch/:id", Tag: "Wishlists", Summary: "Delete a saved search"},
+
+	// Notifications
+	{Method: http.MethodGet, Path: "/notifications/user/:id", Tag: "Notifications", Summary: "List the notifications of a user, newest first", Data: []Entities.Notification{}, Query: []Parameter{
+		query("unread", "boolean", "Only the unread ones when true"),
+	}},
+	{Method: http.MethodPut, Path: "/notifications/:id/read", Tag: "Notifications", Summary: "Mark a notification as read"},
+
+	// Admin
+	{Method: http.MethodGet, Path: "/admin/audit", Tag: "Admin", Summary: "Query the audit log, newest first", Admin: true, Data: []Entities.AuditEntry{}, Query: append([]Parameter{
+		query("entityType", "string", "Like Unit or Booking"),
+		query("entityID", "string", "Needs entityType"),
+		query("actor", "string", ""),
+	}, page(200)...)},
+
+	// Health
+	{Method: http.MethodGet, Path: "/healthz", Tag: "Health", Summary: "Liveness probe, answers as long as the process serves requests"},
+	{Method: http.MethodGet, Path: "/readyz", Tag: "Health", Summary: "Readiness probe: the database answers, its migrations are at head and the storage can be read. 503 with the failing checks in details, or while shutting down.", Data: map[string]string{}},
+
+	// Documentation and diagnostics
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "Documentation", Summary: "This document", ContentType: "application/json"},
+	{Method: http.MethodGet, Path: "/docs", Tag: "Documentation", Summary: "Interactive documentation of this document", ContentType: "text/html"},
+	{Method: http.MethodGet, Path: "/debug/vars", Tag: "Documentation", Summary: "Runtime and cache counters, when the debugVars feature is on", ContentType: "application/json"},
+	{Method: http.MethodGet, Path: "/metrics", Tag: "Documentation", Summary: "Prometheus metrics, when the metrics feature is on", ContentType: "text/plain"},
+}
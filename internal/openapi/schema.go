@@ -0,0 +1,229 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"GraduationProject.com/m/internal/apperror"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the API needs
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// knownTypes are written to JSON by their own marshalers, not field by field
+var knownTypes = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):           {Type: "string", Format: "date-time"},
+	reflect.TypeOf(Entities.Date{}):       {Type: "string", Format: "date"},
+	reflect.TypeOf(Entities.Coordinate{}): {Type: "number", Format: "double", Nullable: true},
+	reflect.TypeOf(json.RawMessage{}):     {Description: "Any JSON value"},
+	reflect.TypeOf(apperror.Code("")):     {Type: "string", Enum: codes()},
+}
+
+func codes() []string {
+	var names []string
+	for _, code := range apperror.Codes {
+		names = append(names, string(code))
+	}
+	return names
+}
+
+// schemas generates schemas from Go types the way encoding/json writes them. Named structs are added to
+// the components once and referenced from everywhere else.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// of returns the schema of the type of value
+func (s *schemas) of(value interface{}) *Schema {
+	if alternatives, ok := value.(OneOf); ok {
+		schema := &Schema{}
+		for _, alternative := range alternatives {
+			schema.OneOf = append(schema.OneOf, s.of(alternative))
+		}
+		return schema
+	}
+	return s.schema(reflect.TypeOf(value))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	if known, ok := knownTypes[t]; ok {
+		return &known
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.ref(t)
+	default:
+		// interface{} can hold anything
+		return &Schema{}
+	}
+}
+
+// ref adds the struct to the components the first time and returns a reference to it
+func (s *schemas) ref(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.components[name]; taken {
+			// Two packages use the same name, like handler.Report and model.Report
+			pkg := t.PkgPath()
+			name = pkg[strings.LastIndex(pkg, "/")+1:] + name
+		}
+		s.names[t] = name
+		// Reserved before the fields are walked, so types that refer to themselves stop here
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fields(t, object)
+	return object
+}
+
+// fields adds the exported fields of the struct to object, embedded structs are flattened like encoding/json does
+func (s *schemas) fields(t reflect.Type, object *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.fields(field.Type, object)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := s.schema(field.Type)
+		if field.Type.Kind() == reflect.Ptr && property.Ref == "" {
+			property.Nullable = true
+		}
+		if constrain(property, field.Tag.Get("binding")) {
+			object.Required = append(object.Required, name)
+		}
+		object.Properties[name] = property
+	}
+}
+
+// constrain turns the validator rules of a binding tag into schema constraints, and reports whether the
+// field is required. Rules with no counterpart in the schema are left to the validation error.
+func constrain(schema *Schema, binding string) (required bool) {
+	if binding == "" || schema.Ref != "" {
+		return binding != "" && strings.Contains(","+binding+",", ",required,")
+	}
+	for _, rule := range strings.Split(binding, ",") {
+		if rule == "dive" {
+			// The rules after dive are about the elements
+			break
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "timezone":
+			schema.Description = "IANA time zone, like Europe/Berlin"
+		case "datetime":
+			schema.Description = "Written in the Go layout " + param
+		case "min", "gte":
+			bound(schema, param, false)
+		case "max", "lte":
+			bound(schema, param, true)
+		case "gt":
+			bound(schema, param, false)
+			schema.ExclusiveMinimum = true
+		}
+	}
+	return required
+}
+
+// bound sets the lower or upper limit, which is a length for strings and a count for arrays
+func bound(schema *Schema, param string, upper bool) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	count := int(value)
+	switch schema.Type {
+	case "string":
+		if upper {
+			schema.MaxLength = &count
+		} else {
+			schema.MinLength = &count
+		}
+	case "array":
+		if upper {
+			schema.MaxItems = &count
+		} else {
+			schema.MinItems = &count
+		}
+	default:
+		if upper {
+			schema.Maximum = &value
+		} else {
+			schema.Minimum = &value
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsPage renders /openapi.json with Swagger UI, which the browser loads from a CDN
+//
+//go:embed docs.html
+var docsPage []byte
+
+// ServeSpec answers GET /openapi.json with the document itself, not wrapped in the response envelope
+func ServeSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
+
+// ServeDocs answers GET /docs with the interactive documentation
+func ServeDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
@@ -0,0 +1,261 @@
+// Package openapi describes the API as an OpenAPI 3 document. The routes are listed by hand in routes.go,
+// the schemas of their bodies are generated from the request and response types, binding tags included.
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	Handlers "GraduationProject.com/m/internal/handler"
+)
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"` // Path -> lower case method -> operation
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+const adminToken = "adminToken"
+
+var etagHeader = Header{Description: "The version of the entity, send it in If-Match to update it", Schema: &Schema{Type: "string"}}
+
+var (
+	spec     *Document
+	specOnce sync.Once
+)
+
+// Spec returns the document of every route in routes.go, it is built on the first call
+func Spec() *Document {
+	specOnce.Do(func() {
+		spec = Build(routes)
+	})
+	return spec
+}
+
+// Build describes the routes. Every JSON response is wrapped in the Response envelope, failures carry a code.
+func Build(routes []Route) *Document {
+	s := newSchemas()
+	envelope := s.of(Handlers.Response{})
+	failure := Response{Description: "The request failed, see code", Content: jsonContent(envelope)}
+
+	document := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "GraduationProject API",
+			Version:     "1.0.0",
+			Description: "Property rental API: users, properties, units, bookings, payments, reviews and messages.",
+		},
+		Paths: make(map[string]map[string]Operation),
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{adminToken: {Type: "apiKey", In: "header", Name: "X-Admin-Token"}},
+		},
+	}
+	for _, route := range routes {
+		path := Path(route.Path)
+		operation := Operation{
+			Tags:        []string{route.Tag},
+			Summary:     route.Summary,
+			OperationID: operationID(route.Method, path),
+			Parameters:  pathParameters(route.Path),
+			Responses:   map[string]Response{"default": failure},
+		}
+		operation.Parameters = append(operation.Parameters, route.Query...)
+		if route.Versioned && route.Method != http.MethodGet {
+			operation.Parameters = append(operation.Parameters, Parameter{
+				Name: "If-Match", In: "header",
+				Description: "The ETag the change is based on, the update is refused with 412 when the entity changed since",
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+		if route.Idempotent {
+			operation.Parameters = append(operation.Parameters, Parameter{
+				Name: "Idempotency-Key", In: "header",
+				Description: "Up to 255 printable ASCII characters, like a UUID. Retries with the key get the first response again instead of creating twice, for 24 hours by default.",
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+
+		switch {
+		case route.Body != nil && route.Method == http.MethodPatch:
+			schema := s.of(route.Body)
+			operation.RequestBody = &RequestBody{
+				Description: "A JSON Merge Patch (RFC 7396) of these fields. Fields that are left out keep their value and null clears one, required fields must still have a value after the patch.",
+				Required:    true,
+				Content:     map[string]MediaType{"application/merge-patch+json": {Schema: schema}, "application/json": {Schema: schema}},
+			}
+		case route.Body != nil:
+			operation.RequestBody = &RequestBody{Required: true, Content: jsonContent(s.of(route.Body))}
+		}
+		if len(route.Form) > 0 {
+			form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			for _, field := range route.Form {
+				form.Properties[field.Name] = field.Schema
+				if field.Required {
+					form.Required = append(form.Required, field.Name)
+				}
+			}
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/x-www-form-urlencoded": {Schema: form},
+				"multipart/form-data":               {Schema: form},
+			}}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		switch {
+		case route.ContentType != "":
+			success.Content = map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		case route.Data != nil:
+			success.Content = jsonContent(&Schema{AllOf: []*Schema{envelope, {
+				Type:       "object",
+				Properties: map[string]*Schema{"data": s.of(route.Data)},
+			}}})
+		default:
+			success.Content = jsonContent(envelope)
+		}
+		if route.Idempotent {
+			success.Headers = map[string]Header{"Idempotent-Replayed": {Description: "true when this is the stored response to an earlier request with the Idempotency-Key", Schema: &Schema{Type: "boolean"}}}
+		}
+		if route.Versioned {
+			success.Headers = map[string]Header{"ETag": etagHeader}
+		}
+		operation.Responses[strconv.Itoa(status)] = success
+
+		if operation.RequestBody != nil || len(route.Query) > 0 {
+			operation.Responses[strconv.Itoa(http.StatusBadRequest)] = Response{Description: "The request is not valid, details lists each field", Content: jsonContent(envelope)}
+		}
+		if strings.Contains(route.Path, "/:") {
+			operation.Responses[strconv.Itoa(http.StatusNotFound)] = Response{Description: "Not found", Content: jsonContent(envelope)}
+		}
+		if route.Idempotent {
+			operation.Responses[strconv.Itoa(http.StatusConflict)] = Response{Description: "The Idempotency-Key was used for a different request, or its first request is still running", Content: jsonContent(envelope)}
+		}
+		if route.Versioned && route.Method != http.MethodGet {
+			operation.Responses[strconv.Itoa(http.StatusPreconditionFailed)] = Response{
+				Description: "The entity changed since the version in If-Match, or while it was being updated",
+				Headers:     map[string]Header{"ETag": etagHeader},
+				Content:     jsonContent(envelope),
+			}
+		}
+		if route.RateLimited {
+			operation.Responses[strconv.Itoa(http.StatusTooManyRequests)] = Response{
+				Description: "Too many requests, or for logins too many failures with the email",
+				Headers:     map[string]Header{"Retry-After": {Description: "Seconds to wait before trying again", Schema: &Schema{Type: "integer"}}},
+				Content:     jsonContent(envelope),
+			}
+		}
+		if route.Admin {
+			operation.Security = []map[string][]string{{adminToken: {}}}
+			operation.Responses[strconv.Itoa(http.StatusUnauthorized)] = Response{Description: "X-Admin-Token is missing or wrong", Content: jsonContent(envelope)}
+		}
+
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]Operation)
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = operation
+	}
+	document.Components.Schemas = s.components
+	return document
+}
+
+// Has reports whether the document describes the route, path written the way gin registers it
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[Path(path)][strings.ToLower(method)]
+	return ok
+}
+
+// Path turns a gin path like /users/:id into an OpenAPI one like /users/{id}
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParameters(ginPath string) []Parameter {
+	var parameters []Parameter
+	for _, segment := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(segment, ":") {
+			parameters = append(parameters, Parameter{Name: segment[1:], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return parameters
+}
+
+// operationID is unique per route, like getUsersId for GET /users/{id}
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '.' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
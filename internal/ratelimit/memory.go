@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how often MemoryStore drops the buckets that refilled and the attempts that expired
+const sweepEvery = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // When the bucket is full again if nothing is taken, it can be dropped then
+}
+
+type attempts struct {
+	Attempts
+	expires time.Time
+}
+
+// MemoryStore keeps the state in the process, each instance of the app counts on its own
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	attempts  map[string]attempts
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		attempts:  make(map[string]attempts),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if limit.Unlimited() {
+		return Decision{Allowed: true}, nil
+	}
+	now := time.Now()
+	perToken := limit.Per / time.Duration(limit.Requests)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens += float64(now.Sub(b.updated)) / float64(perToken)
+	if b.tokens > float64(limit.Requests) {
+		b.tokens = float64(limit.Requests)
+	}
+	b.updated = now
+
+	decision := Decision{Allowed: b.tokens >= 1}
+	if decision.Allowed {
+		b.tokens--
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	b.full = now.Add(time.Duration((float64(limit.Requests) - b.tokens) * float64(perToken)))
+	return decision, nil
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, window time.Duration, wait func(Attempts) time.Duration) (time.Duration, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	a, ok := s.attempts[key]
+	if !ok || now.After(a.expires) {
+		a = attempts{}
+	}
+	if w := wait(a.Attempts); w > 0 {
+		return w, nil
+	}
+	a.Failures++
+	a.Last = now
+	a.expires = now.Add(window)
+	s.attempts[key] = a
+	return 0, nil
+}
+
+func (s *MemoryStore) Clear(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// sweep keeps the maps from growing with every client ever seen, s.mu must be held
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepEvery {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, a := range s.attempts {
+		if now.After(a.expires) {
+			delete(s.attempts, key)
+		}
+	}
+}
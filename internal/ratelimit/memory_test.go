@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// take waits for sleep, then takes a token and expects it to be allowed or not
+type take struct {
+	sleep   time.Duration
+	allowed bool
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		limit Limit
+		takes []take
+	}{
+		{
+			name:  "a burst up to the limit, then none",
+			limit: Limit{Requests: 3, Per: time.Hour},
+			takes: []take{{0, true}, {0, true}, {0, true}, {0, false}},
+		},
+		{
+			name:  "tokens come back as the bucket refills",
+			limit: Limit{Requests: 2, Per: 100 * time.Millisecond},
+			takes: []take{{0, true}, {0, true}, {0, false}, {60 * time.Millisecond, true}, {0, false}},
+		},
+		{
+			name:  "the bucket does not fill past the limit",
+			limit: Limit{Requests: 1, Per: 20 * time.Millisecond},
+			takes: []take{{0, true}, {100 * time.Millisecond, true}, {0, false}},
+		},
+		{
+			name:  "no limit",
+			limit: Limit{},
+			takes: []take{{0, true}, {0, true}, {0, true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, take := range tt.takes {
+				time.Sleep(take.sleep)
+				decision, err := store.Take(ctx, "key", tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if decision.Allowed != take.allowed {
+					t.Fatalf("take %d allowed %v, want %v", i, decision.Allowed, take.allowed)
+				}
+				perToken := tt.limit.Per / time.Duration(max(tt.limit.Requests, 1))
+				if !decision.Allowed && (decision.RetryAfter <= 0 || decision.RetryAfter > perToken) {
+					t.Errorf("take %d has to wait %s, want up to %s for the next token", i, decision.RetryAfter, perToken)
+				}
+			}
+		})
+	}
+}
+
+func TestKeysHaveTheirOwnBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Per: time.Hour}
+	for _, key := range []string{"a", "b"} {
+		if decision, _ := store.Take(context.Background(), key, limit); !decision.Allowed {
+			t.Errorf("the first take of %q was not allowed", key)
+		}
+	}
+	if decision, _ := store.Take(context.Background(), "a", limit); decision.Allowed {
+		t.Error("the second take of a was allowed")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	var seen []int
+	count := func(attempts Attempts) time.Duration {
+		seen = append(seen, attempts.Failures)
+		return 0
+	}
+	for i := 0; i < 3; i++ {
+		if wait, err := store.Reserve(ctx, "login", time.Hour, count); wait != 0 || err != nil {
+			t.Fatalf("got wait %s, %v, want the attempt to go ahead", wait, err)
+		}
+	}
+	refuse := func(Attempts) time.Duration { return time.Minute }
+	if wait, _ := store.Reserve(ctx, "login", time.Hour, refuse); wait != time.Minute {
+		t.Errorf("got wait %s, want a minute", wait)
+	}
+	store.Reserve(ctx, "login", time.Hour, count)
+	store.Clear(ctx, "login")
+	store.Reserve(ctx, "login", time.Hour, count)
+	// A refused attempt is not counted, and Clear forgets the ones before it
+	if want := []int{0, 1, 2, 3, 0}; fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("wait saw %v failures, want %v", seen, want)
+	}
+}
+
+func TestLockoutAttemptsAtOnce(t *testing.T) {
+	lockout := Lockout{Store: NewMemoryStore(), Threshold: 5, Duration: time.Hour, Delay: time.Minute}
+	const attempts = 20
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if wait, err := lockout.Attempt(context.Background(), "login"); err == nil && wait == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	// The first attempt makes the others wait out the delay, however close together they come
+	if allowed.Load() != 1 {
+		t.Errorf("%d of %d attempts at once went ahead, want 1", allowed.Load(), attempts)
+	}
+}
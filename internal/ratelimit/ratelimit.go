@@ -0,0 +1,91 @@
+// Package ratelimit keeps the token buckets of the rate limits and the failed attempts of the login lockout.
+// The state is behind Store: MemoryStore keeps it in the process, a store shared by every instance of the
+// app can take its place when there is more than one.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Requests at once, and Requests more every Per as the bucket refills. Zero Requests is no limit.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// Decision is the answer to taking a token, RetryAfter is how long until there is one when none was left
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Attempts are the recent failures on a key, like the logins to one account
+type Attempts struct {
+	Failures int
+	Last     time.Time
+}
+
+type Store interface {
+	// Take takes a token from the bucket of key, which starts full
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+	// Reserve counts an attempt on key as a failure before it is made, unless wait, given the failures so
+	// far, says it has to wait. It returns that wait, or zero when the attempt was counted. Checking and
+	// counting is one step, so attempts made at the same time cannot all go ahead. Failures are counted
+	// again from one when the previous one is older than window.
+	Reserve(ctx context.Context, key string, window time.Duration, wait func(Attempts) time.Duration) (time.Duration, error)
+	// Clear forgets the failures on key
+	Clear(ctx context.Context, key string) error
+}
+
+// Lockout slows down guessing passwords, then stops it for a while. After a failure the next attempt has to
+// wait Delay, doubled by every further failure. Threshold failures in a row lock the key for Duration,
+// after which its failures are forgotten.
+type Lockout struct {
+	Store     Store
+	Threshold int
+	Duration  time.Duration
+	Delay     time.Duration
+}
+
+// Attempt reserves an attempt on key and returns zero when it may go ahead, or how long it has to wait.
+// The attempt counts as a failure until Succeeded forgets it.
+func (l *Lockout) Attempt(ctx context.Context, key string) (time.Duration, error) {
+	return l.Store.Reserve(ctx, key, l.Duration, l.wait)
+}
+
+// wait is how long the attempt after attempts has to wait, zero when it may go ahead
+func (l *Lockout) wait(attempts Attempts) time.Duration {
+	if attempts.Failures == 0 {
+		return 0
+	}
+	wait := l.Duration
+	if attempts.Failures < l.Threshold {
+		wait = l.delay(attempts.Failures)
+	}
+	if remaining := time.Until(attempts.Last.Add(wait)); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// delay is the wait after failures short of the threshold, never longer than a lockout
+func (l *Lockout) delay(failures int) time.Duration {
+	delay := l.Delay
+	for i := 1; i < failures && delay < l.Duration; i++ {
+		delay *= 2
+	}
+	if delay > l.Duration {
+		return l.Duration
+	}
+	return delay
+}
+
+// Succeeded forgets the failures on key
+func (l *Lockout) Succeeded(ctx context.Context, key string) error {
+	return l.Store.Clear(ctx, key)
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// auditLimit caps how many entries List returns when the filter does not say
+const auditLimit = 100
+
+type SQLAuditRepository struct {
+	db *sql.DB
+}
+
+func NewSQLAuditRepository(db *sql.DB) *SQLAuditRepository {
+	return &SQLAuditRepository{db: db}
+}
+
+func (repo *SQLAuditRepository) Append(ctx context.Context, entry *Entities.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	entry.CreateTime = time.Now().UTC()
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO AuditLog (Actor, EntityType, EntityID, Action, Changes, RequestID, CreateTime) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Actor, entry.EntityType, entry.EntityID, entry.Action, string(changes), entry.RequestID, entry.CreateTime)
+	if err != nil {
+		return err
+	}
+	entry.AuditID, err = insertedID(result)
+	return err
+}
+
+func (repo *SQLAuditRepository) List(ctx context.Context, filter AuditFilter) ([]Entities.AuditEntry, error) {
+	query := `SELECT AuditID, Actor, EntityType, EntityID, Action, Changes, RequestID, CreateTime FROM AuditLog WHERE 1 = 1`
+	var args []interface{}
+	where := func(column, value string) {
+		if value != "" {
+			query += ` AND ` + column + ` = ?`
+			args = append(args, value)
+		}
+	}
+	where("EntityType", filter.EntityType)
+	where("EntityID", filter.EntityID)
+	where("Actor", filter.Actor)
+	if filter.Limit <= 0 {
+		filter.Limit = auditLimit
+	}
+	query += ` ORDER BY AuditID DESC LIMIT ? OFFSET ?`
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []Entities.AuditEntry{}
+	for rows.Next() {
+		var entry Entities.AuditEntry
+		var changes string
+		if err := rows.Scan(&entry.AuditID, &entry.Actor, &entry.EntityType, &entry.EntityID, &entry.Action, &changes, &entry.RequestID, &entry.CreateTime); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
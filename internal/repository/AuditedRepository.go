@@ -0,0 +1,493 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"GraduationProject.com/m/internal/audit"
+	"GraduationProject.com/m/internal/logging"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// auditor appends an entry to the audit log for every write that succeeded. Writes that record the entity
+// before and after run in one transaction of tx with those reads and the entry, so no other write can come
+// between them. A failure to record the entry is logged rather than returned, the write goes through without it.
+type auditor struct {
+	log AuditRepository
+	tx  Transactor
+}
+
+// atomically runs fn in a transaction of tx, or just runs it when the repositories have no transactions
+func (a auditor) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.tx == nil {
+		return fn(ctx)
+	}
+	return a.tx.InTx(ctx, fn)
+}
+
+func (a auditor) record(ctx context.Context, entityType, entityID, action string, before, after interface{}) {
+	changes, err := audit.Diff(before, after)
+	if err == nil {
+		origin := audit.OriginFrom(ctx)
+		// The write went through, so its entry should too even when the client has gone away
+		err = a.log.Append(context.WithoutCancel(ctx), &Entities.AuditEntry{
+			Actor:      origin.Actor,
+			EntityType: entityType,
+			EntityID:   entityID,
+			Action:     action,
+			Changes:    changes,
+			RequestID:  origin.RequestID,
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to audit a write", slog.String("action", action), slog.String("entityType", entityType),
+			slog.String("entityID", entityID), slog.Any("error", err))
+	}
+}
+
+// snapshot returns the entity as get finds it, or nil when it does not, like before a restore or after a delete
+func snapshot[T any](ctx context.Context, get func(context.Context, string) (T, error), id string) interface{} {
+	entity, err := get(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// audited runs write and records it with the entity as get finds it before and after, in one transaction
+func audited[T any](ctx context.Context, a auditor, entityType, entityID, action string, get func(context.Context, string) (T, error), write func(ctx context.Context) error) error {
+	return a.atomically(ctx, func(ctx context.Context) error {
+		before := snapshot(ctx, get, entityID)
+		if err := write(ctx); err != nil {
+			return err
+		}
+		a.record(ctx, entityType, entityID, action, before, snapshot(ctx, get, entityID))
+		return nil
+	})
+}
+
+// created runs create and records the entity as it was stored, under the ID create sets in id
+func created[T any](ctx context.Context, a auditor, entityType string, id *string, get func(context.Context, string) (T, error), create func(ctx context.Context) error) error {
+	return a.atomically(ctx, func(ctx context.Context) error {
+		if err := create(ctx); err != nil {
+			return err
+		}
+		a.record(ctx, entityType, *id, Entities.AuditCreate, nil, snapshot(ctx, get, *id))
+		return nil
+	})
+}
+
+// alongside wraps write to also record action on every entity that list finds before it and not after, or
+// after it and not before: the ones a delete, archive or restore takes along with the entity it is made on
+func alongside[T any](a auditor, entityType, action string, list func(context.Context) ([]T, error), id func(T) string, write func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		before, err := list(ctx)
+		if err != nil {
+			return err
+		}
+		if err := write(ctx); err != nil {
+			return err
+		}
+		after, err := list(ctx)
+		if err != nil {
+			return err
+		}
+		listed := func(entities []T) map[string]bool {
+			ids := make(map[string]bool, len(entities))
+			for _, entity := range entities {
+				ids[id(entity)] = true
+			}
+			return ids
+		}
+		existed, exists := listed(before), listed(after)
+		for _, entity := range before {
+			if !exists[id(entity)] {
+				a.record(ctx, entityType, id(entity), action, entity, nil)
+			}
+		}
+		for _, entity := range after {
+			if !existed[id(entity)] {
+				a.record(ctx, entityType, id(entity), action, nil, entity)
+			}
+		}
+		return nil
+	}
+}
+
+func idOfUnit(unit Entities.Unit) string {
+	return unit.UnitID
+}
+
+func idOfProperty(property Entities.Property) string {
+	return property.PropertyID
+}
+
+// auditedUserRepository also records the properties and units that a delete or restore of a user takes along
+type auditedUserRepository struct {
+	UserRepository
+	properties PropertyRepository
+	units      UnitRepository
+	auditor
+}
+
+// withProperties wraps write to record the user's properties and units it deletes or restores
+func (repo *auditedUserRepository) withProperties(userID, action string, write func(ctx context.Context) error) func(ctx context.Context) error {
+	properties := func(ctx context.Context) ([]Entities.Property, error) {
+		return repo.properties.ListByOwner(ctx, userID)
+	}
+	units := func(ctx context.Context) ([]Entities.Unit, error) { return repo.units.ListByOwner(ctx, userID) }
+	return alongside(repo.auditor, "Property", action, properties, idOfProperty, alongside(repo.auditor, "Unit", action, units, idOfUnit, write))
+}
+
+func (repo *auditedUserRepository) Create(ctx context.Context, user *Entities.User) error {
+	return created(ctx, repo.auditor, "User", &user.UserID, repo.GetByID, func(ctx context.Context) error {
+		return repo.UserRepository.Create(ctx, user)
+	})
+}
+
+func (repo *auditedUserRepository) Update(ctx context.Context, user Entities.User) error {
+	return audited(ctx, repo.auditor, "User", user.UserID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.UserRepository.Update(ctx, user)
+	})
+}
+
+func (repo *auditedUserRepository) Delete(ctx context.Context, userID string) error {
+	return audited(ctx, repo.auditor, "User", userID, Entities.AuditDelete, repo.GetByID, repo.withProperties(userID, Entities.AuditDelete, func(ctx context.Context) error {
+		return repo.UserRepository.Delete(ctx, userID)
+	}))
+}
+
+func (repo *auditedUserRepository) Restore(ctx context.Context, userID string) error {
+	return audited(ctx, repo.auditor, "User", userID, Entities.AuditRestore, repo.GetByID, repo.withProperties(userID, Entities.AuditRestore, func(ctx context.Context) error {
+		return repo.UserRepository.Restore(ctx, userID)
+	}))
+}
+
+type auditedUnitRepository struct {
+	UnitRepository
+	auditor
+}
+
+func (repo *auditedUnitRepository) Create(ctx context.Context, unit *Entities.Unit) error {
+	return created(ctx, repo.auditor, "Unit", &unit.UnitID, repo.GetByID, func(ctx context.Context) error {
+		return repo.UnitRepository.Create(ctx, unit)
+	})
+}
+
+func (repo *auditedUnitRepository) Update(ctx context.Context, unit Entities.Unit) error {
+	return audited(ctx, repo.auditor, "Unit", unit.UnitID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.UnitRepository.Update(ctx, unit)
+	})
+}
+
+func (repo *auditedUnitRepository) Delete(ctx context.Context, unitID string) error {
+	return audited(ctx, repo.auditor, "Unit", unitID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.UnitRepository.Delete(ctx, unitID)
+	})
+}
+
+func (repo *auditedUnitRepository) Restore(ctx context.Context, unitID string) error {
+	return audited(ctx, repo.auditor, "Unit", unitID, Entities.AuditRestore, repo.GetByID, func(ctx context.Context) error {
+		return repo.UnitRepository.Restore(ctx, unitID)
+	})
+}
+
+// SaveImages records how many images were saved, not the images themselves
+func (repo *auditedUnitRepository) SaveImages(ctx context.Context, unitID string, images []string) error {
+	if err := repo.UnitRepository.SaveImages(ctx, unitID, images); err != nil {
+		return err
+	}
+	repo.record(ctx, "Unit", unitID, Entities.AuditUpdate, nil, map[string]int{"savedImages": len(images)})
+	return nil
+}
+
+// auditedPropertyRepository also records the units that a delete, archive or restore of a property takes along
+type auditedPropertyRepository struct {
+	PropertyRepository
+	units UnitRepository
+	auditor
+}
+
+// withUnits wraps write to record the units of the property it deletes, archives or restores
+func (repo *auditedPropertyRepository) withUnits(propertyID, action string, write func(ctx context.Context) error) func(ctx context.Context) error {
+	units := func(ctx context.Context) ([]Entities.Unit, error) { return repo.units.ListByProperty(ctx, propertyID) }
+	return alongside(repo.auditor, "Unit", action, units, idOfUnit, write)
+}
+
+func (repo *auditedPropertyRepository) Create(ctx context.Context, property *Entities.Property) error {
+	return created(ctx, repo.auditor, "Property", &property.PropertyID, repo.GetByID, func(ctx context.Context) error {
+		return repo.PropertyRepository.Create(ctx, property)
+	})
+}
+
+func (repo *auditedPropertyRepository) Update(ctx context.Context, property Entities.Property) error {
+	return audited(ctx, repo.auditor, "Property", property.PropertyID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.PropertyRepository.Update(ctx, property)
+	})
+}
+
+func (repo *auditedPropertyRepository) Delete(ctx context.Context, propertyID string) error {
+	return audited(ctx, repo.auditor, "Property", propertyID, Entities.AuditDelete, repo.GetByID, repo.withUnits(propertyID, Entities.AuditDelete, func(ctx context.Context) error {
+		return repo.PropertyRepository.Delete(ctx, propertyID)
+	}))
+}
+
+func (repo *auditedPropertyRepository) Restore(ctx context.Context, propertyID string) error {
+	return audited(ctx, repo.auditor, "Property", propertyID, Entities.AuditRestore, repo.GetByID, repo.withUnits(propertyID, Entities.AuditRestore, func(ctx context.Context) error {
+		return repo.PropertyRepository.Restore(ctx, propertyID)
+	}))
+}
+
+func (repo *auditedPropertyRepository) Archive(ctx context.Context, propertyID string) error {
+	return audited(ctx, repo.auditor, "Property", propertyID, Entities.AuditArchive, repo.GetByID, repo.withUnits(propertyID, Entities.AuditArchive, func(ctx context.Context) error {
+		return repo.PropertyRepository.Archive(ctx, propertyID)
+	}))
+}
+
+func (repo *auditedPropertyRepository) SaveProof(ctx context.Context, propertyID string, url string) error {
+	if err := repo.PropertyRepository.SaveProof(ctx, propertyID, url); err != nil {
+		return err
+	}
+	repo.record(ctx, "Property", propertyID, Entities.AuditUpdate, nil, map[string]string{"proof": url})
+	return nil
+}
+
+type auditedBookingRepository struct {
+	BookingRepository
+	auditor
+}
+
+func (repo *auditedBookingRepository) Create(ctx context.Context, booking *Entities.Booking) error {
+	return created(ctx, repo.auditor, "Booking", &booking.BookingID, repo.GetByID, func(ctx context.Context) error {
+		return repo.BookingRepository.Create(ctx, booking)
+	})
+}
+
+func (repo *auditedBookingRepository) Update(ctx context.Context, booking Entities.Booking) error {
+	return audited(ctx, repo.auditor, "Booking", booking.BookingID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.BookingRepository.Update(ctx, booking)
+	})
+}
+
+func (repo *auditedBookingRepository) Delete(ctx context.Context, bookingID string) error {
+	return audited(ctx, repo.auditor, "Booking", bookingID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.BookingRepository.Delete(ctx, bookingID)
+	})
+}
+
+type auditedReviewRepository struct {
+	ReviewRepository
+	auditor
+}
+
+func (repo *auditedReviewRepository) Create(ctx context.Context, review *Entities.Review) error {
+	return created(ctx, repo.auditor, "Review", &review.ReviewID, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReviewRepository.Create(ctx, review)
+	})
+}
+
+func (repo *auditedReviewRepository) Update(ctx context.Context, review Entities.Review) error {
+	return audited(ctx, repo.auditor, "Review", review.ReviewID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReviewRepository.Update(ctx, review)
+	})
+}
+
+func (repo *auditedReviewRepository) Delete(ctx context.Context, reviewID string) error {
+	return audited(ctx, repo.auditor, "Review", reviewID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReviewRepository.Delete(ctx, reviewID)
+	})
+}
+
+type auditedMessageRepository struct {
+	MessageRepository
+	auditor
+}
+
+// GetOrCreateChat records the chat when it creates one, without its messages
+func (repo *auditedMessageRepository) GetOrCreateChat(ctx context.Context, senderID, receiverID string) (Entities.Chat, bool, error) {
+	chat, created, err := repo.MessageRepository.GetOrCreateChat(ctx, senderID, receiverID)
+	if err == nil && created {
+		stored := chat
+		stored.Messages = nil
+		repo.record(ctx, "Chat", chat.ChatID, Entities.AuditCreate, nil, stored)
+	}
+	return chat, created, err
+}
+
+func (repo *auditedMessageRepository) CreateMessage(ctx context.Context, message *Entities.Message) error {
+	if err := repo.MessageRepository.CreateMessage(ctx, message); err != nil {
+		return err
+	}
+	repo.record(ctx, "Message", message.MessageID, Entities.AuditCreate, nil, *message)
+	return nil
+}
+
+type auditedTransactionRepository struct {
+	TransactionRepository
+	auditor
+}
+
+func (repo *auditedTransactionRepository) Create(ctx context.Context, transaction *Entities.FinancialTransaction) error {
+	return created(ctx, repo.auditor, "FinancialTransaction", &transaction.TransactionID, repo.GetByID, func(ctx context.Context) error {
+		return repo.TransactionRepository.Create(ctx, transaction)
+	})
+}
+
+func (repo *auditedTransactionRepository) Update(ctx context.Context, transaction Entities.FinancialTransaction) error {
+	return audited(ctx, repo.auditor, "FinancialTransaction", transaction.TransactionID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.TransactionRepository.Update(ctx, transaction)
+	})
+}
+
+func (repo *auditedTransactionRepository) Delete(ctx context.Context, transactionID string) error {
+	return audited(ctx, repo.auditor, "FinancialTransaction", transactionID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.TransactionRepository.Delete(ctx, transactionID)
+	})
+}
+
+type auditedTicketRepository struct {
+	TicketRepository
+	auditor
+}
+
+func (repo *auditedTicketRepository) Create(ctx context.Context, ticket *Entities.MaintenanceTicket) error {
+	return created(ctx, repo.auditor, "MaintenanceTicket", &ticket.TicketID, repo.GetByID, func(ctx context.Context) error {
+		return repo.TicketRepository.Create(ctx, ticket)
+	})
+}
+
+func (repo *auditedTicketRepository) Update(ctx context.Context, ticket Entities.MaintenanceTicket) error {
+	return audited(ctx, repo.auditor, "MaintenanceTicket", ticket.TicketID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.TicketRepository.Update(ctx, ticket)
+	})
+}
+
+func (repo *auditedTicketRepository) Delete(ctx context.Context, ticketID string) error {
+	return audited(ctx, repo.auditor, "MaintenanceTicket", ticketID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.TicketRepository.Delete(ctx, ticketID)
+	})
+}
+
+type auditedReportRepository struct {
+	ReportRepository
+	auditor
+}
+
+func (repo *auditedReportRepository) Create(ctx context.Context, report *Entities.Report) error {
+	return created(ctx, repo.auditor, "Report", &report.ReportID, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReportRepository.Create(ctx, report)
+	})
+}
+
+func (repo *auditedReportRepository) Update(ctx context.Context, report Entities.Report) error {
+	return audited(ctx, repo.auditor, "Report", report.ReportID, Entities.AuditUpdate, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReportRepository.Update(ctx, report)
+	})
+}
+
+func (repo *auditedReportRepository) Delete(ctx context.Context, reportID string) error {
+	return audited(ctx, repo.auditor, "Report", reportID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.ReportRepository.Delete(ctx, reportID)
+	})
+}
+
+type auditedWishlistRepository struct {
+	WishlistRepository
+	auditor
+}
+
+func (repo *auditedWishlistRepository) Create(ctx context.Context, wishlist *Entities.Wishlist) error {
+	return created(ctx, repo.auditor, "Wishlist", &wishlist.WishlistID, repo.GetByID, func(ctx context.Context) error {
+		return repo.WishlistRepository.Create(ctx, wishlist)
+	})
+}
+
+// update records a change to the wishlist or the units on it
+func (repo *auditedWishlistRepository) update(ctx context.Context, wishlistID string, write func(ctx context.Context) error) error {
+	return audited(ctx, repo.auditor, "Wishlist", wishlistID, Entities.AuditUpdate, repo.GetByID, write)
+}
+
+func (repo *auditedWishlistRepository) Rename(ctx context.Context, wishlistID, name string) error {
+	return repo.update(ctx, wishlistID, func(ctx context.Context) error { return repo.WishlistRepository.Rename(ctx, wishlistID, name) })
+}
+
+func (repo *auditedWishlistRepository) Delete(ctx context.Context, wishlistID string) error {
+	return audited(ctx, repo.auditor, "Wishlist", wishlistID, Entities.AuditDelete, repo.GetByID, func(ctx context.Context) error {
+		return repo.WishlistRepository.Delete(ctx, wishlistID)
+	})
+}
+
+func (repo *auditedWishlistRepository) AddUnit(ctx context.Context, wishlistID, unitID string) error {
+	return repo.update(ctx, wishlistID, func(ctx context.Context) error { return repo.WishlistRepository.AddUnit(ctx, wishlistID, unitID) })
+}
+
+func (repo *auditedWishlistRepository) RemoveUnit(ctx context.Context, wishlistID, unitID string) error {
+	return repo.update(ctx, wishlistID, func(ctx context.Context) error { return repo.WishlistRepository.RemoveUnit(ctx, wishlistID, unitID) })
+}
+
+func (repo *auditedWishlistRepository) SetShareToken(ctx context.Context, wishlistID, token string) error {
+	return repo.update(ctx, wishlistID, func(ctx context.Context) error { return repo.WishlistRepository.SetShareToken(ctx, wishlistID, token) })
+}
+
+type auditedSavedSearchRepository struct {
+	SavedSearchRepository
+	auditor
+}
+
+func (repo *auditedSavedSearchRepository) Create(ctx context.Context, search *Entities.SavedSearch) error {
+	if err := repo.SavedSearchRepository.Create(ctx, search); err != nil {
+		return err
+	}
+	repo.record(ctx, "SavedSearch", search.SavedSearchID, Entities.AuditCreate, nil, *search)
+	return nil
+}
+
+// Delete records no changes, saved searches cannot be looked up by ID to keep a copy
+func (repo *auditedSavedSearchRepository) Delete(ctx context.Context, savedSearchID string) error {
+	if err := repo.SavedSearchRepository.Delete(ctx, savedSearchID); err != nil {
+		return err
+	}
+	repo.record(ctx, "SavedSearch", savedSearchID, Entities.AuditDelete, nil, nil)
+	return nil
+}
+
+type auditedNotificationRepository struct {
+	NotificationRepository
+	auditor
+}
+
+func (repo *auditedNotificationRepository) Create(ctx context.Context, notification *Entities.Notification) error {
+	if err := repo.NotificationRepository.Create(ctx, notification); err != nil {
+		return err
+	}
+	repo.record(ctx, "Notification", notification.NotificationID, Entities.AuditCreate, nil, *notification)
+	return nil
+}
+
+func (repo *auditedNotificationRepository) MarkRead(ctx context.Context, notificationID string) error {
+	if err := repo.NotificationRepository.MarkRead(ctx, notificationID); err != nil {
+		return err
+	}
+	repo.record(ctx, "Notification", notificationID, Entities.AuditUpdate, nil, map[string]bool{"read": true})
+	return nil
+}
+
+// WithAudit records every write made through the returned repositories in repos.Audit, with the actor and
+// request ID from the context. Put it in front of the database and behind WithCache, so before and after
+// are read from the database.
+func WithAudit(repos Repositories) Repositories {
+	a := auditor{log: repos.Audit, tx: repos.Transactor}
+	repos.Users = &auditedUserRepository{repos.Users, repos.Properties, repos.Units, a}
+	repos.Properties = &auditedPropertyRepository{repos.Properties, repos.Units, a}
+	repos.Units = &auditedUnitRepository{repos.Units, a}
+	repos.Bookings = &auditedBookingRepository{repos.Bookings, a}
+	repos.Reviews = &auditedReviewRepository{repos.Reviews, a}
+	repos.Messages = &auditedMessageRepository{repos.Messages, a}
+	repos.Transactions = &auditedTransactionRepository{repos.Transactions, a}
+	repos.Tickets = &auditedTicketRepository{repos.Tickets, a}
+	repos.Reports = &auditedReportRepository{repos.Reports, a}
+	repos.Wishlists = &auditedWishlistRepository{repos.Wishlists, a}
+	repos.SavedSearches = &auditedSavedSearchRepository{repos.SavedSearches, a}
+	repos.Notifications = &auditedNotificationRepository{repos.Notifications, a}
+	return repos
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLBookingRepository struct {
+	db *sql.DB
+}
+
+func NewSQLBookingRepository(db *sql.DB) *SQLBookingRepository {
+	return &SQLBookingRepository{db: db}
+}
+
+const bookingQuery = `SELECT b.BookingID, b.UnitID, b.UserID, b.CheckInDate, b.CheckOutDate, b.EndDate, b.CreateTime, b.StartDate, b.Summary, b.Version FROM Booking b`
+
+func scanBooking(row scanner) (Entities.Booking, error) {
+	var booking Entities.Booking
+	err := row.Scan(&booking.BookingID, &booking.UnitID, &booking.UserID, &booking.CheckIn, &booking.CheckOut, &booking.EndDate, &booking.CreateTime, &booking.StartDate, (*nullableString)(&booking.Summary), &booking.Version)
+	return booking, err
+}
+
+func (repo *SQLBookingRepository) query(ctx context.Context, where string, args ...interface{}) ([]Entities.Booking, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, bookingQuery+where+` ORDER BY b.StartDate, b.BookingID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	bookings := []Entities.Booking{}
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+func (repo *SQLBookingRepository) GetByID(ctx context.Context, bookingID string) (Entities.Booking, error) {
+	booking, err := scanBooking(conn(ctx, repo.db).QueryRowContext(ctx, bookingQuery+` WHERE b.BookingID = ?`, bookingID))
+	return booking, notFound(err)
+}
+
+func (repo *SQLBookingRepository) ListByUnit(ctx context.Context, unitID string) ([]Entities.Booking, error) {
+	return repo.query(ctx, ` WHERE b.UnitID = ?`, unitID)
+}
+
+func (repo *SQLBookingRepository) ListByUser(ctx context.Context, userID string) ([]Entities.Booking, error) {
+	return repo.query(ctx, ` WHERE b.UserID = ?`, userID)
+}
+
+func (repo *SQLBookingRepository) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Booking, error) {
+	return repo.query(ctx, ` JOIN Unit u ON b.UnitID = u.UnitID JOIN Property p ON u.PropertyID = p.PropertyID WHERE p.OwnerID = ?`, ownerID)
+}
+
+// reserve locks the booking's user and unit, the rows that deleting either of them locks before it checks for
+// upcoming bookings, then makes sure the unit is still there and the dates are free
+func reserve(ctx context.Context, tx *sql.Tx, booking Entities.Booking) error {
+	if err := lockRows(ctx, tx, "User", "UserID", `UserID = ?`, booking.UserID); err != nil {
+		return err
+	}
+	if err := lockRows(ctx, tx, "Unit", "UnitID", `UnitID = ?`, booking.UnitID); err != nil {
+		return err
+	}
+	var deleteTime sql.NullTime
+	if err := tx.QueryRowContext(ctx, `SELECT DeleteTime FROM Unit WHERE UnitID = ?`, booking.UnitID).Scan(&deleteTime); err != nil {
+		return notFound(err)
+	}
+	if deleteTime.Valid {
+		return ErrNotFound
+	}
+	// A new booking has no ID yet and excludes nothing, a changed one does not overlap itself
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM Booking WHERE UnitID = ? AND StartDate < ? AND EndDate > ? AND BookingID <> ?`,
+		booking.UnitID, booking.EndDate.UTC(), booking.StartDate.UTC(), booking.BookingID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrOverlap
+	}
+	return nil
+}
+
+func (repo *SQLBookingRepository) Create(ctx context.Context, booking *Entities.Booking) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := reserve(ctx, tx, *booking); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO Booking (UnitID, UserID, CheckInDate, CheckOutDate, EndDate, StartDate, Summary) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			booking.UnitID, booking.UserID, booking.CheckIn, booking.CheckOut, booking.EndDate.UTC(), booking.StartDate.UTC(), booking.Summary)
+		if err != nil {
+			return err
+		}
+		booking.BookingID, err = insertedID(result)
+		booking.Version = 1
+		return err
+	})
+}
+
+func (repo *SQLBookingRepository) Update(ctx context.Context, booking Entities.Booking) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := reserve(ctx, tx, booking); err != nil {
+			return err
+		}
+		return versioned(tx.ExecContext(ctx, `UPDATE Booking SET CheckInDate = ?, CheckOutDate = ?, StartDate = ?, EndDate = ?, Summary = ?, Version = Version + 1 WHERE BookingID = ? AND Version = ?`,
+			booking.CheckIn, booking.CheckOut, booking.StartDate.UTC(), booking.EndDate.UTC(), booking.Summary, booking.BookingID, booking.Version))
+	})
+}
+
+func (repo *SQLBookingRepository) Delete(ctx context.Context, bookingID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM Booking WHERE BookingID = ?`, bookingID))
+}
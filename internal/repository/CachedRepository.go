@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+
+	"GraduationProject.com/m/internal/cache"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// CachedUnitRepository serves GetByID from memory, every write through it invalidates the unit. The address
+// of a unit is its property's, so a unit update that changes it invalidates the property and its units too.
+type CachedUnitRepository struct {
+	UnitRepository
+	cache      *cache.Cache[Entities.Unit]
+	properties *cache.Cache[Entities.Property]
+}
+
+func (repo *CachedUnitRepository) GetByID(ctx context.Context, unitID string) (Entities.Unit, error) {
+	return repo.cache.GetOrLoad(ctx, unitID, func(ctx context.Context) (Entities.Unit, error) {
+		return repo.UnitRepository.GetByID(ctx, unitID)
+	})
+}
+
+func (repo *CachedUnitRepository) Create(ctx context.Context, unit *Entities.Unit) error {
+	err := repo.UnitRepository.Create(ctx, unit)
+	repo.cache.Delete(unit.UnitID)
+	return err
+}
+
+func (repo *CachedUnitRepository) Update(ctx context.Context, unit Entities.Unit) error {
+	// Without the unit in the cache there is nothing to compare with, the address may have changed
+	previous, cached := repo.cache.Get(unit.UnitID)
+	before, after := previous.Address, unit.Address
+	before.AddressID, after.AddressID = "", ""
+	if !cached || before != after || previous.PropertyID != unit.PropertyID {
+		defer repo.invalidateProperty(previous.PropertyID, unit.PropertyID)
+	}
+	defer repo.cache.Delete(unit.UnitID)
+	return repo.UnitRepository.Update(ctx, unit)
+}
+
+// invalidateProperty drops the given properties and every cached unit, since no unit can be told apart by
+// its property without loading it
+func (repo *CachedUnitRepository) invalidateProperty(propertyIDs ...string) {
+	for _, propertyID := range propertyIDs {
+		repo.properties.Delete(propertyID)
+	}
+	repo.cache.Clear()
+}
+
+func (repo *CachedUnitRepository) Delete(ctx context.Context, unitID string) error {
+	defer repo.cache.Delete(unitID)
+	return repo.UnitRepository.Delete(ctx, unitID)
+}
+
+func (repo *CachedUnitRepository) Restore(ctx context.Context, unitID string) error {
+	defer repo.cache.Delete(unitID)
+	return repo.UnitRepository.Restore(ctx, unitID)
+}
+
+func (repo *CachedUnitRepository) SaveImages(ctx context.Context, unitID string, images []string) error {
+	defer repo.cache.Delete(unitID)
+	return repo.UnitRepository.SaveImages(ctx, unitID, images)
+}
+
+// CachedPropertyRepository serves GetByID from memory. Units carry the type and address of their property,
+// so writes to a property invalidate the cached units too.
+type CachedPropertyRepository struct {
+	PropertyRepository
+	cache *cache.Cache[Entities.Property]
+	units *cache.Cache[Entities.Unit]
+}
+
+func (repo *CachedPropertyRepository) GetByID(ctx context.Context, propertyID string) (Entities.Property, error) {
+	return repo.cache.GetOrLoad(ctx, propertyID, func(ctx context.Context) (Entities.Property, error) {
+		return repo.PropertyRepository.GetByID(ctx, propertyID)
+	})
+}
+
+func (repo *CachedPropertyRepository) invalidate(propertyID string) {
+	repo.cache.Delete(propertyID)
+	repo.units.Clear()
+}
+
+func (repo *CachedPropertyRepository) Create(ctx context.Context, property *Entities.Property) error {
+	err := repo.PropertyRepository.Create(ctx, property)
+	repo.cache.Delete(property.PropertyID)
+	return err
+}
+
+func (repo *CachedPropertyRepository) Update(ctx context.Context, property Entities.Property) error {
+	defer repo.invalidate(property.PropertyID)
+	return repo.PropertyRepository.Update(ctx, property)
+}
+
+func (repo *CachedPropertyRepository) Delete(ctx context.Context, propertyID string) error {
+	defer repo.invalidate(propertyID)
+	return repo.PropertyRepository.Delete(ctx, propertyID)
+}
+
+func (repo *CachedPropertyRepository) Restore(ctx context.Context, propertyID string) error {
+	defer repo.invalidate(propertyID)
+	return repo.PropertyRepository.Restore(ctx, propertyID)
+}
+
+func (repo *CachedPropertyRepository) Archive(ctx context.Context, propertyID string) error {
+	defer repo.invalidate(propertyID)
+	return repo.PropertyRepository.Archive(ctx, propertyID)
+}
+
+// cachedUserRepository invalidates the cached units when a user changes, since units carry their owner's name.
+// Deleting and restoring a user does the same to their properties.
+type cachedUserRepository struct {
+	UserRepository
+	units      *cache.Cache[Entities.Unit]
+	properties *cache.Cache[Entities.Property]
+}
+
+func (repo *cachedUserRepository) Update(ctx context.Context, user Entities.User) error {
+	defer repo.units.Clear()
+	return repo.UserRepository.Update(ctx, user)
+}
+
+func (repo *cachedUserRepository) Delete(ctx context.Context, userID string) error {
+	defer repo.invalidate()
+	return repo.UserRepository.Delete(ctx, userID)
+}
+
+func (repo *cachedUserRepository) Restore(ctx context.Context, userID string) error {
+	defer repo.invalidate()
+	return repo.UserRepository.Restore(ctx, userID)
+}
+
+func (repo *cachedUserRepository) invalidate() {
+	repo.units.Clear()
+	repo.properties.Clear()
+}
+
+// WithCache puts a read cache in front of the unit and property lookups by ID.
+// Writes only invalidate it when they go through the returned repositories.
+func WithCache(repos Repositories, options cache.Options) Repositories {
+	units := cache.New[Entities.Unit]("units", options)
+	properties := cache.New[Entities.Property]("properties", options)
+	repos.Units = &CachedUnitRepository{UnitRepository: repos.Units, cache: units, properties: properties}
+	repos.Properties = &CachedPropertyRepository{PropertyRepository: repos.Properties, cache: properties, units: units}
+	repos.Users = &cachedUserRepository{UserRepository: repos.Users, units: units, properties: properties}
+	return repos
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLIdempotencyRepository(db *sql.DB) *SQLIdempotencyRepository {
+	return &SQLIdempotencyRepository{db: db}
+}
+
+// Reserve relies on the primary key, of two requests racing with the same key only one inserts it
+func (repo *SQLIdempotencyRepository) Reserve(ctx context.Context, key *Entities.IdempotencyKey) (Entities.IdempotencyKey, error) {
+	// Whole seconds compare the same way in both dialects
+	now := time.Now().UTC().Truncate(time.Second)
+	if _, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM IdempotencyKey WHERE Actor = ? AND IdempotencyKey = ? AND ExpireTime <= ?`, key.Actor, key.Key, now); err != nil {
+		return Entities.IdempotencyKey{}, err
+	}
+	key.CreateTime = now
+	key.ExpireTime = key.ExpireTime.UTC().Truncate(time.Second)
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO IdempotencyKey (Actor, IdempotencyKey, Route, RequestHash, CreateTime, ExpireTime) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.Actor, key.Key, key.Route, key.RequestHash, key.CreateTime, key.ExpireTime)
+	if err = duplicate(err); err != ErrDuplicate {
+		return Entities.IdempotencyKey{}, err
+	}
+
+	var stored Entities.IdempotencyKey
+	err = conn(ctx, repo.db).QueryRowContext(ctx, `SELECT Actor, IdempotencyKey, Route, RequestHash, Status, ContentType, Body, CreateTime, ExpireTime FROM IdempotencyKey WHERE Actor = ? AND IdempotencyKey = ?`,
+		key.Actor, key.Key).Scan(&stored.Actor, &stored.Key, &stored.Route, &stored.RequestHash, &stored.Status, &stored.ContentType, &stored.Body, &stored.CreateTime, &stored.ExpireTime)
+	if err != nil {
+		return stored, notFound(err)
+	}
+	return stored, ErrDuplicate
+}
+
+func (repo *SQLIdempotencyRepository) Complete(ctx context.Context, key Entities.IdempotencyKey) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `UPDATE IdempotencyKey SET Status = ?, ContentType = ?, Body = ? WHERE Actor = ? AND IdempotencyKey = ?`,
+		key.Status, key.ContentType, key.Body, key.Actor, key.Key)
+	return affectedOne(result, err)
+}
+
+func (repo *SQLIdempotencyRepository) Release(ctx context.Context, actor, key string) error {
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM IdempotencyKey WHERE Actor = ? AND IdempotencyKey = ?`, actor, key)
+	return err
+}
+
+func (repo *SQLIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM IdempotencyKey WHERE ExpireTime <= ?`, before.UTC().Truncate(time.Second))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLMessageRepository struct {
+	db *sql.DB
+}
+
+func NewSQLMessageRepository(db *sql.DB) *SQLMessageRepository {
+	return &SQLMessageRepository{db: db}
+}
+
+const (
+	chatQuery    = `SELECT c.ChatID, c.SenderID, c.ReceiverID, c.CreateTime FROM Chat c`
+	messageQuery = `SELECT m.MessageID, m.ChatID, m.SenderID, m.Content, m.CreateTime FROM Message m JOIN Chat c ON m.ChatID = c.ChatID`
+	chatsOfUser  = ` WHERE c.SenderID = ? OR c.ReceiverID = ?`
+)
+
+func scanChat(row scanner) (Entities.Chat, error) {
+	var chat Entities.Chat
+	err := row.Scan(&chat.ChatID, &chat.SenderID, &chat.ReceiverID, &chat.CreateTime)
+	return chat, err
+}
+
+// messages returns the messages of the chats matching where, keyed by ChatID
+func (repo *SQLMessageRepository) messages(ctx context.Context, where string, args ...interface{}) (map[string][]Entities.Message, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, messageQuery+where+` ORDER BY m.CreateTime, m.MessageID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := make(map[string][]Entities.Message)
+	for rows.Next() {
+		var message Entities.Message
+		if err := rows.Scan(&message.MessageID, &message.ChatID, &message.SenderID, &message.Content, &message.CreateTime); err != nil {
+			return nil, err
+		}
+		messages[message.ChatID] = append(messages[message.ChatID], message)
+	}
+	return messages, rows.Err()
+}
+
+// withMessages attaches the messages to the chat, filling in who each one was sent to
+func withMessages(chat Entities.Chat, messages []Entities.Message) Entities.Chat {
+	chat.Messages = []Entities.Message{}
+	for _, message := range messages {
+		message.ReceiverID = chat.ReceiverID
+		if message.SenderID == chat.ReceiverID {
+			message.ReceiverID = chat.SenderID
+		}
+		chat.Messages = append(chat.Messages, message)
+	}
+	return chat
+}
+
+func (repo *SQLMessageRepository) GetChat(ctx context.Context, chatID string) (Entities.Chat, error) {
+	chat, err := scanChat(conn(ctx, repo.db).QueryRowContext(ctx, chatQuery+` WHERE c.ChatID = ?`, chatID))
+	if err != nil {
+		return chat, notFound(err)
+	}
+	messages, err := repo.messages(ctx, ` WHERE c.ChatID = ?`, chatID)
+	if err != nil {
+		return chat, err
+	}
+	return withMessages(chat, messages[chatID]), nil
+}
+
+func (repo *SQLMessageRepository) ListChatsByUser(ctx context.Context, userID string) ([]Entities.Chat, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, chatQuery+chatsOfUser+` ORDER BY c.CreateTime, c.ChatID`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var chats []Entities.Chat
+	for rows.Next() {
+		chat, err := scanChat(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	messages, err := repo.messages(ctx, chatsOfUser, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Entities.Chat, 0, len(chats))
+	for _, chat := range chats {
+		result = append(result, withMessages(chat, messages[chat.ChatID]))
+	}
+	return result, nil
+}
+
+func (repo *SQLMessageRepository) GetOrCreateChat(ctx context.Context, senderID, receiverID string) (Entities.Chat, bool, error) {
+	chat, err := scanChat(conn(ctx, repo.db).QueryRowContext(ctx, chatQuery+` WHERE (c.SenderID = ? AND c.ReceiverID = ?) OR (c.SenderID = ? AND c.ReceiverID = ?) ORDER BY c.ChatID LIMIT 1`,
+		senderID, receiverID, receiverID, senderID))
+	if err == nil {
+		chat, err = repo.GetChat(ctx, chat.ChatID)
+		return chat, false, err
+	}
+	if err != sql.ErrNoRows {
+		return chat, false, err
+	}
+
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Chat (SenderID, ReceiverID) VALUES (?, ?)`, senderID, receiverID)
+	if err != nil {
+		return chat, false, err
+	}
+	chatID, err := insertedID(result)
+	if err != nil {
+		return chat, false, err
+	}
+	return Entities.Chat{ChatID: chatID, SenderID: senderID, ReceiverID: receiverID, CreateTime: time.Now().UTC(), Messages: []Entities.Message{}}, true, nil
+}
+
+func (repo *SQLMessageRepository) CreateMessage(ctx context.Context, message *Entities.Message) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Message (ChatID, SenderID, Content) VALUES (?, ?, ?)`, message.ChatID, message.SenderID, message.Content)
+	if err != nil {
+		return err
+	}
+	message.MessageID, err = insertedID(result)
+	message.CreateTime = time.Now().UTC()
+	return err
+}
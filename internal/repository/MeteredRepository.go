@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+
+	"GraduationProject.com/m/internal/metrics"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type meteredBookingRepository struct {
+	BookingRepository
+	metrics *metrics.Metrics
+}
+
+func (repo *meteredBookingRepository) Create(ctx context.Context, booking *Entities.Booking) error {
+	if err := repo.BookingRepository.Create(ctx, booking); err != nil {
+		return err
+	}
+	repo.metrics.BookingsCreated.Inc()
+	return nil
+}
+
+// Delete counts a cancellation unless the stay is already over, deleting old bookings is cleanup
+func (repo *meteredBookingRepository) Delete(ctx context.Context, bookingID string) error {
+	booking, lookupErr := repo.GetByID(ctx, bookingID)
+	if err := repo.BookingRepository.Delete(ctx, bookingID); err != nil {
+		return err
+	}
+	if lookupErr == nil && !booking.IsPastBooking() {
+		repo.metrics.BookingsCancelled.Inc()
+	}
+	return nil
+}
+
+type meteredTransactionRepository struct {
+	TransactionRepository
+	metrics *metrics.Metrics
+}
+
+func (repo *meteredTransactionRepository) Create(ctx context.Context, transaction *Entities.FinancialTransaction) error {
+	err := repo.TransactionRepository.Create(ctx, transaction)
+	repo.metrics.Payment(err)
+	return err
+}
+
+type meteredMessageRepository struct {
+	MessageRepository
+	metrics *metrics.Metrics
+}
+
+func (repo *meteredMessageRepository) CreateMessage(ctx context.Context, message *Entities.Message) error {
+	if err := repo.MessageRepository.CreateMessage(ctx, message); err != nil {
+		return err
+	}
+	repo.metrics.MessagesSent.Inc()
+	return nil
+}
+
+type meteredTicketRepository struct {
+	TicketRepository
+	metrics *metrics.Metrics
+}
+
+func (repo *meteredTicketRepository) Create(ctx context.Context, ticket *Entities.MaintenanceTicket) error {
+	if err := repo.TicketRepository.Create(ctx, ticket); err != nil {
+		return err
+	}
+	repo.metrics.TicketsOpened.Inc()
+	return nil
+}
+
+// WithMetrics counts the business events behind the writes made through the returned repositories:
+// bookings created and cancelled, payments stored or not, messages sent and maintenance tickets opened
+func WithMetrics(repos Repositories, m *metrics.Metrics) Repositories {
+	repos.Bookings = &meteredBookingRepository{repos.Bookings, m}
+	repos.Transactions = &meteredTransactionRepository{repos.Transactions, m}
+	repos.Messages = &meteredMessageRepository{repos.Messages, m}
+	repos.Tickets = &meteredTicketRepository{repos.Tickets, m}
+	return repos
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+// notificationLimit caps how many notifications a user gets back at once
+const notificationLimit = 100
+
+type SQLNotificationRepository struct {
+	db *sql.DB
+}
+
+func NewSQLNotificationRepository(db *sql.DB) *SQLNotificationRepository {
+	return &SQLNotificationRepository{db: db}
+}
+
+// ListByUser returns the newest notifications first
+func (repo *SQLNotificationRepository) ListByUser(ctx context.Context, userID string, unreadOnly bool) ([]Entities.Notification, error) {
+	query := `SELECT NotificationID, UserID, Type, UnitID, Message, CreateTime, ReadTime FROM Notification WHERE UserID = ?`
+	if unreadOnly {
+		query += ` AND ReadTime IS NULL`
+	}
+	query += ` ORDER BY CreateTime DESC, NotificationID DESC LIMIT ?`
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, query, userID, notificationLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	notifications := []Entities.Notification{}
+	for rows.Next() {
+		var notification Entities.Notification
+		if err := rows.Scan(&notification.NotificationID, &notification.UserID, &notification.Type, &notification.UnitID, &notification.Message,
+			&notification.CreateTime, nullTimeColumn{&notification.ReadTime}); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+func (repo *SQLNotificationRepository) Create(ctx context.Context, notification *Entities.Notification) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Notification (UserID, Type, UnitID, Message) VALUES (?, ?, ?, ?)`,
+		notification.UserID, notification.Type, notification.UnitID, notification.Message)
+	if err != nil {
+		return err
+	}
+	notification.NotificationID, err = insertedID(result)
+	return err
+}
+
+// MarkRead returns ErrNotFound when there is no unread notification with the ID
+func (repo *SQLNotificationRepository) MarkRead(ctx context.Context, notificationID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `UPDATE Notification SET ReadTime = CURRENT_TIMESTAMP WHERE NotificationID = ? AND ReadTime IS NULL`, notificationID))
+}
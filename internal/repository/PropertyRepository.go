@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLPropertyRepository struct {
+	db *sql.DB
+}
+
+func NewSQLPropertyRepository(db *sql.DB) *SQLPropertyRepository {
+	return &SQLPropertyRepository{db: db}
+}
+
+const propertyQuery = `
+    SELECT
+        p.PropertyID, p.OwnerID, p.AddressID, p.Name, p.Description, p.Type, p.Rules, p.TimeZone, p.CheckInTime, p.CheckOutTime, p.CreateTime, p.Version,
+        ` + addressColumns + `
+    FROM
+        Property p
+    LEFT JOIN
+        Address a ON p.AddressID = a.AddressID`
+
+func scanProperty(row scanner) (Entities.Property, error) {
+	var property Entities.Property
+	fields := []interface{}{&property.PropertyID, (*nullableString)(&property.OwnerID), (*nullableString)(&property.AddressID), &property.Name,
+		(*nullableString)(&property.Description), (*nullableString)(&property.Type), (*nullableString)(&property.Rules),
+		&property.TimeZone, &property.CheckInTime, &property.CheckOutTime, &property.CreateTime, &property.Version}
+	err := row.Scan(append(fields, addressFields(&property.Address)...)...)
+	return property, err
+}
+
+// query loads the properties that are not deleted and match the conditions, which start with AND
+func (repo *SQLPropertyRepository) query(ctx context.Context, conditions string, args ...interface{}) ([]Entities.Property, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, propertyQuery+` WHERE p.DeleteTime IS NULL`+conditions+` ORDER BY p.PropertyID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	properties := []Entities.Property{}
+	for rows.Next() {
+		property, err := scanProperty(rows)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, property)
+	}
+	return properties, rows.Err()
+}
+
+func (repo *SQLPropertyRepository) GetByID(ctx context.Context, propertyID string) (Entities.Property, error) {
+	property, err := scanProperty(conn(ctx, repo.db).QueryRowContext(ctx, propertyQuery+` WHERE p.PropertyID = ? AND p.DeleteTime IS NULL`, propertyID))
+	return property, notFound(err)
+}
+
+func (repo *SQLPropertyRepository) List(ctx context.Context) ([]Entities.Property, error) {
+	return repo.query(ctx, ``)
+}
+
+func (repo *SQLPropertyRepository) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Property, error) {
+	return repo.query(ctx, ` AND p.OwnerID = ?`, ownerID)
+}
+
+func (repo *SQLPropertyRepository) ListByType(ctx context.Context, propertyType string) ([]Entities.Property, error) {
+	return repo.query(ctx, ` AND p.Type = ?`, propertyType)
+}
+
+func (repo *SQLPropertyRepository) Create(ctx context.Context, property *Entities.Property) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := insertAddress(ctx, tx, &property.Address); err != nil {
+			return err
+		}
+		property.AddressID = property.Address.AddressID
+		result, err := tx.ExecContext(ctx, `INSERT INTO Property (OwnerID, AddressID, Name, Description, Type, Rules, TimeZone, CheckInTime, CheckOutTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			property.OwnerID, property.AddressID, property.Name, property.Description, property.Type, property.Rules, property.TimeZone, property.CheckInTime, property.CheckOutTime)
+		if err != nil {
+			return err
+		}
+		property.PropertyID, err = insertedID(result)
+		property.Version = 1
+		return err
+	})
+}
+
+func (repo *SQLPropertyRepository) Update(ctx context.Context, property Entities.Property) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		err := versioned(tx.ExecContext(ctx, `UPDATE Property SET Name = ?, Description = ?, Type = ?, Rules = ?, TimeZone = ?, CheckInTime = ?, CheckOutTime = ?, Version = Version + 1 WHERE PropertyID = ? AND Version = ?`,
+			property.Name, property.Description, property.Type, property.Rules, property.TimeZone, property.CheckInTime, property.CheckOutTime, property.PropertyID, property.Version))
+		if err != nil {
+			return err
+		}
+		property.Address.AddressID = property.AddressID
+		return updateAddress(ctx, tx, property.Address)
+	})
+}
+
+func (repo *SQLPropertyRepository) Delete(ctx context.Context, propertyID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := lockRows(ctx, tx, "Unit", "UnitID", `PropertyID = ?`, propertyID); err != nil {
+			return err
+		}
+		if err := noUpcomingBookings(ctx, tx, `u.PropertyID = ?`, propertyID); err != nil {
+			return err
+		}
+		now := deletionTime()
+		if err := affectedOne(tx.ExecContext(ctx, `UPDATE Property SET DeleteTime = ? WHERE PropertyID = ? AND DeleteTime IS NULL`, now, propertyID)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = ? WHERE PropertyID = ? AND DeleteTime IS NULL`, now, propertyID)
+		return err
+	})
+}
+
+// Restore brings back a deleted property and the units that were deleted with it, unless it was archived or its owner is deleted
+func (repo *SQLPropertyRepository) Restore(ctx context.Context, propertyID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		var deleteTime, archiveTime, ownerDeleteTime sql.NullTime
+		err := tx.QueryRowContext(ctx, `SELECT p.DeleteTime, p.ArchiveTime, o.DeleteTime FROM Property p LEFT JOIN User o ON p.OwnerID = o.UserID WHERE p.PropertyID = ?`, propertyID).
+			Scan(&deleteTime, &archiveTime, &ownerDeleteTime)
+		if err != nil {
+			return notFound(err)
+		}
+		if !deleteTime.Valid {
+			return ErrNotFound
+		}
+		if archiveTime.Valid || ownerDeleteTime.Valid {
+			return ErrNotRestorable
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = NULL WHERE PropertyID = ? AND DeleteTime = ? AND ArchiveTime IS NULL`, propertyID, deleteTime.Time); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE Property SET DeleteTime = NULL WHERE PropertyID = ?`, propertyID)
+		return err
+	})
+}
+
+func (repo *SQLPropertyRepository) Archive(ctx context.Context, propertyID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := lockRows(ctx, tx, "Unit", "UnitID", `PropertyID = ?`, propertyID); err != nil {
+			return err
+		}
+		if err := noUpcomingBookings(ctx, tx, `u.PropertyID = ?`, propertyID); err != nil {
+			return err
+		}
+		now := deletionTime()
+		err := affectedOne(tx.ExecContext(ctx, `UPDATE Property SET DeleteTime = COALESCE(DeleteTime, ?), ArchiveTime = ? WHERE PropertyID = ? AND ArchiveTime IS NULL`, now, now, propertyID))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = COALESCE(DeleteTime, ?), ArchiveTime = ? WHERE PropertyID = ? AND ArchiveTime IS NULL`, now, now, propertyID); err != nil {
+			return err
+		}
+		// The ownership proof stays with the property's records
+		_, err = tx.ExecContext(ctx, `DELETE FROM Images WHERE Type <> 'proof' AND (PropertyID = ? OR UnitID IN (SELECT UnitID FROM Unit WHERE PropertyID = ?))`, propertyID, propertyID)
+		return err
+	})
+}
+
+func (repo *SQLPropertyRepository) GetProof(ctx context.Context, propertyID string) ([]byte, error) {
+	var proof []byte
+	err := conn(ctx, repo.db).QueryRowContext(ctx, `SELECT Image FROM Images WHERE PropertyID = ? AND Type = 'proof'`, propertyID).Scan(&proof)
+	return proof, notFound(err)
+}
+
+func (repo *SQLPropertyRepository) SaveProof(ctx context.Context, propertyID string, url string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE Images SET Image = ? WHERE PropertyID = ? AND Type = 'proof'`, url, propertyID)
+		if err != nil {
+			return err
+		}
+		// No affected rows can also mean the proof did not change, so count before inserting
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			return nil
+		}
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM Images WHERE PropertyID = ? AND Type = 'proof'`, propertyID).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO Images (PropertyID, Type, Image) VALUES (?, 'proof', ?)`, propertyID, url)
+		return err
+	})
+}
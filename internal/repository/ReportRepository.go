@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLReportRepository struct {
+	db *sql.DB
+}
+
+func NewSQLReportRepository(db *sql.DB) *SQLReportRepository {
+	return &SQLReportRepository{db: db}
+}
+
+func (repo *SQLReportRepository) GetByID(ctx context.Context, reportID string) (Entities.Report, error) {
+	var report Entities.Report
+	err := conn(ctx, repo.db).QueryRowContext(ctx, `SELECT ReportID, UserID, Type, CreateTime, Data, Version FROM Report WHERE ReportID = ?`, reportID).
+		Scan(&report.ReportID, &report.UserID, &report.Type, &report.CreateTime, &report.Data, &report.Version)
+	return report, notFound(err)
+}
+
+// Create keeps the ReportID when the client chose one, otherwise the database assigns it
+func (repo *SQLReportRepository) Create(ctx context.Context, report *Entities.Report) error {
+	if report.CreateTime.IsZero() {
+		report.CreateTime = time.Now().UTC()
+	}
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Report (ReportID, UserID, Type, CreateTime, Data) VALUES (?, ?, ?, ?, ?)`,
+		nullString(report.ReportID), report.UserID, report.Type, report.CreateTime.UTC(), report.Data)
+	if err != nil {
+		return duplicate(err)
+	}
+	if report.ReportID == "" {
+		report.ReportID, err = insertedID(result)
+	}
+	report.Version = 1
+	return err
+}
+
+func (repo *SQLReportRepository) Update(ctx context.Context, report Entities.Report) error {
+	return versioned(conn(ctx, repo.db).ExecContext(ctx, `UPDATE Report SET UserID = ?, Type = ?, CreateTime = ?, Data = ?, Version = Version + 1 WHERE ReportID = ? AND Version = ?`,
+		report.UserID, report.Type, report.CreateTime, report.Data, report.ReportID, report.Version))
+}
+
+func (repo *SQLReportRepository) Delete(ctx context.Context, reportID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM Report WHERE ReportID = ?`, reportID))
+}
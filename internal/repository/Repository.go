@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"GraduationProject.com/m/internal/listing"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+	// ErrUpcomingBookings is returned when deleting something that has bookings which have not ended yet
+	ErrUpcomingBookings = errors.New("has upcoming bookings")
+	// ErrNotRestorable is returned when restoring something that was archived, or whose owner is still deleted
+	ErrNotRestorable = errors.New("cannot be restored")
+	// ErrVersionConflict is returned when updating from a version that is no longer the current one
+	ErrVersionConflict = errors.New("was changed by another update")
+	// ErrOverlap is returned when a booking would overlap another booking of the same unit
+	ErrOverlap = errors.New("overlaps another booking")
+)
+
+type UserRepository interface {
+	GetByID(ctx context.Context, userID string) (Entities.User, error)
+	GetByEmail(ctx context.Context, email string) (Entities.User, error)
+	List(ctx context.Context) ([]Entities.User, error)
+	// Create inserts the user and its address, and sets UserID and AddressID
+	Create(ctx context.Context, user *Entities.User) error
+	// Update writes the user and its address as given and bumps the version, or returns ErrVersionConflict
+	// when user.Version is not the current one
+	Update(ctx context.Context, user Entities.User) error
+	// Delete soft deletes the user together with their properties and units, which Restore brings back
+	Delete(ctx context.Context, userID string) error
+	Restore(ctx context.Context, userID string) error
+}
+
+type UnitRepository interface {
+	GetByID(ctx context.Context, unitID string) (Entities.Unit, error)
+	List(ctx context.Context) ([]Entities.Unit, error)
+	ListByProperty(ctx context.Context, propertyID string) ([]Entities.Unit, error)
+	ListByOwner(ctx context.Context, ownerID string) ([]Entities.Unit, error)
+	// ListPage returns one page of the units that match the filter in the sort order, with the total and the
+	// facet counts. The request is normalized, an invalid sort or cursor is an apperror.FieldError.
+	ListPage(ctx context.Context, req listing.Request) (listing.Page, error)
+	// SearchByAddress returns the units whose address contains any of the given parts of address
+	SearchByAddress(ctx context.Context, address Entities.Address) ([]Entities.Unit, error)
+	// Create inserts the unit with its amenities and images, and sets UnitID and AddressID
+	Create(ctx context.Context, unit *Entities.Unit) error
+	// Update writes the unit, its amenities and its address as given, and bumps the version. A unit moved to
+	// another property comes with the AddressID of that one. Images are only replaced when unit.Images is not
+	// nil. It returns ErrVersionConflict when unit.Version is not the current one.
+	Update(ctx context.Context, unit Entities.Unit) error
+	// Delete soft deletes the unit, its bookings, images and reviews are kept
+	Delete(ctx context.Context, unitID string) error
+	Restore(ctx context.Context, unitID string) error
+	ListImages(ctx context.Context, unitID string) ([]Entities.Image, error)
+	// SaveImages overwrites the unit's images in order and adds the ones that do not exist yet
+	SaveImages(ctx context.Context, unitID string, images []string) error
+}
+
+type PropertyRepository interface {
+	GetByID(ctx context.Context, propertyID string) (Entities.Property, error)
+	List(ctx context.Context) ([]Entities.Property, error)
+	ListByOwner(ctx context.Context, ownerID string) ([]Entities.Property, error)
+	ListByType(ctx context.Context, propertyType string) ([]Entities.Property, error)
+	// Create inserts the property and its address, and sets PropertyID and AddressID
+	Create(ctx context.Context, property *Entities.Property) error
+	// Update writes the property and its address and bumps the version, or returns ErrVersionConflict when
+	// property.Version is not the current one
+	Update(ctx context.Context, property Entities.Property) error
+	// Delete soft deletes the property together with its units, which Restore brings back
+	Delete(ctx context.Context, propertyID string) error
+	Restore(ctx context.Context, propertyID string) error
+	// Archive deletes the property and its units for good and removes their photos. Bookings and transactions are kept for reporting.
+	Archive(ctx context.Context, propertyID string) error
+	GetProof(ctx context.Context, propertyID string) ([]byte, error)
+	SaveProof(ctx context.Context, propertyID string, url string) error
+}
+
+type BookingRepository interface {
+	GetByID(ctx context.Context, bookingID string) (Entities.Booking, error)
+	ListByUnit(ctx context.Context, unitID string) ([]Entities.Booking, error)
+	ListByUser(ctx context.Context, userID string) ([]Entities.Booking, error)
+	// ListByOwner returns the bookings of every unit in the owner's properties
+	ListByOwner(ctx context.Context, ownerID string) ([]Entities.Booking, error)
+	// Create returns ErrOverlap when another booking of the unit overlaps the dates, and ErrNotFound when the
+	// unit is deleted. The check and the insert hold the unit's lock, so two bookings cannot both pass it.
+	Create(ctx context.Context, booking *Entities.Booking) error
+	// Update bumps the version, or returns ErrVersionConflict when booking.Version is not the current one. Like
+	// Create it returns ErrOverlap when the new dates overlap another booking of the unit.
+	Update(ctx context.Context, booking Entities.Booking) error
+	Delete(ctx context.Context, bookingID string) error
+}
+
+type ReviewRepository interface {
+	GetByID(ctx context.Context, reviewID string) (Entities.Review, error)
+	ListByUnit(ctx context.Context, unitID string) ([]Entities.Review, error)
+	Create(ctx context.Context, review *Entities.Review) error
+	// Update bumps the version, or returns ErrVersionConflict when review.Version is not the current one
+	Update(ctx context.Context, review Entities.Review) error
+	Delete(ctx context.Context, reviewID string) error
+}
+
+type MessageRepository interface {
+	// GetChat returns the chat with its messages, oldest first
+	GetChat(ctx context.Context, chatID string) (Entities.Chat, error)
+	ListChatsByUser(ctx context.Context, userID string) ([]Entities.Chat, error)
+	// GetOrCreateChat returns the chat between the two users in either direction, creating it when there is
+	// none, and whether it did
+	GetOrCreateChat(ctx context.Context, senderID, receiverID string) (Entities.Chat, bool, error)
+	CreateMessage(ctx context.Context, message *Entities.Message) error
+}
+
+type TransactionRepository interface {
+	GetByID(ctx context.Context, transactionID string) (Entities.FinancialTransaction, error)
+	ListByUser(ctx context.Context, userID string) ([]Entities.FinancialTransaction, error)
+	// ListByOwner returns the transactions of every booking of the owner's units
+	ListByOwner(ctx context.Context, ownerID string) ([]Entities.FinancialTransaction, error)
+	Create(ctx context.Context, transaction *Entities.FinancialTransaction) error
+	// Update bumps the version, or returns ErrVersionConflict when transaction.Version is not the current one
+	Update(ctx context.Context, transaction Entities.FinancialTransaction) error
+	Delete(ctx context.Context, transactionID string) error
+}
+
+type TicketRepository interface {
+	GetByID(ctx context.Context, ticketID string) (Entities.MaintenanceTicket, error)
+	Create(ctx context.Context, ticket *Entities.MaintenanceTicket) error
+	// Update bumps the version, or returns ErrVersionConflict when ticket.Version is not the current one
+	Update(ctx context.Context, ticket Entities.MaintenanceTicket) error
+	Delete(ctx context.Context, ticketID string) error
+}
+
+type ReportRepository interface {
+	GetByID(ctx context.Context, reportID string) (Entities.Report, error)
+	Create(ctx context.Context, report *Entities.Report) error
+	// Update bumps the version, or returns ErrVersionConflict when report.Version is not the current one
+	Update(ctx context.Context, report Entities.Report) error
+	Delete(ctx context.Context, reportID string) error
+}
+
+type WishlistRepository interface {
+	GetByID(ctx context.Context, wishlistID string) (Entities.Wishlist, error)
+	GetByShareToken(ctx context.Context, token string) (Entities.Wishlist, error)
+	ListByUser(ctx context.Context, userID string) ([]Entities.Wishlist, error)
+	// ListUserIDsByUnit returns the users that have the unit on any of their wishlists
+	ListUserIDsByUnit(ctx context.Context, unitID string) ([]string, error)
+	Create(ctx context.Context, wishlist *Entities.Wishlist) error
+	Rename(ctx context.Context, wishlistID, name string) error
+	Delete(ctx context.Context, wishlistID string) error
+	// AddUnit keeps the original entry when the unit is already on the wishlist
+	AddUnit(ctx context.Context, wishlistID, unitID string) error
+	RemoveUnit(ctx context.Context, wishlistID, unitID string) error
+	// SetShareToken sets the token when the wishlist has none yet, an empty token revokes it
+	SetShareToken(ctx context.Context, wishlistID, token string) error
+}
+
+type SavedSearchRepository interface {
+	List(ctx context.Context) ([]Entities.SavedSearch, error)
+	ListByUser(ctx context.Context, userID string) ([]Entities.SavedSearch, error)
+	Create(ctx context.Context, search *Entities.SavedSearch) error
+	Delete(ctx context.Context, savedSearchID string) error
+}
+
+type NotificationRepository interface {
+	ListByUser(ctx context.Context, userID string, unreadOnly bool) ([]Entities.Notification, error)
+	Create(ctx context.Context, notification *Entities.Notification) error
+	MarkRead(ctx context.Context, notificationID string) error
+}
+
+// AuditFilter narrows the audit log down, empty fields match every entry
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Actor      string
+	Offset     int
+	Limit      int
+}
+
+// AuditRepository is append-only, there is no way to change or remove an entry
+type AuditRepository interface {
+	// Append inserts the entry and sets AuditID and CreateTime
+	Append(ctx context.Context, entry *Entities.AuditEntry) error
+	// List returns the matching entries, newest first
+	List(ctx context.Context, filter AuditFilter) ([]Entities.AuditEntry, error)
+}
+
+// IdempotencyRepository keeps the responses to requests sent with an Idempotency-Key
+type IdempotencyRepository interface {
+	// Reserve inserts the key, without a response yet, and sets CreateTime. When the actor used the key before
+	// and it has not expired, it returns the stored key with ErrDuplicate. Expired keys can be used again.
+	Reserve(ctx context.Context, key *Entities.IdempotencyKey) (Entities.IdempotencyKey, error)
+	// Complete stores the response of a reserved key
+	Complete(ctx context.Context, key Entities.IdempotencyKey) error
+	// Release removes a key, so the request can be retried with it
+	Release(ctx context.Context, actor, key string) error
+	// DeleteExpired removes the keys that expired before the time and returns how many there were
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Transactor runs fn in one transaction. The repositories called with the context fn is given read and write
+// in it, and everything fn wrote is committed together when it returns nil.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Repositories bundles one repository per aggregate
+type Repositories struct {
+	Users         UserRepository
+	Units         UnitRepository
+	Properties    PropertyRepository
+	Bookings      BookingRepository
+	Reviews       ReviewRepository
+	Messages      MessageRepository
+	Transactions  TransactionRepository
+	Tickets       TicketRepository
+	Reports       ReportRepository
+	Wishlists     WishlistRepository
+	SavedSearches SavedSearchRepository
+	Notifications NotificationRepository
+	Audit         AuditRepository
+	Idempotency   IdempotencyRepository
+	Transactor    Transactor
+}
+
+// NewSQLRepositories backs every repository with the database
+func NewSQLRepositories(db *sql.DB) Repositories {
+	return Repositories{
+		Users:         NewSQLUserRepository(db),
+		Units:         NewSQLUnitRepository(db),
+		Properties:    NewSQLPropertyRepository(db),
+		Bookings:      NewSQLBookingRepository(db),
+		Reviews:       NewSQLReviewRepository(db),
+		Messages:      NewSQLMessageRepository(db),
+		Transactions:  NewSQLTransactionRepository(db),
+		Tickets:       NewSQLTicketRepository(db),
+		Reports:       NewSQLReportRepository(db),
+		Wishlists:     NewSQLWishlistRepository(db),
+		SavedSearches: NewSQLSavedSearchRepository(db),
+		Notifications: NewSQLNotificationRepository(db),
+		Audit:         NewSQLAuditRepository(db),
+		Idempotency:   NewSQLIdempotencyRepository(db),
+		Transactor:    sqlTransactor{db},
+	}
+}
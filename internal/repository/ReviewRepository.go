@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLReviewRepository struct {
+	db *sql.DB
+}
+
+func NewSQLReviewRepository(db *sql.DB) *SQLReviewRepository {
+	return &SQLReviewRepository{db: db}
+}
+
+const reviewQuery = `SELECT ReviewID, UserID, UnitID, Review, Rating, Comment, CreateTime, Version FROM Review`
+
+func scanReview(row scanner) (Entities.Review, error) {
+	var review Entities.Review
+	err := row.Scan(&review.ReviewID, &review.UserID, &review.UnitID, (*nullableString)(&review.Review), &review.Rating, (*nullableString)(&review.Comment), &review.CreateTime, &review.Version)
+	return review, err
+}
+
+func (repo *SQLReviewRepository) GetByID(ctx context.Context, reviewID string) (Entities.Review, error) {
+	review, err := scanReview(conn(ctx, repo.db).QueryRowContext(ctx, reviewQuery+` WHERE ReviewID = ?`, reviewID))
+	return review, notFound(err)
+}
+
+func (repo *SQLReviewRepository) ListByUnit(ctx context.Context, unitID string) ([]Entities.Review, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, reviewQuery+` WHERE UnitID = ? ORDER BY CreateTime, ReviewID`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reviews := []Entities.Review{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+func (repo *SQLReviewRepository) Create(ctx context.Context, review *Entities.Review) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Review (UserID, UnitID, Review, Rating, Comment) VALUES (?, ?, ?, ?, ?)`,
+		review.UserID, review.UnitID, review.Review, review.Rating, review.Comment)
+	if err != nil {
+		return err
+	}
+	review.ReviewID, err = insertedID(result)
+	review.Version = 1
+	return err
+}
+
+func (repo *SQLReviewRepository) Update(ctx context.Context, review Entities.Review) error {
+	return versioned(conn(ctx, repo.db).ExecContext(ctx, `UPDATE Review SET UserID = ?, UnitID = ?, Review = ?, Rating = ?, Comment = ?, Version = Version + 1 WHERE ReviewID = ? AND Version = ?`,
+		review.UserID, review.UnitID, review.Review, review.Rating, review.Comment, review.ReviewID, review.Version))
+}
+
+func (repo *SQLReviewRepository) Delete(ctx context.Context, reviewID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM Review WHERE ReviewID = ?`, reviewID))
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLTicketRepository struct {
+	db *sql.DB
+}
+
+func NewSQLTicketRepository(db *sql.DB) *SQLTicketRepository {
+	return &SQLTicketRepository{db: db}
+}
+
+func (repo *SQLTicketRepository) GetByID(ctx context.Context, ticketID string) (Entities.MaintenanceTicket, error) {
+	var ticket Entities.MaintenanceTicket
+	err := conn(ctx, repo.db).QueryRowContext(ctx, `SELECT TicketID, MaintenancePresenterID, TenantID, PropertyID, Description, UrgencyLevel, CreateTime, Status, Version FROM MaintenanceTicket WHERE TicketID = ?`, ticketID).
+		Scan(&ticket.TicketID, (*nullableString)(&ticket.MaintenancePresenterID), &ticket.TenantID, &ticket.PropertyID, &ticket.Description, &ticket.UrgencyLevel, &ticket.CreateTime, &ticket.Status, &ticket.Version)
+	return ticket, notFound(err)
+}
+
+// Create keeps the TicketID when the client chose one, otherwise the database assigns it
+func (repo *SQLTicketRepository) Create(ctx context.Context, ticket *Entities.MaintenanceTicket) error {
+	ticket.CreateTime = time.Now().UTC()
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO MaintenanceTicket (TicketID, MaintenancePresenterID, TenantID, PropertyID, Description, UrgencyLevel, CreateTime, Status) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		nullString(ticket.TicketID), nullString(ticket.MaintenancePresenterID), ticket.TenantID, ticket.PropertyID, ticket.Description, ticket.UrgencyLevel, ticket.CreateTime, ticket.Status)
+	if err != nil {
+		return duplicate(err)
+	}
+	if ticket.TicketID == "" {
+		ticket.TicketID, err = insertedID(result)
+	}
+	ticket.Version = 1
+	return err
+}
+
+func (repo *SQLTicketRepository) Update(ctx context.Context, ticket Entities.MaintenanceTicket) error {
+	return versioned(conn(ctx, repo.db).ExecContext(ctx, `UPDATE MaintenanceTicket SET MaintenancePresenterID = ?, TenantID = ?, PropertyID = ?, Description = ?, UrgencyLevel = ?, Status = ?, Version = Version + 1 WHERE TicketID = ? AND Version = ?`,
+		nullString(ticket.MaintenancePresenterID), ticket.TenantID, ticket.PropertyID, ticket.Description, ticket.UrgencyLevel, ticket.Status, ticket.TicketID, ticket.Version))
+}
+
+func (repo *SQLTicketRepository) Delete(ctx context.Context, ticketID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM MaintenanceTicket WHERE TicketID = ?`, ticketID))
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLTransactionRepository struct {
+	db *sql.DB
+}
+
+func NewSQLTransactionRepository(db *sql.DB) *SQLTransactionRepository {
+	return &SQLTransactionRepository{db: db}
+}
+
+const transactionQuery = `SELECT t.TransactionID, t.UserID, t.BookingID, t.PaymentMethod, t.Amount, t.CreateTime, t.Version FROM FinancialTransaction t`
+
+func scanTransaction(row scanner) (Entities.FinancialTransaction, error) {
+	var transaction Entities.FinancialTransaction
+	err := row.Scan(&transaction.TransactionID, &transaction.UserID, &transaction.BookingID, &transaction.PaymentMethod, &transaction.Amount, &transaction.CreateTime, &transaction.Version)
+	return transaction, err
+}
+
+func (repo *SQLTransactionRepository) query(ctx context.Context, where string, args ...interface{}) ([]Entities.FinancialTransaction, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, transactionQuery+where+` ORDER BY t.CreateTime, t.TransactionID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	transactions := []Entities.FinancialTransaction{}
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+func (repo *SQLTransactionRepository) GetByID(ctx context.Context, transactionID string) (Entities.FinancialTransaction, error) {
+	transaction, err := scanTransaction(conn(ctx, repo.db).QueryRowContext(ctx, transactionQuery+` WHERE t.TransactionID = ?`, transactionID))
+	return transaction, notFound(err)
+}
+
+func (repo *SQLTransactionRepository) ListByUser(ctx context.Context, userID string) ([]Entities.FinancialTransaction, error) {
+	return repo.query(ctx, ` WHERE t.UserID = ?`, userID)
+}
+
+func (repo *SQLTransactionRepository) ListByOwner(ctx context.Context, ownerID string) ([]Entities.FinancialTransaction, error) {
+	return repo.query(ctx, ` JOIN Booking b ON t.BookingID = b.BookingID JOIN Unit u ON b.UnitID = u.UnitID JOIN Property p ON u.PropertyID = p.PropertyID WHERE p.OwnerID = ?`, ownerID)
+}
+
+func (repo *SQLTransactionRepository) Create(ctx context.Context, transaction *Entities.FinancialTransaction) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO FinancialTransaction (UserID, BookingID, PaymentMethod, Amount) VALUES (?, ?, ?, ?)`,
+		transaction.UserID, transaction.BookingID, transaction.PaymentMethod, transaction.Amount)
+	if err != nil {
+		return err
+	}
+	transaction.TransactionID, err = insertedID(result)
+	transaction.Version = 1
+	return err
+}
+
+func (repo *SQLTransactionRepository) Update(ctx context.Context, transaction Entities.FinancialTransaction) error {
+	return versioned(conn(ctx, repo.db).ExecContext(ctx, `UPDATE FinancialTransaction SET PaymentMethod = ?, Amount = ?, Version = Version + 1 WHERE TransactionID = ? AND Version = ?`,
+		transaction.PaymentMethod, transaction.Amount, transaction.TransactionID, transaction.Version))
+}
+
+func (repo *SQLTransactionRepository) Delete(ctx context.Context, transactionID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM FinancialTransaction WHERE TransactionID = ?`, transactionID))
+}
@@ -0,0 +1,539 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"GraduationProject.com/m/internal/listing"
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLUnitRepository struct {
+	db *sql.DB
+}
+
+func NewSQLUnitRepository(db *sql.DB) *SQLUnitRepository {
+	return &SQLUnitRepository{db: db}
+}
+
+// unitFrom joins the property for its type and the owner for their name
+const unitFrom = `
+    FROM
+        Unit u
+    LEFT JOIN
+        Property p ON u.PropertyID = p.PropertyID
+    LEFT JOIN
+        User o ON p.OwnerID = o.UserID
+    LEFT JOIN
+        Address a ON u.AddressID = a.AddressID`
+
+// listingFrom is unitFrom without the owner, which listings neither filter nor count by
+const listingFrom = `
+    FROM
+        Unit u
+    LEFT JOIN
+        Property p ON u.PropertyID = p.PropertyID
+    LEFT JOIN
+        Address a ON u.AddressID = a.AddressID`
+
+const unitQuery = `
+    SELECT
+        u.UnitID, u.PropertyID, u.AddressID, u.Name, u.RentalPrice, u.Description, u.Rating, u.StructuralProperties, u.CreateTime, u.Version,
+        u.Bedrooms, u.Beds, u.Bathrooms, u.MaxGuests, u.Size, u.Floor, p.Type, o.Name,
+        ` + addressColumns + unitFrom
+
+func scanUnit(row scanner) (Entities.Unit, error) {
+	var unit Entities.Unit
+	attributes := &unit.Attributes
+	fields := []interface{}{
+		&unit.UnitID, &unit.PropertyID, (*nullableString)(&unit.AddressID), (*nullableString)(&unit.Name), &unit.RentalPrice,
+		(*nullableString)(&unit.Description), &unit.Rating, (*nullableString)(&unit.StructuralProperties), &unit.CreateTime, &unit.Version,
+		&attributes.Bedrooms, &attributes.Beds, &attributes.Bathrooms, &attributes.MaxGuests, &attributes.Size, &attributes.Floor,
+		(*nullableString)(&unit.PropertyType), (*nullableString)(&unit.OwnerName),
+	}
+	err := row.Scan(append(fields, addressFields(&unit.Address)...)...)
+	return unit, err
+}
+
+// query loads the units that are not deleted and match the conditions, together with their amenities in one extra query.
+// conditions start with AND.
+func (repo *SQLUnitRepository) query(ctx context.Context, conditions string, args ...interface{}) ([]Entities.Unit, error) {
+	where := ` WHERE u.DeleteTime IS NULL` + conditions
+	units, err := repo.scanUnits(ctx, unitQuery+where+` ORDER BY u.UnitID`, args...)
+	if err != nil || len(units) == 0 {
+		return units, err
+	}
+	amenities, err := repo.amenities(ctx, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	setAmenities(units, amenities)
+	return units, nil
+}
+
+func (repo *SQLUnitRepository) scanUnits(ctx context.Context, query string, args ...interface{}) ([]Entities.Unit, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	units := []Entities.Unit{}
+	for rows.Next() {
+		unit, err := scanUnit(rows)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, unit)
+	}
+	return units, rows.Err()
+}
+
+// amenities returns the amenities of the units matching where, keyed by UnitID
+func (repo *SQLUnitRepository) amenities(ctx context.Context, where string, args ...interface{}) (map[string][]string, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, `SELECT ua.UnitID, ua.Amenity`+listingFrom+` JOIN UnitAmenity ua ON ua.UnitID = u.UnitID`+where+` ORDER BY ua.UnitID, ua.Amenity`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	amenities := make(map[string][]string)
+	for rows.Next() {
+		var unitID, amenity string
+		if err := rows.Scan(&unitID, &amenity); err != nil {
+			return nil, err
+		}
+		amenities[unitID] = append(amenities[unitID], amenity)
+	}
+	return amenities, rows.Err()
+}
+
+func setAmenities(units []Entities.Unit, amenities map[string][]string) {
+	for i := range units {
+		units[i].Amenities = amenities[units[i].UnitID]
+		if units[i].Amenities == nil {
+			units[i].Amenities = []string{}
+		}
+	}
+}
+
+func (repo *SQLUnitRepository) GetByID(ctx context.Context, unitID string) (Entities.Unit, error) {
+	units, err := repo.query(ctx, ` AND u.UnitID = ?`, unitID)
+	if err != nil {
+		return Entities.Unit{}, err
+	}
+	if len(units) == 0 {
+		return Entities.Unit{}, ErrNotFound
+	}
+	return units[0], nil
+}
+
+func (repo *SQLUnitRepository) List(ctx context.Context) ([]Entities.Unit, error) {
+	return repo.query(ctx, ``)
+}
+
+func (repo *SQLUnitRepository) ListByProperty(ctx context.Context, propertyID string) ([]Entities.Unit, error) {
+	return repo.query(ctx, ` AND u.PropertyID = ?`, propertyID)
+}
+
+func (repo *SQLUnitRepository) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Unit, error) {
+	return repo.query(ctx, ` AND p.OwnerID = ?`, ownerID)
+}
+
+// ListPage filters, sorts and pages the units in the database, and counts the facets of the filter there
+func (repo *SQLUnitRepository) ListPage(ctx context.Context, req listing.Request) (listing.Page, error) {
+	after, err := req.Normalize()
+	if err != nil {
+		return listing.Page{}, err
+	}
+	conditions, args := filterConditions(req.Filter, "")
+	where := ` WHERE u.DeleteTime IS NULL` + conditions
+	order, start, startArgs := keyset(req.Sort, after)
+	// One unit more than the page tells whether there is a next one
+	units, err := repo.scanUnits(ctx, unitQuery+where+start+order+` LIMIT ?`, append(append(args, startArgs...), req.Limit+1)...)
+	if err != nil {
+		return listing.Page{}, err
+	}
+
+	var page listing.Page
+	if len(units) > req.Limit {
+		units = units[:req.Limit]
+		page.NextCursor = listing.CursorOf(req.Sort, units[len(units)-1]).Encode()
+	}
+	if len(units) > 0 {
+		ids := make([]interface{}, len(units))
+		for i, unit := range units {
+			ids[i] = unit.UnitID
+		}
+		amenities, err := repo.amenities(ctx, ` WHERE u.UnitID IN (`+placeholders(len(ids))+`)`, ids...)
+		if err != nil {
+			return listing.Page{}, err
+		}
+		setAmenities(units, amenities)
+	}
+	page.Units = units
+
+	if err := conn(ctx, repo.db).QueryRowContext(ctx, `SELECT COUNT(*)`+listingFrom+where, args...).Scan(&page.Total); err != nil {
+		return listing.Page{}, err
+	}
+	if page.Facets, err = repo.facets(ctx, req.Filter); err != nil {
+		return listing.Page{}, err
+	}
+	return page, nil
+}
+
+// facets counts every facet over the units that match the other criteria of the filter
+func (repo *SQLUnitRepository) facets(ctx context.Context, filter listing.Filter) (listing.Facets, error) {
+	facets := listing.Facets{}
+	grouped := []struct{ facet, from, column string }{
+		{listing.FacetType, listingFrom, `p.Type`},
+		{listing.FacetCity, listingFrom, `a.City`},
+		{listing.FacetAmenities, listingFrom + ` JOIN UnitAmenity ua ON ua.UnitID = u.UnitID`, `ua.Amenity`},
+	}
+	for _, g := range grouped {
+		conditions, args := filterConditions(filter, g.facet)
+		counts, err := repo.countBy(ctx, `SELECT `+g.column+`, COUNT(*)`+g.from+` WHERE u.DeleteTime IS NULL`+conditions+` AND `+g.column+` <> '' GROUP BY `+g.column, args...)
+		if err != nil {
+			return nil, err
+		}
+		facets[g.facet] = listing.SortedCounts(counts)
+	}
+
+	// A unit is in the highest price bucket it reaches
+	var prices []string
+	var bounds []interface{}
+	for i, from := range listing.PriceBuckets {
+		if i+1 < len(listing.PriceBuckets) {
+			prices = append(prices, `COUNT(CASE WHEN u.RentalPrice >= ? AND u.RentalPrice < ? THEN 1 END)`)
+			bounds = append(bounds, from, listing.PriceBuckets[i+1])
+		} else {
+			prices = append(prices, `COUNT(CASE WHEN u.RentalPrice >= ? THEN 1 END)`)
+			bounds = append(bounds, from)
+		}
+	}
+	counts, err := repo.countSteps(ctx, listing.FacetPrice, filter, prices, bounds)
+	if err != nil {
+		return nil, err
+	}
+	facets[listing.FacetPrice] = listing.PriceFacet(counts)
+
+	stepped := []struct {
+		facet, column string
+		steps         []int
+	}{
+		{listing.FacetRating, `u.Rating`, listing.RatingSteps},
+		{listing.FacetGuests, `u.MaxGuests`, listing.GuestSteps},
+	}
+	for _, s := range stepped {
+		columns := make([]string, len(s.steps))
+		steps := make([]interface{}, len(s.steps))
+		for i, step := range s.steps {
+			columns[i] = `COUNT(CASE WHEN ` + s.column + ` >= ? THEN 1 END)`
+			steps[i] = step
+		}
+		counts, err := repo.countSteps(ctx, s.facet, filter, columns, steps)
+		if err != nil {
+			return nil, err
+		}
+		facets[s.facet] = listing.StepFacet(s.steps, counts)
+	}
+	return facets, nil
+}
+
+// countBy returns the counts of a query that selects a value and its count
+func (repo *SQLUnitRepository) countBy(ctx context.Context, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, err
+		}
+		counts[value] = count
+	}
+	return counts, rows.Err()
+}
+
+// countSteps selects the counting columns over the units that match every criterion but the one of facet,
+// columnArgs are the arguments of the columns
+func (repo *SQLUnitRepository) countSteps(ctx context.Context, facet string, filter listing.Filter, columns []string, columnArgs []interface{}) ([]int, error) {
+	conditions, args := filterConditions(filter, facet)
+	counts := make([]int, len(columns))
+	fields := make([]interface{}, len(columns))
+	for i := range counts {
+		fields[i] = &counts[i]
+	}
+	err := conn(ctx, repo.db).QueryRowContext(ctx, `SELECT `+strings.Join(columns, `, `)+listingFrom+` WHERE u.DeleteTime IS NULL`+conditions, append(columnArgs, args...)...).Scan(fields...)
+	return counts, err
+}
+
+// filterConditions turns the criteria of the filter, except the one of the skipped facet, into conditions
+// that start with AND. Types and cities are compared in lower case, which migration 11 indexes.
+func filterConditions(filter listing.Filter, skip string) (string, []interface{}) {
+	var conditions strings.Builder
+	var args []interface{}
+	if skip != listing.FacetPrice {
+		if filter.MinPrice != nil {
+			conditions.WriteString(` AND u.RentalPrice >= ?`)
+			args = append(args, *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			conditions.WriteString(` AND u.RentalPrice <= ?`)
+			args = append(args, *filter.MaxPrice)
+		}
+	}
+	if skip != listing.FacetType && len(filter.Types) > 0 {
+		conditions.WriteString(` AND LOWER(p.Type) IN (` + placeholders(len(filter.Types)) + `)`)
+		args = append(args, lowered(filter.Types)...)
+	}
+	if skip != listing.FacetCity && len(filter.Cities) > 0 {
+		conditions.WriteString(` AND LOWER(a.City) IN (` + placeholders(len(filter.Cities)) + `)`)
+		args = append(args, lowered(filter.Cities)...)
+	}
+	if skip != listing.FacetRating && filter.MinRating != nil {
+		conditions.WriteString(` AND u.Rating >= ?`)
+		args = append(args, *filter.MinRating)
+	}
+	if skip != listing.FacetGuests && filter.Guests != nil {
+		conditions.WriteString(` AND u.MaxGuests >= ?`)
+		args = append(args, *filter.Guests)
+	}
+	if skip != listing.FacetAmenities {
+		for _, amenity := range filter.Amenities {
+			conditions.WriteString(` AND EXISTS (SELECT 1 FROM UnitAmenity fa WHERE fa.UnitID = u.UnitID AND fa.Amenity = ?)`)
+			args = append(args, amenity)
+		}
+	}
+	return conditions.String(), args
+}
+
+// keyset returns the ORDER BY of the sort, and the condition that starts the page right after the cursor.
+// Ties on the sort key are ordered by UnitID.
+func keyset(sortBy string, after *listing.Cursor) (order, start string, args []interface{}) {
+	column, direction, comparison := `u.CreateTime`, ` DESC`, `<`
+	var key interface{}
+	if after != nil {
+		// Both dialects compare times in this format, SQLite stores CURRENT_TIMESTAMP in it
+		key = time.Unix(0, after.Time).UTC().Format(time.DateTime)
+	}
+	switch sortBy {
+	case listing.SortPriceAsc, listing.SortPriceDesc:
+		column = `u.RentalPrice`
+		if sortBy == listing.SortPriceAsc {
+			direction, comparison = ``, `>`
+		}
+		if after != nil {
+			key = after.Price
+		}
+	case listing.SortRating:
+		column = `u.Rating`
+		if after != nil {
+			key = after.Rating
+		}
+	}
+	order = ` ORDER BY ` + column + direction + `, u.UnitID`
+	if after == nil {
+		return order, ``, nil
+	}
+	start = ` AND (` + column + ` ` + comparison + ` ? OR (` + column + ` = ? AND u.UnitID > ?))`
+	return order, start, []interface{}{key, key, after.UnitID}
+}
+
+// SearchByAddress returns the units whose address contains any of the parts of address that are given,
+// ignoring case, or every unit when none is
+func (repo *SQLUnitRepository) SearchByAddress(ctx context.Context, address Entities.Address) ([]Entities.Unit, error) {
+	parts := []struct{ column, value string }{
+		{`a.PostalCode`, address.PostalCode},
+		{`a.Country`, address.Country},
+		{`a.State`, address.State},
+		{`a.City`, address.City},
+		{`a.Street`, address.Street},
+	}
+	var matches []string
+	var args []interface{}
+	for _, part := range parts {
+		if value := strings.TrimSpace(part.value); value != "" {
+			matches = append(matches, `LOWER(`+part.column+`) LIKE ? ESCAPE '!'`)
+			args = append(args, "%"+likeEscaper.Replace(strings.ToLower(value))+"%")
+		}
+	}
+	if len(matches) == 0 {
+		return repo.query(ctx, ``)
+	}
+	return repo.query(ctx, ` AND (`+strings.Join(matches, ` OR `)+`)`, args...)
+}
+
+// likeEscaper escapes the wildcards of LIKE with !, backslashes are read differently by MySQL and SQLite
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat(`?, `, n), `, `)
+}
+
+func lowered(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = strings.ToLower(value)
+	}
+	return args
+}
+
+func (repo *SQLUnitRepository) Create(ctx context.Context, unit *Entities.Unit) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		// Units share the address of their property
+		err := tx.QueryRowContext(ctx, `SELECT AddressID FROM Property WHERE PropertyID = ? AND DeleteTime IS NULL`, unit.PropertyID).Scan((*nullableString)(&unit.AddressID))
+		if err != nil {
+			return notFound(err)
+		}
+		attributes := unit.Attributes
+		result, err := tx.ExecContext(ctx, `INSERT INTO Unit (PropertyID, AddressID, Name, RentalPrice, Description, Rating, StructuralProperties, Bedrooms, Beds, Bathrooms, MaxGuests, Size, Floor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			unit.PropertyID, nullString(unit.AddressID), unit.Name, unit.RentalPrice, unit.Description, unit.Rating, unit.StructuralProperties,
+			attributes.Bedrooms, attributes.Beds, attributes.Bathrooms, attributes.MaxGuests, attributes.Size, attributes.Floor)
+		if err != nil {
+			return err
+		}
+		if unit.UnitID, err = insertedID(result); err != nil {
+			return err
+		}
+		unit.Version = 1
+		if err := saveAmenities(ctx, tx, unit.UnitID, unit.Amenities); err != nil {
+			return err
+		}
+		return insertImages(ctx, tx, unit.UnitID, unit.Images)
+	})
+}
+
+func (repo *SQLUnitRepository) Update(ctx context.Context, unit Entities.Unit) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		attributes := unit.Attributes
+		err := versioned(tx.ExecContext(ctx, `UPDATE Unit SET PropertyID = ?, AddressID = ?, Name = ?, Description = ?, StructuralProperties = ?, Rating = ?, RentalPrice = ?, Bedrooms = ?, Beds = ?, Bathrooms = ?, MaxGuests = ?, Size = ?, Floor = ?, Version = Version + 1 WHERE UnitID = ? AND Version = ?`,
+			unit.PropertyID, unit.AddressID, unit.Name, unit.Description, unit.StructuralProperties, unit.Rating, unit.RentalPrice,
+			attributes.Bedrooms, attributes.Beds, attributes.Bathrooms, attributes.MaxGuests, attributes.Size, attributes.Floor, unit.UnitID, unit.Version))
+		if err != nil {
+			return err
+		}
+		if err := saveAmenities(ctx, tx, unit.UnitID, unit.Amenities); err != nil {
+			return err
+		}
+		if unit.Images != nil {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM Images WHERE UnitID = ? AND Type = 'Unit'`, unit.UnitID); err != nil {
+				return err
+			}
+			if err := insertImages(ctx, tx, unit.UnitID, unit.Images); err != nil {
+				return err
+			}
+		}
+		unit.Address.AddressID = unit.AddressID
+		return updateAddress(ctx, tx, unit.Address)
+	})
+}
+
+func (repo *SQLUnitRepository) Delete(ctx context.Context, unitID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := lockRows(ctx, tx, "Unit", "UnitID", `UnitID = ?`, unitID); err != nil {
+			return err
+		}
+		if err := noUpcomingBookings(ctx, tx, `b.UnitID = ?`, unitID); err != nil {
+			return err
+		}
+		return affectedOne(tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = ? WHERE UnitID = ? AND DeleteTime IS NULL`, deletionTime(), unitID))
+	})
+}
+
+// Restore brings back a deleted unit, unless it was archived or its property is deleted
+func (repo *SQLUnitRepository) Restore(ctx context.Context, unitID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		var deleteTime, archiveTime, propertyDeleteTime sql.NullTime
+		err := tx.QueryRowContext(ctx, `SELECT u.DeleteTime, u.ArchiveTime, p.DeleteTime FROM Unit u JOIN Property p ON u.PropertyID = p.PropertyID WHERE u.UnitID = ?`, unitID).
+			Scan(&deleteTime, &archiveTime, &propertyDeleteTime)
+		if err != nil {
+			return notFound(err)
+		}
+		if !deleteTime.Valid {
+			return ErrNotFound
+		}
+		if archiveTime.Valid || propertyDeleteTime.Valid {
+			return ErrNotRestorable
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = NULL WHERE UnitID = ?`, unitID)
+		return err
+	})
+}
+
+func (repo *SQLUnitRepository) ListImages(ctx context.Context, unitID string) ([]Entities.Image, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, `SELECT ImageID, UnitID, Image, Type FROM Images WHERE UnitID = ? AND Type = 'Unit' ORDER BY ImageID`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	images := []Entities.Image{}
+	for rows.Next() {
+		var image Entities.Image
+		if err := rows.Scan(&image.ImageID, &image.UnitID, &image.Image, &image.Type); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
+
+func (repo *SQLUnitRepository) SaveImages(ctx context.Context, unitID string, images []string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT ImageID FROM Images WHERE UnitID = ? AND Type = 'Unit' ORDER BY ImageID LIMIT 4`, unitID)
+		if err != nil {
+			return err
+		}
+		var existing []string
+		for rows.Next() {
+			var imageID string
+			if err := rows.Scan(&imageID); err != nil {
+				rows.Close()
+				return err
+			}
+			existing = append(existing, imageID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, image := range images {
+			if i < len(existing) {
+				_, err = tx.ExecContext(ctx, `UPDATE Images SET Image = ? WHERE ImageID = ?`, image, existing[i])
+			} else {
+				_, err = tx.ExecContext(ctx, `INSERT INTO Images (UnitID, Type, Image) VALUES (?, 'Unit', ?)`, unitID, image)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveAmenities replaces the amenities of the unit
+func saveAmenities(ctx context.Context, tx *sql.Tx, unitID string, amenities []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM UnitAmenity WHERE UnitID = ?`, unitID); err != nil {
+		return err
+	}
+	for _, amenity := range amenities {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO UnitAmenity (UnitID, Amenity) VALUES (?, ?)`, unitID, amenity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertImages(ctx context.Context, tx *sql.Tx, unitID string, images [][]byte) error {
+	for _, image := range images {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO Images (UnitID, Image, Type) VALUES (?, ?, 'Unit')`, unitID, image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLUserRepository struct {
+	db *sql.DB
+}
+
+func NewSQLUserRepository(db *sql.DB) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+const userQuery = `
+    SELECT
+        u.UserID, u.AddressID, u.Name, u.PhoneNumber, u.Email, u.Password, u.CreateTime, u.UserRole, u.Version,
+        ` + addressColumns + `
+    FROM
+        User u
+    LEFT JOIN
+        Address a ON u.AddressID = a.AddressID`
+
+func scanUser(row scanner) (Entities.User, error) {
+	var user Entities.User
+	fields := []interface{}{(*nullableString)(&user.UserID), (*nullableString)(&user.AddressID), &user.Name, (*nullableString)(&user.PhoneNumber), &user.Email, &user.Password, &user.CreateTime, &user.UserRole, &user.Version}
+	err := row.Scan(append(fields, addressFields(&user.Address)...)...)
+	return user, err
+}
+
+// query loads the users that are not deleted, conditions start with AND
+func (repo *SQLUserRepository) query(ctx context.Context, conditions string, args ...interface{}) ([]Entities.User, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, userQuery+` WHERE u.DeleteTime IS NULL`+conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := []Entities.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (repo *SQLUserRepository) GetByID(ctx context.Context, userID string) (Entities.User, error) {
+	user, err := scanUser(conn(ctx, repo.db).QueryRowContext(ctx, userQuery+` WHERE u.UserID = ? AND u.DeleteTime IS NULL`, userID))
+	return user, notFound(err)
+}
+
+func (repo *SQLUserRepository) GetByEmail(ctx context.Context, email string) (Entities.User, error) {
+	user, err := scanUser(conn(ctx, repo.db).QueryRowContext(ctx, userQuery+` WHERE u.Email = ? AND u.DeleteTime IS NULL`, email))
+	return user, notFound(err)
+}
+
+func (repo *SQLUserRepository) List(ctx context.Context) ([]Entities.User, error) {
+	return repo.query(ctx, ` ORDER BY u.UserID`)
+}
+
+func (repo *SQLUserRepository) Create(ctx context.Context, user *Entities.User) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if err := insertAddress(ctx, tx, &user.Address); err != nil {
+			return err
+		}
+		user.AddressID = user.Address.AddressID
+		result, err := tx.ExecContext(ctx, `INSERT INTO User (AddressID, Name, Email, PhoneNumber, Password, UserRole) VALUES (?, ?, ?, ?, ?, ?)`,
+			user.AddressID, user.Name, user.Email, user.PhoneNumber, user.Password, user.UserRole)
+		if err != nil {
+			return duplicate(err)
+		}
+		user.UserID, err = insertedID(result)
+		user.Version = 1
+		return err
+	})
+}
+
+func (repo *SQLUserRepository) Update(ctx context.Context, user Entities.User) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		err := versioned(tx.ExecContext(ctx, `UPDATE User SET Name = ?, PhoneNumber = ?, Email = ?, UserRole = ?, Version = Version + 1 WHERE UserID = ? AND Version = ?`,
+			user.Name, user.PhoneNumber, user.Email, user.UserRole, user.UserID, user.Version))
+		if err != nil {
+			return duplicate(err)
+		}
+		user.Address.AddressID = user.AddressID
+		return updateAddress(ctx, tx, user.Address)
+	})
+}
+
+func (repo *SQLUserRepository) Delete(ctx context.Context, userID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		// Bookings the user made and bookings of the units they own
+		if err := lockRows(ctx, tx, "User", "UserID", `UserID = ?`, userID); err != nil {
+			return err
+		}
+		if err := lockRows(ctx, tx, "Unit", "UnitID", `PropertyID IN (SELECT PropertyID FROM Property WHERE OwnerID = ?)`, userID); err != nil {
+			return err
+		}
+		if err := noUpcomingBookings(ctx, tx, `b.UserID = ? OR p.OwnerID = ?`, userID, userID); err != nil {
+			return err
+		}
+		now := deletionTime()
+		if err := affectedOne(tx.ExecContext(ctx, `UPDATE User SET DeleteTime = ? WHERE UserID = ? AND DeleteTime IS NULL`, now, userID)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = ? WHERE DeleteTime IS NULL AND PropertyID IN (SELECT PropertyID FROM Property WHERE OwnerID = ? AND DeleteTime IS NULL)`, now, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE Property SET DeleteTime = ? WHERE OwnerID = ? AND DeleteTime IS NULL`, now, userID)
+		return err
+	})
+}
+
+// Restore brings back a deleted user with the properties and units that were deleted with them, or returns
+// ErrDuplicate when a live user has their email
+func (repo *SQLUserRepository) Restore(ctx context.Context, userID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		var deleteTime sql.NullTime
+		if err := tx.QueryRowContext(ctx, `SELECT DeleteTime FROM User WHERE UserID = ?`, userID).Scan(&deleteTime); err != nil {
+			return notFound(err)
+		}
+		if !deleteTime.Valid {
+			return ErrNotFound
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE Unit SET DeleteTime = NULL WHERE DeleteTime = ? AND ArchiveTime IS NULL AND PropertyID IN (SELECT PropertyID FROM Property WHERE OwnerID = ? AND DeleteTime = ? AND ArchiveTime IS NULL)`,
+			deleteTime.Time, userID, deleteTime.Time)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE Property SET DeleteTime = NULL WHERE OwnerID = ? AND DeleteTime = ? AND ArchiveTime IS NULL`, userID, deleteTime.Time); err != nil {
+			return err
+		}
+		// Another user can have signed up with the email since
+		_, err = tx.ExecContext(ctx, `UPDATE User SET DeleteTime = NULL WHERE UserID = ?`, userID)
+		return duplicate(err)
+	})
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	Entities "GraduationProject.com/m/internal/model"
+)
+
+type SQLWishlistRepository struct {
+	db *sql.DB
+}
+
+func NewSQLWishlistRepository(db *sql.DB) *SQLWishlistRepository {
+	return &SQLWishlistRepository{db: db}
+}
+
+const (
+	wishlistQuery = `SELECT w.WishlistID, w.UserID, w.Name, w.ShareToken, w.CreateTime FROM Wishlist w`
+	itemQuery     = `
+        SELECT wu.WishlistID, wu.UnitID, u.Name, u.RentalPrice, wu.CreateTime
+        FROM WishlistUnit wu
+        JOIN Unit u ON wu.UnitID = u.UnitID AND u.DeleteTime IS NULL
+        JOIN Wishlist w ON wu.WishlistID = w.WishlistID`
+)
+
+// query loads the wishlists matching where, together with their units in one extra query
+func (repo *SQLWishlistRepository) query(ctx context.Context, where string, args ...interface{}) ([]Entities.Wishlist, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, wishlistQuery+where+` ORDER BY w.CreateTime, w.WishlistID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	wishlists := []Entities.Wishlist{}
+	for rows.Next() {
+		var wishlist Entities.Wishlist
+		if err := rows.Scan(&wishlist.WishlistID, &wishlist.UserID, &wishlist.Name, (*nullableString)(&wishlist.ShareToken), &wishlist.CreateTime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		wishlist.Items = []Entities.WishlistItem{}
+		wishlists = append(wishlists, wishlist)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(wishlists) == 0 {
+		return wishlists, err
+	}
+
+	rows, err = conn(ctx, repo.db).QueryContext(ctx, itemQuery+where+` ORDER BY wu.CreateTime, wu.UnitID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make(map[string][]Entities.WishlistItem)
+	for rows.Next() {
+		var wishlistID string
+		var item Entities.WishlistItem
+		if err := rows.Scan(&wishlistID, &item.UnitID, (*nullableString)(&item.Name), &item.RentalPrice, &item.CreateTime); err != nil {
+			return nil, err
+		}
+		items[wishlistID] = append(items[wishlistID], item)
+	}
+	for i := range wishlists {
+		if found := items[wishlists[i].WishlistID]; found != nil {
+			wishlists[i].Items = found
+		}
+	}
+	return wishlists, rows.Err()
+}
+
+func (repo *SQLWishlistRepository) get(ctx context.Context, where string, args ...interface{}) (Entities.Wishlist, error) {
+	wishlists, err := repo.query(ctx, where, args...)
+	if err != nil {
+		return Entities.Wishlist{}, err
+	}
+	if len(wishlists) == 0 {
+		return Entities.Wishlist{}, ErrNotFound
+	}
+	return wishlists[0], nil
+}
+
+func (repo *SQLWishlistRepository) GetByID(ctx context.Context, wishlistID string) (Entities.Wishlist, error) {
+	return repo.get(ctx, ` WHERE w.WishlistID = ?`, wishlistID)
+}
+
+func (repo *SQLWishlistRepository) GetByShareToken(ctx context.Context, token string) (Entities.Wishlist, error) {
+	return repo.get(ctx, ` WHERE w.ShareToken = ?`, token)
+}
+
+func (repo *SQLWishlistRepository) ListByUser(ctx context.Context, userID string) ([]Entities.Wishlist, error) {
+	return repo.query(ctx, ` WHERE w.UserID = ?`, userID)
+}
+
+func (repo *SQLWishlistRepository) ListUserIDsByUnit(ctx context.Context, unitID string) ([]string, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, `SELECT DISTINCT w.UserID FROM WishlistUnit wu JOIN Wishlist w ON wu.WishlistID = w.WishlistID WHERE wu.UnitID = ?`, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (repo *SQLWishlistRepository) Create(ctx context.Context, wishlist *Entities.Wishlist) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO Wishlist (UserID, Name) VALUES (?, ?)`, wishlist.UserID, wishlist.Name)
+	if err != nil {
+		return err
+	}
+	wishlist.WishlistID, err = insertedID(result)
+	return err
+}
+
+func (repo *SQLWishlistRepository) Rename(ctx context.Context, wishlistID, name string) error {
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `UPDATE Wishlist SET Name = ? WHERE WishlistID = ?`, name, wishlistID)
+	return err
+}
+
+func (repo *SQLWishlistRepository) Delete(ctx context.Context, wishlistID string) error {
+	return inTx(ctx, repo.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM WishlistUnit WHERE WishlistID = ?`, wishlistID); err != nil {
+			return err
+		}
+		return affectedOne(tx.ExecContext(ctx, `DELETE FROM Wishlist WHERE WishlistID = ?`, wishlistID))
+	})
+}
+
+func (repo *SQLWishlistRepository) AddUnit(ctx context.Context, wishlistID, unitID string) error {
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `
+        INSERT INTO WishlistUnit (WishlistID, UnitID)
+        SELECT w.WishlistID, ? FROM Wishlist w
+        WHERE w.WishlistID = ? AND NOT EXISTS (SELECT 1 FROM WishlistUnit wu WHERE wu.WishlistID = w.WishlistID AND wu.UnitID = ?)`,
+		unitID, wishlistID, unitID)
+	// Two requests adding the same unit at once both pass the NOT EXISTS check
+	if err = duplicate(err); err == ErrDuplicate {
+		return nil
+	}
+	return err
+}
+
+func (repo *SQLWishlistRepository) RemoveUnit(ctx context.Context, wishlistID, unitID string) error {
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM WishlistUnit WHERE WishlistID = ? AND UnitID = ?`, wishlistID, unitID)
+	return err
+}
+
+func (repo *SQLWishlistRepository) SetShareToken(ctx context.Context, wishlistID, token string) error {
+	if token == "" {
+		_, err := conn(ctx, repo.db).ExecContext(ctx, `UPDATE Wishlist SET ShareToken = NULL WHERE WishlistID = ?`, wishlistID)
+		return err
+	}
+	_, err := conn(ctx, repo.db).ExecContext(ctx, `UPDATE Wishlist SET ShareToken = ? WHERE WishlistID = ? AND ShareToken IS NULL`, token, wishlistID)
+	return err
+}
+
+type SQLSavedSearchRepository struct {
+	db *sql.DB
+}
+
+func NewSQLSavedSearchRepository(db *sql.DB) *SQLSavedSearchRepository {
+	return &SQLSavedSearchRepository{db: db}
+}
+
+func (repo *SQLSavedSearchRepository) query(ctx context.Context, where string, args ...interface{}) ([]Entities.SavedSearch, error) {
+	rows, err := conn(ctx, repo.db).QueryContext(ctx, `SELECT SavedSearchID, UserID, Name, Criteria, CreateTime FROM SavedSearch`+where+` ORDER BY CreateTime, SavedSearchID`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	searches := []Entities.SavedSearch{}
+	for rows.Next() {
+		var search Entities.SavedSearch
+		if err := rows.Scan(&search.SavedSearchID, &search.UserID, &search.Name, &search.Criteria, &search.CreateTime); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+func (repo *SQLSavedSearchRepository) List(ctx context.Context) ([]Entities.SavedSearch, error) {
+	return repo.query(ctx, ``)
+}
+
+func (repo *SQLSavedSearchRepository) ListByUser(ctx context.Context, userID string) ([]Entities.SavedSearch, error) {
+	return repo.query(ctx, ` WHERE UserID = ?`, userID)
+}
+
+func (repo *SQLSavedSearchRepository) Create(ctx context.Context, search *Entities.SavedSearch) error {
+	result, err := conn(ctx, repo.db).ExecContext(ctx, `INSERT INTO SavedSearch (UserID, Name, Criteria) VALUES (?, ?, ?)`, search.UserID, search.Name, search.Criteria)
+	if err != nil {
+		return err
+	}
+	search.SavedSearchID, err = insertedID(result)
+	return err
+}
+
+func (repo *SQLSavedSearchRepository) Delete(ctx context.Context, savedSearchID string) error {
+	return affectedOne(conn(ctx, repo.db).ExecContext(ctx, `DELETE FROM SavedSearch WHERE SavedSearchID = ?`, savedSearchID))
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Audit struct {
+	s *store
+}
+
+func (repo *Audit) Append(ctx context.Context, entry *Entities.AuditEntry) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	entry.AuditID = repo.s.nextID()
+	entry.CreateTime = time.Now().UTC()
+	repo.s.audit[entry.AuditID] = *entry
+	return nil
+}
+
+// List returns the newest entries first, at most 100 unless the filter says otherwise like the SQL repository
+func (repo *Audit) List(ctx context.Context, filter repository.AuditFilter) ([]Entities.AuditEntry, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	all := sorted(repo.s.audit, func(entry Entities.AuditEntry) bool {
+		return (filter.EntityType == "" || entry.EntityType == filter.EntityType) &&
+			(filter.EntityID == "" || entry.EntityID == filter.EntityID) &&
+			(filter.Actor == "" || entry.Actor == filter.Actor)
+	})
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	entries := []Entities.AuditEntry{}
+	for i := len(all) - 1 - filter.Offset; i >= 0 && len(entries) < filter.Limit; i-- {
+		entries = append(entries, all[i])
+	}
+	return entries, nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Bookings struct {
+	s *store
+}
+
+func (repo *Bookings) list(keep func(Entities.Booking) bool) []Entities.Booking {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.bookings, keep)
+}
+
+func (repo *Bookings) GetByID(ctx context.Context, bookingID string) (Entities.Booking, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	booking, ok := repo.s.bookings[bookingID]
+	if !ok {
+		return Entities.Booking{}, repository.ErrNotFound
+	}
+	return booking, nil
+}
+
+func (repo *Bookings) ListByUnit(ctx context.Context, unitID string) ([]Entities.Booking, error) {
+	return repo.list(func(booking Entities.Booking) bool { return booking.UnitID == unitID }), nil
+}
+
+func (repo *Bookings) ListByUser(ctx context.Context, userID string) ([]Entities.Booking, error) {
+	return repo.list(func(booking Entities.Booking) bool { return booking.UserID == userID }), nil
+}
+
+func (repo *Bookings) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Booking, error) {
+	return repo.list(func(booking Entities.Booking) bool {
+		unit, ok := repo.s.units[booking.UnitID]
+		return ok && repo.s.properties[unit.PropertyID].OwnerID == ownerID
+	}), nil
+}
+
+// reserve checks that the unit is there and the dates are free like the SQL query, callers hold the write lock
+func (repo *Bookings) reserve(booking Entities.Booking) error {
+	unit, ok := repo.s.units[booking.UnitID]
+	if !ok || unit.DeleteTime != nil {
+		return repository.ErrNotFound
+	}
+	for _, other := range repo.s.bookings {
+		if other.UnitID == booking.UnitID && other.BookingID != booking.BookingID && other.StartDate.Before(booking.EndDate) && other.EndDate.After(booking.StartDate) {
+			return repository.ErrOverlap
+		}
+	}
+	return nil
+}
+
+func (repo *Bookings) Create(ctx context.Context, booking *Entities.Booking) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if err := repo.reserve(*booking); err != nil {
+		return err
+	}
+	booking.BookingID = repo.s.nextID()
+	booking.CreateTime, booking.Version = time.Now().UTC(), 1
+	repo.s.bookings[booking.BookingID] = *booking
+	return nil
+}
+
+func (repo *Bookings) Update(ctx context.Context, booking Entities.Booking) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if err := repo.reserve(booking); err != nil {
+		return err
+	}
+	existing, ok := repo.s.bookings[booking.BookingID]
+	if !ok || existing.Version != booking.Version {
+		return repository.ErrVersionConflict
+	}
+	existing.Version++
+	existing.CheckIn, existing.CheckOut = booking.CheckIn, booking.CheckOut
+	existing.StartDate, existing.EndDate, existing.Summary = booking.StartDate, booking.EndDate, booking.Summary
+	repo.s.bookings[booking.BookingID] = existing
+	return nil
+}
+
+func (repo *Bookings) Delete(ctx context.Context, bookingID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.bookings[bookingID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.bookings, bookingID)
+	return nil
+}
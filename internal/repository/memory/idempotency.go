@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Idempotency struct {
+	s *store
+}
+
+func idempotencyID(actor, key string) string {
+	return actor + "\x00" + key
+}
+
+func (repo *Idempotency) Reserve(ctx context.Context, key *Entities.IdempotencyKey) (Entities.IdempotencyKey, error) {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	now := time.Now().UTC()
+	id := idempotencyID(key.Actor, key.Key)
+	if stored, ok := repo.s.idempotency[id]; ok && stored.ExpireTime.After(now) {
+		return stored, repository.ErrDuplicate
+	}
+	key.CreateTime = now
+	repo.s.idempotency[id] = *key
+	return Entities.IdempotencyKey{}, nil
+}
+
+func (repo *Idempotency) Complete(ctx context.Context, key Entities.IdempotencyKey) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	id := idempotencyID(key.Actor, key.Key)
+	stored, ok := repo.s.idempotency[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	stored.Status, stored.ContentType, stored.Body = key.Status, key.ContentType, key.Body
+	repo.s.idempotency[id] = stored
+	return nil
+}
+
+func (repo *Idempotency) Release(ctx context.Context, actor, key string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	delete(repo.s.idempotency, idempotencyID(actor, key))
+	return nil
+}
+
+func (repo *Idempotency) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	var deleted int64
+	for id, key := range repo.s.idempotency {
+		if !key.ExpireTime.After(before) {
+			delete(repo.s.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,154 @@
+// Package memory implements the repositories in memory, for tests that should not need a database.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+// store holds every table. Like the database, addresses are their own table so units see changes to
+// the address of their property, and the repositories only ever hand out copies.
+type store struct {
+	mu            sync.RWMutex
+	tx            sync.Mutex // Held by InTx, mu is taken by every call on its own
+	lastID        int64
+	addresses     map[string]Entities.Address
+	users         map[string]Entities.User
+	units         map[string]Entities.Unit
+	images        map[string]Entities.Image
+	properties    map[string]Entities.Property
+	bookings      map[string]Entities.Booking
+	reviews       map[string]Entities.Review
+	chats         map[string]Entities.Chat
+	messages      map[string]Entities.Message
+	transactions  map[string]Entities.FinancialTransaction
+	tickets       map[string]Entities.MaintenanceTicket
+	reports       map[string]Entities.Report
+	wishlists     map[string]Entities.Wishlist
+	savedSearches map[string]Entities.SavedSearch
+	notifications map[string]Entities.Notification
+	audit         map[string]Entities.AuditEntry
+	idempotency   map[string]Entities.IdempotencyKey // By actor and key
+}
+
+// New returns empty in-memory repositories that share one store
+func New() repository.Repositories {
+	s := &store{
+		addresses:     make(map[string]Entities.Address),
+		users:         make(map[string]Entities.User),
+		units:         make(map[string]Entities.Unit),
+		images:        make(map[string]Entities.Image),
+		properties:    make(map[string]Entities.Property),
+		bookings:      make(map[string]Entities.Booking),
+		reviews:       make(map[string]Entities.Review),
+		chats:         make(map[string]Entities.Chat),
+		messages:      make(map[string]Entities.Message),
+		transactions:  make(map[string]Entities.FinancialTransaction),
+		tickets:       make(map[string]Entities.MaintenanceTicket),
+		reports:       make(map[string]Entities.Report),
+		wishlists:     make(map[string]Entities.Wishlist),
+		savedSearches: make(map[string]Entities.SavedSearch),
+		notifications: make(map[string]Entities.Notification),
+		audit:         make(map[string]Entities.AuditEntry),
+		idempotency:   make(map[string]Entities.IdempotencyKey),
+	}
+	return repository.Repositories{
+		Users:         &Users{s},
+		Units:         &Units{s},
+		Properties:    &Properties{s},
+		Bookings:      &Bookings{s},
+		Reviews:       &Reviews{s},
+		Messages:      &Messages{s},
+		Transactions:  &Transactions{s},
+		Tickets:       &Tickets{s},
+		Reports:       &Reports{s},
+		Wishlists:     &Wishlists{s},
+		SavedSearches: &SavedSearches{s},
+		Notifications: &Notifications{s},
+		Audit:         &Audit{s},
+		Idempotency:   &Idempotency{s},
+		Transactor:    &transactor{s},
+	}
+}
+
+type transactor struct {
+	s *store
+}
+
+// inTxKey marks the context of InTx, the calls in it are already one at a time
+type inTxKey struct{}
+
+// InTx runs one fn at a time, so the writes of another InTx do not come between the calls of fn. There is no
+// rollback, what fn wrote before it failed stays.
+func (t *transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(inTxKey{}) != nil {
+		return fn(ctx)
+	}
+	t.s.tx.Lock()
+	defer t.s.tx.Unlock()
+	return fn(context.WithValue(ctx, inTxKey{}, true))
+}
+
+// nextID hands out increasing IDs like AUTO_INCREMENT, callers hold the write lock
+func (s *store) nextID() string {
+	s.lastID++
+	return strconv.FormatInt(s.lastID, 10)
+}
+
+func (s *store) saveAddress(address *Entities.Address) {
+	if address.AddressID == "" {
+		address.AddressID = s.nextID()
+	}
+	s.addresses[address.AddressID] = *address
+}
+
+// sorted returns the values of the map that match keep, ordered by their numeric ID
+func sorted[T any](values map[string]T, keep func(T) bool) []T {
+	ids := make([]string, 0, len(values))
+	for id, value := range values {
+		if keep == nil || keep(value) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		if a != b {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, values[id])
+	}
+	return result
+}
+
+// deletionTime is stamped on everything deleted together, like in the database
+func deletionTime() *time.Time {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &now
+}
+
+// hasUpcomingBookings reports whether a booking matching keep has not ended yet, callers hold the lock
+func (s *store) hasUpcomingBookings(keep func(booking Entities.Booking, unit Entities.Unit, property Entities.Property) bool) bool {
+	now := time.Now()
+	for _, booking := range s.bookings {
+		unit := s.units[booking.UnitID]
+		if booking.EndDate.After(now) && keep(booking, unit, s.properties[unit.PropertyID]) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameTime(a, b *time.Time) bool {
+	return a != nil && b != nil && a.Equal(*b)
+}
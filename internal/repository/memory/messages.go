@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Messages struct {
+	s *store
+}
+
+// read attaches the chat's messages, filling in who each one was sent to
+func (repo *Messages) read(chat Entities.Chat) Entities.Chat {
+	chat.Messages = sorted(repo.s.messages, func(message Entities.Message) bool { return message.ChatID == chat.ChatID })
+	for i, message := range chat.Messages {
+		chat.Messages[i].ReceiverID = chat.ReceiverID
+		if message.SenderID == chat.ReceiverID {
+			chat.Messages[i].ReceiverID = chat.SenderID
+		}
+	}
+	return chat
+}
+
+func (repo *Messages) GetChat(ctx context.Context, chatID string) (Entities.Chat, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	chat, ok := repo.s.chats[chatID]
+	if !ok {
+		return Entities.Chat{}, repository.ErrNotFound
+	}
+	return repo.read(chat), nil
+}
+
+func (repo *Messages) ListChatsByUser(ctx context.Context, userID string) ([]Entities.Chat, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	chats := sorted(repo.s.chats, func(chat Entities.Chat) bool { return chat.SenderID == userID || chat.ReceiverID == userID })
+	for i := range chats {
+		chats[i] = repo.read(chats[i])
+	}
+	return chats, nil
+}
+
+func (repo *Messages) GetOrCreateChat(ctx context.Context, senderID, receiverID string) (Entities.Chat, bool, error) {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	for _, chat := range sorted(repo.s.chats, nil) {
+		if (chat.SenderID == senderID && chat.ReceiverID == receiverID) || (chat.SenderID == receiverID && chat.ReceiverID == senderID) {
+			return repo.read(chat), false, nil
+		}
+	}
+	chat := Entities.Chat{ChatID: repo.s.nextID(), SenderID: senderID, ReceiverID: receiverID, CreateTime: time.Now().UTC()}
+	repo.s.chats[chat.ChatID] = chat
+	chat.Messages = []Entities.Message{}
+	return chat, true, nil
+}
+
+func (repo *Messages) CreateMessage(ctx context.Context, message *Entities.Message) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.chats[message.ChatID]; !ok {
+		return repository.ErrNotFound
+	}
+	message.MessageID = repo.s.nextID()
+	message.CreateTime = time.Now().UTC()
+	repo.s.messages[message.MessageID] = *message
+	return nil
+}
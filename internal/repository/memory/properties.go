@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Properties struct {
+	s *store
+}
+
+func (repo *Properties) read(property Entities.Property) Entities.Property {
+	property.Address = repo.s.addresses[property.AddressID]
+	return property
+}
+
+func (repo *Properties) list(keep func(Entities.Property) bool) []Entities.Property {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	properties := sorted(repo.s.properties, func(property Entities.Property) bool {
+		return property.DeleteTime == nil && (keep == nil || keep(property))
+	})
+	for i := range properties {
+		properties[i] = repo.read(properties[i])
+	}
+	return properties
+}
+
+func (repo *Properties) GetByID(ctx context.Context, propertyID string) (Entities.Property, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	property, ok := repo.s.properties[propertyID]
+	if !ok || property.DeleteTime != nil {
+		return Entities.Property{}, repository.ErrNotFound
+	}
+	return repo.read(property), nil
+}
+
+func (repo *Properties) List(ctx context.Context) ([]Entities.Property, error) {
+	return repo.list(nil), nil
+}
+
+func (repo *Properties) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Property, error) {
+	return repo.list(func(property Entities.Property) bool { return property.OwnerID == ownerID }), nil
+}
+
+func (repo *Properties) ListByType(ctx context.Context, propertyType string) ([]Entities.Property, error) {
+	return repo.list(func(property Entities.Property) bool { return property.Type == propertyType }), nil
+}
+
+func (repo *Properties) Create(ctx context.Context, property *Entities.Property) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	property.Address.AddressID = ""
+	repo.s.saveAddress(&property.Address)
+	property.AddressID = property.Address.AddressID
+	property.PropertyID = repo.s.nextID()
+	property.CreateTime, property.Version = time.Now().UTC(), 1
+	stored := *property
+	stored.Address, stored.Units, stored.Photos = Entities.Address{}, nil, nil
+	repo.s.properties[property.PropertyID] = stored
+	return nil
+}
+
+func (repo *Properties) Update(ctx context.Context, property Entities.Property) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.properties[property.PropertyID]
+	if !ok || existing.Version != property.Version {
+		return repository.ErrVersionConflict
+	}
+	existing.Version++
+	existing.Name, existing.Description, existing.Type, existing.Rules = property.Name, property.Description, property.Type, property.Rules
+	existing.TimeZone, existing.CheckInTime, existing.CheckOutTime = property.TimeZone, property.CheckInTime, property.CheckOutTime
+	repo.s.properties[property.PropertyID] = existing
+	if existing.AddressID != "" {
+		property.Address.AddressID = existing.AddressID
+		repo.s.addresses[existing.AddressID] = property.Address
+	}
+	return nil
+}
+
+func (repo *Properties) Delete(ctx context.Context, propertyID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	property, ok := repo.s.properties[propertyID]
+	if !ok || property.DeleteTime != nil {
+		return repository.ErrNotFound
+	}
+	if repo.s.hasUpcomingBookings(func(_ Entities.Booking, unit Entities.Unit, _ Entities.Property) bool {
+		return unit.PropertyID == propertyID
+	}) {
+		return repository.ErrUpcomingBookings
+	}
+	repo.s.deleteProperty(property, deletionTime())
+	return nil
+}
+
+// deleteProperty stamps the property and its units that are not deleted yet, callers hold the write lock
+func (s *store) deleteProperty(property Entities.Property, now *time.Time) {
+	property.DeleteTime = now
+	s.properties[property.PropertyID] = property
+	for id, unit := range s.units {
+		if unit.PropertyID == property.PropertyID && unit.DeleteTime == nil {
+			unit.DeleteTime = now
+			s.units[id] = unit
+		}
+	}
+}
+
+// restoreProperty clears the property and the units that were deleted with it, callers hold the write lock
+func (s *store) restoreProperty(property Entities.Property) {
+	for id, unit := range s.units {
+		if unit.PropertyID == property.PropertyID && unit.ArchiveTime == nil && sameTime(unit.DeleteTime, property.DeleteTime) {
+			unit.DeleteTime = nil
+			s.units[id] = unit
+		}
+	}
+	property.DeleteTime = nil
+	s.properties[property.PropertyID] = property
+}
+
+func (repo *Properties) Restore(ctx context.Context, propertyID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	property, ok := repo.s.properties[propertyID]
+	if !ok || property.DeleteTime == nil {
+		return repository.ErrNotFound
+	}
+	if owner, ok := repo.s.users[property.OwnerID]; property.ArchiveTime != nil || (ok && owner.DeleteTime != nil) {
+		return repository.ErrNotRestorable
+	}
+	repo.s.restoreProperty(property)
+	return nil
+}
+
+func (repo *Properties) Archive(ctx context.Context, propertyID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	property, ok := repo.s.properties[propertyID]
+	if !ok || property.ArchiveTime != nil {
+		return repository.ErrNotFound
+	}
+	if repo.s.hasUpcomingBookings(func(_ Entities.Booking, unit Entities.Unit, _ Entities.Property) bool {
+		return unit.PropertyID == propertyID
+	}) {
+		return repository.ErrUpcomingBookings
+	}
+	now := deletionTime()
+	if property.DeleteTime == nil {
+		property.DeleteTime = now
+	}
+	property.ArchiveTime = now
+	repo.s.properties[propertyID] = property
+	units := make(map[string]bool)
+	for id, unit := range repo.s.units {
+		if unit.PropertyID == propertyID && unit.ArchiveTime == nil {
+			if unit.DeleteTime == nil {
+				unit.DeleteTime = now
+			}
+			unit.ArchiveTime = now
+			repo.s.units[id] = unit
+			units[id] = true
+		}
+	}
+	for id, image := range repo.s.images {
+		if image.Type != "proof" && (image.PropertyID == propertyID || units[image.UnitID]) {
+			delete(repo.s.images, id)
+		}
+	}
+	return nil
+}
+
+func (repo *Properties) GetProof(ctx context.Context, propertyID string) ([]byte, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	for _, image := range repo.s.images {
+		if image.PropertyID == propertyID && image.Type == "proof" {
+			return []byte(image.Image), nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (repo *Properties) SaveProof(ctx context.Context, propertyID string, url string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	for id, image := range repo.s.images {
+		if image.PropertyID == propertyID && image.Type == "proof" {
+			image.Image = url
+			repo.s.images[id] = image
+			return nil
+		}
+	}
+	id := repo.s.nextID()
+	repo.s.images[id] = Entities.Image{ImageID: id, PropertyID: propertyID, Image: url, Type: "proof"}
+	return nil
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Reviews struct {
+	s *store
+}
+
+func (repo *Reviews) GetByID(ctx context.Context, reviewID string) (Entities.Review, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	review, ok := repo.s.reviews[reviewID]
+	if !ok {
+		return Entities.Review{}, repository.ErrNotFound
+	}
+	return review, nil
+}
+
+func (repo *Reviews) ListByUnit(ctx context.Context, unitID string) ([]Entities.Review, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.reviews, func(review Entities.Review) bool { return review.UnitID == unitID }), nil
+}
+
+func (repo *Reviews) Create(ctx context.Context, review *Entities.Review) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	review.ReviewID = repo.s.nextID()
+	review.CreateTime, review.Version = time.Now().UTC(), 1
+	repo.s.reviews[review.ReviewID] = *review
+	return nil
+}
+
+func (repo *Reviews) Update(ctx context.Context, review Entities.Review) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.reviews[review.ReviewID]
+	if !ok || existing.Version != review.Version {
+		return repository.ErrVersionConflict
+	}
+	review.CreateTime, review.Version = existing.CreateTime, existing.Version+1
+	repo.s.reviews[review.ReviewID] = review
+	return nil
+}
+
+func (repo *Reviews) Delete(ctx context.Context, reviewID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.reviews[reviewID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.reviews, reviewID)
+	return nil
+}
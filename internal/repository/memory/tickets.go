@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Tickets struct {
+	s *store
+}
+
+func (repo *Tickets) GetByID(ctx context.Context, ticketID string) (Entities.MaintenanceTicket, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	ticket, ok := repo.s.tickets[ticketID]
+	if !ok {
+		return Entities.MaintenanceTicket{}, repository.ErrNotFound
+	}
+	return ticket, nil
+}
+
+func (repo *Tickets) Create(ctx context.Context, ticket *Entities.MaintenanceTicket) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if ticket.TicketID == "" {
+		ticket.TicketID = repo.s.nextID()
+	} else if _, ok := repo.s.tickets[ticket.TicketID]; ok {
+		return repository.ErrDuplicate
+	}
+	ticket.CreateTime, ticket.Version = time.Now().UTC(), 1
+	repo.s.tickets[ticket.TicketID] = *ticket
+	return nil
+}
+
+func (repo *Tickets) Update(ctx context.Context, ticket Entities.MaintenanceTicket) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.tickets[ticket.TicketID]
+	if !ok || existing.Version != ticket.Version {
+		return repository.ErrVersionConflict
+	}
+	ticket.CreateTime, ticket.Version = existing.CreateTime, existing.Version+1
+	repo.s.tickets[ticket.TicketID] = ticket
+	return nil
+}
+
+func (repo *Tickets) Delete(ctx context.Context, ticketID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.tickets[ticketID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.tickets, ticketID)
+	return nil
+}
+
+type Reports struct {
+	s *store
+}
+
+func (repo *Reports) GetByID(ctx context.Context, reportID string) (Entities.Report, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	report, ok := repo.s.reports[reportID]
+	if !ok {
+		return Entities.Report{}, repository.ErrNotFound
+	}
+	return report, nil
+}
+
+func (repo *Reports) Create(ctx context.Context, report *Entities.Report) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if report.ReportID == "" {
+		report.ReportID = repo.s.nextID()
+	} else if _, ok := repo.s.reports[report.ReportID]; ok {
+		return repository.ErrDuplicate
+	}
+	if report.CreateTime.IsZero() {
+		report.CreateTime = time.Now().UTC()
+	}
+	report.Version = 1
+	repo.s.reports[report.ReportID] = *report
+	return nil
+}
+
+func (repo *Reports) Update(ctx context.Context, report Entities.Report) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.reports[report.ReportID]
+	if !ok || existing.Version != report.Version {
+		return repository.ErrVersionConflict
+	}
+	report.Version = existing.Version + 1
+	repo.s.reports[report.ReportID] = report
+	return nil
+}
+
+func (repo *Reports) Delete(ctx context.Context, reportID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.reports[reportID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.reports, reportID)
+	return nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Transactions struct {
+	s *store
+}
+
+func (repo *Transactions) GetByID(ctx context.Context, transactionID string) (Entities.FinancialTransaction, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	transaction, ok := repo.s.transactions[transactionID]
+	if !ok {
+		return Entities.FinancialTransaction{}, repository.ErrNotFound
+	}
+	return transaction, nil
+}
+
+func (repo *Transactions) ListByUser(ctx context.Context, userID string) ([]Entities.FinancialTransaction, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.transactions, func(transaction Entities.FinancialTransaction) bool { return transaction.UserID == userID }), nil
+}
+
+func (repo *Transactions) ListByOwner(ctx context.Context, ownerID string) ([]Entities.FinancialTransaction, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.transactions, func(transaction Entities.FinancialTransaction) bool {
+		unit, ok := repo.s.units[repo.s.bookings[transaction.BookingID].UnitID]
+		return ok && repo.s.properties[unit.PropertyID].OwnerID == ownerID
+	}), nil
+}
+
+func (repo *Transactions) Create(ctx context.Context, transaction *Entities.FinancialTransaction) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	transaction.TransactionID = repo.s.nextID()
+	transaction.CreateTime, transaction.Version = time.Now().UTC(), 1
+	repo.s.transactions[transaction.TransactionID] = *transaction
+	return nil
+}
+
+func (repo *Transactions) Update(ctx context.Context, transaction Entities.FinancialTransaction) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.transactions[transaction.TransactionID]
+	if !ok || existing.Version != transaction.Version {
+		return repository.ErrVersionConflict
+	}
+	existing.Version++
+	existing.PaymentMethod, existing.Amount = transaction.PaymentMethod, transaction.Amount
+	repo.s.transactions[transaction.TransactionID] = existing
+	return nil
+}
+
+func (repo *Transactions) Delete(ctx context.Context, transactionID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.transactions[transactionID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.transactions, transactionID)
+	return nil
+}
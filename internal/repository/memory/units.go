@@ -0,0 +1,343 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"GraduationProject.com/m/internal/listing"
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Units struct {
+	s *store
+}
+
+// read fills in what the SQL query joins in: the address, the property type and the owner's name
+func (repo *Units) read(unit Entities.Unit) Entities.Unit {
+	unit.Address = repo.s.addresses[unit.AddressID]
+	property := repo.s.properties[unit.PropertyID]
+	unit.PropertyType = property.Type
+	unit.OwnerName = repo.s.users[property.OwnerID].Name
+	unit.Amenities = append([]string{}, unit.Amenities...)
+	unit.Images = nil
+	return unit
+}
+
+func (repo *Units) list(keep func(Entities.Unit) bool) []Entities.Unit {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	units := sorted(repo.s.units, func(unit Entities.Unit) bool { return unit.DeleteTime == nil && (keep == nil || keep(unit)) })
+	for i := range units {
+		units[i] = repo.read(units[i])
+	}
+	return units
+}
+
+func (repo *Units) GetByID(ctx context.Context, unitID string) (Entities.Unit, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	unit, ok := repo.s.units[unitID]
+	if !ok || unit.DeleteTime != nil {
+		return Entities.Unit{}, repository.ErrNotFound
+	}
+	return repo.read(unit), nil
+}
+
+func (repo *Units) List(ctx context.Context) ([]Entities.Unit, error) {
+	return repo.list(nil), nil
+}
+
+func (repo *Units) ListByProperty(ctx context.Context, propertyID string) ([]Entities.Unit, error) {
+	return repo.list(func(unit Entities.Unit) bool { return unit.PropertyID == propertyID }), nil
+}
+
+func (repo *Units) ListByOwner(ctx context.Context, ownerID string) ([]Entities.Unit, error) {
+	return repo.list(func(unit Entities.Unit) bool { return repo.s.properties[unit.PropertyID].OwnerID == ownerID }), nil
+}
+
+// ListPage filters, sorts and pages the units like the SQL query, and counts the facets of the filter
+func (repo *Units) ListPage(ctx context.Context, req listing.Request) (listing.Page, error) {
+	after, err := req.Normalize()
+	if err != nil {
+		return listing.Page{}, err
+	}
+	all := repo.list(nil)
+	var matches []Entities.Unit
+	for _, unit := range all {
+		if req.Filter.Matches(unit) {
+			matches = append(matches, unit)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return compare(listing.CursorOf(req.Sort, matches[i]), listing.CursorOf(req.Sort, matches[j])) < 0
+	})
+
+	page := listing.Page{Total: len(matches), Units: []Entities.Unit{}, Facets: facets(all, req.Filter)}
+	for _, unit := range matches {
+		if after != nil && compare(listing.CursorOf(req.Sort, unit), *after) <= 0 {
+			continue
+		}
+		if len(page.Units) == req.Limit {
+			page.NextCursor = listing.CursorOf(req.Sort, page.Units[len(page.Units)-1]).Encode()
+			break
+		}
+		page.Units = append(page.Units, unit)
+	}
+	return page, nil
+}
+
+// compare orders two cursors of the same sort like the SQL query: by the sort key, then by UnitID
+func compare(a, b listing.Cursor) int {
+	var order int
+	switch a.Sort {
+	case listing.SortPriceAsc:
+		order = a.Price - b.Price
+	case listing.SortPriceDesc:
+		order = b.Price - a.Price
+	case listing.SortRating:
+		if a.Rating != b.Rating {
+			order = 1
+			if a.Rating > b.Rating {
+				order = -1
+			}
+		}
+	default:
+		if a.Time != b.Time {
+			order = 1
+			if a.Time > b.Time {
+				order = -1
+			}
+		}
+	}
+	if order != 0 {
+		return order
+	}
+	x, _ := strconv.ParseInt(a.UnitID, 10, 64)
+	y, _ := strconv.ParseInt(b.UnitID, 10, 64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// facets counts every facet over the units that match the other criteria of the filter
+func facets(units []Entities.Unit, filter listing.Filter) listing.Facets {
+	without := func(facet string) []Entities.Unit {
+		others := filter
+		switch facet {
+		case listing.FacetPrice:
+			others.MinPrice, others.MaxPrice = nil, nil
+		case listing.FacetType:
+			others.Types = nil
+		case listing.FacetCity:
+			others.Cities = nil
+		case listing.FacetRating:
+			others.MinRating = nil
+		case listing.FacetGuests:
+			others.Guests = nil
+		case listing.FacetAmenities:
+			others.Amenities = nil
+		}
+		var matches []Entities.Unit
+		for _, unit := range units {
+			if others.Matches(unit) {
+				matches = append(matches, unit)
+			}
+		}
+		return matches
+	}
+
+	facets := listing.Facets{}
+	grouped := []struct {
+		facet  string
+		values func(Entities.Unit) []string
+	}{
+		{listing.FacetType, func(unit Entities.Unit) []string { return []string{unit.PropertyType} }},
+		{listing.FacetCity, func(unit Entities.Unit) []string { return []string{unit.Address.City} }},
+		{listing.FacetAmenities, listing.Amenities},
+	}
+	for _, g := range grouped {
+		counts := map[string]int{}
+		for _, unit := range without(g.facet) {
+			for _, value := range g.values(unit) {
+				if value != "" {
+					counts[value]++
+				}
+			}
+		}
+		facets[g.facet] = listing.SortedCounts(counts)
+	}
+
+	// A unit is in the highest price bucket it reaches
+	prices := make([]int, len(listing.PriceBuckets))
+	for _, unit := range without(listing.FacetPrice) {
+		for i := len(listing.PriceBuckets) - 1; i >= 0; i-- {
+			if unit.RentalPrice >= listing.PriceBuckets[i] {
+				prices[i]++
+				break
+			}
+		}
+	}
+	facets[listing.FacetPrice] = listing.PriceFacet(prices)
+
+	stepped := []struct {
+		facet string
+		value func(Entities.Unit) float64
+		steps []int
+	}{
+		{listing.FacetRating, func(unit Entities.Unit) float64 { return float64(unit.Rating) }, listing.RatingSteps},
+		{listing.FacetGuests, func(unit Entities.Unit) float64 { return float64(listing.Guests(unit)) }, listing.GuestSteps},
+	}
+	for _, s := range stepped {
+		counts := make([]int, len(s.steps))
+		for _, unit := range without(s.facet) {
+			for i, step := range s.steps {
+				if s.value(unit) >= float64(step) {
+					counts[i]++
+				}
+			}
+		}
+		facets[s.facet] = listing.StepFacet(s.steps, counts)
+	}
+	return facets
+}
+
+// SearchByAddress matches any of the given parts of address anywhere in the unit's address, ignoring case
+func (repo *Units) SearchByAddress(ctx context.Context, address Entities.Address) ([]Entities.Unit, error) {
+	var parts []string
+	for _, part := range []string{address.PostalCode, address.Country, address.State, address.City, address.Street} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, strings.ToLower(part))
+		}
+	}
+	return repo.list(func(unit Entities.Unit) bool {
+		if len(parts) == 0 {
+			return true
+		}
+		found := repo.s.addresses[unit.AddressID]
+		for _, part := range parts {
+			for _, value := range []string{found.PostalCode, found.Country, found.State, found.City, found.Street} {
+				if strings.Contains(strings.ToLower(value), part) {
+					return true
+				}
+			}
+		}
+		return false
+	}), nil
+}
+
+func (repo *Units) Create(ctx context.Context, unit *Entities.Unit) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	property, ok := repo.s.properties[unit.PropertyID]
+	if !ok || property.DeleteTime != nil {
+		return repository.ErrNotFound
+	}
+	unit.AddressID = property.AddressID
+	unit.UnitID = repo.s.nextID()
+	unit.CreateTime, unit.Version = time.Now().UTC(), 1
+	repo.s.units[unit.UnitID] = repo.stored(*unit)
+	repo.insertImages(unit.UnitID, unit.Images)
+	return nil
+}
+
+func (repo *Units) Update(ctx context.Context, unit Entities.Unit) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.units[unit.UnitID]
+	if !ok || existing.Version != unit.Version {
+		return repository.ErrVersionConflict
+	}
+	unit.CreateTime, unit.Version = existing.CreateTime, existing.Version+1
+	repo.s.units[unit.UnitID] = repo.stored(unit)
+	if unit.Images != nil {
+		for id, image := range repo.s.images {
+			if image.UnitID == unit.UnitID && image.Type == "Unit" {
+				delete(repo.s.images, id)
+			}
+		}
+		repo.insertImages(unit.UnitID, unit.Images)
+	}
+	if unit.AddressID != "" {
+		unit.Address.AddressID = unit.AddressID
+		repo.s.addresses[unit.AddressID] = unit.Address
+	}
+	return nil
+}
+
+// stored strips what lives in other tables
+func (repo *Units) stored(unit Entities.Unit) Entities.Unit {
+	unit.Address = Entities.Address{}
+	unit.PropertyType, unit.OwnerName = "", ""
+	unit.Amenities = append([]string{}, unit.Amenities...)
+	unit.Images = nil
+	return unit
+}
+
+func (repo *Units) insertImages(unitID string, images [][]byte) {
+	for _, image := range images {
+		id := repo.s.nextID()
+		repo.s.images[id] = Entities.Image{ImageID: id, UnitID: unitID, Image: string(image), Type: "Unit"}
+	}
+}
+
+func (repo *Units) Delete(ctx context.Context, unitID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	unit, ok := repo.s.units[unitID]
+	if !ok || unit.DeleteTime != nil {
+		return repository.ErrNotFound
+	}
+	if repo.s.hasUpcomingBookings(func(booking Entities.Booking, _ Entities.Unit, _ Entities.Property) bool {
+		return booking.UnitID == unitID
+	}) {
+		return repository.ErrUpcomingBookings
+	}
+	unit.DeleteTime = deletionTime()
+	repo.s.units[unitID] = unit
+	return nil
+}
+
+func (repo *Units) Restore(ctx context.Context, unitID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	unit, ok := repo.s.units[unitID]
+	if !ok || unit.DeleteTime == nil {
+		return repository.ErrNotFound
+	}
+	if unit.ArchiveTime != nil || repo.s.properties[unit.PropertyID].DeleteTime != nil {
+		return repository.ErrNotRestorable
+	}
+	unit.DeleteTime = nil
+	repo.s.units[unitID] = unit
+	return nil
+}
+
+func (repo *Units) ListImages(ctx context.Context, unitID string) ([]Entities.Image, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.images, func(image Entities.Image) bool { return image.UnitID == unitID && image.Type == "Unit" }), nil
+}
+
+func (repo *Units) SaveImages(ctx context.Context, unitID string, images []string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing := sorted(repo.s.images, func(image Entities.Image) bool { return image.UnitID == unitID && image.Type == "Unit" })
+	for i, image := range images {
+		if i < len(existing) && i < 4 {
+			existing[i].Image = image
+			repo.s.images[existing[i].ImageID] = existing[i]
+			continue
+		}
+		id := repo.s.nextID()
+		repo.s.images[id] = Entities.Image{ImageID: id, UnitID: unitID, Image: image, Type: "Unit"}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Users struct {
+	s *store
+}
+
+func (repo *Users) read(user Entities.User) Entities.User {
+	user.Address = repo.s.addresses[user.AddressID]
+	return user
+}
+
+func (repo *Users) GetByID(ctx context.Context, userID string) (Entities.User, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	user, ok := repo.s.users[userID]
+	if !ok || user.DeleteTime != nil {
+		return Entities.User{}, repository.ErrNotFound
+	}
+	return repo.read(user), nil
+}
+
+func (repo *Users) GetByEmail(ctx context.Context, email string) (Entities.User, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	for _, user := range repo.s.users {
+		if user.Email == email && user.DeleteTime == nil {
+			return repo.read(user), nil
+		}
+	}
+	return Entities.User{}, repository.ErrNotFound
+}
+
+func (repo *Users) List(ctx context.Context) ([]Entities.User, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	users := sorted(repo.s.users, func(user Entities.User) bool { return user.DeleteTime == nil })
+	for i := range users {
+		users[i] = repo.read(users[i])
+	}
+	return users, nil
+}
+
+// emailTaken reports whether another live user has the email, deleted users free theirs like the partial
+// unique index. Callers hold the lock.
+func (repo *Users) emailTaken(email, userID string) bool {
+	for _, other := range repo.s.users {
+		if other.Email == email && other.UserID != userID && other.DeleteTime == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (repo *Users) Create(ctx context.Context, user *Entities.User) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if repo.emailTaken(user.Email, "") {
+		return repository.ErrDuplicate
+	}
+	user.Address.AddressID = ""
+	repo.s.saveAddress(&user.Address)
+	user.AddressID = user.Address.AddressID
+	user.UserID = repo.s.nextID()
+	user.CreateTime, user.Version = time.Now().UTC(), 1
+	repo.s.users[user.UserID] = *user
+	return nil
+}
+
+func (repo *Users) Update(ctx context.Context, user Entities.User) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	existing, ok := repo.s.users[user.UserID]
+	if !ok || existing.Version != user.Version {
+		return repository.ErrVersionConflict
+	}
+	if repo.emailTaken(user.Email, user.UserID) {
+		return repository.ErrDuplicate
+	}
+	existing.Version++
+	existing.Name, existing.PhoneNumber, existing.Email, existing.UserRole = user.Name, user.PhoneNumber, user.Email, user.UserRole
+	repo.s.users[user.UserID] = existing
+	if existing.AddressID != "" {
+		user.Address.AddressID = existing.AddressID
+		repo.s.addresses[existing.AddressID] = user.Address
+	}
+	return nil
+}
+
+func (repo *Users) Delete(ctx context.Context, userID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	user, ok := repo.s.users[userID]
+	if !ok || user.DeleteTime != nil {
+		return repository.ErrNotFound
+	}
+	if repo.s.hasUpcomingBookings(func(booking Entities.Booking, _ Entities.Unit, property Entities.Property) bool {
+		return booking.UserID == userID || property.OwnerID == userID
+	}) {
+		return repository.ErrUpcomingBookings
+	}
+	now := deletionTime()
+	user.DeleteTime = now
+	repo.s.users[userID] = user
+	for _, property := range repo.s.properties {
+		if property.OwnerID == userID && property.DeleteTime == nil {
+			repo.s.deleteProperty(property, now)
+		}
+	}
+	return nil
+}
+
+func (repo *Users) Restore(ctx context.Context, userID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	user, ok := repo.s.users[userID]
+	if !ok || user.DeleteTime == nil {
+		return repository.ErrNotFound
+	}
+	// Another user can have signed up with the email since
+	if repo.emailTaken(user.Email, userID) {
+		return repository.ErrDuplicate
+	}
+	for _, property := range repo.s.properties {
+		if property.OwnerID == userID && property.ArchiveTime == nil && sameTime(property.DeleteTime, user.DeleteTime) {
+			repo.s.restoreProperty(property)
+		}
+	}
+	user.DeleteTime = nil
+	repo.s.users[userID] = user
+	return nil
+}
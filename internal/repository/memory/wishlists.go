@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"GraduationProject.com/m/internal/repository"
+)
+
+type Wishlists struct {
+	s *store
+}
+
+// read copies the items and fills in the current name and price of each unit, deleted units are left out
+func (repo *Wishlists) read(wishlist Entities.Wishlist) Entities.Wishlist {
+	items := []Entities.WishlistItem{}
+	for _, item := range wishlist.Items {
+		unit := repo.s.units[item.UnitID]
+		if unit.DeleteTime != nil {
+			continue
+		}
+		item.Name, item.RentalPrice = unit.Name, unit.RentalPrice
+		items = append(items, item)
+	}
+	wishlist.Items = items
+	return wishlist
+}
+
+func (repo *Wishlists) find(keep func(Entities.Wishlist) bool) (Entities.Wishlist, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	found := sorted(repo.s.wishlists, keep)
+	if len(found) == 0 {
+		return Entities.Wishlist{}, repository.ErrNotFound
+	}
+	return repo.read(found[0]), nil
+}
+
+func (repo *Wishlists) GetByID(ctx context.Context, wishlistID string) (Entities.Wishlist, error) {
+	return repo.find(func(wishlist Entities.Wishlist) bool { return wishlist.WishlistID == wishlistID })
+}
+
+func (repo *Wishlists) GetByShareToken(ctx context.Context, token string) (Entities.Wishlist, error) {
+	return repo.find(func(wishlist Entities.Wishlist) bool { return token != "" && wishlist.ShareToken == token })
+}
+
+func (repo *Wishlists) ListByUser(ctx context.Context, userID string) ([]Entities.Wishlist, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	wishlists := sorted(repo.s.wishlists, func(wishlist Entities.Wishlist) bool { return wishlist.UserID == userID })
+	for i := range wishlists {
+		wishlists[i] = repo.read(wishlists[i])
+	}
+	return wishlists, nil
+}
+
+func (repo *Wishlists) ListUserIDsByUnit(ctx context.Context, unitID string) ([]string, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	var userIDs []string
+	seen := make(map[string]bool)
+	for _, wishlist := range sorted(repo.s.wishlists, nil) {
+		for _, item := range wishlist.Items {
+			if item.UnitID == unitID && !seen[wishlist.UserID] {
+				seen[wishlist.UserID] = true
+				userIDs = append(userIDs, wishlist.UserID)
+			}
+		}
+	}
+	return userIDs, nil
+}
+
+func (repo *Wishlists) Create(ctx context.Context, wishlist *Entities.Wishlist) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	wishlist.WishlistID = repo.s.nextID()
+	wishlist.CreateTime = time.Now().UTC()
+	wishlist.ShareToken = ""
+	wishlist.Items = []Entities.WishlistItem{}
+	repo.s.wishlists[wishlist.WishlistID] = *wishlist
+	return nil
+}
+
+// update applies change to the stored wishlist, doing nothing when it does not exist
+func (repo *Wishlists) update(wishlistID string, change func(*Entities.Wishlist)) {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if wishlist, ok := repo.s.wishlists[wishlistID]; ok {
+		change(&wishlist)
+		repo.s.wishlists[wishlistID] = wishlist
+	}
+}
+
+func (repo *Wishlists) Rename(ctx context.Context, wishlistID, name string) error {
+	repo.update(wishlistID, func(wishlist *Entities.Wishlist) { wishlist.Name = name })
+	return nil
+}
+
+func (repo *Wishlists) Delete(ctx context.Context, wishlistID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.wishlists[wishlistID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.wishlists, wishlistID)
+	return nil
+}
+
+func (repo *Wishlists) AddUnit(ctx context.Context, wishlistID, unitID string) error {
+	repo.update(wishlistID, func(wishlist *Entities.Wishlist) {
+		for _, item := range wishlist.Items {
+			if item.UnitID == unitID {
+				return
+			}
+		}
+		wishlist.Items = append(append([]Entities.WishlistItem{}, wishlist.Items...), Entities.WishlistItem{UnitID: unitID, CreateTime: time.Now().UTC()})
+	})
+	return nil
+}
+
+func (repo *Wishlists) RemoveUnit(ctx context.Context, wishlistID, unitID string) error {
+	repo.update(wishlistID, func(wishlist *Entities.Wishlist) {
+		items := []Entities.WishlistItem{}
+		for _, item := range wishlist.Items {
+			if item.UnitID != unitID {
+				items = append(items, item)
+			}
+		}
+		wishlist.Items = items
+	})
+	return nil
+}
+
+func (repo *Wishlists) SetShareToken(ctx context.Context, wishlistID, token string) error {
+	repo.update(wishlistID, func(wishlist *Entities.Wishlist) {
+		if token == "" || wishlist.ShareToken == "" {
+			wishlist.ShareToken = token
+		}
+	})
+	return nil
+}
+
+type SavedSearches struct {
+	s *store
+}
+
+func (repo *SavedSearches) List(ctx context.Context) ([]Entities.SavedSearch, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.savedSearches, nil), nil
+}
+
+func (repo *SavedSearches) ListByUser(ctx context.Context, userID string) ([]Entities.SavedSearch, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	return sorted(repo.s.savedSearches, func(search Entities.SavedSearch) bool { return search.UserID == userID }), nil
+}
+
+func (repo *SavedSearches) Create(ctx context.Context, search *Entities.SavedSearch) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	search.SavedSearchID = repo.s.nextID()
+	search.CreateTime = time.Now().UTC()
+	repo.s.savedSearches[search.SavedSearchID] = *search
+	return nil
+}
+
+func (repo *SavedSearches) Delete(ctx context.Context, savedSearchID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	if _, ok := repo.s.savedSearches[savedSearchID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.s.savedSearches, savedSearchID)
+	return nil
+}
+
+type Notifications struct {
+	s *store
+}
+
+// ListByUser returns the newest notifications first, at most 100 of them like the SQL repository
+func (repo *Notifications) ListByUser(ctx context.Context, userID string, unreadOnly bool) ([]Entities.Notification, error) {
+	repo.s.mu.RLock()
+	defer repo.s.mu.RUnlock()
+	all := sorted(repo.s.notifications, func(notification Entities.Notification) bool {
+		return notification.UserID == userID && (!unreadOnly || notification.ReadTime == nil)
+	})
+	notifications := []Entities.Notification{}
+	for i := len(all) - 1; i >= 0 && len(notifications) < 100; i-- {
+		notifications = append(notifications, all[i])
+	}
+	return notifications, nil
+}
+
+func (repo *Notifications) Create(ctx context.Context, notification *Entities.Notification) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	notification.NotificationID = repo.s.nextID()
+	notification.CreateTime = time.Now().UTC()
+	repo.s.notifications[notification.NotificationID] = *notification
+	return nil
+}
+
+func (repo *Notifications) MarkRead(ctx context.Context, notificationID string) error {
+	repo.s.mu.Lock()
+	defer repo.s.mu.Unlock()
+	notification, ok := repo.s.notifications[notificationID]
+	if !ok || notification.ReadTime != nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now().UTC()
+	notification.ReadTime = &now
+	repo.s.notifications[notificationID] = notification
+	return nil
+}
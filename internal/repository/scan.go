@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	Entities "GraduationProject.com/m/internal/model"
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey holds the transaction of InTx in the context
+type txKey struct{}
+
+// conn returns the transaction the repository is called in by InTx, or db outside of one
+func conn(ctx context.Context, db *sql.DB) queryer {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// nullTimeColumn scans a nullable DATETIME column, leaving the pointer nil for NULL
+type nullTimeColumn struct {
+	t **time.Time
+}
+
+func (c nullTimeColumn) Scan(value interface{}) error {
+	var t sql.NullTime
+	if err := t.Scan(value); err != nil {
+		return err
+	}
+	if !t.Valid {
+		*c.t = nil
+		return nil
+	}
+	*c.t = &t.Time
+	return nil
+}
+
+// nullString stores an empty string as NULL, for optional foreign keys and tokens
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func insertedID(result sql.Result) (string, error) {
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// affectedOne turns an update or delete that matched no row into ErrNotFound
+func affectedOne(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// versioned turns an update on the condition of the version that matched no row into ErrVersionConflict. The
+// entity was read just before, so another update changed its version, or it was deleted since.
+func versioned(result sql.Result, err error) error {
+	if err = affectedOne(result, err); err == ErrNotFound {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate maps unique key violations to ErrDuplicate
+func duplicate(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrDuplicate
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+const addressColumns = `a.AddressID, a.Country, a.City, a.State, a.Street, a.PostalCode, a.AdditionalNumber, a.MapLocation, a.Latitude, a.Longitude`
+
+// addressFields returns the scan targets matching addressColumns. Rows without an address scan as an empty one.
+func addressFields(address *Entities.Address) []interface{} {
+	return []interface{}{
+		(*nullableString)(&address.AddressID), (*nullableString)(&address.Country), (*nullableString)(&address.City),
+		(*nullableString)(&address.State), (*nullableString)(&address.Street), (*nullableString)(&address.PostalCode),
+		(*nullableString)(&address.AdditionalNumber), (*nullableString)(&address.MapLocation), &address.Latitude, &address.Longitude,
+	}
+}
+
+// nullableString scans NULL as an empty string
+type nullableString string
+
+func (s *nullableString) Scan(value interface{}) error {
+	var ns sql.NullString
+	if err := ns.Scan(value); err != nil {
+		return err
+	}
+	*s = nullableString(ns.String)
+	return nil
+}
+
+func insertAddress(ctx context.Context, db execer, address *Entities.Address) error {
+	result, err := db.ExecContext(ctx, `INSERT INTO Address (Country, City, State, Street, PostalCode, AdditionalNumber, MapLocation, Latitude, Longitude) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		address.Country, address.City, address.State, address.Street, address.PostalCode, address.AdditionalNumber, address.MapLocation, address.Latitude, address.Longitude)
+	if err != nil {
+		return err
+	}
+	address.AddressID, err = insertedID(result)
+	return err
+}
+
+func updateAddress(ctx context.Context, db execer, address Entities.Address) error {
+	if address.AddressID == "" {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `UPDATE Address SET Country = ?, City = ?, State = ?, Street = ?, PostalCode = ?, AdditionalNumber = ?, MapLocation = ?, Latitude = ?, Longitude = ? WHERE AddressID = ?`,
+		address.Country, address.City, address.State, address.Street, address.PostalCode, address.AdditionalNumber, address.MapLocation, address.Latitude, address.Longitude, address.AddressID)
+	return err
+}
+
+// inTx runs fn in a transaction, committing when it returns nil. Called by InTx, fn runs in its transaction,
+// which commits or rolls back with the rest of it.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqlTransactor struct {
+	db *sql.DB
+}
+
+// InTx reads what is committed at each statement rather than at the first one, so the checks a write makes
+// after locking its rows see every commit before the lock, like in a transaction of the write's own
+func (t sqlTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deletionTime is stamped on a row and on everything deleted along with it, so Restore can tell them apart
+// from what was deleted on its own. Whole seconds compare equal after a round trip through either database.
+func deletionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// lockRows takes the write lock of the rows of table that match where until the transaction ends, by an
+// update that changes nothing. It stands in for SELECT ... FOR UPDATE, which SQLite does not have, and on
+// SQLite it takes the database's write lock. Transactions that check bookings and then write lock the
+// users before the units, so they wait for each other instead of deadlocking.
+func lockRows(ctx context.Context, tx *sql.Tx, table, key, where string, args ...interface{}) error {
+	_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET `+key+` = `+key+` WHERE `+where, args...)
+	return err
+}
+
+// noUpcomingBookings returns ErrUpcomingBookings when a booking matching where has not ended yet.
+// where can use b for the booking, u for its unit and p for the unit's property. The units the bookings can
+// be of, and for a user the user, must be locked with lockRows first, or a booking could be made after the check.
+func noUpcomingBookings(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM Booking b JOIN Unit u ON b.UnitID = u.UnitID JOIN Property p ON u.PropertyID = p.PropertyID WHERE b.EndDate > ? AND (`+where+`)`,
+		append([]interface{}{time.Now().UTC()}, args...)...).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUpcomingBookings
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Kind string
+
+const (
+	KindUnit     Kind = "unit"
+	KindProperty Kind = "property"
+)
+
+// Fields that are indexed for every document, together with how much a match in that field is worth
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldCity        = "city"
+	FieldAmenities   = "amenities"
+	FieldRules       = "rules"
+)
+
+var fieldBoost = map[string]float64{
+	FieldName:        3,
+	FieldCity:        2,
+	FieldAmenities:   1.5,
+	FieldDescription: 1,
+	FieldRules:       1,
+}
+
+// Document is one unit or property as the index sees it
+type Document struct {
+	Kind   Kind
+	ID     string
+	Fields map[string]string
+	Source interface{} // Returned as-is with every hit
+}
+
+type Query struct {
+	Text     string
+	Kinds    []Kind   // Empty means every kind
+	Fields   []string // Empty means every field
+	Page     int
+	PageSize int
+}
+
+type Hit struct {
+	Kind       Kind              `json:"kind"`
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+	Data       interface{}       `json:"data"`
+}
+
+type Result struct {
+	Total    int   `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"pageSize"`
+	Hits     []Hit `json:"hits"`
+}
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type docKey struct {
+	kind Kind
+	id   string
+}
+
+type indexedDoc struct {
+	doc    Document
+	tokens map[string][]token // field -> tokens in order
+	length int
+}
+
+// Index is an in-memory inverted index over units and properties, safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[docKey]*indexedDoc
+	postings map[string]map[docKey]map[string]int // term -> document -> field -> term frequency
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[docKey]*indexedDoc),
+		postings: make(map[string]map[docKey]map[string]int),
+	}
+}
+
+// Put adds the document to the index or replaces the previous version of it
+func (index *Index) Put(doc Document) {
+	indexed := newIndexedDoc(doc)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.add(indexed)
+}
+
+func (index *Index) Delete(kind Kind, id string) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	index.remove(docKey{kind, id})
+}
+
+// Replace drops every document of the given kind and indexes docs instead. Searches see either the old
+// documents or the new ones, never an index without the kind.
+func (index *Index) Replace(kind Kind, docs []Document) {
+	indexed := make([]*indexedDoc, 0, len(docs))
+	for _, doc := range docs {
+		indexed = append(indexed, newIndexedDoc(doc))
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for key := range index.docs {
+		if key.kind == kind {
+			index.remove(key)
+		}
+	}
+	for _, doc := range indexed {
+		index.add(doc)
+	}
+}
+
+// newIndexedDoc tokenizes the fields of doc, which needs no lock
+func newIndexedDoc(doc Document) *indexedDoc {
+	indexed := &indexedDoc{doc: doc, tokens: make(map[string][]token)}
+	for field, text := range doc.Fields {
+		tokens := tokenize(text)
+		indexed.tokens[field] = tokens
+		indexed.length += len(tokens)
+	}
+	return indexed
+}
+
+// add puts the document in the postings in place of its previous version, index.mu must be held
+func (index *Index) add(indexed *indexedDoc) {
+	key := docKey{indexed.doc.Kind, indexed.doc.ID}
+	index.remove(key)
+	index.docs[key] = indexed
+	for field, tokens := range indexed.tokens {
+		for _, t := range tokens {
+			docs, ok := index.postings[t.term]
+			if !ok {
+				docs = make(map[docKey]map[string]int)
+				index.postings[t.term] = docs
+			}
+			fields, ok := docs[key]
+			if !ok {
+				fields = make(map[string]int)
+				docs[key] = fields
+			}
+			fields[field]++
+		}
+	}
+}
+
+func (index *Index) Len() int {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	return len(index.docs)
+}
+
+func (index *Index) remove(key docKey) {
+	old, ok := index.docs[key]
+	if !ok {
+		return
+	}
+	for _, tokens := range old.tokens {
+		for _, t := range tokens {
+			if docs, ok := index.postings[t.term]; ok {
+				delete(docs, key)
+				if len(docs) == 0 {
+					delete(index.postings, t.term)
+				}
+			}
+		}
+	}
+	delete(index.docs, key)
+}
+
+// Search ranks documents by a tf-idf score weighted per field. Query terms that are not in the index
+// are matched against similar terms, so small typos still find results, but those matches score lower.
+func (index *Index) Search(q Query) Result {
+	page, pageSize := normalizePaging(q.Page, q.PageSize)
+	result := Result{Page: page, PageSize: pageSize, Hits: []Hit{}}
+
+	terms := tokenize(q.Text)
+	if len(terms) == 0 {
+		return result
+	}
+
+	kinds := make(map[Kind]bool)
+	for _, kind := range q.Kinds {
+		kinds[kind] = true
+	}
+	fields := make(map[string]bool)
+	for _, field := range q.Fields {
+		fields[field] = true
+	}
+
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	scores := make(map[docKey]float64)
+	matched := make(map[docKey]map[string]bool) // document -> matched index terms
+	total := float64(len(index.docs))
+
+	for _, queryTerm := range terms {
+		for term, distance := range index.expand(queryTerm.term) {
+			docs := index.postings[term]
+			idf := math.Log(1 + total/float64(len(docs)))
+			similarity := 1 / float64(1+distance)
+			for key, fieldFreq := range docs {
+				if len(kinds) > 0 && !kinds[key.kind] {
+					continue
+				}
+				var score float64
+				for field, freq := range fieldFreq {
+					if len(fields) > 0 && !fields[field] {
+						continue
+					}
+					score += fieldBoost[field] * (1 + math.Log(float64(freq)))
+				}
+				if score == 0 {
+					continue
+				}
+				// Normalize by length so that long descriptions do not drown out short names
+				length := float64(index.docs[key].length)
+				scores[key] += score * idf * similarity / math.Sqrt(1+length/10)
+				if matched[key] == nil {
+					matched[key] = make(map[string]bool)
+				}
+				matched[key][term] = true
+			}
+		}
+	}
+
+	keys := make([]docKey, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].id < keys[j].id
+	})
+
+	result.Total = len(keys)
+	start := (page - 1) * pageSize
+	if start >= len(keys) {
+		return result
+	}
+	end := start + pageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+	for _, key := range keys[start:end] {
+		doc := index.docs[key]
+		result.Hits = append(result.Hits, Hit{
+			Kind:       key.kind,
+			ID:         key.id,
+			Score:      math.Round(scores[key]*1000) / 1000,
+			Highlights: highlight(doc, matched[key], fields),
+			Data:       doc.doc.Source,
+		})
+	}
+	return result
+}
+
+// expand returns the index terms that match the query term, with their edit distance from it.
+// The last resort is a prefix match, so "riy" still finds "riyadh" while the user is typing.
+func (index *Index) expand(queryTerm string) map[string]int {
+	terms := make(map[string]int)
+	if _, ok := index.postings[queryTerm]; ok {
+		terms[queryTerm] = 0
+	}
+	maxDistance := allowedDistance(queryTerm)
+	for term := range index.postings {
+		if term == queryTerm {
+			continue
+		}
+		if maxDistance > 0 {
+			if d := levenshtein(queryTerm, term, maxDistance); d <= maxDistance {
+				terms[term] = d
+				continue
+			}
+		}
+		if len([]rune(queryTerm)) >= 3 && strings.HasPrefix(term, queryTerm) {
+			terms[term] = 1
+		}
+	}
+	return terms
+}
+
+func allowedDistance(term string) int {
+	switch n := len([]rune(term)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func normalizePaging(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRanking(t *testing.T) {
+	riyadh := []Document{{Kind: KindUnit, ID: "a", Fields: map[string]string{FieldName: "Riyadh apartment"}}}
+	tests := []struct {
+		name  string
+		docs  []Document
+		query Query
+		want  []string
+	}{
+		{
+			name: "a name match outranks a description match",
+			docs: []Document{
+				{Kind: KindUnit, ID: "description", Fields: map[string]string{FieldName: "Flat", FieldDescription: "pool"}},
+				{Kind: KindUnit, ID: "name", Fields: map[string]string{FieldName: "Pool"}},
+			},
+			query: Query{Text: "pool"},
+			want:  []string{"name", "description"},
+		},
+		{
+			name: "an exact match outranks a typo",
+			docs: []Document{
+				{Kind: KindUnit, ID: "typo", Fields: map[string]string{FieldName: "Poll house"}},
+				{Kind: KindUnit, ID: "exact", Fields: map[string]string{FieldName: "Pool house"}},
+			},
+			query: Query{Text: "pool"},
+			want:  []string{"exact", "typo"},
+		},
+		{
+			name: "ties are broken by ID",
+			docs: []Document{
+				{Kind: KindUnit, ID: "2", Fields: map[string]string{FieldName: "Loft"}},
+				{Kind: KindUnit, ID: "1", Fields: map[string]string{FieldName: "Loft"}},
+			},
+			query: Query{Text: "loft"},
+			want:  []string{"1", "2"},
+		},
+		{name: "one typo in a word of up to six letters", docs: riyadh, query: Query{Text: "riyad"}, want: []string{"a"}},
+		{name: "two typos in a longer word", docs: riyadh, query: Query{Text: "apartmnet"}, want: []string{"a"}},
+		{name: "a prefix while typing", docs: riyadh, query: Query{Text: "riy"}, want: []string{"a"}},
+		{name: "words of three letters have to be exact", docs: riyadh, query: Query{Text: "rii"}, want: []string{}},
+		{name: "too many typos", docs: riyadh, query: Query{Text: "ryiahd"}, want: []string{}},
+		{name: "a field that does not match", docs: riyadh, query: Query{Text: "riyadh", Fields: []string{FieldCity}}, want: []string{}},
+		{name: "a kind that does not match", docs: riyadh, query: Query{Text: "riyadh", Kinds: []Kind{KindProperty}}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := NewIndex()
+			for _, doc := range tt.docs {
+				index.Put(doc)
+			}
+			result := index.Search(tt.query)
+			got := []string{}
+			for _, hit := range result.Hits {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if result.Total != len(tt.want) {
+				t.Errorf("got total %d, want %d", result.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestHighlightsAreEscaped(t *testing.T) {
+	index := NewIndex()
+	index.Put(Document{Kind: KindUnit, ID: "1", Fields: map[string]string{
+		FieldDescription: `Sea view <img src=x onerror="alert(1)"> & a <b>pool</b>`,
+	}})
+
+	result := index.Search(Query{Text: "pool"})
+	if len(result.Hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(result.Hits))
+	}
+	want := `Sea view &lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; a &lt;b&gt;<mark>pool</mark>&lt;/b&gt;`
+	if got := result.Hits[0].Highlights[FieldDescription]; got != want {
+		t.Errorf("got highlight %q, want %q", got, want)
+	}
+}
+
+func TestReplaceIsAtomic(t *testing.T) {
+	docs := make([]Document, 200)
+	for i := range docs {
+		docs[i] = Document{Kind: KindUnit, ID: strconv.Itoa(i), Fields: map[string]string{FieldName: "Garden flat"}}
+	}
+	index := NewIndex()
+	index.Replace(KindUnit, docs)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			index.Replace(KindUnit, docs)
+		}
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if total := index.Search(Query{Text: "garden"}).Total; total != len(docs) {
+			t.Fatalf("a search during Replace found %d documents, want %d", total, len(docs))
+		}
+	}
+	wg.Wait()
+}
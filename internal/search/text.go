@@ -0,0 +1,160 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+	snippetRunes   = 160
+)
+
+type token struct {
+	term       string
+	start, end int // Byte offsets in the original text
+}
+
+// tokenize splits text into lower case words made of letters and digits, keeping where each word came from
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// levenshtein returns the edit distance between a and b, or max+1 as soon as it is clear the distance is larger than max
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// highlight builds a snippet for every field that contains one of the matched terms, with the terms wrapped in <mark>
+func highlight(doc *indexedDoc, terms map[string]bool, fields map[string]bool) map[string]string {
+	highlights := make(map[string]string)
+	for field, tokens := range doc.tokens {
+		if len(fields) > 0 && !fields[field] {
+			continue
+		}
+		var hits []token
+		for _, t := range tokens {
+			if terms[t.term] {
+				hits = append(hits, t)
+			}
+		}
+		if len(hits) > 0 {
+			highlights[field] = snippet(doc.doc.Fields[field], hits)
+		}
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// snippet cuts a window of the text around the first hit and marks every hit inside that window. The text is
+// HTML-escaped, so clients can render the snippet as HTML without running markup stored in a description.
+func snippet(text string, hits []token) string {
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetRunes {
+		from = backRunes(text, hits[0].start, snippetRunes/4)
+		to = forwardRunes(text, from, snippetRunes)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, hit := range hits {
+		if hit.start < pos || hit.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:hit.start]))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(text[hit.start:hit.end]))
+		b.WriteString(highlightClose)
+		pos = hit.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func backRunes(text string, offset, n int) int {
+	for ; n > 0 && offset > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= size
+	}
+	return offset
+}
+
+func forwardRunes(text string, offset, n int) int {
+	for ; n > 0 && offset < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -2,18 +2,77 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
 	"os"
+	_ "time/tzdata" // Property time zones work on hosts without a zoneinfo database
 
 	App "GraduationProject.com/m/cmd/api"
+	"GraduationProject.com/m/internal/config"
+	Database "GraduationProject.com/m/internal/db"
+	"GraduationProject.com/m/internal/logging"
 )
 
 func main() {
-	app := App.App{}
-	app.Initialize("root", "wgLCfSQUYtKqCGBfviHSyMRtIloljyqm", "viaduct.proxy.rlwy.net:38199", "Hotel")
-	port := os.Getenv("PORT") // Get the PORT environment variable
-	if port == "" {
-		port = "8080" // Default to 8080 if not specified
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional JSON config file, the environment overrides it")
+	migrate := flag.String("migrate", "", "manage the database schema, then exit: up, down or status")
+	steps := flag.Int("steps", 1, "number of migrations -migrate down rolls back")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	// Packages without a request to take their logger from use the default one
+	slog.SetDefault(logger)
+	// Secrets print as [REDACTED]
+	logger.Info("Configuration", slog.Any("config", cfg))
+
+	if *migrate != "" {
+		database, err := Database.InitDB(cfg.Database)
+		if err != nil {
+			logger.Error("Could not connect to the database", slog.Any("error", err))
+			os.Exit(1)
+		}
+		if err := runMigrations(database, *migrate, *steps); err != nil {
+			logger.Error("Migration failed", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
 	}
-	addr := "0.0.0.0:" + port
+
+	app := App.App{Logger: logger}
+	app.Initialize(cfg)
+	addr := "0.0.0.0:" + cfg.Port
 	app.Run(addr)
 }
+
+func runMigrations(database *Database.DBExecutor, command string, steps int) error {
+	switch command {
+	case "up":
+		applied, err := Database.MigrateUp(database)
+		slog.Info("Applied migrations", slog.Int("count", len(applied)))
+		return err
+	case "down":
+		rolledBack, err := Database.MigrateDown(database, steps)
+		slog.Info("Rolled back migrations", slog.Int("count", len(rolledBack)))
+		return err
+	case "status":
+		statuses, err := Database.MigrationStatuses(database)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedTime != nil {
+				applied = "applied " + status.AppliedTime.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-30s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown -migrate command %q, expected up, down or status", command)
+}